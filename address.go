@@ -0,0 +1,74 @@
+package rcon
+
+import (
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"net"
+	"strconv"
+)
+
+// Address returns the host and port of the server the client connects to.
+func (c *Client) Address() (string, uint16) {
+	c.addrLock.Lock()
+	defer c.addrLock.Unlock()
+
+	return c.Host, c.Port
+}
+
+// addressString returns the address of the server the client connects to in host:port form. Unix socket and named pipe
+// addresses are returned as is.
+func (c *Client) addressString() string {
+	host, port := c.Address()
+	if isLocalAddress(host) {
+		return host
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+// SetAddress changes the address of the server the client connects to. If the client is connected, it reconnects to
+// the new address. Handlers, broadcast sources and queued commands are preserved.
+//
+// A connection attempt which is already in progress can't be redirected, so the new address is used from the next
+// attempt on. Retries of Connect and automatic reconnects look up the address before every attempt.
+//
+// This is useful for game servers which move between hosts or ports after restarts.
+func (c *Client) SetAddress(host string, port uint16) error {
+	c.addrLock.Lock()
+	c.Host = host
+	c.Port = port
+	c.addrLock.Unlock()
+
+	c.log.Info("Server address changed to ", host, ":", port)
+
+	switch c.State() {
+	case StateDisconnected, StateConnecting:
+		return nil
+	}
+
+	return c.Reconnect()
+}
+
+// Reconnect closes the current connection, if any, and connects to the server again. Handlers, broadcast sources and
+// queued commands are preserved. The disconnect is reported to the DisconnectHandler as expected.
+//
+// The client keeps running across the reconnect, so the channel returned by Done is only closed if connecting again
+// fails, with the error Reconnect returns. If another call is already connecting the client, ErrConnectInProgress is
+// returned and the client is left to that call.
+func (c *Client) Reconnect() error {
+	c.log.Debug("Reconnect called")
+
+	// Stop any automatic reconnection in progress so that it doesn't race with us.
+	c.stopReconnect()
+	c.disconnectForRestart()
+
+	if err := c.Connect(); err != nil {
+		if errors.Cause(err) != errs.ErrConnectInProgress {
+			c.markDone(err)
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package rcon
+
+import (
+	"context"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAddress(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Reconnect", func() {
+		g.It("Should keep the client running across reconnects", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			result := make(chan error, 1)
+			go func() {
+				result <- c.ListenAndServeBroadcasts(ctx)
+			}()
+
+			Eventually(c.State).Should(Equal(StateConnected))
+			done := c.Done()
+
+			Expect(c.Reconnect()).To(Succeed())
+			Expect(c.SetAddress(c.Address())).To(Succeed())
+			Consistently(result, time.Millisecond*100).ShouldNot(Receive())
+			Expect(done).NotTo(BeClosed())
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+
+			cancel()
+			Eventually(result).Should(Receive(Equal(context.Canceled)))
+		})
+
+		g.It("Should stop the client if reconnecting fails", func() {
+			s := newTestServer(echoHandler)
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+
+			Expect(s.Close()).To(Succeed())
+			Expect(c.Reconnect()).ToNot(Succeed())
+			Expect(c.Done()).To(BeClosed())
+			Expect(c.Err()).To(HaveOccurred())
+		})
+	})
+
+	g.Describe("SetAddress", func() {
+		g.It("Should use the new address for the next attempt while connecting", func() {
+			// The old address accepts connections but never answers the auth request.
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			defer l.Close()
+
+			go func() {
+				for {
+					conn, err := l.Accept()
+					if err != nil {
+						return
+					}
+					defer conn.Close()
+				}
+			}()
+
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{
+				ConnTimeout:         time.Millisecond * 200,
+				RetryInitialConnect: true,
+				ReconnectPolicy:     &ReconnectPolicy{InitialDelay: time.Millisecond, MaxAttempts: 3},
+			})
+			defer c.Close()
+
+			host, port := c.Address()
+			Expect(c.SetAddress("127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port))).To(Succeed())
+
+			result := make(chan error, 1)
+			go func() {
+				result <- c.Connect()
+			}()
+
+			Eventually(c.State).Should(Equal(StateConnecting))
+			Expect(c.SetAddress(host, port)).To(Succeed())
+			Expect(c.Done()).NotTo(BeClosed())
+
+			Eventually(result, time.Second).Should(Receive(BeNil()))
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+		})
+	})
+}
@@ -0,0 +1,128 @@
+package rcon
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultAlertDebounce is the minimum time between two alerts for the same condition if AlertDebounce is zero.
+const DefaultAlertDebounce = time.Minute * 5
+
+// AlertCondition identifies the condition an Alert was raised for.
+type AlertCondition string
+
+const (
+	// AlertAuthFailed is raised when the server rejected the password.
+	AlertAuthFailed = AlertCondition("auth_failed")
+
+	// AlertCircuitOpen is raised when the reconnect circuit breaker opened after too many failed attempts.
+	AlertCircuitOpen = AlertCondition("circuit_open")
+
+	// AlertBroadcastSilence is raised when no broadcasts were received even after resubscribing.
+	AlertBroadcastSilence = AlertCondition("broadcast_silence")
+
+	// AlertHeartbeatFailing is raised when several consecutive heartbeats were missed.
+	AlertHeartbeatFailing = AlertCondition("heartbeat_failing")
+)
+
+// alertHeartbeatMisses is the number of consecutive missed heartbeats which raise AlertHeartbeatFailing. A single
+// missed heartbeat is too common to be worth an alert.
+const alertHeartbeatMisses = 2
+
+// Alert describes a significant condition which an operator should be notified of.
+type Alert struct {
+	Condition AlertCondition
+	Address   string
+	Message   string
+	Err       error
+	Time      time.Time
+
+	// Details holds condition specific context, such as the number of missed heartbeats, for display in alerts.
+	Details map[string]string
+}
+
+// Alerter is notified of significant conditions, so that they can be forwarded to external alerting systems such as
+// PagerDuty or Slack. Alerts for the same condition are debounced by the client.
+type Alerter interface {
+	Alert(alert Alert)
+}
+
+// AlerterFunc is an adapter allowing an ordinary function to be used as an Alerter.
+type AlerterFunc func(alert Alert)
+
+func (f AlerterFunc) Alert(alert Alert) {
+	f(alert)
+}
+
+// alertDebouncer suppresses alerts for a condition which was alerted on recently.
+type alertDebouncer struct {
+	lock sync.Mutex
+	last map[AlertCondition]time.Time
+}
+
+// allow returns true if no alert for condition was allowed within window of now, and records now if so.
+func (d *alertDebouncer) allow(condition AlertCondition, now time.Time, window time.Duration) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if last, ok := d.last[condition]; ok && now.Sub(last) < window {
+		return false
+	}
+
+	if d.last == nil {
+		d.last = map[AlertCondition]time.Time{}
+	}
+	d.last[condition] = now
+
+	return true
+}
+
+// subscribeAlerts turns events which indicate significant conditions into alerts.
+func (c *Client) subscribeAlerts() {
+	c.events.Subscribe(func(event Event) {
+		switch e := event.(type) {
+		case AuthFailedEvent:
+			c.raiseAlert(AlertAuthFailed, "Authentication failed", e.Err, nil)
+		case HeartbeatMissedEvent:
+			if e.Missed >= alertHeartbeatMisses || e.Missed >= c.HeartbeatFailureThreshold {
+				c.raiseAlert(AlertHeartbeatFailing, "Heartbeats are failing", e.Err, map[string]string{
+					"missed":    fmt.Sprint(e.Missed),
+					"threshold": fmt.Sprint(c.HeartbeatFailureThreshold),
+				})
+			}
+		case BroadcastInactiveEvent:
+			if e.Action == InactivityReconnect {
+				c.raiseAlert(AlertBroadcastSilence, "No broadcasts received after resubscribing", nil, map[string]string{
+					"idle": e.Idle.Round(time.Second).String(),
+				})
+			}
+		}
+	}, EventAuthFailed, EventHeartbeatMissed, EventBroadcastInactive)
+}
+
+// raiseAlert passes an alert to the Alerter, unless an alert for the same condition was raised within AlertDebounce.
+func (c *Client) raiseAlert(condition AlertCondition, message string, err error, details map[string]string) {
+	if c.Alerter == nil {
+		return
+	}
+
+	now := time.Now()
+	if !c.alerts.allow(condition, now, c.AlertDebounce) {
+		c.log.Debug("Alert ", condition, " debounced")
+		return
+	}
+
+	alert := Alert{
+		Condition: condition,
+		Address:   c.addressString(),
+		Message:   message,
+		Err:       err,
+		Time:      now,
+		Details:   details,
+	}
+
+	c.callHandler("alerter", func() {
+		c.Alerter.Alert(alert)
+	})
+}
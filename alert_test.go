@@ -0,0 +1,41 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/errs"
+	"testing"
+	"time"
+)
+
+func TestAlert(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Alerter", func() {
+		g.It("Should alert on significant conditions and debounce repeats", func() {
+			var alerts []Alert
+			c := NewClient(&Config{
+				AlertDebounce: time.Hour,
+				Alerter: AlerterFunc(func(alert Alert) {
+					alerts = append(alerts, alert)
+				}),
+			}, nil)
+
+			c.events.emit(HeartbeatMissedEvent{Missed: 1})
+			Expect(alerts).To(BeEmpty())
+
+			c.events.emit(HeartbeatMissedEvent{Missed: 2})
+			c.events.emit(HeartbeatMissedEvent{Missed: 3})
+			c.events.emit(AuthFailedEvent{Err: errs.ErrAuthentication})
+
+			Expect(alerts).To(HaveLen(2))
+			Expect(alerts[0].Condition).To(Equal(AlertHeartbeatFailing))
+			Expect(alerts[0].Details).To(HaveKeyWithValue("missed", "2"))
+			Expect(alerts[1].Condition).To(Equal(AlertAuthFailed))
+			Expect(alerts[1].Err).To(Equal(errs.ErrAuthentication))
+		})
+	})
+}
@@ -0,0 +1,31 @@
+package rcon
+
+// AuditEntry is an executed command passed to the AuditSinks.
+type AuditEntry struct {
+	HistoryEntry
+
+	// Address is the address of the server the command was executed on.
+	Address string
+}
+
+// AuditSink is a function which will be called with every executed command, such as a sink from the audit package
+// which persists commands to a database.
+type AuditSink func(entry AuditEntry)
+
+// recordAudit passes an executed command to the AuditSinks.
+func (c *Client) recordAudit(entry HistoryEntry) {
+	if len(c.AuditSinks) == 0 {
+		return
+	}
+
+	audit := AuditEntry{
+		HistoryEntry: entry,
+		Address:      c.addressString(),
+	}
+
+	for _, sink := range c.AuditSinks {
+		c.callHandler("audit sink", func() {
+			sink(audit)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Driver adapts a SQLSink to the SQL dialect of a database. The database/sql driver itself, such as
+// github.com/mattn/go-sqlite3 or github.com/jackc/pgx/v4/stdlib, is registered and opened by the caller.
+type Driver interface {
+	// Placeholder returns the bind parameter of the n-th argument of a statement, starting at 1.
+	Placeholder(n int) string
+
+	// Migrations returns the statements which create and upgrade the audit table, in order. Statements which were
+	// applied once are never applied again, so existing statements must not be changed.
+	Migrations(table string) []string
+}
+
+// SQLite is the Driver for SQLite databases.
+var SQLite Driver = sqliteDriver{}
+
+// Postgres is the Driver for PostgreSQL databases.
+var Postgres Driver = postgresDriver{}
+
+type sqliteDriver struct{}
+
+func (sqliteDriver) Placeholder(int) string {
+	return "?"
+}
+
+func (sqliteDriver) Migrations(table string) []string {
+	return []string{
+		fmt.Sprintf(`CREATE TABLE %s (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	time        TIMESTAMP NOT NULL,
+	address     TEXT NOT NULL,
+	command     TEXT NOT NULL,
+	response    TEXT NOT NULL,
+	error       TEXT,
+	initiator   TEXT NOT NULL,
+	reason      TEXT NOT NULL,
+	labels      TEXT NOT NULL,
+	priority    INTEGER NOT NULL,
+	duration_ms INTEGER NOT NULL,
+	redacted    BOOLEAN NOT NULL,
+	dry_run     BOOLEAN NOT NULL
+)`, table),
+		fmt.Sprintf(`CREATE INDEX %[1]s_time ON %[1]s (time)`, table),
+		fmt.Sprintf(`CREATE INDEX %[1]s_initiator ON %[1]s (initiator)`, table),
+	}
+}
+
+type postgresDriver struct{}
+
+func (postgresDriver) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgresDriver) Migrations(table string) []string {
+	return []string{
+		fmt.Sprintf(`CREATE TABLE %s (
+	id          BIGSERIAL PRIMARY KEY,
+	time        TIMESTAMPTZ NOT NULL,
+	address     TEXT NOT NULL,
+	command     TEXT NOT NULL,
+	response    TEXT NOT NULL,
+	error       TEXT,
+	initiator   TEXT NOT NULL,
+	reason      TEXT NOT NULL,
+	labels      JSONB NOT NULL,
+	priority    INTEGER NOT NULL,
+	duration_ms BIGINT NOT NULL,
+	redacted    BOOLEAN NOT NULL,
+	dry_run     BOOLEAN NOT NULL
+)`, table),
+		fmt.Sprintf(`CREATE INDEX %[1]s_time ON %[1]s (time)`, table),
+		fmt.Sprintf(`CREATE INDEX %[1]s_initiator ON %[1]s (initiator)`, table),
+	}
+}
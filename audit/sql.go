@@ -0,0 +1,245 @@
+// Package audit provides ready-made audit sinks which persist executed commands. Sinks can be attached to a client
+// using the AuditSinks config field.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTable is the name of the audit table if SQLSinkConfig.Table is empty.
+const DefaultTable = "rcon_audit"
+
+// DefaultQueueSize is the number of entries a SQLSink buffers if SQLSinkConfig.QueueSize is zero.
+const DefaultQueueSize = 1024
+
+// tableName matches valid table names. Table names can't be bound as parameters, so they are validated instead.
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQLSinkConfig configures a SQLSink.
+type SQLSinkConfig struct {
+	// Table is the name of the audit table. The migrations table is named after it, with a "_migrations" suffix.
+	//
+	// Default: DefaultTable
+	Table string
+
+	// Timeout limits how long writing a single entry may take.
+	//
+	// Default: 5s
+	Timeout time.Duration
+
+	// QueueSize is the number of entries buffered while they wait to be written. Once the queue is full, further entries
+	// are dropped and counted by Dropped.
+	//
+	// Default: DefaultQueueSize
+	QueueSize int
+
+	// ErrorHandler is called if an entry could not be written. If nil, errors are ignored. It is called on the sink's
+	// writer routine.
+	ErrorHandler func(error)
+}
+
+// SQLSink writes executed commands, their responses, call metadata and timestamps to a SQL database. Entries are
+// written by a background routine, so that a slow or unavailable database doesn't delay commands. It is safe for
+// concurrent use.
+type SQLSink struct {
+	db     *sql.DB
+	driver Driver
+	config SQLSinkConfig
+	insert string
+
+	queueLock sync.RWMutex
+	queue     chan rcon.AuditEntry
+	closed    bool
+	done      chan struct{}
+	dropped   uint64
+}
+
+// NewSQLSink creates a sink which writes to db, using driver for the database's SQL dialect, and starts its writer
+// routine. Migrate must be called before the first entry is recorded, and Close once the sink is no longer used.
+func NewSQLSink(db *sql.DB, driver Driver, config SQLSinkConfig) (*SQLSink, error) {
+	if config.Table == "" {
+		config.Table = DefaultTable
+	}
+
+	if !tableName.MatchString(config.Table) {
+		return nil, errors.Errorf("invalid table name %q", config.Table)
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = time.Second * 5
+	}
+
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+
+	columns := []string{"time", "address", "command", "response", "error", "initiator", "reason", "labels",
+		"priority", "duration_ms", "redacted", "dry_run"}
+
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = driver.Placeholder(i + 1)
+	}
+
+	s := &SQLSink{
+		db:     db,
+		driver: driver,
+		config: config,
+		insert: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", config.Table, strings.Join(columns, ", "),
+			strings.Join(placeholders, ", ")),
+		queue: make(chan rcon.AuditEntry, config.QueueSize),
+		done:  make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s, nil
+}
+
+// Migrate creates the audit table, or upgrades it to the latest schema. Applied migrations are tracked in a separate
+// table, so Migrate can be called every time the application starts.
+func (s *SQLSink) Migrate(ctx context.Context) error {
+	migrations := s.config.Table + "_migrations"
+
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version INTEGER PRIMARY KEY)", migrations))
+	if err != nil {
+		return errors.Wrap(err, "could not create migrations table")
+	}
+
+	var applied int
+	row := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s", migrations))
+	if err := row.Scan(&applied); err != nil {
+		return errors.Wrap(err, "could not read schema version")
+	}
+
+	statements := s.driver.Migrations(s.config.Table)
+	for version := applied + 1; version <= len(statements); version++ {
+		if err := s.migrate(ctx, migrations, version, statements[version-1]); err != nil {
+			return errors.Wrapf(err, "could not apply migration %d", version)
+		}
+	}
+
+	return nil
+}
+
+// migrate applies a single migration and records its version in the same transaction.
+func (s *SQLSink) migrate(ctx context.Context, migrations string, version int, statement string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, statement); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	record := fmt.Sprintf("INSERT INTO %s (version) VALUES (%s)", migrations, s.driver.Placeholder(1))
+	if _, err := tx.ExecContext(ctx, record, version); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Record queues an entry to be written to the database. It never blocks: if the queue is full or the sink was closed,
+// the entry is dropped and counted by Dropped. It matches the signature of rcon.AuditSink.
+func (s *SQLSink) Record(entry rcon.AuditEntry) {
+	s.queueLock.RLock()
+	defer s.queueLock.RUnlock()
+
+	if s.closed {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+
+	select {
+	case s.queue <- entry:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// Dropped returns the number of entries which were dropped because the queue was full or the sink was closed.
+func (s *SQLSink) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close stops accepting entries and waits until the queued entries were written. It doesn't close the database.
+func (s *SQLSink) Close() error {
+	s.queueLock.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.queueLock.Unlock()
+
+	<-s.done
+
+	return nil
+}
+
+// run writes queued entries until the sink is closed.
+func (s *SQLSink) run() {
+	defer close(s.done)
+
+	for entry := range s.queue {
+		if err := s.write(entry); err != nil {
+			s.handleError(errors.Wrap(err, "could not write audit entry"))
+		}
+	}
+}
+
+func (s *SQLSink) write(entry rcon.AuditEntry) error {
+	labels := entry.Metadata.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	encodedLabels, err := json.Marshal(labels)
+	if err != nil {
+		return err
+	}
+
+	var errMessage sql.NullString
+	if entry.Err != nil {
+		errMessage = sql.NullString{String: entry.Err.Error(), Valid: true}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	_, err = s.db.ExecContext(ctx, s.insert,
+		entry.Time.UTC(),
+		entry.Address,
+		entry.Command,
+		entry.Response,
+		errMessage,
+		entry.Metadata.Initiator,
+		entry.Metadata.Reason,
+		string(encodedLabels),
+		int(entry.Priority),
+		entry.Duration.Milliseconds(),
+		entry.Redacted,
+		entry.DryRun,
+	)
+
+	return err
+}
+
+func (s *SQLSink) handleError(err error) {
+	if s.config.ErrorHandler != nil {
+		s.config.ErrorHandler(err)
+	}
+}
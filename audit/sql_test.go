@@ -0,0 +1,272 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	entry := rcon.AuditEntry{
+		HistoryEntry: rcon.HistoryEntry{
+			Command:  "kick bob",
+			Response: "kicked",
+			Err:      errors.New("timeout"),
+			Time:     time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC),
+			Duration: time.Millisecond * 42,
+			Metadata: rcon.CallMetadata{Initiator: "alice", Reason: "cheating", Labels: map[string]string{"tenant": "a"}},
+		},
+		Address: "10.0.0.1:7778",
+	}
+
+	g.Describe("SQLSink", func() {
+		g.It("Should create the SQLite table and record applied migrations", func() {
+			db := &fakeDB{}
+			sink, err := NewSQLSink(sql.OpenDB(db), SQLite, SQLSinkConfig{})
+			Expect(err).ToNot(HaveOccurred())
+			defer sink.Close()
+
+			Expect(sink.Migrate(context.Background())).To(Succeed())
+
+			migrations := SQLite.Migrations(DefaultTable)
+			Expect(db.Statements()).To(Equal([]fakeStatement{
+				{Query: "CREATE TABLE IF NOT EXISTS rcon_audit_migrations (version INTEGER PRIMARY KEY)"},
+				{Query: "SELECT COALESCE(MAX(version), 0) FROM rcon_audit_migrations"},
+				{Query: "BEGIN"},
+				{Query: migrations[0]},
+				{Query: "INSERT INTO rcon_audit_migrations (version) VALUES (?)", Args: []driver.Value{int64(1)}},
+				{Query: "COMMIT"},
+				{Query: "BEGIN"},
+				{Query: migrations[1]},
+				{Query: "INSERT INTO rcon_audit_migrations (version) VALUES (?)", Args: []driver.Value{int64(2)}},
+				{Query: "COMMIT"},
+				{Query: "BEGIN"},
+				{Query: migrations[2]},
+				{Query: "INSERT INTO rcon_audit_migrations (version) VALUES (?)", Args: []driver.Value{int64(3)}},
+				{Query: "COMMIT"},
+			}))
+		})
+
+		g.It("Should only apply newer Postgres migrations", func() {
+			db := &fakeDB{version: 2}
+			sink, err := NewSQLSink(sql.OpenDB(db), Postgres, SQLSinkConfig{Table: "audit"})
+			Expect(err).ToNot(HaveOccurred())
+			defer sink.Close()
+
+			Expect(sink.Migrate(context.Background())).To(Succeed())
+
+			Expect(db.Statements()).To(Equal([]fakeStatement{
+				{Query: "CREATE TABLE IF NOT EXISTS audit_migrations (version INTEGER PRIMARY KEY)"},
+				{Query: "SELECT COALESCE(MAX(version), 0) FROM audit_migrations"},
+				{Query: "BEGIN"},
+				{Query: Postgres.Migrations("audit")[2]},
+				{Query: "INSERT INTO audit_migrations (version) VALUES ($1)", Args: []driver.Value{int64(3)}},
+				{Query: "COMMIT"},
+			}))
+		})
+
+		g.It("Should insert entries using the placeholders of the driver", func() {
+			args := []driver.Value{entry.Time, "10.0.0.1:7778", "kick bob", "kicked", "timeout", "alice", "cheating",
+				`{"tenant":"a"}`, int64(0), int64(42), false, false}
+
+			for d, placeholders := range map[Driver]string{
+				SQLite:   "?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?",
+				Postgres: "$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12",
+			} {
+				db := &fakeDB{}
+				sink, err := NewSQLSink(sql.OpenDB(db), d, SQLSinkConfig{})
+				Expect(err).ToNot(HaveOccurred())
+
+				sink.Record(entry)
+				Expect(sink.Close()).To(Succeed())
+
+				Expect(db.Statements()).To(Equal([]fakeStatement{{
+					Query: "INSERT INTO rcon_audit (time, address, command, response, error, initiator, reason, labels, " +
+						"priority, duration_ms, redacted, dry_run) VALUES (" + placeholders + ")",
+					Args: args,
+				}}))
+			}
+		})
+
+		g.It("Should drop entries instead of blocking once the queue is full", func() {
+			db := &fakeDB{blocked: make(chan struct{}), unblock: make(chan struct{})}
+			sink, err := NewSQLSink(sql.OpenDB(db), SQLite, SQLSinkConfig{QueueSize: 1})
+			Expect(err).ToNot(HaveOccurred())
+
+			sink.Record(entry)
+			Eventually(db.blocked).Should(BeClosed())
+
+			start := time.Now()
+			sink.Record(entry)
+			sink.Record(entry)
+			Expect(time.Since(start)).To(BeNumerically("<", time.Millisecond*100))
+			Expect(sink.Dropped()).To(Equal(uint64(1)))
+
+			close(db.unblock)
+			Expect(sink.Close()).To(Succeed())
+			Expect(db.Statements()).To(HaveLen(2))
+
+			sink.Record(entry)
+			Expect(sink.Dropped()).To(Equal(uint64(2)))
+		})
+
+		g.It("Should reject invalid table names", func() {
+			_, err := NewSQLSink(nil, SQLite, SQLSinkConfig{Table: "audit; DROP TABLE users"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+}
+
+// fakeStatement is a statement executed on a fakeDB. Transactions are recorded as BEGIN and COMMIT statements.
+type fakeStatement struct {
+	Query string
+	Args  []driver.Value
+}
+
+// fakeDB is a database/sql connector which records executed statements. Queries return version as their only row.
+type fakeDB struct {
+	version int64
+
+	// If blocked is set, it is closed once an insert is executed, which then waits for unblock to be closed.
+	blocked chan struct{}
+	unblock chan struct{}
+
+	mu         sync.Mutex
+	statements []fakeStatement
+}
+
+func (db *fakeDB) Statements() []fakeStatement {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return append([]fakeStatement(nil), db.statements...)
+}
+
+func (db *fakeDB) record(query string, args []driver.Value) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if len(args) == 0 {
+		args = nil
+	}
+
+	db.statements = append(db.statements, fakeStatement{Query: query, Args: args})
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{db: db}, nil
+}
+
+func (db *fakeDB) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("use sql.OpenDB")
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.record("BEGIN", nil)
+	return &fakeTx{db: c.db}, nil
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.db.record("COMMIT", nil)
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.db.record("ROLLBACK", nil)
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.db.blocked != nil && strings.HasPrefix(s.query, "INSERT INTO rcon_audit ") {
+		select {
+		case <-s.db.blocked:
+		default:
+			close(s.db.blocked)
+		}
+
+		<-s.db.unblock
+	}
+
+	s.db.record(s.query, args)
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.record(s.query, args)
+
+	return &fakeRows{version: s.db.version}, nil
+}
+
+type fakeRows struct {
+	version int64
+	read    bool
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"version"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+
+	r.read = true
+	dest[0] = r.version
+
+	return nil
+}
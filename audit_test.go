@@ -0,0 +1,42 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Audit sinks", func() {
+		g.It("Should receive redacted commands without a history", func() {
+			var entries []AuditEntry
+			c := NewClient(&Config{
+				Host:   "127.0.0.1",
+				Port:   27015,
+				DryRun: true,
+				AuditSinks: []AuditSink{func(entry AuditEntry) {
+					entries = append(entries, entry)
+				}},
+				HistoryRedactor: func(entry HistoryEntry) HistoryEntry {
+					entry.Command = "rcon_password <redacted>"
+					return entry
+				},
+			}, nil)
+
+			_, err := c.ExecCommand("rcon_password secret", WithInitiator("admin:1"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Command).To(Equal("rcon_password <redacted>"))
+			Expect(entries[0].Redacted).To(BeTrue())
+			Expect(entries[0].Metadata.Initiator).To(Equal("admin:1"))
+			Expect(entries[0].Address).To(Equal("127.0.0.1:27015"))
+			Expect(c.History()).To(BeEmpty())
+		})
+	})
+}
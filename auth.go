@@ -0,0 +1,50 @@
+package rcon
+
+import (
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// AuthMode controls how the client authenticates after connecting.
+type AuthMode string
+
+const (
+	// AuthPassword means the client authenticates with Config.Password. This is the default.
+	AuthPassword = AuthMode("password")
+
+	// AuthEmptyPassword means the client sends an auth packet with an empty password, for servers which run without a
+	// password but still expect the auth exchange.
+	AuthEmptyPassword = AuthMode("empty")
+
+	// AuthNone means the auth exchange is skipped entirely, for servers which accept commands right after connecting.
+	AuthNone = AuthMode("none")
+)
+
+// authMode returns the configured AuthMode, defaulting to AuthPassword.
+func (c *Client) authMode() AuthMode {
+	if c.AuthMode == "" {
+		return AuthPassword
+	}
+
+	return c.AuthMode
+}
+
+// authPassword returns the password sent in auth packets.
+func (c *Client) authPassword() string {
+	if c.authMode() == AuthEmptyPassword {
+		return ""
+	}
+
+	return c.Password
+}
+
+func parseAuthMode(s string) (AuthMode, error) {
+	switch mode := AuthMode(strings.ToLower(s)); mode {
+	case "", AuthPassword:
+		return AuthPassword, nil
+	case AuthEmptyPassword, AuthNone:
+		return mode, nil
+	default:
+		return "", errors.Errorf("must be \"password\", \"empty\" or \"none\", got %q", s)
+	}
+}
@@ -0,0 +1,35 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/errs"
+	"testing"
+)
+
+func TestAuth(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Auth modes", func() {
+		g.It("Should only require a password when authenticating with one", func() {
+			_, err := (&FileConfig{Host: "127.0.0.1", Port: 7778}).ServerConfig("")
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*errs.FieldError).Field).To(Equal("password"))
+
+			sc, err := (&FileConfig{Host: "127.0.0.1", Port: 7778, AuthMode: "none"}).ServerConfig("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sc.Config.AuthMode).To(Equal(AuthNone))
+
+			_, err = (&FileConfig{Host: "127.0.0.1", Port: 7778, AuthMode: "anonymous"}).ServerConfig("")
+			Expect(err.(*errs.FieldError).Field).To(Equal("auth_mode"))
+		})
+
+		g.It("Should send an empty password with AuthEmptyPassword", func() {
+			c := NewClient(&Config{Password: "secret", AuthMode: AuthEmptyPassword}, nil)
+			Expect(c.authPassword()).To(Equal(""))
+		})
+	})
+}
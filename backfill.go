@@ -0,0 +1,174 @@
+package rcon
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// backfillHistorySize is the number of most recent broadcasts remembered to find the position in the recent log up to
+// which broadcasts were already received.
+const backfillHistorySize = 256
+
+// backfillAnchorSize is the maximum number of broadcasts received before an outage which are matched against the recent
+// log to find the last one, so that repeated lines such as identical chat messages don't throw the position off.
+const backfillAnchorSize = 16
+
+// LogParser splits the response to a dialect's RecentLogCommand into broadcast messages, oldest first.
+type LogParser func(response string) []string
+
+// SplitLogLines is a LogParser which treats every non-empty line of the response as a message.
+func SplitLogLines(response string) []string {
+	var lines []string
+	for _, line := range strings.Split(response, "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// recentBroadcasts remembers the most recent broadcast messages in the order they were received, so that a backfill
+// only delivers the lines of the recent log which come after the last message received before the outage.
+type recentBroadcasts struct {
+	lock     sync.Mutex
+	received []string
+	hasPrior bool
+
+	// connStart is the index in received of the first message received on the current connection.
+	connStart int
+}
+
+func (r *recentBroadcasts) add(message string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.received = append(r.received, message)
+	r.trim()
+}
+
+// trim drops the oldest messages beyond backfillHistorySize. It must be called with the lock held.
+func (r *recentBroadcasts) trim() {
+	excess := len(r.received) - backfillHistorySize
+	if excess <= 0 {
+		return
+	}
+
+	r.received = append([]string(nil), r.received[excess:]...)
+	if r.connStart -= excess; r.connStart < 0 {
+		r.connStart = 0
+	}
+}
+
+// connected marks the start of a new connection. It must be called before broadcasts are read from the connection. It
+// returns true if the client was connected before, which means there may be a gap to backfill.
+func (r *recentBroadcasts) connected() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	prior := r.hasPrior
+	r.hasPrior = true
+	r.connStart = len(r.received)
+
+	return prior
+}
+
+// missed returns the lines of the recent log which weren't received, and remembers them as received. The log is
+// expected to hold the messages received before the outage, the missed ones and then those received since
+// reconnecting, in that order. If none of the messages received before the outage are in the log, every line before
+// those received since reconnecting is considered missed.
+func (r *recentBroadcasts) missed(lines []string) []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	before := r.received[:r.connStart]
+	live := r.received[r.connStart:]
+
+	missed := lines[logAnchor(lines, before)+1:]
+
+	// Messages received since reconnecting are at the end of the log, unless they were sent after it was read.
+	k := len(live)
+	if k > len(missed) {
+		k = len(missed)
+	}
+
+	for ; k > 0; k-- {
+		if stringsEqual(missed[len(missed)-k:], live[:k]) {
+			missed = missed[:len(missed)-k]
+			break
+		}
+	}
+
+	missed = append([]string(nil), missed...)
+
+	r.received = append(append(append([]string(nil), before...), missed...), live...)
+	r.connStart += len(missed)
+	r.trim()
+
+	return missed
+}
+
+// logAnchor returns the index of the line of the log matching the last message of received, or -1 if there is none. If
+// several lines match, the one preceded by the most matching messages wins, and the latest one of those.
+func logAnchor(lines, received []string) int {
+	anchor, longest := -1, 0
+
+	for i := len(lines) - 1; i >= 0; i-- {
+		n := 0
+		for n < backfillAnchorSize && n <= i && n < len(received) && lines[i-n] == received[len(received)-1-n] {
+			n++
+		}
+
+		if n > longest {
+			anchor, longest = i, n
+		}
+	}
+
+	return anchor
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// backfillBroadcasts executes the dialect's RecentLogCommand after a reconnect and delivers the lines which weren't
+// received before the outage as replayed broadcasts.
+func (c *Client) backfillBroadcasts(ctx context.Context) error {
+	d := c.Dialect()
+	if d.RecentLogCommand == "" {
+		return nil
+	}
+
+	res, err := c.execCommandTraced(ctx, d.RecentLogCommand, PriorityHigh, nil)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.log.Error("Could not backfill broadcasts. Error: ", err)
+		}
+		return nil
+	}
+
+	parse := d.ParseRecentLog
+	if parse == nil {
+		parse = SplitLogLines
+	}
+
+	missed := c.recentBroadcasts.missed(parse(res))
+	for _, message := range missed {
+		c.deliverBroadcast(message, true, 0)
+	}
+
+	c.log.Info("Backfilled ", len(missed), " broadcasts missed while disconnected")
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/packet"
+	"github.com/refractorgscm/rcon/rcontest"
+	"testing"
+	"time"
+)
+
+func TestBackfill(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("SplitLogLines", func() {
+		g.It("Should split lines and skip empty ones", func() {
+			Expect(SplitLogLines("a\r\n\n  \nb\n")).To(Equal([]string{"a", "b"}))
+			Expect(SplitLogLines("")).To(BeEmpty())
+		})
+	})
+
+	g.Describe("recentBroadcasts", func() {
+		g.It("Should return the lines after the last received broadcast", func() {
+			tests := []struct {
+				name     string
+				before   []string
+				live     []string
+				log      []string
+				expected []string
+			}{
+				{"gap", []string{"a", "b"}, nil, []string{"a", "b", "c", "d"}, []string{"c", "d"}},
+				{"no gap", []string{"a", "b"}, nil, []string{"a", "b"}, nil},
+				{"ambiguous repeated lines", []string{"hi", "hi"}, nil, []string{"hi", "hi", "hi", "x"}, []string{"x"}},
+				{"repeated last line", []string{"a", "b"}, nil, []string{"x", "a", "b", "b", "c"}, []string{"b", "c"}},
+				{"rotated log", []string{"a", "b"}, nil, []string{"c", "d"}, []string{"c", "d"}},
+				{"nothing received", nil, nil, []string{"a", "b"}, []string{"a", "b"}},
+				{"received since reconnecting", []string{"a"}, []string{"d", "e"}, []string{"a", "b", "c", "d"},
+					[]string{"b", "c"}},
+				{"only received since reconnecting", []string{"a"}, []string{"b"}, []string{"a", "b"}, nil},
+			}
+
+			for _, test := range tests {
+				r := &recentBroadcasts{}
+				r.connected()
+				for _, message := range test.before {
+					r.add(message)
+				}
+
+				Expect(r.connected()).To(BeTrue(), test.name)
+				for _, message := range test.live {
+					r.add(message)
+				}
+
+				Expect(r.missed(test.log)).To(Equal(test.expected), test.name)
+			}
+		})
+
+		g.It("Should remember backfilled lines before those received since reconnecting", func() {
+			r := &recentBroadcasts{}
+			r.connected()
+			r.add("a")
+			r.connected()
+			r.add("d")
+
+			Expect(r.missed([]string{"a", "b", "c", "d"})).To(Equal([]string{"b", "c"}))
+			Expect(r.received).To(Equal([]string{"a", "b", "c", "d"}))
+
+			// After the next outage, the log is read from the last line received on any connection.
+			r.connected()
+			Expect(r.missed([]string{"b", "c", "d", "e"})).To(Equal([]string{"e"}))
+		})
+
+		g.It("Should only remember the most recent broadcasts", func() {
+			r := &recentBroadcasts{}
+			r.connected()
+			for i := 0; i < backfillHistorySize+10; i++ {
+				r.add("line")
+			}
+
+			Expect(r.received).To(HaveLen(backfillHistorySize))
+		})
+	})
+
+	g.Describe("BackfillBroadcasts", func() {
+		g.It("Should deliver broadcasts missed while disconnected after reconnecting", func() {
+			s := newTestServer(func(command string) string {
+				if command == "log recent" {
+					return "joined\nhello\nhello\nleft\n"
+				}
+
+				return ""
+			})
+			defer s.Close()
+
+			dialect := *DefaultDialect
+			dialect.RecentLogCommand = "log recent"
+
+			broadcasts := make(chan Broadcast, 10)
+			c := newTestClient(s, &Config{
+				Dialect:            &dialect,
+				BackfillBroadcasts: true,
+				ReconnectPolicy:    &ReconnectPolicy{InitialDelay: time.Millisecond * 10},
+				BroadcastChecker: func(p packet.Packet) bool {
+					return p.ID() == rcontest.BroadcastID
+				},
+				BroadcastListeners: []BroadcastListener{
+					func(b Broadcast) {
+						broadcasts <- b
+					},
+				},
+			})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			s.Broadcast("joined")
+			s.Broadcast("hello")
+			for i := 0; i < 2; i++ {
+				Eventually(broadcasts).Should(Receive())
+			}
+
+			s.CloseConnections()
+
+			var replayed []string
+			for i := 0; i < 2; i++ {
+				var b Broadcast
+				Eventually(broadcasts).Should(Receive(&b))
+				Expect(b.Replayed).To(BeTrue())
+				replayed = append(replayed, b.Message)
+			}
+
+			Expect(replayed).To(Equal([]string{"hello", "left"}))
+			Consistently(broadcasts, time.Millisecond*100).ShouldNot(Receive())
+		})
+	})
+}
@@ -0,0 +1,93 @@
+package rcon
+
+import (
+	"sync"
+	"time"
+)
+
+// BroadcastBatcher collects broadcasts and passes them to a flush function in batches, which reduces the number of
+// writes for consumers inserting broadcasts into databases. A batch is flushed once it is full or its time window has
+// passed since its first broadcast, whichever happens first. Batches are flushed in order, one at a time, so the flush
+// function must not call back into the batcher.
+//
+// The Listen method is a BroadcastListener:
+//
+//	batcher := rcon.NewBroadcastBatcher(100, time.Millisecond*500, insertEvents)
+//	client.AddBroadcastListener(batcher.Listen)
+type BroadcastBatcher struct {
+	maxSize int
+	window  time.Duration
+	flush   func([]Broadcast)
+
+	lock      sync.Mutex
+	flushLock sync.Mutex
+	batch     []Broadcast
+	timer     *time.Timer
+	closed    bool
+}
+
+// NewBroadcastBatcher creates a BroadcastBatcher which flushes batches of up to maxSize broadcasts, at most window after
+// their first broadcast arrived. If maxSize is 0 or less, batches are only flushed by time. If window is 0 or less,
+// they are only flushed by size.
+func NewBroadcastBatcher(maxSize int, window time.Duration, flush func([]Broadcast)) *BroadcastBatcher {
+	return &BroadcastBatcher{
+		maxSize: maxSize,
+		window:  window,
+		flush:   flush,
+	}
+}
+
+// Listen adds a broadcast to the current batch. It matches the signature of BroadcastListener. Broadcasts received
+// after Close are flushed right away.
+func (b *BroadcastBatcher) Listen(broadcast Broadcast) {
+	b.lock.Lock()
+
+	b.batch = append(b.batch, broadcast)
+
+	if b.closed || (b.maxSize > 0 && len(b.batch) >= b.maxSize) {
+		b.deliver()
+		return
+	}
+
+	if len(b.batch) == 1 && b.window > 0 {
+		b.timer = time.AfterFunc(b.window, b.Flush)
+	}
+
+	b.lock.Unlock()
+}
+
+// Flush flushes the current batch, if it isn't empty.
+func (b *BroadcastBatcher) Flush() {
+	b.lock.Lock()
+	b.deliver()
+}
+
+// Close flushes the current batch and stops the window timer.
+func (b *BroadcastBatcher) Close() {
+	b.lock.Lock()
+	b.closed = true
+	b.deliver()
+}
+
+// deliver removes the current batch, stops its timer and flushes it. The caller must hold the lock, which is released
+// before the flush function is called. The flush lock is taken first, so that batches are flushed in order.
+func (b *BroadcastBatcher) deliver() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	batch := b.batch
+	b.batch = nil
+
+	if len(batch) == 0 {
+		b.lock.Unlock()
+		return
+	}
+
+	b.flushLock.Lock()
+	b.lock.Unlock()
+	defer b.flushLock.Unlock()
+
+	b.flush(batch)
+}
@@ -0,0 +1,43 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestBatch(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("BroadcastBatcher", func() {
+		g.It("Should flush batches by size and time window", func() {
+			batches := make(chan []string, 10)
+			b := NewBroadcastBatcher(2, time.Millisecond*20, func(batch []Broadcast) {
+				messages := make([]string, len(batch))
+				for i, broadcast := range batch {
+					messages[i] = broadcast.Message
+				}
+				batches <- messages
+			})
+
+			c := NewClient(&Config{}, nil)
+			c.AddBroadcastListener(b.Listen)
+
+			c.handleBroadcast("first")
+			c.handleBroadcast("second")
+			Expect(batches).To(Receive(Equal([]string{"first", "second"})))
+
+			c.handleBroadcast("third")
+			Expect(batches).NotTo(Receive())
+			Eventually(batches).Should(Receive(Equal([]string{"third"})))
+
+			c.handleBroadcast("fourth")
+			b.Close()
+			Expect(batches).To(Receive(Equal([]string{"fourth"})))
+		})
+	})
+}
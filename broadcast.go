@@ -0,0 +1,223 @@
+package rcon
+
+import (
+	"bytes"
+	"regexp"
+	"regexp/syntax"
+)
+
+// handleBroadcast delivers a broadcast message to the broadcast handler, sinks and listeners, and emits any events the
+// BroadcastParsers recognise. Messages dropped by the BroadcastFilter and messages received while the client is paused
+// are ignored.
+func (c *Client) handleBroadcast(message string) {
+	c.handleBroadcastSeq(message, 0)
+}
+
+// handleBroadcastSeq handles a broadcast like handleBroadcast. arrivalSeq is the arrival sequence number of the packet
+// carrying the broadcast, or zero if it wasn't read from the connection.
+func (c *Client) handleBroadcastSeq(message string, arrivalSeq uint64) {
+	if c.BackfillBroadcasts {
+		c.recentBroadcasts.add(message)
+	}
+
+	c.deliverBroadcast(message, false, arrivalSeq)
+}
+
+// deliverBroadcast delivers a broadcast message like handleBroadcast. replayed is set for messages recovered by a
+// backfill after a reconnect, and is passed on to listeners and parsers.
+func (c *Client) deliverBroadcast(message string, replayed bool, arrivalSeq uint64) {
+	if c.Paused() {
+		return
+	}
+
+	if c.injectBroadcastFault() {
+		c.log.Debug("Injected broadcast drop: ", message)
+		return
+	}
+
+	body := []byte(message)
+	if (c.BroadcastFilter != nil && !c.BroadcastFilter.Accept(body)) || !c.patterns.accept(body) {
+		c.events.emit(BroadcastDroppedEvent{Message: message})
+		return
+	}
+
+	c.stats.recordBroadcast()
+
+	if !c.hasBroadcastConsumer() {
+		c.broadcastBuffer.add(message)
+	}
+
+	if c.BroadcastHandler != nil {
+		c.callHandler("broadcast handler", func() {
+			c.BroadcastHandler(message)
+		})
+	}
+
+	for _, sink := range c.BroadcastSinks {
+		c.callHandler("broadcast sink", func() {
+			sink(message)
+		})
+	}
+
+	seq := c.broadcastLog.next()
+
+	if len(c.BroadcastListeners) == 0 && len(c.BroadcastParsers) == 0 && c.BroadcastTimestamps == nil &&
+		!c.broadcastLog.enabled() {
+		return
+	}
+
+	b := c.newBroadcast(message)
+	b.Replayed = replayed
+	b.Seq = seq
+	b.ArrivalSeq = arrivalSeq
+	c.broadcastLog.add(b)
+
+	for _, listener := range c.BroadcastListeners {
+		c.callHandler("broadcast listener", func() {
+			listener(b)
+		})
+	}
+
+	for _, parse := range c.BroadcastParsers {
+		var event Event
+		c.callHandler("broadcast parser", func() {
+			event = parse(b)
+		})
+
+		if event != nil {
+			c.events.emit(event)
+		}
+	}
+}
+
+// BroadcastFilter decides which broadcast messages are delivered to the BroadcastHandler and BroadcastSinks. It can be
+// implemented with arbitrary logic, such as prefix maps or checks on fields of JSON messages. PatternFilter is a
+// built-in implementation using regular expressions.
+type BroadcastFilter interface {
+	// Accept returns true if the message should be delivered. body must not be modified or retained.
+	Accept(body []byte) bool
+}
+
+// BroadcastFilterFunc is an adapter allowing an ordinary function to be used as a BroadcastFilter.
+type BroadcastFilterFunc func(body []byte) bool
+
+func (f BroadcastFilterFunc) Accept(body []byte) bool {
+	return f(body)
+}
+
+// PatternFilter is a BroadcastFilter built from an allowlist and a denylist of patterns.
+//
+// Patterns which are plain literals, optionally anchored to the start of the message, are checked using string
+// comparisons instead of the regexp engine, which is considerably cheaper for chatty servers with many patterns.
+// Combining patterns into a single alternation was benchmarked too, but is slower with Go's regexp engine.
+type PatternFilter struct {
+	allow patternSet
+	deny  patternSet
+}
+
+// NewPatternFilter creates a PatternFilter. If allow is not empty, only messages matching at least one of its patterns
+// are wanted. Messages matching any pattern in deny are never wanted.
+func NewPatternFilter(allow, deny []*regexp.Regexp) *PatternFilter {
+	return &PatternFilter{
+		allow: compilePatternSet(allow),
+		deny:  compilePatternSet(deny),
+	}
+}
+
+func (f *PatternFilter) Accept(body []byte) bool {
+	if f.deny.match(body) {
+		return false
+	}
+
+	if len(f.allow) > 0 && !f.allow.match(body) {
+		return false
+	}
+
+	return true
+}
+
+// patternSet is a list of matchers, one for each pattern.
+type patternSet []func(body []byte) bool
+
+// compilePatternSet creates a matcher for each pattern, using byte comparisons where the pattern is a literal.
+func compilePatternSet(patterns []*regexp.Regexp) patternSet {
+	set := make(patternSet, len(patterns))
+	for i, pattern := range patterns {
+		set[i] = literalMatcher(pattern)
+		if set[i] == nil {
+			set[i] = pattern.Match
+		}
+	}
+
+	return set
+}
+
+// match returns true if body matches any pattern in the set.
+func (s patternSet) match(body []byte) bool {
+	for _, m := range s {
+		if m(body) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// literalMatcher returns a matcher using byte comparisons if the pattern is a literal or a literal anchored to the
+// start of the message. Otherwise, nil is returned.
+func literalMatcher(pattern *regexp.Regexp) func([]byte) bool {
+	re, err := syntax.Parse(pattern.String(), syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	re = re.Simplify()
+
+	isLiteral := func(re *syntax.Regexp) bool {
+		return re.Op == syntax.OpLiteral && re.Flags&syntax.FoldCase == 0
+	}
+
+	switch {
+	case isLiteral(re):
+		literal := []byte(string(re.Rune))
+		return func(body []byte) bool {
+			return bytes.Contains(body, literal)
+		}
+	case re.Op == syntax.OpConcat && len(re.Sub) == 2 && re.Sub[0].Op == syntax.OpBeginText && isLiteral(re.Sub[1]):
+		literal := []byte(string(re.Sub[1].Rune))
+		return func(body []byte) bool {
+			return bytes.HasPrefix(body, literal)
+		}
+	}
+
+	return nil
+}
+
+// BroadcastSource is an external source of broadcast messages, such as a game server's log file. Messages emitted by a
+// source are delivered exactly like broadcasts received over the RCON connection, so consumers get a uniform stream of
+// messages regardless of how the game server provides them.
+//
+// Sources set in the client config are started once the client has connected and stopped when it disconnects.
+type BroadcastSource interface {
+	// Start starts the source. emit must be called with each message read by the source. Start must not block.
+	Start(emit func(message string)) error
+
+	// Stop stops the source. A stopped source may be started again.
+	Stop() error
+}
+
+func (c *Client) startBroadcastSources() {
+	for _, src := range c.BroadcastSources {
+		if err := src.Start(c.handleBroadcast); err != nil {
+			c.log.Error("Could not start broadcast source. Error: ", err)
+			c.stats.recordError(err)
+		}
+	}
+}
+
+func (c *Client) stopBroadcastSources() {
+	for _, src := range c.BroadcastSources {
+		if err := src.Stop(); err != nil {
+			c.log.Error("Could not stop broadcast source. Error: ", err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package rcon
+
+import (
+	"fmt"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"regexp"
+	"testing"
+)
+
+func TestBroadcast(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("PatternFilter", func() {
+		g.It("Should want everything if no patterns are set", func() {
+			f := NewPatternFilter(nil, nil)
+
+			Expect(f.Accept([]byte("anything"))).To(BeTrue())
+		})
+
+		g.It("Should only want messages matching the allowlist", func() {
+			f := NewPatternFilter([]*regexp.Regexp{
+				regexp.MustCompile(`^Chat:`),
+				regexp.MustCompile(`^Login:`),
+			}, nil)
+
+			Expect(f.Accept([]byte("Chat: hello"))).To(BeTrue())
+			Expect(f.Accept([]byte("Login: player"))).To(BeTrue())
+			Expect(f.Accept([]byte("Tick: 1234"))).To(BeFalse())
+		})
+
+		g.It("Should drop messages matching the denylist even if they are allowed", func() {
+			f := NewPatternFilter([]*regexp.Regexp{
+				regexp.MustCompile(`^Chat:`),
+			}, []*regexp.Regexp{
+				regexp.MustCompile(`(?i)spam`),
+			})
+
+			Expect(f.Accept([]byte("Chat: hello"))).To(BeTrue())
+			Expect(f.Accept([]byte("Chat: SPAM"))).To(BeFalse())
+		})
+
+		g.It("Should match literal patterns anywhere unless anchored", func() {
+			f := NewPatternFilter([]*regexp.Regexp{
+				regexp.MustCompile(`joined`),
+				regexp.MustCompile(`^Chat:`),
+			}, nil)
+
+			Expect(f.Accept([]byte("Player joined the game"))).To(BeTrue())
+			Expect(f.Accept([]byte("Chat: hello"))).To(BeTrue())
+			Expect(f.Accept([]byte("Team Chat: hello"))).To(BeFalse())
+		})
+
+		g.It("Should keep flags scoped to their own pattern", func() {
+			f := NewPatternFilter([]*regexp.Regexp{
+				regexp.MustCompile(`(?i)^chat:`),
+				regexp.MustCompile(`^Login:`),
+			}, nil)
+
+			Expect(f.Accept([]byte("CHAT: hello"))).To(BeTrue())
+			Expect(f.Accept([]byte("LOGIN: player"))).To(BeFalse())
+		})
+	})
+}
+
+// benchmarkPatterns creates a pattern for each format, similar to what a chatty server's filter could hold.
+func benchmarkPatterns(format string) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, 50)
+	for i := range patterns {
+		patterns[i] = regexp.MustCompile(fmt.Sprintf(format, i))
+	}
+
+	return patterns
+}
+
+const benchmarkMessage = "Chat: SomePlayer (0123456789ABCDEF) said something to everyone in the server"
+
+func benchmarkIteration(b *testing.B, patterns []*regexp.Regexp) {
+	for i := 0; i < b.N; i++ {
+		for _, pattern := range patterns {
+			if pattern.MatchString(benchmarkMessage) {
+				break
+			}
+		}
+	}
+}
+
+func benchmarkFilter(b *testing.B, patterns []*regexp.Regexp) {
+	f := NewPatternFilter(nil, patterns)
+	body := []byte(benchmarkMessage)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.Accept(body)
+	}
+}
+
+func BenchmarkIterationLiteral(b *testing.B) {
+	benchmarkIteration(b, benchmarkPatterns("Event%d"))
+}
+
+func BenchmarkPatternFilterLiteral(b *testing.B) {
+	benchmarkFilter(b, benchmarkPatterns("Event%d"))
+}
+
+func BenchmarkIterationAnchored(b *testing.B) {
+	benchmarkIteration(b, benchmarkPatterns("^Event%d:"))
+}
+
+func BenchmarkPatternFilterAnchored(b *testing.B) {
+	benchmarkFilter(b, benchmarkPatterns("^Event%d:"))
+}
+
+func BenchmarkIterationRegexp(b *testing.B) {
+	benchmarkIteration(b, benchmarkPatterns(`Event%d \d+ joined`))
+}
+
+func BenchmarkPatternFilterRegexp(b *testing.B) {
+	benchmarkFilter(b, benchmarkPatterns(`Event%d \d+ joined`))
+}
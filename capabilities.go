@@ -0,0 +1,47 @@
+package rcon
+
+// Capabilities describes what the server of a dialect supports, so that generic tooling can adapt its behavior to the
+// server instead of hard-coding knowledge about games. They are derived from the dialect, which may have been
+// adjusted by dialect detection.
+type Capabilities struct {
+	// Dialect is the name of the dialect the capabilities were derived from.
+	Dialect string
+
+	// SupportsBroadcasts is true if the server sends broadcast messages over RCON.
+	SupportsBroadcasts bool
+
+	// SupportsFragmentation is true if the end of responses split across several packets can be detected.
+	SupportsFragmentation bool
+
+	// MaxPayloadSize is the maximum size of a command in bytes, or zero if it isn't limited.
+	MaxPayloadSize int
+
+	// RequiresSingleConnection is true if the server only accepts a single RCON connection at a time, so that
+	// additional clients, such as clones, would disconnect existing ones.
+	RequiresSingleConnection bool
+
+	// SupportsFingerprint is true if the server can be fingerprinted, see Client.Fingerprint.
+	SupportsFingerprint bool
+
+	// SupportsBackfill is true if broadcasts missed while disconnected can be recovered, see BackfillBroadcasts.
+	SupportsBackfill bool
+}
+
+// Capabilities returns the capabilities of servers of the dialect.
+func (d *Dialect) Capabilities() Capabilities {
+	return Capabilities{
+		Dialect:                  d.Name,
+		SupportsBroadcasts:       d.SupportsBroadcasts || len(d.UnsolicitedPacketTypes) > 0,
+		SupportsFragmentation:    d.SupportsFragmentation || d.ResponseTerminator != nil,
+		MaxPayloadSize:           d.MaxPayloadSize,
+		RequiresSingleConnection: d.RequiresSingleConnection,
+		SupportsFingerprint:      d.FingerprintCommand != "",
+		SupportsBackfill:         d.RecentLogCommand != "",
+	}
+}
+
+// Capabilities returns the capabilities of the server, derived from the dialect currently in use. If DetectDialect is
+// set, they reflect the detected dialect once the client connected.
+func (c *Client) Capabilities() Capabilities {
+	return c.Dialect().Capabilities()
+}
@@ -0,0 +1,33 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/packet"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Capabilities", func() {
+		g.It("Should derive capabilities from the dialect", func() {
+			c := NewClient(&Config{}, nil)
+			Expect(c.Capabilities()).To(Equal(Capabilities{
+				Dialect:               "source",
+				SupportsFragmentation: true,
+				SupportsFingerprint:   true,
+			}))
+
+			c = NewClient(&Config{Dialect: &Dialect{
+				Name:                   "detected",
+				UnsolicitedPacketTypes: []packet.PacketType{packet.TypeCommandRes},
+			}}, nil)
+			Expect(c.Capabilities().SupportsBroadcasts).To(BeTrue())
+			Expect(c.Capabilities().SupportsFragmentation).To(BeFalse())
+		})
+	})
+}
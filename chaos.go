@@ -0,0 +1,127 @@
+package rcon
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Chaos injects faults into a live client, so that the resilience of an application built on it can be tested without
+// touching the game server. Rates are probabilities between 0 and 1. The rcontest package offers the same for the
+// server side.
+type Chaos struct {
+	// WriteDelayRate is the probability of a packet being delayed by WriteDelay before it is written. Since packets are
+	// written in order, a delayed packet delays the packets queued after it too.
+	WriteDelayRate float64
+	WriteDelay     time.Duration
+
+	// BroadcastDropRate is the probability of a broadcast being dropped instead of delivered.
+	BroadcastDropRate float64
+
+	// ReconnectRate is the probability of the connection being torn down with ErrInjectedFault before a packet is
+	// written, which triggers the ReconnectPolicy like a real connection failure.
+	ReconnectRate float64
+
+	// Seed seeds the random number generator, so that faults can be reproduced. If zero, a random seed is used.
+	Seed int64
+}
+
+// chaosState holds the active Chaos of a client. Its zero value doesn't inject any faults.
+type chaosState struct {
+	lock   sync.Mutex
+	config *Chaos
+	rand   *rand.Rand
+}
+
+func (s *chaosState) set(config *Chaos) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if config == nil {
+		s.config = nil
+		return
+	}
+
+	copied := *config
+	s.config = &copied
+
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	s.rand = rand.New(rand.NewSource(seed))
+}
+
+func (s *chaosState) get() *Chaos {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.config == nil {
+		return nil
+	}
+
+	copied := *s.config
+	return &copied
+}
+
+// roll returns true with the probability returned by rate, or false if no Chaos is active.
+func (s *chaosState) roll(rate func(c *Chaos) float64) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.config == nil || rate(s.config) <= 0 {
+		return false
+	}
+
+	return s.rand.Float64() < rate(s.config)
+}
+
+// SetChaos starts injecting the faults described by chaos, replacing any faults injected before. It can be called at
+// any time, and takes effect immediately. Passing nil stops injecting faults.
+func (c *Client) SetChaos(chaos *Chaos) {
+	if chaos != nil {
+		c.log.Info("Injecting faults: ", *chaos)
+	} else {
+		c.log.Info("No longer injecting faults")
+	}
+
+	c.chaos.set(chaos)
+}
+
+// Chaos returns the faults currently being injected, or nil if none are.
+func (c *Client) Chaos() *Chaos {
+	return c.chaos.get()
+}
+
+// injectWriteFault delays or fails the write of a packet according to the active Chaos. An error means that the
+// connection should be torn down.
+func (c *Client) injectWriteFault(ctx context.Context) error {
+	if c.chaos.roll(func(chaos *Chaos) float64 { return chaos.ReconnectRate }) {
+		c.log.Info("Injecting connection failure")
+		return errors.Wrap(errs.ErrInjectedFault, "forced reconnect")
+	}
+
+	if !c.chaos.roll(func(chaos *Chaos) float64 { return chaos.WriteDelayRate }) {
+		return nil
+	}
+
+	delay := time.Duration(0)
+	if chaos := c.chaos.get(); chaos != nil {
+		delay = chaos.WriteDelay
+	}
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+	}
+
+	return nil
+}
+
+// injectBroadcastFault returns true if a broadcast should be dropped according to the active Chaos.
+func (c *Client) injectBroadcastFault() bool {
+	return c.chaos.roll(func(chaos *Chaos) float64 { return chaos.BroadcastDropRate })
+}
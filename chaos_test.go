@@ -0,0 +1,89 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"github.com/refractorgscm/rcon/packet"
+	"github.com/refractorgscm/rcon/rcontest"
+	"testing"
+	"time"
+)
+
+func TestChaos(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("SetChaos", func() {
+		g.It("Should delay writes until chaos is cleared", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			c.SetChaos(&Chaos{WriteDelayRate: 1, WriteDelay: time.Millisecond * 200, Seed: 1})
+			Expect(c.Chaos()).ToNot(BeNil())
+
+			start := time.Now()
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+			Expect(time.Since(start)).To(BeNumerically(">=", time.Millisecond*200))
+
+			c.SetChaos(nil)
+			Expect(c.Chaos()).To(BeNil())
+
+			start = time.Now()
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+			Expect(time.Since(start)).To(BeNumerically("<", time.Millisecond*200))
+		})
+
+		g.It("Should drop broadcasts until chaos is cleared", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			broadcasts := make(chan string, 10)
+			c := newTestClient(s, &Config{
+				BroadcastChecker: func(p packet.Packet) bool {
+					return p.ID() == rcontest.BroadcastID
+				},
+				BroadcastHandler: func(msg string) {
+					broadcasts <- msg
+				},
+			})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			c.SetChaos(&Chaos{BroadcastDropRate: 1})
+			s.Broadcast("dropped")
+			Consistently(broadcasts, time.Millisecond*100).ShouldNot(Receive())
+
+			c.SetChaos(nil)
+			s.Broadcast("delivered")
+			Eventually(broadcasts).Should(Receive(Equal("delivered")))
+		})
+
+		g.It("Should tear the connection down with ErrInjectedFault", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{QueueReadTimeout: time.Millisecond * 200})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			c.SetChaos(&Chaos{ReconnectRate: 1})
+
+			_, err := c.ExecCommand("status")
+			Expect(err).To(HaveOccurred())
+			Eventually(c.State).Should(Equal(StateDisconnected))
+			Expect(errors.Cause(c.Err())).To(Equal(errs.ErrInjectedFault))
+
+			c.SetChaos(nil)
+			Expect(c.Connect()).To(Succeed())
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+		})
+	})
+}
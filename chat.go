@@ -0,0 +1,35 @@
+package rcon
+
+import "regexp"
+
+// NewChatParser creates a BroadcastParser which emits a ChatMessageEvent for broadcasts matching pattern. The player's
+// ID, name and message are taken from the capture groups named "id", "name" and "message". If pattern has no "id"
+// group, the name is used as the ID.
+func NewChatParser(pattern *regexp.Regexp) BroadcastParser {
+	idIdx := pattern.SubexpIndex("id")
+	nameIdx := pattern.SubexpIndex("name")
+	messageIdx := pattern.SubexpIndex("message")
+
+	return func(b Broadcast) Event {
+		m := pattern.FindStringSubmatch(b.Message)
+		if m == nil || messageIdx < 0 {
+			return nil
+		}
+
+		event := ChatMessageEvent{
+			Message: m[messageIdx],
+			Time:    b.ReceivedAt,
+		}
+
+		if nameIdx >= 0 {
+			event.Name = m[nameIdx]
+		}
+
+		event.PlayerID = event.Name
+		if idIdx >= 0 {
+			event.PlayerID = m[idIdx]
+		}
+
+		return event
+	}
+}
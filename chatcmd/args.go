@@ -0,0 +1,38 @@
+package chatcmd
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SplitArgs splits a chat command into its arguments. Arguments are separated by whitespace, and double quotes can be
+// used to include whitespace in an argument. An unterminated quote runs to the end of the message.
+func SplitArgs(s string) []string {
+	var args []string
+	var current strings.Builder
+	inQuotes := false
+	inArg := false
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			inArg = true
+		case unicode.IsSpace(r) && !inQuotes:
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args
+}
@@ -0,0 +1,211 @@
+// Package chatcmd routes in-game chat commands, such as "!report name reason", to handlers. Chat messages are read from
+// the ChatMessageEvent events emitted by a client's BroadcastParsers, see rcon.NewChatParser.
+package chatcmd
+
+import (
+	"fmt"
+	"github.com/refractorgscm/rcon"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultPrefix = "!"
+
+// Handler handles a chat command. Handlers are called on their own goroutine, so they may execute commands.
+type Handler func(ctx *Context) error
+
+// ErrorHandler is called with errors returned by handlers or encountered while replying.
+type ErrorHandler func(ctx *Context, err error)
+
+// Config configures a Router.
+type Config struct {
+	// Prefix is the prefix which marks a chat message as a command.
+	//
+	// Default: DefaultPrefix
+	Prefix string
+
+	// SayFormat is the format of the command used to reply to players. It must contain a single %s verb, which is
+	// replaced with the reply. If empty, replies are sent using Client.SayChunked.
+	SayFormat string
+
+	// CooldownMessage is sent as a reply when a player uses a command which is on cooldown for them. It may contain a
+	// single %s verb, which is replaced with the remaining cooldown. If empty, commands on cooldown are ignored silently.
+	CooldownMessage string
+
+	// ErrorHandler is called with errors returned by handlers. If nil, errors are ignored.
+	ErrorHandler ErrorHandler
+}
+
+// Router dispatches chat commands to their handlers.
+type Router struct {
+	client *rcon.Client
+	config Config
+
+	lock        sync.Mutex
+	commands    map[string]*command
+	unsubscribe func()
+}
+
+type command struct {
+	handler  Handler
+	cooldown time.Duration
+	lastUsed map[string]time.Time
+}
+
+// Option configures a registered command.
+type Option func(cmd *command)
+
+// WithCooldown sets the amount of time a player has to wait between uses of a command. Cooldowns are tracked per
+// player.
+func WithCooldown(d time.Duration) Option {
+	return func(cmd *command) {
+		cmd.cooldown = d
+	}
+}
+
+// NewRouter creates a Router which listens for chat messages on the client's event bus. Call Stop to stop listening.
+func NewRouter(c *rcon.Client, config Config) *Router {
+	if config.Prefix == "" {
+		config.Prefix = DefaultPrefix
+	}
+
+	r := &Router{
+		client:   c,
+		config:   config,
+		commands: map[string]*command{},
+	}
+
+	r.unsubscribe = c.Events().Subscribe(func(event rcon.Event) {
+		if e, ok := event.(rcon.ChatMessageEvent); ok {
+			r.dispatch(e)
+		}
+	}, rcon.EventChatMessage)
+
+	return r
+}
+
+// Handle registers a handler for a command. name is matched case-insensitively and without the prefix, e.g. "report".
+func (r *Router) Handle(name string, handler Handler, opts ...Option) {
+	cmd := &command{
+		handler:  handler,
+		lastUsed: map[string]time.Time{},
+	}
+
+	for _, opt := range opts {
+		opt(cmd)
+	}
+
+	r.lock.Lock()
+	r.commands[strings.ToLower(name)] = cmd
+	r.lock.Unlock()
+}
+
+// Stop stops listening for chat messages.
+func (r *Router) Stop() {
+	r.unsubscribe()
+}
+
+// dispatch calls the handler of the command in a chat message, if any. Events are delivered on the client's reader
+// routine, so handlers are called on their own goroutine to let them execute commands.
+func (r *Router) dispatch(e rcon.ChatMessageEvent) {
+	if !strings.HasPrefix(e.Message, r.config.Prefix) {
+		return
+	}
+
+	args := SplitArgs(strings.TrimPrefix(e.Message, r.config.Prefix))
+	if len(args) == 0 {
+		return
+	}
+
+	name := strings.ToLower(args[0])
+
+	ctx := &Context{
+		PlayerID: e.PlayerID,
+		Name:     e.Name,
+		Command:  name,
+		Args:     args[1:],
+		Raw:      e.Message,
+		router:   r,
+	}
+
+	r.lock.Lock()
+	cmd, ok := r.commands[name]
+	if !ok {
+		r.lock.Unlock()
+		return
+	}
+
+	remaining := cmd.remainingCooldown(e.PlayerID, e.Time)
+	if remaining <= 0 {
+		cmd.lastUsed[e.PlayerID] = e.Time
+	}
+	r.lock.Unlock()
+
+	go func() {
+		if remaining > 0 {
+			if r.config.CooldownMessage != "" {
+				r.handleError(ctx, ctx.Reply(r.cooldownMessage(remaining)))
+			}
+			return
+		}
+
+		r.handleError(ctx, cmd.handler(ctx))
+	}()
+}
+
+func (r *Router) cooldownMessage(remaining time.Duration) string {
+	if !strings.Contains(r.config.CooldownMessage, "%s") {
+		return r.config.CooldownMessage
+	}
+
+	return fmt.Sprintf(r.config.CooldownMessage, remaining.Round(time.Second))
+}
+
+func (r *Router) handleError(ctx *Context, err error) {
+	if err != nil && r.config.ErrorHandler != nil {
+		r.config.ErrorHandler(ctx, err)
+	}
+}
+
+// remainingCooldown must be called with the router's lock held.
+func (cmd *command) remainingCooldown(playerID string, now time.Time) time.Duration {
+	if cmd.cooldown <= 0 {
+		return 0
+	}
+
+	last, ok := cmd.lastUsed[playerID]
+	if !ok {
+		return 0
+	}
+
+	return cmd.cooldown - now.Sub(last)
+}
+
+// Context holds the details of a chat command invocation.
+type Context struct {
+	PlayerID string
+	Name     string
+
+	// Command is the lowercased name of the command, without the prefix.
+	Command string
+	Args    []string
+
+	// Raw is the full chat message.
+	Raw string
+
+	router *Router
+}
+
+// Reply sends a message to the server's chat using the router's SayFormat, or Client.SayChunked if it isn't set. The
+// message is formatted with the dialect's SayCommand, so it may echo what players wrote.
+func (ctx *Context) Reply(message string) error {
+	client := ctx.router.client
+
+	if ctx.router.config.SayFormat == "" {
+		return client.SayChunked(message)
+	}
+
+	_, err := client.ExecCommand(client.Dialect().SayCommand(ctx.router.config.SayFormat, message))
+	return err
+}
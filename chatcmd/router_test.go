@@ -0,0 +1,82 @@
+package chatcmd
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon"
+	"github.com/refractorgscm/rcon/rcontest"
+	"testing"
+	"time"
+)
+
+func Test(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("SplitArgs", func() {
+		g.It("Should split arguments on whitespace", func() {
+			Expect(SplitArgs("report  bob   cheating")).To(Equal([]string{"report", "bob", "cheating"}))
+		})
+
+		g.It("Should keep quoted whitespace", func() {
+			Expect(SplitArgs(`report "bad guy" "" x`)).To(Equal([]string{"report", "bad guy", "", "x"}))
+		})
+
+		g.It("Should return nothing for empty messages", func() {
+			Expect(SplitArgs("   ")).To(BeEmpty())
+		})
+	})
+
+	g.Describe("Router", func() {
+		g.It("Should enforce per-player cooldowns", func() {
+			r := NewRouter(rcon.NewClient(&rcon.Config{}, nil), Config{})
+			defer r.Stop()
+
+			calls := make(chan *Context, 10)
+			r.Handle("Admin", func(ctx *Context) error {
+				calls <- ctx
+				return nil
+			}, WithCooldown(time.Minute))
+
+			now := time.Now()
+			r.dispatch(rcon.ChatMessageEvent{PlayerID: "1", Message: "!admin help me", Time: now})
+			r.dispatch(rcon.ChatMessageEvent{PlayerID: "1", Message: "!admin again", Time: now.Add(time.Second)})
+			r.dispatch(rcon.ChatMessageEvent{PlayerID: "2", Message: "!ADMIN", Time: now.Add(time.Second)})
+			r.dispatch(rcon.ChatMessageEvent{PlayerID: "1", Message: "!admin later", Time: now.Add(2 * time.Minute)})
+			r.dispatch(rcon.ChatMessageEvent{PlayerID: "1", Message: "admin no prefix", Time: now.Add(time.Hour)})
+
+			var args [][]string
+			for i := 0; i < 3; i++ {
+				ctx := <-calls
+				args = append(args, ctx.Args)
+			}
+
+			Expect(args).To(ConsistOf([]string{"help", "me"}, []string{}, []string{"later"}))
+			Consistently(calls).ShouldNot(Receive())
+		})
+
+		g.It("Should quote replies echoing what players wrote", func() {
+			commands := make(chan string, 1)
+			s, err := rcontest.NewServer(rcontest.Config{Password: "pw", Handler: func(command string) string {
+				commands <- command
+				return ""
+			}})
+			Expect(err).ToNot(HaveOccurred())
+			defer s.Close()
+
+			host, port := s.Addr()
+			c := rcon.NewClient(&rcon.Config{Host: host, Port: port, Password: "pw"}, nil)
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			r := NewRouter(c, Config{SayFormat: "say %s"})
+			defer r.Stop()
+
+			ctx := &Context{Command: "kick;quit", router: r}
+			Expect(ctx.Reply("Unknown command " + ctx.Command + "\nquit")).To(Succeed())
+			Expect(<-commands).To(Equal(`say "Unknown command kick;quit quit"`))
+		})
+	})
+}
@@ -0,0 +1,121 @@
+package rcon
+
+import "sync"
+
+// broadcastLog numbers delivered broadcasts and keeps the most recent ones so that consumers can resume after the last
+// broadcast they acknowledged.
+type broadcastLog struct {
+	lock       sync.Mutex
+	seq        uint64
+	acked      uint64
+	broadcasts []Broadcast
+	size       int
+}
+
+func (l *broadcastLog) next() uint64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.seq++
+
+	return l.seq
+}
+
+func (l *broadcastLog) enabled() bool {
+	return l.size > 0
+}
+
+func (l *broadcastLog) add(b Broadcast) {
+	if !l.enabled() {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if len(l.broadcasts) >= l.size {
+		l.broadcasts = l.broadcasts[1:]
+	}
+
+	l.broadcasts = append(l.broadcasts, b)
+}
+
+func (l *broadcastLog) since(seq uint64) []Broadcast {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var broadcasts []Broadcast
+	for _, b := range l.broadcasts {
+		if b.Seq > seq {
+			broadcasts = append(broadcasts, b)
+		}
+	}
+
+	return broadcasts
+}
+
+// LastBroadcastSeq returns the sequence number of the latest delivered broadcast, or 0 if none was delivered yet.
+func (c *Client) LastBroadcastSeq() uint64 {
+	c.broadcastLog.lock.Lock()
+	defer c.broadcastLog.lock.Unlock()
+
+	return c.broadcastLog.seq
+}
+
+// AckBroadcast marks every broadcast up to and including seq as processed. Acknowledging a sequence number lower than
+// the current checkpoint has no effect.
+func (c *Client) AckBroadcast(seq uint64) {
+	c.broadcastLog.lock.Lock()
+	defer c.broadcastLog.lock.Unlock()
+
+	if seq > c.broadcastLog.acked {
+		c.broadcastLog.acked = seq
+	}
+}
+
+// AckedBroadcastSeq returns the sequence number of the last acknowledged broadcast.
+func (c *Client) AckedBroadcastSeq() uint64 {
+	c.broadcastLog.lock.Lock()
+	defer c.broadcastLog.lock.Unlock()
+
+	return c.broadcastLog.acked
+}
+
+// ReplayBroadcasts returns the kept broadcasts with a sequence number greater than seq, oldest first. At most
+// BroadcastReplaySize broadcasts are kept, so the result starts later than seq+1 if older broadcasts were dropped.
+func (c *Client) ReplayBroadcasts(seq uint64) []Broadcast {
+	return c.broadcastLog.since(seq)
+}
+
+// ResumeBroadcasts adds listener as a BroadcastListener, after calling it with the kept broadcasts which weren't
+// acknowledged yet. Every broadcast is passed to listener once and in order, even if it arrives while the backlog is
+// replayed. The listener should call AckBroadcast with Broadcast.Seq once it processed a broadcast.
+func (c *Client) ResumeBroadcasts(listener BroadcastListener) {
+	var lock sync.Mutex
+	var last uint64
+
+	deliver := func(b Broadcast) {
+		if b.Seq <= last {
+			return
+		}
+
+		last = b.Seq
+		c.callHandler("broadcast listener", func() {
+			listener(b)
+		})
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	c.AddBroadcastListener(func(b Broadcast) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		deliver(b)
+	})
+
+	for _, b := range c.ReplayBroadcasts(c.AckedBroadcastSeq()) {
+		deliver(b)
+	}
+}
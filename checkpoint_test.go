@@ -0,0 +1,40 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Broadcast checkpoints", func() {
+		g.It("Should resume after the last acknowledged broadcast", func() {
+			c := NewClient(&Config{BroadcastHandler: NopBroadcastHandler, BroadcastReplaySize: 10}, nil)
+
+			c.handleBroadcast("first")
+			c.handleBroadcast("second")
+			c.handleBroadcast("third")
+			Expect(c.LastBroadcastSeq()).To(Equal(uint64(3)))
+
+			c.AckBroadcast(1)
+			c.AckBroadcast(0)
+			Expect(c.AckedBroadcastSeq()).To(Equal(uint64(1)))
+
+			var received []string
+			c.ResumeBroadcasts(func(b Broadcast) {
+				received = append(received, b.Message)
+				c.AckBroadcast(b.Seq)
+			})
+			c.handleBroadcast("fourth")
+
+			Expect(received).To(Equal([]string{"second", "third", "fourth"}))
+			Expect(c.AckedBroadcastSeq()).To(Equal(uint64(4)))
+			Expect(c.ReplayBroadcasts(3)).To(HaveLen(1))
+		})
+	})
+}
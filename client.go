@@ -1,6 +1,8 @@
 package rcon
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/refractorgscm/rcon/endian"
@@ -8,23 +10,88 @@ import (
 	"github.com/refractorgscm/rcon/packet"
 	"io"
 	"net"
+	"regexp"
+	"strconv"
 	"sync"
 	"time"
 )
 
 type Client struct {
 	*Config
-	conn     *net.TCPConn
+	conn     net.Conn
+	reader   *bufio.Reader
 	connLock sync.Mutex
 	log      Logger
 
-	terminate  chan uint8
+	state     State
+	stateLock sync.Mutex
+
+	addrLock sync.Mutex
+
+	breaker       circuitBreaker
+	alerts        alertDebouncer
+	reconnectStop chan struct{}
+	reconnectDone chan struct{}
+	nextRetryAt   time.Time
+
+	done     chan struct{}
+	doneErr  error
+	doneLock sync.Mutex
+
+	// readLoops receives the read loop of each new connection while ListenAndServeBroadcasts runs them on its caller's
+	// goroutine. It is nil otherwise.
+	readLoops     chan func()
+	readLoopsLock sync.Mutex
+
+	// resumed is non-nil while the client is paused, and is closed when it is resumed.
+	resumed   chan struct{}
+	pauseLock sync.Mutex
+
+	// inflight is the number of commands waiting for a response. drained is closed once it drops to zero while the
+	// client is draining.
+	draining  bool
+	inflight  int
+	drained   chan struct{}
+	drainLock sync.Mutex
+
+	routines   *routineGroup
 	waitGroup  *sync.WaitGroup
 	wqLock     sync.Mutex
 	rqLock     sync.Mutex
-	wgLock     sync.Mutex
-	writeQueue chan packet.Packet
+	writeQueue [priorityLevels]chan packet.Packet
 	readQueue  map[int32]chan packet.Packet
+	sentAt     map[int32]time.Time
+
+	stats   clientStats
+	history commandHistory
+	events  EventBus
+
+	dialect      *Dialect
+	dialectLock  sync.Mutex
+	authPreamble bool
+	authLock     sync.Mutex
+
+	recentBroadcasts recentBroadcasts
+	limiter          *commandLimiter
+	chaos            chaosState
+	patterns         patternRegistry
+	broadcastBuffer  broadcastBuffer
+	broadcastLog     broadcastLog
+	arrivals         arrivalCounter
+	responseMappings responseMappings
+
+	// patternFilter is the BroadcastFilter built from BroadcastPatterns and NonBroadcastPatterns, if any.
+	patternFilter *PatternFilter
+
+	macros    map[string][]string
+	macroLock sync.Mutex
+
+	fingerprint      ServerFingerprint
+	fingerprintKnown bool
+	fingerprintLock  sync.Mutex
+
+	oqLock      sync.Mutex
+	oqFlushLock sync.Mutex
 }
 
 type BroadcastHandler func(string)
@@ -36,9 +103,33 @@ type Config struct {
 	Port     uint16
 	Password string
 
+	// AuthMode controls how the client authenticates. Servers without a password can use AuthEmptyPassword or AuthNone,
+	// see AuthMode. Defaults to AuthPassword.
+	AuthMode AuthMode
+
+	// QueryPort is the port of the server's Steam query (A2S) interface. It is used by the query package. If zero, Port
+	// is used.
+	QueryPort uint16
+
 	// ConnTimeout is the timeout for TCP connection read/write operations with a deadline.
 	ConnTimeout time.Duration
 
+	// DialTimeout is the timeout for establishing the TCP connection.
+	//
+	// Default: ConnTimeout
+	DialTimeout time.Duration
+
+	// Transport establishes the connection to the server. Host may be a unix socket address (see UnixScheme) when
+	// using the default transport.
+	//
+	// Default: DefaultTransport
+	Transport Transport
+
+	// RetryInitialConnect makes Connect retry failed attempts using the ReconnectPolicy's backoff. If ReconnectPolicy
+	// is nil, a policy with default values is used. If the policy's MaxAttempts is zero, DefaultConnectAttempts is used.
+	// Authentication failures are not retried.
+	RetryInitialConnect bool
+
 	// QueueWriteTimeout is the timeout for writing to the internal packet queues. Higher values can cause delays if
 	// unexpected packets are received.
 	//
@@ -50,17 +141,111 @@ type Config struct {
 	// Default: 2s
 	QueueReadTimeout time.Duration
 
+	// LenientParsing tolerates common protocol violations by noncompliant servers, such as missing null terminators or
+	// trailing bytes after packet bodies. Violations are logged as protocol warnings instead of failing the read.
+	LenientParsing bool
+
+	// StrictProtocol rejects packets which violate the protocol spec, such as packets with bad null terminators,
+	// unexpected types or auth responses with mismatched IDs, instead of tolerating them. Each violation is emitted as a
+	// ProtocolViolationEvent with a hex dump of the packet, which helps reporting bugs to game developers. It takes
+	// precedence over LenientParsing.
+	StrictProtocol bool
+
+	// ArrivalSequence numbers the packets read from the server in the order they arrived, which preserves the order
+	// of command responses relative to broadcasts. The numbers are set in Broadcast.ArrivalSeq and
+	// HistoryEntry.ArrivalSeq, so that log reconstruction tools can place a command before the game events it caused.
+	ArrivalSequence bool
+
+	// MaxResponseSize is the maximum size of a packet body read from the server, and of a decompressed response, in
+	// bytes. Larger packets are rejected before their body is read, which tears the connection down with
+	// ErrResponseTooLarge, so that a broken or malicious server advertising a gigantic packet size can't exhaust the
+	// client's memory. Set it to a negative value to disable the limit.
+	//
+	// Default: DefaultMaxResponseSize
+	MaxResponseSize int
+
 	// EndianMode represents the byte order being used by whatever game you're using this library with. Valve games
 	// typically use little endian, but other games may use big endian. You can switch this as needed.
 	EndianMode endian.Mode
 
 	// BroadcastHandler is a function which will be called with a message whenever a broadcast message is received.
+	// If no BroadcastHandler, sink or listener is set, broadcasts are buffered instead, see BroadcastBufferSize.
 	BroadcastHandler BroadcastHandler
 
+	// ErrorHistorySize is the number of recent errors kept for Client.ErrorsSummary. Set it to a negative value to
+	// only count errors.
+	//
+	// Default: DefaultErrorHistorySize
+	ErrorHistorySize int
+
+	// BroadcastBufferSize is the number of broadcasts kept while no BroadcastHandler, sink or listener is set, so that
+	// they can be retrieved with DrainBroadcasts. Set it to a negative value to discard them instead.
+	//
+	// Default: DefaultBroadcastBufferSize
+	BroadcastBufferSize int
+
+	// BroadcastReplaySize is the number of recent broadcasts kept for Client.ReplayBroadcasts and
+	// Client.ResumeBroadcasts, so that consumers can resume after the last broadcast they acknowledged. No broadcasts
+	// are kept if it is 0.
+	BroadcastReplaySize int
+
+	// BroadcastSinks are additional functions which will be called with every broadcast message after the
+	// BroadcastHandler. Ready-made sinks which persist broadcasts can be found in the sinks package.
+	BroadcastSinks []BroadcastHandler
+
+	// BroadcastListeners are called with every broadcast after the BroadcastSinks. Unlike sinks, they receive the
+	// receive time and server timestamp of the broadcast along with the message.
+	BroadcastListeners []BroadcastListener
+
+	// BackfillBroadcasts executes the dialect's RecentLogCommand after every reconnect, and delivers the log lines
+	// following the last broadcast received before the outage as broadcasts with Broadcast.Replayed set. This closes gaps in kill or
+	// chat feeds caused by disconnects. The BroadcastHandler and BroadcastSinks receive replayed messages too.
+	BackfillBroadcasts bool
+
+	// BroadcastJSON parses broadcasts which are JSON objects, such as the events of newer games, into Broadcast.JSON
+	// so that BroadcastListeners don't have to parse them again. Other broadcasts, including JSON arrays, are delivered
+	// as text and can be unmarshalled with Broadcast.Decode.
+	BroadcastJSON bool
+
+	// BroadcastTimestamps extracts the server-side timestamp from broadcast messages. Extracted timestamps are passed to
+	// BroadcastListeners and used to estimate the clock skew reported in Stats.
+	BroadcastTimestamps TimestampExtractor
+
+	// BroadcastParsers turn broadcasts into events, such as PlayerJoinedEvent, which are emitted on the client's
+	// EventBus.
+	BroadcastParsers []BroadcastParser
+
+	// BroadcastSources are external sources of broadcast messages, such as log file tailers from the logtail package.
+	// They are started once the client has connected and stopped when it disconnects.
+	BroadcastSources []BroadcastSource
+
+	// BroadcastPatterns is an allowlist of broadcast messages. If set, only broadcasts matching at least one of the
+	// patterns are delivered to the BroadcastHandler and BroadcastSinks. Everything else is dropped.
+	BroadcastPatterns []*regexp.Regexp
+
+	// NonBroadcastPatterns is a denylist of broadcast messages. Broadcasts matching any of the patterns are dropped,
+	// even if they match BroadcastPatterns.
+	NonBroadcastPatterns []*regexp.Regexp
+
+	// BroadcastFilter decides which broadcasts are delivered. If set, BroadcastPatterns and NonBroadcastPatterns are
+	// ignored.
+	//
+	// Default: a PatternFilter built from BroadcastPatterns and NonBroadcastPatterns
+	BroadcastFilter BroadcastFilter
+
 	// BroadcastChecker is a function which should be implemented. It is used to check if a packet is a broadcast.
 	// If BroadcastChecker returns true, the packet will be treated as a broadcast.
 	BroadcastChecker BroadcastMessageChecker
 
+	// PacketIDs allocates the IDs of packets sent by the client. IDs keep increasing across reconnects.
+	//
+	// Default: a new IDAllocator for each client
+	PacketIDs *packet.IDAllocator
+
+	// RandomizePacketIDs makes each connection start allocating packet IDs at a random ID instead of continuing where
+	// the previous connection left off.
+	RandomizePacketIDs bool
+
 	// RestrictedPacketIDs is a slice of int32s which cannot be used as packet IDs. Some games use certain packet IDs to
 	// denote a special response or message. For example, Mordhau uses these packet IDs to denote broadcast messages.
 	//
@@ -68,26 +253,207 @@ type Config struct {
 	// that the received and sent data is as you'd expect and to avoid potential client/server confusion.
 	RestrictedPacketIDs []int32
 
-	// DisconnectHandler is a function which will be called when the client gets disconnected.
+	// DisconnectHandler is a function which will be called when the client gets disconnected. Whether or not it is set,
+	// disconnects are emitted on the event bus as DisconnectedEvent.
 	DisconnectHandler DisconnectHandler
+
+	// PanicHandler is called when a handler, such as the BroadcastHandler, DisconnectHandler or an event handler,
+	// panics. The panic is recovered, so the client keeps running. If nil, the panic is logged as an error.
+	PanicHandler PanicHandler
+
+	// DisconnectEvents receives a DisconnectEvent for every disconnect. If the client reconnects automatically, the
+	// event is sent once reconnecting has succeeded or been given up on. Events are dropped if the channel is full, so
+	// it should be buffered.
+	DisconnectEvents chan<- DisconnectEvent
+
+	// HeartbeatInterval is the interval at which heartbeats are sent to detect dead connections. If zero, no heartbeats
+	// are sent.
+	HeartbeatInterval time.Duration
+
+	// HeartbeatTimeout is the amount of time to wait for the response to a heartbeat.
+	//
+	// Default: ConnTimeout
+	HeartbeatTimeout time.Duration
+
+	// HeartbeatFailureThreshold is the number of consecutive missed heartbeats after which the connection is considered
+	// dead. The client then disconnects with ErrHeartbeatFailed, which triggers the ReconnectPolicy if one is set.
+	//
+	// Default: 3
+	HeartbeatFailureThreshold int
+
+	// HeartbeatCommand is the command sent as a heartbeat. It should be cheap for the server to execute. By default, an
+	// empty command is sent.
+	HeartbeatCommand string
+
+	// BroadcastHeartbeatResponses lets responses to heartbeats reach the BroadcastChecker. By default they are
+	// recognised by their packet ID and never treated as broadcasts, so that replies such as "Alive" don't leak into the
+	// broadcast handler of servers whose broadcasts can't be told apart from responses.
+	BroadcastHeartbeatResponses bool
+
+	// SubscribeCommands are executed after every connect, for servers which only send broadcasts to clients which
+	// subscribed to them.
+	SubscribeCommands []string
+
+	// ResubscribeOnReAuth re-establishes broadcast subscriptions after a successful ReAuthenticate, for servers which
+	// invalidate them along with the session, for example when the password is changed. The BroadcastSources are
+	// stopped and started again and the SubscribeCommands are executed again.
+	ResubscribeOnReAuth bool
+
+	// BroadcastInactivityTimeout enables a watchdog for servers which silently stop sending broadcasts. If no broadcast
+	// is received for this long, the SubscribeCommands are executed again. If there is still no broadcast after another
+	// period, the client disconnects with ErrBroadcastInactive, which triggers the ReconnectPolicy if one is set. Each
+	// step is reported as a BroadcastInactiveEvent. If zero, broadcast activity isn't watched.
+	BroadcastInactivityTimeout time.Duration
+
+	// ReconnectPolicy enables automatic reconnection after an unexpected disconnect. If nil, the client does not reconnect
+	// by itself.
+	ReconnectPolicy *ReconnectPolicy
+
+	// BreakerStateHandler is a function which will be called whenever the state of the reconnect circuit breaker
+	// changes.
+	BreakerStateHandler BreakerStateHandler
+
+	// Alerter is notified of significant conditions: authentication failures, the reconnect circuit opening, broadcasts
+	// staying silent after resubscribing and repeated heartbeat failures. If nil, no alerts are raised.
+	Alerter Alerter
+
+	// AlertDebounce is the minimum time between two alerts for the same condition, so that a flapping server doesn't
+	// flood the alerting system.
+	//
+	// Default: 5m
+	AlertDebounce time.Duration
+
+	// Tracer is used to create spans around client operations. If nil, operations are not traced.
+	Tracer Tracer
+
+	// CommandRedactor is used to redact commands before they are recorded in traces.
+	//
+	// Default: RedactArguments
+	CommandRedactor CommandRedactor
+
+	// CommandRetryPolicy enables retries of failed commands. Only commands which IsIdempotent reports as idempotent
+	// are retried. The policy can be overridden per call using WithRetry.
+	CommandRetryPolicy *RetryPolicy
+
+	// IsIdempotent reports whether a command can safely be retried. It can be overridden per call using WithIdempotent.
+	// If nil, no commands are retried unless marked using WithIdempotent.
+	IsIdempotent IdempotencyChecker
+
+	// Compression enables compressed command and response bodies using the provided codec. It must only be set if the
+	// server is known to support compressed bodies, such as servers and proxies built using this library. Compressed
+	// responses are decompressed using the codec named in the response.
+	Compression packet.Codec
+
+	// CompressionThreshold is the minimum size of a command for it to be compressed. Compressing small commands only
+	// makes them larger.
+	CompressionThreshold int
+
+	// Signer signs auth and command bodies and verifies signed responses. Like Compression, it must only be set if the
+	// server is known to support signed bodies.
+	Signer *packet.Signer
+
+	// ScriptDelay is the amount of time ExecScript waits between commands, which can be used to avoid flooding servers
+	// which rate limit commands.
+	ScriptDelay time.Duration
+
+	// SayPrefix is prepended to every chunk sent by SayChunked, e.g. "[Server] ".
+	SayPrefix string
+
+	// SayDelay is the amount of time SayChunked waits between chunks, so that the server doesn't kick or mute the sender
+	// for flooding the chat.
+	//
+	// Default: DefaultSayDelay
+	SayDelay time.Duration
+
+	// DryRun makes commands go through validation, tracing and the command history without being sent to the server,
+	// which allows automation scripts to be tested against production configs. Responses are synthesized by the
+	// DryRunResponder. It can be overridden per call using WithDryRun.
+	DryRun bool
+
+	// DryRunResponder synthesizes the responses of commands executed in dry-run mode. If nil, responses are empty.
+	DryRunResponder DryRunResponder
+
+	// Macros are registered with RegisterMacro when the client is created, keyed by macro name. Invalid macros are
+	// logged and skipped.
+	Macros map[string][]string
+
+	// MaxInFlightCommands limits the number of commands awaiting a response at once. Commands beyond the limit wait
+	// for a slot, and waiting commands are admitted round robin across initiators set with WithInitiator, so that an
+	// aggressive poller can't starve interactive users sharing the client. If zero, commands aren't limited.
+	MaxInFlightCommands int
+
+	// Chaos injects faults such as delayed writes, dropped broadcasts and forced reconnects, so that an application's
+	// resilience can be tested against a live server. It can be changed at runtime using Client.SetChaos. If nil, no
+	// faults are injected.
+	Chaos *Chaos
+
+	// HistorySize is the number of executed commands kept in the command history, which can be retrieved using
+	// Client.History. If zero, no history is kept.
+	HistorySize int
+
+	// AuditSinks are called with every executed command after it was redacted by the HistoryRedactor, for durable
+	// audit logs. Ready-made sinks which store commands in SQL databases can be found in the audit package.
+	AuditSinks []AuditSink
+
+	// HistoryRedactor is used to redact commands and responses before they are kept in the command history. If nil,
+	// entries are kept as is.
+	HistoryRedactor HistoryRedactor
+
+	// Dialect describes the quirks of the server's RCON implementation. If DetectDialect is false, it is used as is.
+	//
+	// Default: DefaultDialect
+	Dialect *Dialect
+
+	// DetectDialect enables a probe on connect which detects the server's quirks. If the observed behavior matches
+	// exactly one of the CandidateDialects, that dialect is used. Otherwise, a copy of Dialect adjusted to the observed
+	// behavior is used. The dialect in use can be retrieved with Client.Dialect.
+	DetectDialect bool
+
+	// CandidateDialects are the dialects which can be selected by dialect detection.
+	CandidateDialects []*Dialect
+
+	// DetectFingerprint executes the dialect's FingerprintCommand after every connect, and makes the parsed result
+	// available through Client.Fingerprint. If the fingerprint changed since the previous connection, for example
+	// because the server was updated or migrated, a FingerprintChangedEvent is emitted.
+	DetectFingerprint bool
+
+	// ProbeTimeout is the amount of time the dialect detection probe waits for responses.
+	//
+	// Default: 1s
+	ProbeTimeout time.Duration
+
+	// OfflineQueue holds commands queued with QueueCommand until the client is connected. If nil, QueueCommand
+	// cannot be used.
+	OfflineQueue *OfflineQueue
 }
 
 const DefaultTimeout = time.Second * 2
 
+// DefaultMaxResponseSize is the default MaxResponseSize. It is far larger than any response servers normally send.
+const DefaultMaxResponseSize = 4 << 20
+
 func NewClient(config *Config, logger Logger) *Client {
 	c := &Client{
-		Config:     config,
-		log:        &DefaultLogger{},
-		waitGroup:  &sync.WaitGroup{},
-		terminate:  make(chan uint8),
-		writeQueue: make(chan packet.Packet),
-		readQueue:  map[int32]chan packet.Packet{},
+		Config:    config,
+		log:       &DefaultLogger{},
+		waitGroup: &sync.WaitGroup{},
+		done:      make(chan struct{}),
+		readQueue: map[int32]chan packet.Packet{},
+		sentAt:    map[int32]time.Time{},
+	}
+
+	for i := range c.writeQueue {
+		c.writeQueue[i] = make(chan packet.Packet)
 	}
 
 	if logger != nil {
 		c.log = logger
 	}
 
+	if c.PacketIDs == nil {
+		c.PacketIDs = packet.NewIDAllocator()
+	}
+
 	if c.EndianMode == nil {
 		c.EndianMode = endian.Little
 	}
@@ -96,6 +462,26 @@ func NewClient(config *Config, logger Logger) *Client {
 		c.ConnTimeout = DefaultTimeout
 	}
 
+	if c.DialTimeout <= 0 {
+		c.DialTimeout = c.ConnTimeout
+	}
+
+	if c.SayDelay <= 0 {
+		c.SayDelay = DefaultSayDelay
+	}
+
+	if c.Transport == nil {
+		c.Transport = DefaultTransport
+	}
+
+	if c.HeartbeatTimeout <= 0 {
+		c.HeartbeatTimeout = c.ConnTimeout
+	}
+
+	if c.HeartbeatFailureThreshold <= 0 {
+		c.HeartbeatFailureThreshold = DefaultHeartbeatFailureThreshold
+	}
+
 	if c.BroadcastChecker == nil {
 		c.BroadcastChecker = func(p packet.Packet) bool {
 			return false
@@ -110,6 +496,92 @@ func NewClient(config *Config, logger Logger) *Client {
 		c.QueueReadTimeout = time.Second * 2
 	}
 
+	if c.BroadcastBufferSize == 0 {
+		c.BroadcastBufferSize = DefaultBroadcastBufferSize
+	}
+	c.broadcastBuffer.size = c.BroadcastBufferSize
+	c.broadcastLog.size = c.BroadcastReplaySize
+
+	if c.ErrorHistorySize == 0 {
+		c.ErrorHistorySize = DefaultErrorHistorySize
+	}
+	c.stats.errors.size = c.ErrorHistorySize
+
+	if c.MaxResponseSize == 0 {
+		c.MaxResponseSize = DefaultMaxResponseSize
+	}
+
+	if c.BroadcastFilter == nil && (len(c.BroadcastPatterns) > 0 || len(c.NonBroadcastPatterns) > 0) {
+		c.patternFilter = NewPatternFilter(c.BroadcastPatterns, c.NonBroadcastPatterns)
+		c.BroadcastFilter = c.patternFilter
+	}
+
+	if c.Tracer == nil {
+		c.Tracer = noopTracer{}
+	}
+
+	if c.CommandRedactor == nil {
+		c.CommandRedactor = RedactArguments
+	}
+
+	if c.ReconnectPolicy != nil {
+		c.ReconnectPolicy.setDefaults()
+		c.breaker.threshold = c.ReconnectPolicy.BreakerThreshold
+		c.breaker.cooldown = c.ReconnectPolicy.BreakerCooldown
+	}
+	if c.BreakerStateHandler != nil || c.Alerter != nil {
+		c.breaker.onChange = func(state BreakerState) {
+			if state == BreakerOpen {
+				_, failures := c.breaker.current()
+				c.raiseAlert(AlertCircuitOpen, "Reconnect circuit opened", nil, map[string]string{
+					"failures": strconv.Itoa(failures),
+					"cooldown": c.breaker.cooldown.String(),
+				})
+			}
+
+			if c.BreakerStateHandler != nil {
+				c.callHandler("breaker state handler", func() {
+					c.BreakerStateHandler(state)
+				})
+			}
+		}
+	}
+
+	c.events.callHandler = c.callHandler
+
+	if c.AlertDebounce <= 0 {
+		c.AlertDebounce = DefaultAlertDebounce
+	}
+
+	if c.Alerter != nil {
+		c.subscribeAlerts()
+	}
+
+	if c.Config.Dialect == nil {
+		c.Config.Dialect = DefaultDialect
+	}
+	c.dialect = c.Config.Dialect
+
+	if c.ProbeTimeout <= 0 {
+		c.ProbeTimeout = DefaultProbeTimeout
+	}
+
+	c.chaos.set(c.Config.Chaos)
+
+	if c.MaxInFlightCommands > 0 {
+		c.limiter = newCommandLimiter(c.MaxInFlightCommands)
+	}
+
+	for name, commands := range c.Macros {
+		if err := c.RegisterMacro(name, commands...); err != nil {
+			c.log.Error("Could not register macro. Error: ", err)
+		}
+	}
+
+	if c.OfflineQueue != nil && c.OfflineQueue.Store == nil {
+		c.OfflineQueue.Store = NewMemoryCommandStore()
+	}
+
 	return c
 }
 
@@ -117,6 +589,16 @@ func (c *Client) SetBroadcastHandler(handler BroadcastHandler) {
 	c.BroadcastHandler = handler
 }
 
+// AddBroadcastSink adds a function which will be called with every broadcast message after the BroadcastHandler.
+func (c *Client) AddBroadcastSink(sink BroadcastHandler) {
+	c.BroadcastSinks = append(c.BroadcastSinks, sink)
+}
+
+// AddBroadcastListener adds a function which will be called with every broadcast after the BroadcastSinks.
+func (c *Client) AddBroadcastListener(listener BroadcastListener) {
+	c.BroadcastListeners = append(c.BroadcastListeners, listener)
+}
+
 func (c *Client) SetDisconnectHandler(handler DisconnectHandler) {
 	c.DisconnectHandler = handler
 }
@@ -129,183 +611,466 @@ func (c *Client) SetRestrictedPacketIDs(restrictedIDs []int32) {
 	c.RestrictedPacketIDs = restrictedIDs
 }
 
+// Connect connects to the server and authenticates. If the client is already connected, Connect does nothing and
+// returns nil. If another call to Connect is in progress, ErrConnectInProgress is returned.
+//
+// If RetryInitialConnect is set, failed attempts are retried using the reconnect backoff policy. If every attempt
+// fails, an *errs.AttemptsError holding the error of each attempt is returned.
 func (c *Client) Connect() error {
-	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", c.Host, c.Port), c.ConnTimeout)
+	return c.ConnectContext(context.Background())
+}
+
+// ConnectContext connects to the server and authenticates, like Connect. If ctx is cancelled while dialing,
+// authenticating or waiting to retry, the attempt is aborted and ctx.Err() is returned. ctx only applies to connecting,
+// cancelling it once the client is connected has no effect.
+func (c *Client) ConnectContext(ctx context.Context) error {
+	if c.RetryInitialConnect {
+		return c.connectWithRetry(ctx)
+	}
+
+	return c.connectOnce(ctx)
+}
+
+func (c *Client) connectOnce(ctx context.Context) error {
+	return c.connectOnceThen(ctx, nil)
+}
+
+// connectOnceThen connects like connectOnce. If connected isn't nil, it is called with the state lock held as the client
+// is marked connected.
+func (c *Client) connectOnceThen(ctx context.Context, connected func()) error {
+	c.stateLock.Lock()
+	switch c.state {
+	case StateConnected:
+		c.stateLock.Unlock()
+		c.log.Debug("Connect called while already connected")
+		return nil
+	case StateConnecting:
+		c.stateLock.Unlock()
+		return errs.ErrConnectInProgress
+	}
+	c.state = StateConnecting
+	c.stateLock.Unlock()
+
+	ctx, span := c.startSpan(ctx, SpanConnect, nil)
+
+	err := c.connect(ctx)
+	span.End(err)
+
+	c.stateLock.Lock()
 	if err != nil {
-		return errors.Wrap(err, "tcp dial failure")
+		if c.conn != nil {
+			_ = c.conn.Close()
+			c.conn = nil
+		}
+
+		c.state = StateDisconnected
+		c.stateLock.Unlock()
+
+		return err
+	}
+	c.stateLock.Unlock()
+
+	c.startConnection(connected)
+
+	return nil
+}
+
+// startConnection marks the client as connected and starts the routines for the current connection, which must already
+// be authenticated. If connected isn't nil, it is called with the state lock held as the client is marked connected.
+func (c *Client) startConnection(connected func()) {
+	c.stateLock.Lock()
+	routines := newRoutineGroup(c.waitGroup)
+	c.routines = routines
+	c.state = StateConnected
+	conn, reader := c.conn, c.reader
+	if connected != nil {
+		connected()
+	}
+	c.stateLock.Unlock()
+
+	c.renewDone()
+	c.startRoutines(routines, conn, reader)
+
+	c.events.emit(ConnectedEvent{Address: c.addressString()})
+}
+
+func (c *Client) connect(ctx context.Context) (err error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
 	}
 	c.log.Debug("Dial successful, connection established.")
 
-	var ok bool
-	c.conn, ok = conn.(*net.TCPConn)
-	if !ok {
-		return errors.Wrap(err, "tcp dial failure")
+	c.useConn(conn)
+
+	// Authentication and dialect detection read from the connection directly, so they are aborted by closing it once
+	// ctx is cancelled.
+	if ctx.Done() != nil {
+		stop := make(chan struct{})
+		cancelled := make(chan bool, 1)
+
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = conn.Close()
+				cancelled <- true
+			case <-stop:
+				cancelled <- false
+			}
+		}()
+
+		defer func() {
+			close(stop)
+
+			if <-cancelled {
+				err = ctx.Err()
+			}
+		}()
 	}
 
 	if err := c.conn.SetDeadline(time.Now().Add(c.ConnTimeout)); err != nil {
-		return errors.Wrap(err, "could not set tcp connection deadline")
+		return errors.Wrap(err, "could not set connection deadline")
 	}
 
 	if err := c.authenticate(); err != nil {
 		c.log.Debug("Authentication failed", err)
+		c.stats.recordError(err)
+
+		if errors.Cause(err) == errs.ErrAuthentication {
+			c.events.emit(AuthFailedEvent{Address: c.addressString(), Err: err})
+		}
+
 		return err
 	}
 
-	c.log.Debug("Starting writer routine")
+	if c.DetectDialect {
+		c.log.Debug("Detecting dialect")
+
+		if err := c.detectDialect(); err != nil {
+			c.log.Error("Dialect detection failed. Error: ", err)
+			c.stats.recordError(err)
+		}
+	}
+
+	c.stats.recordConnect()
+
+	return nil
+}
+
+// dial establishes a connection to the current address using the transport. Transports don't accept a context, so if
+// ctx is cancelled first, the dial is abandoned and its connection is closed once it is established.
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	host, port := c.Address()
+
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+
+	result := make(chan dialResult, 1)
 	go func() {
-		c.wgLock.Lock()
-		c.waitGroup.Add(1)
-		c.wgLock.Unlock()
-		c.startWriter()
+		conn, err := c.Transport.Dial(host, port, c.DialTimeout)
+		result <- dialResult{conn: conn, err: err}
 	}()
 
+	select {
+	case res := <-result:
+		if res.err != nil {
+			return nil, errors.Wrap(res.err, "dial failure")
+		}
+
+		return res.conn, nil
+	case <-ctx.Done():
+		go func() {
+			if res := <-result; res.conn != nil {
+				_ = res.conn.Close()
+			}
+		}()
+
+		return nil, ctx.Err()
+	}
+}
+
+// useConn makes conn the current connection.
+func (c *Client) useConn(conn net.Conn) {
+	c.conn = conn
+	c.stats.resetSocket()
+
+	if c.RandomizePacketIDs {
+		c.PacketIDs.Randomize()
+	}
+
+	// A single buffered reader is used for the lifetime of the connection so that no buffered data is lost between
+	// packet reads.
+	c.reader = bufio.NewReader(&countingReader{r: c.conn, stats: &c.stats})
+}
+
+// startRoutines starts the reader, writer and heartbeat routines for a newly established connection. If any of them
+// fails, the connection is torn down with its error.
+func (c *Client) startRoutines(routines *routineGroup, conn net.Conn, reader *bufio.Reader) {
+	// The start of the connection must be marked before the reader receives broadcasts on it.
+	backfill := c.BackfillBroadcasts && c.recentBroadcasts.connected()
+
+	c.log.Debug("Starting writer routine")
+	routines.Go(func(ctx context.Context) error {
+		return c.runWriter(ctx, conn)
+	})
+
 	c.log.Debug("Starting reader routine")
+	c.startReadLoop(routines.wrap(func(ctx context.Context) error {
+		return c.runReader(ctx, conn, reader)
+	}))
+
+	if c.HeartbeatInterval > 0 {
+		c.log.Debug("Starting heartbeat routine")
+		routines.Go(c.runHeartbeat)
+	}
+
+	if c.DetectFingerprint {
+		routines.Go(c.detectFingerprint)
+	}
+
+	if backfill {
+		routines.Go(c.backfillBroadcasts)
+	}
+
+	if len(c.SubscribeCommands) > 0 || c.BroadcastInactivityTimeout > 0 {
+		c.log.Debug("Starting broadcast watchdog routine")
+		routines.Go(c.runBroadcastWatchdog)
+	}
+
+	// The reader can only return once the connection is closed, so the connection is torn down as soon as the group is
+	// cancelled rather than once every routine returned. This is a no-op if the group was cancelled by a disconnect.
 	go func() {
-		c.wgLock.Lock()
-		c.waitGroup.Add(1)
-		c.wgLock.Unlock()
-		c.startReader()
+		err := routines.Err()
+		c.disconnectRoutines(routines, err, false)
 	}()
 
-	return nil
+	c.startBroadcastSources()
+
+	// Execute any commands which were queued while we were disconnected
+	go c.flushOfflineQueue()
 }
 
-func (c *Client) startWriter() {
-	defer func() {
-		c.wgLock.Lock()
-		c.waitGroup.Done()
-		c.wgLock.Unlock()
-		c.log.Debug("Writer routine terminated")
-	}()
+func (c *Client) runWriter(ctx context.Context, conn net.Conn) error {
+	defer c.log.Debug("Writer routine terminated")
 
 	for {
-		select {
-		case p := <-c.writeQueue:
-			if err := c.sendPacket(p); err != nil {
-				c.log.Debug("Could not write packet. Error: ", err)
-			}
-			break
-		case <-c.terminate:
+		p, ok := c.dequeuePacket(ctx.Done())
+		if !ok {
 			c.log.Debug("Writer routine received termination signal")
-			return
+			return nil
 		}
-	}
-}
-
-func (c *Client) startReader() {
-	defer func() {
-		c.wgLock.Lock()
-		c.waitGroup.Done()
-		c.wgLock.Unlock()
-		c.log.Debug("Reader routine terminated")
-	}()
 
-	terminate := false
+		if err := c.injectWriteFault(ctx); err != nil {
+			return err
+		}
 
-	readChan := make(chan packet.Packet)
+		// The send time is recorded before writing since the response could be read before the write returns.
+		c.markSent(p.ID())
 
-	// Start select routine
-	go func() {
-		for {
-			// Add packet to mailbox
-			select {
-			case p := <-readChan:
-				c.readQueue[p.ID()] <- p
-				c.log.Debug("Packet added to mailbox ID: ", p.ID())
-				break
-			case <-c.terminate:
-				terminate = true
-				c.log.Debug("Reader routine received termination signal")
-				return
-			}
+		if err := c.sendPacketTo(conn, p); err != nil {
+			c.log.Debug("Could not write packet. Error: ", err)
 		}
-	}()
+	}
+}
+
+func (c *Client) runReader(ctx context.Context, conn net.Conn, reader *bufio.Reader) error {
+	defer c.log.Debug("Reader routine terminated")
 
 	for {
-		// Break out of the loop if we're meant to terminate this routine.
-		// We can be sure that terminate will be reached beyond the blocking readPacket call because the connection
-		// was closed before we received the termination signal, so the blocking readPacket call will error out and
-		// not block the termination instruction.
-		if terminate {
-			break
+		p, err := c.readPacket(conn, reader)
+
+		// The group's context is cancelled before the connection is closed, so read errors caused by a disconnect are
+		// never mistaken for connection failures.
+		if ctx.Err() != nil {
+			return nil
 		}
 
-		p, err := c.readPacket()
 		if err != nil {
 			switch errors.Cause(err) {
-			case errs.ErrNotConnected:
-				break
 			case io.EOF:
 				c.log.Error("Disconnected by the server. Error: ", err)
-				c.disconnect(err)
-				break
+				return err
 			case io.ErrClosedPipe:
-				c.disconnect(err)
 				c.log.Error("Attempted to read from a closed pipe. Error: ", err)
-				break
+				return err
 			default:
 				c.log.Debug("Reader error: ", err)
+				c.stats.recordError(err)
 			}
 
 			continue
 		}
 
 		packetID := p.ID()
+		seq := c.nextArrivalSeq()
 
 		// Check if this packet is a broadcast message
-		if c.BroadcastChecker(p) {
+		if !c.isHeartbeatResponse(p) && c.BroadcastChecker(p) {
 			c.log.Debug("Packet ", packetID, " is a broadcast message")
 
-			// If this packet is a broadcast, notify broadcast listener and jump to next read.
-			if c.BroadcastHandler != nil {
-				newBody := p.Body()
-				newBody = newBody[:len(newBody)-1] // strip null terminator
+			// If this packet is a broadcast, notify broadcast listeners and jump to next read.
+			newBody := p.Body()
+			newBody = newBody[:len(newBody)-1] // strip null terminator
 
-				c.BroadcastHandler(string(newBody))
-			}
+			c.handleBroadcastSeq(string(newBody), seq)
 
 			continue
-		} else {
-			c.log.Debug("Packet ", packetID, " was not a broadcast", p.Type(), string(p.Body()))
+		}
 
-			// Put packet on the read channel if it's not a broadcast
-			select {
-			case readChan <- p:
-				break
-			case <-time.After(c.QueueWriteTimeout):
-				c.log.Debug("Packet ", packetID, " was unexpected (no open mailbox)")
-				break
-			}
+		c.log.Debug("Packet ", packetID, " was not a broadcast", p.Type(), string(p.Body()))
+
+		if c.ArrivalSequence {
+			p = arrivedPacket{Packet: p, seq: seq}
 		}
+
+		c.routePacket(p)
 	}
 }
 
+// routePacket delivers a packet read by the reader routine according to its type. Command responses are delivered to
+// the mailbox of the command they respond to, and auth responses are only delivered to a pending auth request, so that
+// an auth response can never be mistaken for the response to a command.
+func (c *Client) routePacket(p packet.Packet) {
+	switch p.Type() {
+	case packet.TypeServerDataResponseValue:
+		c.deliverPacket(p)
+	case packet.TypeServerDataAuthResponse:
+		// Failed auth responses carry AuthFailedID instead of the ID of the auth request.
+		if p.ID() == packet.AuthPacketID || p.ID() == packet.AuthFailedID {
+			c.deliverPacketTo(packet.AuthPacketID, p)
+			return
+		}
+
+		// Like packets of non-standard types, these are still delivered if a command is waiting for them, since some
+		// servers respond to commands using this type.
+		c.log.Debug("Auth response has unexpected ID ", p.ID())
+		if c.protocolViolation(p, packetIDOffset, fmt.Sprintf("auth response ID %d or %d", packet.AuthPacketID,
+			packet.AuthFailedID), fmt.Sprintf("ID %d", p.ID())) {
+			return
+		}
+
+		c.deliverPacket(p)
+	default:
+		// Some servers respond to commands using non-standard packet types, so these are still delivered if a command
+		// is waiting for them.
+		c.log.Debug("Packet ", p.ID(), " has non-standard type ", p.Type())
+		if c.protocolViolation(p, c.packetTypeOffset(), fmt.Sprintf("packet type %d or %d", packet.TypeCommandRes,
+			packet.TypeAuthRes), fmt.Sprintf("type %d", p.Type())) {
+			return
+		}
+
+		c.deliverPacket(p)
+	}
+}
+
+// Close disconnects from the server and waits for the client's routines, including any reconnect routine, to return. If
+// the client is not connected, ErrNotConnected is returned.
+//
+// Since Close waits for the client's routines, it must not be called synchronously from handlers which are run by them,
+// such as the BroadcastHandler or event handlers.
 func (c *Client) Close() error {
 	c.log.Debug("Close called")
 
-	if c.conn == nil {
-		return errs.ErrNotConnected
+	// Stop reconnecting first so that the reconnect routine can't reconnect after we disconnect.
+	stopped := c.stopReconnect()
+
+	c.stateLock.Lock()
+	routines := c.routines
+	c.stateLock.Unlock()
+
+	disconnected := c.disconnect(nil)
+
+	if routines != nil {
+		routines.Wait()
 	}
 
-	c.disconnect(nil)
+	if !disconnected && !stopped {
+		return errs.ErrNotConnected
+	}
 
 	return nil
 }
 
-func (c *Client) disconnect(err error) {
-	// Closing the termination channel makes all routines return
-	close(c.terminate)
+// disconnect tears down the current connection. It returns false if the client was not connected, in which case nothing
+// is done.
+func (c *Client) disconnect(err error) bool {
+	return c.disconnectRoutines(nil, err, false)
+}
+
+// disconnectForRestart tears down the current connection like disconnect, but without closing the channel returned by
+// Done, since the caller connects again right away.
+func (c *Client) disconnectForRestart() bool {
+	return c.disconnectRoutines(nil, nil, true)
+}
+
+// disconnectRoutines tears down the connection run by routines, or the current connection if routines is nil. It
+// returns false if that connection is no longer established, in which case nothing is done. This makes it safe for a
+// failing routine and Close to race each other. If restart is set, the client is not marked done.
+func (c *Client) disconnectRoutines(routines *routineGroup, err error, restart bool) bool {
+	c.stateLock.Lock()
+	if c.state != StateConnected || (routines != nil && routines != c.routines) {
+		c.stateLock.Unlock()
+		return false
+	}
+
+	// Cancelling the group makes all routines return. It must happen before the connection is closed so that the
+	// reader can tell the resulting read error apart from a connection failure.
+	c.routines.stop(err)
 
 	_ = c.conn.Close()
 	c.conn = nil
 
+	c.state = StateDisconnected
+
+	event := c.newDisconnectEvent(err)
+	// Disconnects during maintenance are anticipated, so they shouldn't raise alerts.
+	event.Expected = event.Expected || c.Paused()
+
+	reconnecting := false
+	if err != nil {
+		reconnecting = c.startReconnect(event)
+	}
+	c.stateLock.Unlock()
+
+	// If the client is reconnecting, the event is sent and the client is marked done once the outcome is known.
+	if !reconnecting {
+		c.sendDisconnectEvent(event)
+
+		if !restart {
+			c.markDone(err)
+		}
+	}
+
+	// The error is recorded first, so that it is attributed to the connection which was lost.
+	c.stats.recordError(err)
+	c.stats.recordDisconnect()
+
+	c.stopBroadcastSources()
+
+	c.events.emit(DisconnectedEvent{Address: event.Address, Err: err, Expected: event.Expected})
+
 	if c.DisconnectHandler != nil {
-		c.DisconnectHandler(err, err == nil)
+		c.callHandler("disconnect handler", func() {
+			c.DisconnectHandler(err, event.Expected)
+		})
 	}
+
+	return true
 }
 
 func (c *Client) authenticate() error {
-	p := c.newClientPacket(packet.TypeAuth, c.Password)
+	if c.authMode() == AuthNone {
+		c.log.Debug("Skipping authentication")
+		return nil
+	}
+
+	p := c.newClientPacketWithID(packet.TypeAuth, c.authPassword(), packet.AuthPacketID)
 
 	if err := c.sendPacket(p); err != nil {
 		return errors.Wrap(err, "could not send packet")
@@ -316,8 +1081,19 @@ func (c *Client) authenticate() error {
 		return errors.Wrap(err, "could not get auth response")
 	}
 
+	// Some servers send an empty SERVERDATA_RESPONSE_VALUE packet before the auth response.
+	c.authPreamble = res.Type() == packet.TypeCommandRes && res.ID() == p.ID() && len(res.Body()) <= 1
+	if c.authPreamble {
+		c.log.Debug("Skipping empty response preceding auth response")
+
+		res, err = c.readPacketTimeout()
+		if err != nil {
+			return errors.Wrap(err, "could not get auth response")
+		}
+	}
+
 	if res.Type() != packet.TypeAuthRes {
-		return errors.Wrap(err, "packet was not of the type auth response")
+		return fmt.Errorf("packet was not of the type auth response (found type %d)", res.Type())
 	}
 
 	if res.ID() == packet.AuthFailedID {
@@ -329,88 +1105,346 @@ func (c *Client) authenticate() error {
 	return nil
 }
 
+// WaitGroup returns a WaitGroup tracking the routines of the current connection.
+//
+// Deprecated: the WaitGroup is done as soon as the connection is lost, even if the client is reconnecting. Use Done
+// instead.
 func (c *Client) WaitGroup() *sync.WaitGroup {
 	return c.waitGroup
 }
 
-func (c *Client) ExecCommand(command string) (string, error) {
-	p := c.newClientPacket(packet.TypeCommand, command)
+// ExecCommand executes a command and returns the response. Options can be used to attach metadata such as the
+// initiator of the command, which is recorded in traces and the command history.
+func (c *Client) ExecCommand(command string, opts ...ExecOption) (string, error) {
+	return c.execCommandTraced(context.Background(), command, PriorityNormal, opts)
+}
+
+// ExecCommandContext executes a command like ExecCommand. If ctx is cancelled while waiting for the response, the
+// context's error is returned. Any span carried by ctx is used as the parent of the command's span.
+func (c *Client) ExecCommandContext(ctx context.Context, command string, opts ...ExecOption) (string, error) {
+	return c.execCommandTraced(ctx, command, PriorityNormal, opts)
+}
+
+// ExecCommandPriority executes a command with the provided priority. Queued commands with a higher priority are sent
+// before queued commands with a lower priority.
+func (c *Client) ExecCommandPriority(command string, priority Priority, opts ...ExecOption) (string, error) {
+	return c.execCommandTraced(context.Background(), command, priority, opts)
+}
+
+// ExecCommandRaw executes a command whose body is arbitrary bytes, such as serialized structs or compressed data, and
+// returns the raw response body. Bodies are passed through byte for byte: neither the command nor the response is
+// trimmed or re-encoded, and they may contain null bytes.
+//
+// The command is recorded in traces and the command history like any other command, so a CommandRedactor and
+// HistoryRedactor which can cope with binary data should be set when using it.
+func (c *Client) ExecCommandRaw(command []byte, opts ...ExecOption) ([]byte, error) {
+	// Go strings can hold arbitrary bytes, so the conversions don't alter the bodies.
+	opts = append(opts, func(o *execOptions) {
+		o.raw = true
+	})
+
+	res, err := c.execCommandTraced(context.Background(), string(command), PriorityNormal, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(res), nil
+}
+
+func (c *Client) execCommandTraced(ctx context.Context, command string, priority Priority, opts []ExecOption) (string, error) {
+	ctx, o := applyOptions(ctx, opts)
+	md := o.metadata
+
+	attrs := c.commandAttributes(command)
+	for k, v := range md.attributes() {
+		attrs[k] = v
+	}
+
+	dryRun := c.isDryRun(o)
+	if dryRun {
+		attrs[AttrDryRun] = "true"
+	}
+
+	ctx, span := c.startSpan(ctx, SpanExec, attrs)
+	start := time.Now()
+
+	var res string
+	var err error
+	if dryRun {
+		res, err = c.execDryRun(command, priority)
+	} else {
+		res, err = c.execWithRetry(ctx, command, priority, o)
+	}
+	duration := time.Since(start)
+	c.stats.recordCommand(duration, err)
+	span.End(err)
+
+	if err != nil {
+		c.events.emit(CommandFailedEvent{Command: c.CommandRedactor(command), Metadata: md, Err: err})
+	}
+
+	c.recordHistory(HistoryEntry{
+		Command:  command,
+		Response: res,
+		Err:      err,
+		Priority: priority,
+		Metadata: md,
+		Time:     start,
+		Duration: duration,
+		DryRun:   dryRun,
+
+		ArrivalSeq: o.arrivalSeq,
+	})
+
+	return res, err
+}
+
+// execCommand executes a single command. If the raw option is set, the response body is returned exactly as it was sent
+// instead of being trimmed.
+func (c *Client) execCommand(ctx context.Context, command string, priority Priority, o *execOptions) (string, error) {
+	if !c.isConnected() {
+		if c.Paused() {
+			return "", errs.ErrPaused
+		}
+
+		if state, _ := c.breaker.current(); state == BreakerOpen {
+			return "", errs.ErrCircuitOpen
+		}
+
+		return "", errs.ErrNotConnected
+	}
+
+	if !c.beginCommand() {
+		return "", errs.ErrDraining
+	}
+	defer c.endCommand()
+
+	payload, err := c.commandPayload(command)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.limiter.acquire(ctx, o.metadata.Initiator); err != nil {
+		return "", errors.Wrap(err, "waiting for a command slot")
+	}
+	defer c.limiter.release()
+
+	p := c.newClientPacket(packet.TypeCommand, payload)
 
-	c.log.Debug("Executing command: ", command)
+	c.log.Debug("Executing command: ", command, " Priority: ", priority)
 
-	if err := c.enqueuePacket(p, true); err != nil {
-		return "", errors.Wrap(err, "could not enqueue command packet")
+	if o.noResponse {
+		return "", c.execNoResponse(ctx, p, priority, o.confirm)
 	}
 
-	res, err := c.getResponse(p.ID())
+	res, body, err := c.awaitResponse(ctx, p, priority, o.raw)
 	if err != nil {
-		return "", errors.Wrap(err, "could not get command response")
+		return "", err
 	}
 
-	// Trim off null terminator
-	body := res.Body()
-	body = body[:len(body)-1]
+	o.arrivalSeq = arrivalSeq(res)
 
-	return string(body), nil
+	if c.Signer != nil {
+		if body, err = c.Signer.Verify(res.Type(), body); err != nil {
+			return "", errors.Wrap(err, "could not verify command response")
+		}
+	}
+
+	if c.Compression != nil {
+		if body, err = packet.DecompressBodyLimit(body, c.MaxResponseSize); err != nil {
+			return "", errors.Wrap(err, "could not decompress command response")
+		}
+	}
+
+	if o.raw {
+		return string(body), nil
+	}
+
+	return c.canonicalResponse(string(body)), nil
 }
 
-func (c *Client) ExecCommandNoResponse(command string) error {
-	p := c.newClientPacket(packet.TypeCommand, command)
+// awaitResponse queues a command packet and returns its response packet and body. If the dialect has a
+// ResponseTerminator, a response split across several packets is collected with it, and the last packet is returned.
+func (c *Client) awaitResponse(ctx context.Context, p packet.Packet, priority Priority,
+	raw bool) (packet.Packet, []byte, error) {
+	if t := c.Dialect().ResponseTerminator; t != nil {
+		return c.collectResponse(ctx, p, priority, t, raw)
+	}
 
-	c.log.Debug("Executing command (no response needed): ", command)
+	if err := c.enqueuePacket(p, priority, true); err != nil {
+		return nil, nil, errors.Wrap(err, "could not enqueue command packet")
+	}
 
-	if err := c.enqueuePacket(p, true); err != nil {
-		return errors.Wrap(err, "could not enqueue command packet")
+	res, err := c.getResponse(ctx, p.ID())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not get command response")
 	}
 
-	// We still need to try to get the response or the connection will be put in a bad state.
-	// Since we're not actually expecting a response, we can just ignore it or any errors which occurred.
-	_, _ = c.getResponse(p.ID())
+	return res, packetBody(res, raw), nil
+}
 
-	return nil
+// packetBody returns the body of a received packet without its null terminator. If raw is set, leading and trailing
+// null bytes and newlines are kept, see packet.ClientPacket.RawBody.
+func packetBody(p packet.Packet, raw bool) []byte {
+	if a, ok := p.(arrivedPacket); ok {
+		p = a.Packet
+	}
+
+	if cp, ok := p.(*packet.ClientPacket); ok && raw {
+		return cp.RawBody()
+	}
+
+	body := p.Body()
+
+	return body[:len(body)-1]
 }
 
-func (c *Client) enqueuePacket(p packet.Packet, createMailbox bool) error {
+// commandPayload returns the body of the packet carrying command, compressing it if configured to. An error is returned
+// if the body is larger than the dialect allows.
+func (c *Client) commandPayload(command string) (string, error) {
+	payload := command
+	if c.Compression != nil && len(command) >= c.CompressionThreshold {
+		compressed, err := packet.CompressBody(c.Compression, []byte(command))
+		if err != nil {
+			return "", err
+		}
+
+		payload = string(compressed)
+	}
+
+	if max := c.Dialect().MaxPayloadSize; max > 0 && len(payload) > max {
+		return "", errors.Wrapf(errs.ErrPayloadTooLarge, "command is %d bytes, the maximum is %d", len(payload), max)
+	}
+
+	return payload, nil
+}
+
+// ExecCommandNoResponse executes a command without waiting for its response, like ExecCommand with
+// WithNoResponse(false).
+func (c *Client) ExecCommandNoResponse(command string) error {
+	_, err := c.ExecCommand(command, WithNoResponse(false))
+	return err
+}
+
+// enqueuePacket puts a packet on the write queue. If createMailbox is true, a mailbox is opened for the packet's response
+// before the packet is queued, so that the response can't arrive before the mailbox exists. The response must then be
+// collected with getResponse, which closes the mailbox.
+//
+// enqueuePacket and getResponse can be called from many goroutines at once. Packets are written as soon as they are
+// dequeued without waiting for responses to earlier packets, which are matched to their mailbox by packet ID.
+func (c *Client) enqueuePacket(p packet.Packet, priority Priority, createMailbox bool) error {
+	if createMailbox {
+		// Create a mailbox for this packet. A mailbox is simply a channel which responses will be put on. It is
+		// buffered so that the reader routine never blocks on a caller which stopped waiting for its response.
+		c.rqLock.Lock()
+		c.readQueue[p.ID()] = make(chan packet.Packet, 1)
+		c.rqLock.Unlock()
+	}
+
 	// We use c.QueueWriteTimeout to set a timeout for packet queuing. If something happens and the packet cannot be put onto the
 	// queue within the set timeout, an error is returned.
 	select {
-	case c.writeQueue <- p:
+	case c.writeQueue[priority.normalize()] <- p:
 		c.log.Debug("Packet queued", " ID: ", p.ID())
+		return nil
+	case <-time.After(c.QueueWriteTimeout):
+		c.log.Debug("Packet queue timed out", " ID: ", p.ID())
 
 		if createMailbox {
-			// Create a mailbox for this packet. A mailbox is simply a channel which responses will be put on.
-			c.readQueue[p.ID()] = make(chan packet.Packet)
+			c.closeMailbox(p.ID())
 		}
 
-		return nil
-	case <-time.After(c.QueueWriteTimeout):
-		c.log.Debug("Packet queue timed out", " ID: ", p.ID())
 		return errors.Wrap(errs.ErrQueueTimeout, "packet queue operation timed out")
 	}
 }
 
-func (c *Client) getResponse(packetID int32) (packet.Packet, error) {
-	defer func() {
-		// When read operation is complete, delete packet mailbox.
-		c.rqLock.Lock()
-		close(c.readQueue[packetID])
-		delete(c.readQueue, packetID)
-		c.rqLock.Unlock()
-	}()
+// markSent records the time a packet was written to the connection if it has an open mailbox, so that the round trip
+// time can be measured once its response is delivered.
+func (c *Client) markSent(packetID int32) {
+	c.rqLock.Lock()
+	if _, ok := c.readQueue[packetID]; ok {
+		c.sentAt[packetID] = time.Now()
+	}
+	c.rqLock.Unlock()
+}
 
+// deliverPacket puts a received packet in the mailbox with the same ID. Packets without an open mailbox are dropped.
+func (c *Client) deliverPacket(p packet.Packet) {
+	c.deliverPacketTo(p.ID(), p)
+}
+
+// deliverPacketTo delivers a packet to the mailbox of the request with the provided ID.
+func (c *Client) deliverPacketTo(id int32, p packet.Packet) {
+	c.rqLock.Lock()
+	mailbox, ok := c.readQueue[id]
+	sentAt, sent := c.sentAt[id]
+	delete(c.sentAt, id)
+	c.rqLock.Unlock()
+
+	c.stats.recordResponse()
+
+	if sent {
+		c.stats.recordRTT(time.Since(sentAt))
+	}
+
+	if !ok {
+		c.log.Debug("Packet ", id, " was unexpected (no open mailbox)")
+		return
+	}
+
+	select {
+	case mailbox <- p:
+		c.log.Debug("Packet added to mailbox ID: ", id)
+	default:
+		c.log.Debug("Packet ", id, " was dropped (mailbox full)")
+	}
+}
+
+func (c *Client) closeMailbox(packetID int32) {
+	c.rqLock.Lock()
+	delete(c.readQueue, packetID)
+	delete(c.sentAt, packetID)
+	c.rqLock.Unlock()
+}
+
+func (c *Client) getResponse(ctx context.Context, packetID int32) (packet.Packet, error) {
+	c.rqLock.Lock()
+	mailbox := c.readQueue[packetID]
+	c.rqLock.Unlock()
+
+	// When read operation is complete, delete packet mailbox.
+	defer c.closeMailbox(packetID)
+
+	return c.waitMailbox(ctx, packetID, mailbox)
+}
+
+// waitMailbox takes a packet from a mailbox without closing it.
+func (c *Client) waitMailbox(ctx context.Context, packetID int32, mailbox chan packet.Packet) (packet.Packet, error) {
 	// We use c.QueueReadTimeout to set a timeout for response fetching. If something happens and no response can be pulled from
 	// the mailbox with the provided packet ID within the set timeout period, an error is returned.
 	select {
-	case p := <-c.readQueue[packetID]:
+	case p := <-mailbox:
 		c.log.Debug("Packet removed from mailbox ID: ", packetID)
 		return p, nil
 	case <-time.After(c.QueueReadTimeout):
 		return nil, errors.Wrap(errs.ErrReadTimeout, "mailbox read operation timed out")
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
 // newClientPacket is a wrapper function for packet.NewClientPacket. It makes creating packets a bit easier by automatically
 // populating client-specific fields so that this doesn't need to be done manually.
+//
+// If a Signer is configured, auth and command bodies are signed.
 func (c *Client) newClientPacket(pType packet.PacketType, body string) packet.Packet {
-	return packet.NewClientPacket(c.EndianMode, pType, body, c.RestrictedPacketIDs)
+	return c.newClientPacketWithID(pType, body, c.PacketIDs.Next(c.RestrictedPacketIDs))
+}
+
+func (c *Client) newClientPacketWithID(pType packet.PacketType, body string, id int32) packet.Packet {
+	if c.Signer != nil && (pType == packet.TypeAuth || pType == packet.TypeCommand) {
+		body = string(c.Signer.Sign(pType, []byte(body)))
+	}
+
+	return packet.NewClientPacketWithID(c.EndianMode, pType, body, id)
 }
@@ -0,0 +1,280 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/endian"
+	"github.com/refractorgscm/rcon/errs"
+	"github.com/refractorgscm/rcon/packet"
+	"github.com/refractorgscm/rcon/rcontest"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("routePacket", func() {
+		g.It("Should deliver auth responses to pending auth requests", func() {
+			c := NewClient(&Config{}, nil)
+			command := make(chan packet.Packet, 1)
+			auth := make(chan packet.Packet, 1)
+			c.readQueue[5] = command
+			c.readQueue[packet.AuthPacketID] = auth
+
+			c.routePacket(packet.NewClientPacketWithID(c.EndianMode, packet.TypeServerDataAuthResponse, "", -1))
+			Expect(command).NotTo(Receive())
+			Expect(auth).To(Receive())
+
+			c.routePacket(packet.NewClientPacketWithID(c.EndianMode, packet.TypeServerDataResponseValue, "ok", 5))
+			Expect(command).To(Receive())
+		})
+
+		g.It("Should deliver auth responses with other IDs to waiting commands", func() {
+			c := NewClient(&Config{}, nil)
+			command := make(chan packet.Packet, 1)
+			auth := make(chan packet.Packet, 1)
+			c.readQueue[5] = command
+			c.readQueue[packet.AuthPacketID] = auth
+
+			c.routePacket(packet.NewClientPacketWithID(c.EndianMode, packet.TypeServerDataAuthResponse, "ok", 5))
+			Expect(auth).NotTo(Receive())
+
+			var p packet.Packet
+			Expect(command).To(Receive(&p))
+			Expect(p.Type()).To(Equal(packet.TypeServerDataAuthResponse))
+		})
+
+		g.It("Should report protocol violations in strict mode", func() {
+			c := NewClient(&Config{StrictProtocol: true}, nil)
+			command := make(chan packet.Packet, 1)
+			c.readQueue[5] = command
+
+			var violations []*packet.Violation
+			c.Events().Subscribe(func(e Event) {
+				if v, ok := e.(ProtocolViolationEvent); ok {
+					violations = append(violations, v.Violation)
+				}
+			})
+
+			c.routePacket(packet.NewClientPacketWithID(c.EndianMode, packet.PacketType(7), "ok", 5))
+			c.routePacket(packet.NewClientPacketWithID(c.EndianMode, packet.TypeServerDataAuthResponse, "", 5))
+			Expect(command).NotTo(Receive())
+			Expect(violations).To(HaveLen(2))
+			Expect(violations[0].Offset).To(Equal(8))
+			Expect(violations[0].Error()).To(ContainSubstring("found type 7"))
+			Expect(violations[1].Offset).To(Equal(4))
+		})
+	})
+
+	g.Describe("authenticate", func() {
+		g.It("Should fail if the server answers with another packet type", func() {
+			// The server answers the auth request with a command response instead of an auth response.
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			defer l.Close()
+
+			go func() {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+
+				if _, err := conn.Read(make([]byte, 4096)); err != nil {
+					return
+				}
+
+				res, _ := packet.NewClientPacketWithID(endian.Little, packet.TypeServerDataResponseValue, "hello",
+					packet.AuthPacketID).Build()
+				_, _ = conn.Write(res)
+				_, _ = io.Copy(ioutil.Discard, conn)
+			}()
+
+			addr := l.Addr().(*net.TCPAddr)
+			c := NewClient(&Config{
+				Host:        addr.IP.String(),
+				Port:        uint16(addr.Port),
+				Password:    testPassword,
+				ConnTimeout: time.Second,
+			}, nil)
+
+			err = c.Connect()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("packet was not of the type auth response"))
+			Expect(c.State()).To(Equal(StateDisconnected))
+		})
+	})
+
+	g.Describe("Mailboxes", func() {
+		g.It("Should match concurrent responses to their commands", func() {
+			s, err := rcontest.NewServer(rcontest.Config{
+				Password: testPassword,
+				Handler:  echoHandler,
+				Chaos:    &rcontest.Chaos{Jitter: time.Millisecond * 5, Seed: 1},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			const commands = 50
+			responses := make([]string, commands)
+			execErrs := make([]error, commands)
+
+			var wg sync.WaitGroup
+			for i := 0; i < commands; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					responses[i], execErrs[i] = c.ExecCommand("command " + strconv.Itoa(i))
+				}(i)
+			}
+			wg.Wait()
+
+			for i := 0; i < commands; i++ {
+				Expect(execErrs[i]).ToNot(HaveOccurred())
+				Expect(responses[i]).To(Equal("echo: command " + strconv.Itoa(i)))
+			}
+
+			c.rqLock.Lock()
+			Expect(c.readQueue).To(BeEmpty())
+			c.rqLock.Unlock()
+		})
+
+		g.It("Should drop responses arriving after the caller gave up", func() {
+			s := newTestServer(func(command string) string {
+				if command == "slow" {
+					time.Sleep(time.Millisecond * 200)
+				}
+
+				return "echo: " + command
+			})
+			defer s.Close()
+
+			c := newTestClient(s, &Config{QueueReadTimeout: time.Millisecond * 50})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			_, err := c.ExecCommand("slow")
+			Expect(errors.Cause(err)).To(Equal(errs.ErrReadTimeout))
+
+			// The server answers in order, so the late response to "slow" arrives first and must not be mistaken for
+			// the response to "fast".
+			c.QueueReadTimeout = time.Second
+			Expect(c.ExecCommand("fast")).To(Equal("echo: fast"))
+			Expect(c.State()).To(Equal(StateConnected))
+
+			c.rqLock.Lock()
+			Expect(c.readQueue).To(BeEmpty())
+			c.rqLock.Unlock()
+		})
+
+		g.It("Should close the mailbox if the packet can't be queued", func() {
+			// Nothing dequeues packets without a connection, so queueing times out.
+			c := NewClient(&Config{QueueWriteTimeout: time.Millisecond * 10}, nil)
+			p := c.newClientPacket(packet.TypeCommand, "status")
+
+			err := c.enqueuePacket(p, PriorityNormal, true)
+			Expect(errors.Cause(err)).To(Equal(errs.ErrQueueTimeout))
+
+			c.rqLock.Lock()
+			Expect(c.readQueue).ToNot(HaveKey(p.ID()))
+			c.rqLock.Unlock()
+
+			// A response arriving anyway is dropped.
+			c.deliverPacket(packet.NewClientPacketWithID(c.EndianMode, packet.TypeCommandRes, "ok", p.ID()))
+			c.rqLock.Lock()
+			Expect(c.readQueue).To(BeEmpty())
+			c.rqLock.Unlock()
+		})
+	})
+
+	g.Describe("ExecCommandRaw", func() {
+		g.It("Should pass binary bodies through unchanged", func() {
+			var lock sync.Mutex
+			var received string
+			s := newTestServer(func(command string) string {
+				lock.Lock()
+				received = command
+				lock.Unlock()
+
+				// The response reverses the command, so that it can't be mistaken for a mirrored packet.
+				response := []byte(command)
+				for i, j := 0, len(response)-1; i < j; i, j = i+1, j-1 {
+					response[i], response[j] = response[j], response[i]
+				}
+
+				return string(response)
+			})
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			command := []byte{0xff, 0x00, 0xfe, 'a', '\n', 0x00, 0x80}
+			res, err := c.ExecCommandRaw(command)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(Equal([]byte{0x80, 0x00, '\n', 'a', 0xfe, 0x00, 0xff}))
+
+			lock.Lock()
+			defer lock.Unlock()
+			Expect([]byte(received)).To(Equal(command))
+		})
+	})
+}
+
+// testPassword is the password of servers started with newTestServer.
+const testPassword = "pw"
+
+// echoHandler answers commands by echoing them.
+func echoHandler(command string) string {
+	return "echo: " + command
+}
+
+// newTestServer starts a local server answering commands with handler.
+func newTestServer(handler rcontest.Handler) *rcontest.Server {
+	s, err := rcontest.NewServer(rcontest.Config{Password: testPassword, Handler: handler})
+	Expect(err).ToNot(HaveOccurred())
+
+	return s
+}
+
+// newTestClient creates a client for s. The address and password of config are set to those of s.
+func newTestClient(s *rcontest.Server, config *Config) *Client {
+	config.Host, config.Port = s.Addr()
+	config.Password = testPassword
+
+	return NewClient(config, nil)
+}
+
+// recordEvents subscribes to events of the provided types, and returns a function returning the events received so far.
+func recordEvents(c *Client, types ...EventType) func() []Event {
+	var lock sync.Mutex
+	var events []Event
+
+	c.Events().Subscribe(func(e Event) {
+		lock.Lock()
+		events = append(events, e)
+		lock.Unlock()
+	}, types...)
+
+	return func() []Event {
+		lock.Lock()
+		defer lock.Unlock()
+
+		return append([]Event(nil), events...)
+	}
+}
@@ -0,0 +1,66 @@
+package rcon
+
+// ConfigOption changes a Config, such as the config of a client created with CloneWith.
+type ConfigOption func(config *Config)
+
+// CloneWith creates a new client for the same server, with the same password and settings, after applying opts to a
+// copy of the config. It is meant for subsystems which need their own handlers or filters, such as a monitoring
+// component which shouldn't receive the broadcasts of the main application.
+//
+// The clone opens its own connection when connected, and starts out with the client's registered macros and current
+// dialect. State bound to a single client is not shared: the clone allocates its own packet IDs, gets its own offline
+// queue store and has no BroadcastSources, since a source can only feed one client. Runtime filter patterns and
+// response mappings are not copied either.
+//
+// Handlers are bound to a client, so a clone can't share the client's connection. Components which only need to execute
+// commands can share a single connection using CloneShared instead.
+func (c *Client) CloneWith(opts ...ConfigOption) *Client {
+	return NewClient(c.cloneConfig(opts), c.log)
+}
+
+// CloneShared acquires a client for the same server from r, like Registry.Acquire with the config a clone created with
+// CloneWith would have. If the client itself or another clone was acquired from r, the returned handle shares its
+// connection instead of opening a new one.
+//
+// Since the registry ignores the config of clients it already holds, opts only take effect if r has no client for the
+// server yet. Handlers are those of the shared client, so use CloneWith for clones which need their own.
+func (c *Client) CloneShared(r *Registry, opts ...ConfigOption) (*SharedClient, error) {
+	return r.Acquire(c.cloneConfig(opts), c.log)
+}
+
+// cloneConfig returns a copy of the client's config for a clone, with opts applied.
+func (c *Client) cloneConfig(opts []ConfigOption) *Config {
+	// The address is changed under addrLock by SetAddress, so the config is copied while holding it.
+	c.addrLock.Lock()
+	config := *c.Config
+	c.addrLock.Unlock()
+
+	config.PacketIDs = nil
+	config.BroadcastSources = nil
+	config.Dialect = c.Dialect()
+	config.Macros = c.RegisteredMacros()
+
+	// A filter built from the patterns is rebuilt by NewClient, in case opts change the patterns.
+	if c.patternFilter != nil && config.BroadcastFilter == BroadcastFilter(c.patternFilter) {
+		config.BroadcastFilter = nil
+	}
+
+	// Slices are copied, so that appending to them on one client doesn't affect the other.
+	config.BroadcastSinks = append([]BroadcastHandler{}, config.BroadcastSinks...)
+	config.BroadcastListeners = append([]BroadcastListener{}, config.BroadcastListeners...)
+	config.BroadcastParsers = append([]BroadcastParser{}, config.BroadcastParsers...)
+	config.AuditSinks = append([]AuditSink{}, config.AuditSinks...)
+	config.SubscribeCommands = append([]string{}, config.SubscribeCommands...)
+
+	if config.OfflineQueue != nil {
+		queue := *config.OfflineQueue
+		queue.Store = nil
+		config.OfflineQueue = &queue
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &config
+}
@@ -0,0 +1,102 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestClone(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("CloneWith", func() {
+		g.It("Should create an independent client for the same server", func() {
+			c := NewClient(&Config{
+				Host:              "127.0.0.1",
+				Port:              7778,
+				Password:          "secret",
+				BroadcastPatterns: []*regexp.Regexp{regexp.MustCompile(`^Chat:`)},
+			}, nil)
+			Expect(c.RegisterMacro("greet", "say hi $1")).To(Succeed())
+
+			clone := c.CloneWith(func(config *Config) {
+				config.BroadcastPatterns = []*regexp.Regexp{regexp.MustCompile(`^Login:`)}
+				config.BroadcastSinks = append(config.BroadcastSinks, func(string) {})
+			})
+
+			Expect(clone.addressString()).To(Equal("127.0.0.1:7778"))
+			Expect(clone.Password).To(Equal("secret"))
+			Expect(clone.RegisteredMacros()).To(HaveKey("greet"))
+			Expect(clone.PacketIDs).ToNot(BeIdenticalTo(c.PacketIDs))
+			Expect(clone.BroadcastFilter.Accept([]byte("Login: Bob"))).To(BeTrue())
+			Expect(clone.BroadcastFilter.Accept([]byte("Chat: hi"))).To(BeFalse())
+			Expect(c.BroadcastSinks).To(BeEmpty())
+		})
+
+		g.It("Should copy a consistent address while it is changed", func() {
+			c := NewClient(&Config{Host: "10.0.0.1", Port: 1}, nil)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+
+				for i := 0; i < 100; i++ {
+					Expect(c.SetAddress("10.0.0.2", 2)).To(Succeed())
+					Expect(c.SetAddress("10.0.0.1", 1)).To(Succeed())
+				}
+			}()
+
+			for i := 0; i < 100; i++ {
+				Expect(c.CloneWith().addressString()).To(Or(Equal("10.0.0.1:1"), Equal("10.0.0.2:2")))
+			}
+
+			<-done
+		})
+	})
+	g.Describe("CloneShared", func() {
+		g.It("Should share the connection of clients acquired from the registry", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			host, port := s.Addr()
+			r := NewRegistry()
+			main, err := r.Acquire(&Config{Host: host, Port: port, Password: testPassword}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(main.Connect()).To(Succeed())
+			defer main.Close()
+
+			clone, err := main.CloneShared(r, func(config *Config) {
+				config.HeartbeatInterval = time.Second
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(clone.Client).To(BeIdenticalTo(main.Client))
+			Expect(clone.Connect()).To(Succeed())
+			Expect(clone.ExecCommand("status")).To(Equal("echo: status"))
+
+			Expect(clone.Close()).To(Succeed())
+			Expect(main.State()).To(Equal(StateConnected))
+		})
+
+		g.It("Should share a connection between clones of an unshared client", func() {
+			c := NewClient(&Config{Host: "127.0.0.1", Port: 7778, Password: "secret"}, nil)
+			r := NewRegistry()
+
+			first, err := c.CloneShared(r, func(config *Config) {
+				config.HistorySize = 10
+			})
+			Expect(err).ToNot(HaveOccurred())
+			second, err := c.CloneShared(r)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(first.Client).ToNot(BeIdenticalTo(c))
+			Expect(second.Client).To(BeIdenticalTo(first.Client))
+			Expect(second.HistorySize).To(Equal(10))
+			Expect(first.PacketIDs).ToNot(BeIdenticalTo(c.PacketIDs))
+		})
+	})
+}
@@ -0,0 +1,177 @@
+package rcon
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MemoryCommandStore is a CommandStore which keeps queued commands in memory. Queued commands are lost when the process
+// exits.
+type MemoryCommandStore struct {
+	mu       sync.Mutex
+	commands []QueuedCommand
+}
+
+func NewMemoryCommandStore() *MemoryCommandStore {
+	return &MemoryCommandStore{}
+}
+
+func (s *MemoryCommandStore) Append(cmd QueuedCommand) error {
+	s.mu.Lock()
+	s.commands = append(s.commands, cmd)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryCommandStore) Front() (QueuedCommand, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.commands) == 0 {
+		return QueuedCommand{}, false, nil
+	}
+
+	return s.commands[0], true, nil
+}
+
+func (s *MemoryCommandStore) RemoveFront() error {
+	s.mu.Lock()
+	if len(s.commands) > 0 {
+		s.commands = s.commands[1:]
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryCommandStore) Len() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.commands), nil
+}
+
+// FileCommandStore is a CommandStore which persists queued commands to a file as JSON lines, so queued commands
+// survive restarts. The file is rewritten atomically on every change.
+type FileCommandStore struct {
+	mu       sync.Mutex
+	path     string
+	commands []QueuedCommand
+}
+
+// NewFileCommandStore creates a FileCommandStore backed by the file at path. If the file exists, the commands stored in
+// it are loaded.
+func NewFileCommandStore(path string) (*FileCommandStore, error) {
+	s := &FileCommandStore{
+		path: path,
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+
+		return nil, errors.Wrap(err, "could not open command store file")
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var cmd QueuedCommand
+		if err := json.Unmarshal(scanner.Bytes(), &cmd); err != nil {
+			return nil, errors.Wrap(err, "could not decode queued command")
+		}
+
+		s.commands = append(s.commands, cmd)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not read command store file")
+	}
+
+	return s, nil
+}
+
+func (s *FileCommandStore) Append(cmd QueuedCommand) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.save(append(s.commands, cmd))
+}
+
+func (s *FileCommandStore) Front() (QueuedCommand, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.commands) == 0 {
+		return QueuedCommand{}, false, nil
+	}
+
+	return s.commands[0], true, nil
+}
+
+func (s *FileCommandStore) RemoveFront() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.commands) == 0 {
+		return nil
+	}
+
+	return s.save(s.commands[1:])
+}
+
+func (s *FileCommandStore) Len() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.commands), nil
+}
+
+// save writes commands to a temporary file and renames it over the store file. s.commands is only updated if the
+// write succeeded. It must be called with the lock held.
+func (s *FileCommandStore) save(commands []QueuedCommand) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "could not create temporary command store file")
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, cmd := range commands {
+		if err := enc.Encode(cmd); err != nil {
+			_ = tmp.Close()
+			return errors.Wrap(err, "could not encode queued command")
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return errors.Wrap(err, "could not write command store file")
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return errors.Wrap(err, "could not sync command store file")
+	}
+
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "could not close command store file")
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return errors.Wrap(err, "could not replace command store file")
+	}
+
+	s.commands = commands
+
+	return nil
+}
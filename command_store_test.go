@@ -0,0 +1,112 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCommandStore(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	// testStore checks the behavior every CommandStore must have.
+	testStore := func(s CommandStore) {
+		_, ok, err := s.Front()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(s.RemoveFront()).To(Succeed())
+
+		Expect(s.Append(QueuedCommand{ID: 1, Command: "first"})).To(Succeed())
+		Expect(s.Append(QueuedCommand{ID: 2, Command: "second"})).To(Succeed())
+		Expect(s.Len()).To(Equal(2))
+
+		cmd, ok, err := s.Front()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(cmd.Command).To(Equal("first"))
+
+		Expect(s.RemoveFront()).To(Succeed())
+		cmd, _, err = s.Front()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cmd.Command).To(Equal("second"))
+		Expect(s.Len()).To(Equal(1))
+	}
+
+	g.Describe("MemoryCommandStore", func() {
+		g.It("Should return commands in the order they were appended", func() {
+			testStore(NewMemoryCommandStore())
+		})
+	})
+
+	g.Describe("FileCommandStore", func() {
+		var dir string
+
+		g.BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "rcon-command-store")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(dir)
+		})
+
+		g.It("Should return commands in the order they were appended", func() {
+			s, err := NewFileCommandStore(filepath.Join(dir, "queue.jsonl"))
+			Expect(err).ToNot(HaveOccurred())
+
+			testStore(s)
+		})
+
+		g.It("Should keep queued commands across restarts", func() {
+			path := filepath.Join(dir, "queue.jsonl")
+			queuedAt := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+
+			s, err := NewFileCommandStore(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.Append(QueuedCommand{ID: 1, Command: "first", QueuedAt: queuedAt})).To(Succeed())
+			Expect(s.Append(QueuedCommand{ID: 2, Command: "second", Priority: PriorityHigh})).To(Succeed())
+			Expect(s.RemoveFront()).To(Succeed())
+			Expect(s.Append(QueuedCommand{ID: 3, Command: "third", QueuedAt: queuedAt})).To(Succeed())
+
+			s, err = NewFileCommandStore(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.Len()).To(Equal(2))
+
+			cmd, _, err := s.Front()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmd).To(Equal(QueuedCommand{ID: 2, Command: "second", Priority: PriorityHigh}))
+
+			Expect(s.RemoveFront()).To(Succeed())
+			cmd, _, err = s.Front()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmd.ID).To(Equal(uint64(3)))
+			Expect(cmd.QueuedAt.Equal(queuedAt)).To(BeTrue())
+		})
+
+		g.It("Should fail to load corrupt files", func() {
+			path := filepath.Join(dir, "queue.jsonl")
+			Expect(ioutil.WriteFile(path, []byte("{\"command\":\"status\"}\n{\"command\":"), 0600)).To(Succeed())
+
+			_, err := NewFileCommandStore(path)
+			Expect(err).To(HaveOccurred())
+		})
+
+		g.It("Should not leave temporary files behind", func() {
+			s, err := NewFileCommandStore(filepath.Join(dir, "queue.jsonl"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.Append(QueuedCommand{Command: "status"})).To(Succeed())
+
+			files, err := ioutil.ReadDir(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+		})
+	})
+}
@@ -0,0 +1,461 @@
+package rcon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/endian"
+	"github.com/refractorgscm/rcon/errs"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerConfig is a named client config loaded by LoadConfigFromFile or LoadConfigFromEnv.
+type ServerConfig struct {
+	Name   string
+	Config *Config
+
+	// Tags are applied to the server when it is added to a Manager using Manager.AddConfigs.
+	Tags map[string]string
+}
+
+// Duration is a time.Duration which can be decoded from strings such as "2s" or "1m30s" in config files.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// FileConfig is the structure of a single server in a config file. Handlers and other function fields of Config can't
+// be loaded from a file and must be set in code after loading.
+type FileConfig struct {
+	Name                string         `json:"name" yaml:"name" toml:"name"`
+	Host                string         `json:"host" yaml:"host" toml:"host"`
+	Port                int            `json:"port" yaml:"port" toml:"port"`
+	Password            string         `json:"password" yaml:"password" toml:"password"`
+	AuthMode            string         `json:"auth_mode" yaml:"auth_mode" toml:"auth_mode"`
+	QueryPort           int            `json:"query_port" yaml:"query_port" toml:"query_port"`
+	ConnTimeout         Duration       `json:"conn_timeout" yaml:"conn_timeout" toml:"conn_timeout"`
+	DialTimeout         Duration       `json:"dial_timeout" yaml:"dial_timeout" toml:"dial_timeout"`
+	QueueWriteTimeout   Duration       `json:"queue_write_timeout" yaml:"queue_write_timeout" toml:"queue_write_timeout"`
+	QueueReadTimeout    Duration       `json:"queue_read_timeout" yaml:"queue_read_timeout" toml:"queue_read_timeout"`
+	Endian              string         `json:"endian" yaml:"endian" toml:"endian"`
+	RestrictedPacketIDs []int32        `json:"restricted_packet_ids" yaml:"restricted_packet_ids" toml:"restricted_packet_ids"`
+	DetectDialect       bool           `json:"detect_dialect" yaml:"detect_dialect" toml:"detect_dialect"`
+	ProbeTimeout        Duration       `json:"probe_timeout" yaml:"probe_timeout" toml:"probe_timeout"`
+	RetryInitialConnect bool           `json:"retry_initial_connect" yaml:"retry_initial_connect" toml:"retry_initial_connect"`
+	Reconnect           *FileReconnect `json:"reconnect" yaml:"reconnect" toml:"reconnect"`
+
+	Tags   map[string]string   `json:"tags" yaml:"tags" toml:"tags"`
+	Macros map[string][]string `json:"macros" yaml:"macros" toml:"macros"`
+}
+
+// FileReconnect is the structure of a ReconnectPolicy in a config file.
+type FileReconnect struct {
+	InitialDelay     Duration `json:"initial_delay" yaml:"initial_delay" toml:"initial_delay"`
+	MaxDelay         Duration `json:"max_delay" yaml:"max_delay" toml:"max_delay"`
+	Multiplier       float64  `json:"multiplier" yaml:"multiplier" toml:"multiplier"`
+	MaxAttempts      int      `json:"max_attempts" yaml:"max_attempts" toml:"max_attempts"`
+	BreakerThreshold int      `json:"breaker_threshold" yaml:"breaker_threshold" toml:"breaker_threshold"`
+	BreakerCooldown  Duration `json:"breaker_cooldown" yaml:"breaker_cooldown" toml:"breaker_cooldown"`
+}
+
+// fileConfigs is the structure of a config file, which either describes a single server at the top level or holds
+// many servers.
+type fileConfigs struct {
+	Servers []FileConfig `json:"servers" yaml:"servers" toml:"servers"`
+
+	FileConfig `yaml:",inline"`
+}
+
+// LoadConfigFromFile loads one or many server configs from a JSON, YAML or TOML file. The format is chosen using the
+// file extension (.json, .yaml, .yml or .toml).
+//
+// A file can either describe a single server at the top level, or many servers in a list named "servers". Validation
+// errors, including unknown keys such as misspelled field names, are returned as *errs.FieldError, naming the offending
+// field.
+func LoadConfigFromFile(path string) ([]*ServerConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read config file")
+	}
+
+	var decode func([]byte, interface{}) error
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decode = decodeJSONConfig
+	case ".yaml", ".yml":
+		decode = decodeYAMLConfig
+	case ".toml":
+		decode = decodeTOMLConfig
+	default:
+		return nil, errors.Errorf("unsupported config file extension %q", filepath.Ext(path))
+	}
+
+	var file fileConfigs
+	if err := decode(data, &file); err != nil {
+		return nil, err
+	}
+
+	if len(file.Servers) == 0 {
+		sc, err := file.FileConfig.ServerConfig("")
+		if err != nil {
+			return nil, err
+		}
+
+		return []*ServerConfig{sc}, nil
+	}
+
+	if !reflect.DeepEqual(file.FileConfig, FileConfig{}) {
+		return nil, &errs.FieldError{Field: "servers", Reason: "must not be combined with top-level server fields"}
+	}
+
+	configs := make([]*ServerConfig, 0, len(file.Servers))
+	for i, fc := range file.Servers {
+		sc, err := fc.ServerConfig(fmt.Sprintf("servers[%d].", i))
+		if err != nil {
+			return nil, err
+		}
+
+		configs = append(configs, sc)
+	}
+
+	return configs, nil
+}
+
+// yamlUnknownField matches the errors yaml.UnmarshalStrict returns for unknown keys.
+var yamlUnknownField = regexp.MustCompile(`field (\S+) not found in type`)
+
+// decodeJSONConfig decodes a JSON config file. Unknown keys are returned as *errs.FieldError.
+func decodeJSONConfig(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		// encoding/json doesn't export an error type for unknown fields.
+		if msg := err.Error(); strings.HasPrefix(msg, "json: unknown field ") {
+			field := strings.Trim(strings.TrimPrefix(msg, "json: unknown field "), `"`)
+			return &errs.FieldError{Field: field, Reason: "is not a known field"}
+		}
+
+		return errors.Wrap(err, "could not decode config file")
+	}
+
+	return nil
+}
+
+// decodeYAMLConfig decodes a YAML config file. Unknown keys are returned as *errs.FieldError.
+func decodeYAMLConfig(data []byte, v interface{}) error {
+	if err := yaml.UnmarshalStrict(data, v); err != nil {
+		if te, ok := err.(*yaml.TypeError); ok {
+			for _, msg := range te.Errors {
+				if m := yamlUnknownField.FindStringSubmatch(msg); m != nil {
+					return &errs.FieldError{Field: m[1], Reason: "is not a known field"}
+				}
+			}
+		}
+
+		return errors.Wrap(err, "could not decode config file")
+	}
+
+	return nil
+}
+
+// decodeTOMLConfig decodes a TOML config file. Unknown keys are returned as *errs.FieldError.
+func decodeTOMLConfig(data []byte, v interface{}) error {
+	meta, err := toml.Decode(string(data), v)
+	if err != nil {
+		return errors.Wrap(err, "could not decode config file")
+	}
+
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return &errs.FieldError{Field: undecoded[0].String(), Reason: "is not a known field"}
+	}
+
+	return nil
+}
+
+// ServerConfig validates the file config and converts it to a ServerConfig. fieldPrefix is prepended to field names in
+// validation errors.
+func (fc *FileConfig) ServerConfig(fieldPrefix string) (*ServerConfig, error) {
+	field := func(name string) string {
+		return fieldPrefix + name
+	}
+
+	if fc.Host == "" {
+		return nil, &errs.FieldError{Field: field("host"), Reason: "is required"}
+	}
+
+	if !isLocalAddress(fc.Host) && (fc.Port < 1 || fc.Port > 65535) {
+		return nil, &errs.FieldError{Field: field("port"), Reason: "must be between 1 and 65535"}
+	}
+
+	if fc.QueryPort < 0 || fc.QueryPort > 65535 {
+		return nil, &errs.FieldError{Field: field("query_port"), Reason: "must be 0 (unset) or a valid port"}
+	}
+
+	authMode, err := parseAuthMode(fc.AuthMode)
+	if err != nil {
+		return nil, &errs.FieldError{Field: field("auth_mode"), Reason: err.Error()}
+	}
+
+	if fc.Password == "" && authMode == AuthPassword {
+		return nil, &errs.FieldError{Field: field("password"), Reason: "is required unless auth_mode is empty or none"}
+	}
+
+	mode, err := parseEndian(fc.Endian)
+	if err != nil {
+		return nil, &errs.FieldError{Field: field("endian"), Reason: err.Error()}
+	}
+
+	config := &Config{
+		Host:                fc.Host,
+		Port:                uint16(fc.Port),
+		Password:            fc.Password,
+		AuthMode:            authMode,
+		QueryPort:           uint16(fc.QueryPort),
+		ConnTimeout:         time.Duration(fc.ConnTimeout),
+		DialTimeout:         time.Duration(fc.DialTimeout),
+		QueueWriteTimeout:   time.Duration(fc.QueueWriteTimeout),
+		QueueReadTimeout:    time.Duration(fc.QueueReadTimeout),
+		EndianMode:          mode,
+		RestrictedPacketIDs: fc.RestrictedPacketIDs,
+		DetectDialect:       fc.DetectDialect,
+		ProbeTimeout:        time.Duration(fc.ProbeTimeout),
+		RetryInitialConnect: fc.RetryInitialConnect,
+		Macros:              fc.Macros,
+	}
+
+	if fc.Reconnect != nil {
+		if fc.Reconnect.Multiplier != 0 && fc.Reconnect.Multiplier < 1 {
+			return nil, &errs.FieldError{Field: field("reconnect.multiplier"), Reason: "must be at least 1"}
+		}
+
+		if fc.Reconnect.MaxAttempts < 0 {
+			return nil, &errs.FieldError{Field: field("reconnect.max_attempts"), Reason: "must not be negative"}
+		}
+
+		if fc.Reconnect.BreakerThreshold < 0 {
+			return nil, &errs.FieldError{Field: field("reconnect.breaker_threshold"), Reason: "must not be negative"}
+		}
+
+		config.ReconnectPolicy = &ReconnectPolicy{
+			InitialDelay:     time.Duration(fc.Reconnect.InitialDelay),
+			MaxDelay:         time.Duration(fc.Reconnect.MaxDelay),
+			Multiplier:       fc.Reconnect.Multiplier,
+			MaxAttempts:      fc.Reconnect.MaxAttempts,
+			BreakerThreshold: fc.Reconnect.BreakerThreshold,
+			BreakerCooldown:  time.Duration(fc.Reconnect.BreakerCooldown),
+		}
+	}
+
+	name := fc.Name
+	if name == "" && isLocalAddress(fc.Host) {
+		name = fc.Host
+	} else if name == "" {
+		name = net.JoinHostPort(fc.Host, strconv.Itoa(fc.Port))
+	}
+
+	for key := range fc.Tags {
+		if key == "" || strings.ContainsAny(key, ",=!|") {
+			return nil, &errs.FieldError{Field: field("tags"), Reason: "tag names must not be empty or contain , = ! or |"}
+		}
+	}
+
+	for macro, commands := range fc.Macros {
+		if macro == "" || strings.ContainsAny(macro, " \t\r\n") || len(commands) == 0 {
+			return nil, &errs.FieldError{Field: field("macros"), Reason: "macros need a name without spaces and a command"}
+		}
+	}
+
+	return &ServerConfig{
+		Name:   name,
+		Config: config,
+		Tags:   fc.Tags,
+	}, nil
+}
+
+func parseEndian(s string) (endian.Mode, error) {
+	switch strings.ToLower(s) {
+	case "", "little":
+		return endian.Little, nil
+	case "big":
+		return endian.Big, nil
+	default:
+		return nil, errors.Errorf("must be \"little\" or \"big\", got %q", s)
+	}
+}
+
+// LoadConfigFromEnv loads one or many server configs from environment variables with the provided prefix.
+//
+// A single server is described by the variables <PREFIX>_HOST, <PREFIX>_PORT, <PREFIX>_PASSWORD and optionally
+// <PREFIX>_AUTH_MODE, <PREFIX>_QUERY_PORT, <PREFIX>_CONN_TIMEOUT, <PREFIX>_DIAL_TIMEOUT, <PREFIX>_QUEUE_WRITE_TIMEOUT,
+// <PREFIX>_QUEUE_READ_TIMEOUT, <PREFIX>_ENDIAN, <PREFIX>_RESTRICTED_PACKET_IDS (comma separated),
+// <PREFIX>_DETECT_DIALECT, <PREFIX>_PROBE_TIMEOUT, <PREFIX>_RETRY_INITIAL_CONNECT and <PREFIX>_TAGS (comma separated
+// key=value pairs).
+//
+// To describe many servers, set <PREFIX>_SERVERS to a comma separated list of names. Each server is then described by
+// the variables above using the prefix <PREFIX>_<NAME>, for example RCON_EU1_HOST.
+//
+// Validation errors are returned as *errs.FieldError, naming the offending environment variable.
+func LoadConfigFromEnv(prefix string) ([]*ServerConfig, error) {
+	prefix = strings.TrimSuffix(strings.ToUpper(prefix), "_")
+
+	names, ok := os.LookupEnv(prefix + "_SERVERS")
+	if !ok {
+		fc, err := fileConfigFromEnv(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		sc, err := fc.ServerConfig(prefix + "_")
+		if err != nil {
+			return nil, envFieldError(err)
+		}
+
+		return []*ServerConfig{sc}, nil
+	}
+
+	var configs []*ServerConfig
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		serverPrefix := prefix + "_" + strings.ToUpper(name)
+
+		fc, err := fileConfigFromEnv(serverPrefix)
+		if err != nil {
+			return nil, err
+		}
+		fc.Name = name
+
+		sc, err := fc.ServerConfig(serverPrefix + "_")
+		if err != nil {
+			return nil, envFieldError(err)
+		}
+
+		configs = append(configs, sc)
+	}
+
+	if len(configs) == 0 {
+		return nil, &errs.FieldError{Field: prefix + "_SERVERS", Reason: "must list at least one server"}
+	}
+
+	return configs, nil
+}
+
+// envFieldError converts the field name of a validation error to the name of the environment variable.
+func envFieldError(err error) error {
+	if fe, ok := err.(*errs.FieldError); ok {
+		return &errs.FieldError{
+			Field:  strings.ToUpper(strings.Replace(fe.Field, ".", "_", -1)),
+			Reason: fe.Reason,
+		}
+	}
+
+	return err
+}
+
+func fileConfigFromEnv(prefix string) (*FileConfig, error) {
+	fc := &FileConfig{
+		Host:     os.Getenv(prefix + "_HOST"),
+		Password: os.Getenv(prefix + "_PASSWORD"),
+		AuthMode: os.Getenv(prefix + "_AUTH_MODE"),
+		Endian:   os.Getenv(prefix + "_ENDIAN"),
+	}
+
+	ints := map[string]*int{
+		"_PORT":       &fc.Port,
+		"_QUERY_PORT": &fc.QueryPort,
+	}
+	for suffix, dst := range ints {
+		if v, ok := os.LookupEnv(prefix + suffix); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, &errs.FieldError{Field: prefix + suffix, Reason: "must be a number"}
+			}
+
+			*dst = n
+		}
+	}
+
+	durations := map[string]*Duration{
+		"_CONN_TIMEOUT":        &fc.ConnTimeout,
+		"_DIAL_TIMEOUT":        &fc.DialTimeout,
+		"_QUEUE_WRITE_TIMEOUT": &fc.QueueWriteTimeout,
+		"_QUEUE_READ_TIMEOUT":  &fc.QueueReadTimeout,
+		"_PROBE_TIMEOUT":       &fc.ProbeTimeout,
+	}
+	for suffix, dst := range durations {
+		if v, ok := os.LookupEnv(prefix + suffix); ok {
+			if err := dst.UnmarshalText([]byte(v)); err != nil {
+				return nil, &errs.FieldError{Field: prefix + suffix, Reason: "must be a duration such as 2s"}
+			}
+		}
+	}
+
+	bools := map[string]*bool{
+		"_DETECT_DIALECT":        &fc.DetectDialect,
+		"_RETRY_INITIAL_CONNECT": &fc.RetryInitialConnect,
+	}
+	for suffix, dst := range bools {
+		if v, ok := os.LookupEnv(prefix + suffix); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, &errs.FieldError{Field: prefix + suffix, Reason: "must be true or false"}
+			}
+
+			*dst = b
+		}
+	}
+
+	if v, ok := os.LookupEnv(prefix + "_RESTRICTED_PACKET_IDS"); ok && v != "" {
+		for _, s := range strings.Split(v, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 32)
+			if err != nil {
+				return nil, &errs.FieldError{Field: prefix + "_RESTRICTED_PACKET_IDS", Reason: "must be a comma separated list of numbers"}
+			}
+
+			fc.RestrictedPacketIDs = append(fc.RestrictedPacketIDs, int32(id))
+		}
+	}
+
+	if v, ok := os.LookupEnv(prefix + "_TAGS"); ok && v != "" {
+		fc.Tags = map[string]string{}
+
+		for _, pair := range strings.Split(v, ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return nil, &errs.FieldError{Field: prefix + "_TAGS", Reason: "must be a comma separated list of key=value pairs"}
+			}
+
+			fc.Tags[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+
+	return fc, nil
+}
@@ -0,0 +1,205 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/errs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigLoader(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("LoadConfigFromFile", func() {
+		var dir string
+
+		g.BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "rcon-config-loader")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(dir)
+		})
+
+		load := func(name, contents string) ([]*ServerConfig, error) {
+			path := filepath.Join(dir, name)
+			Expect(ioutil.WriteFile(path, []byte(contents), 0600)).To(Succeed())
+
+			return LoadConfigFromFile(path)
+		}
+
+		fieldError := func(err error) *errs.FieldError {
+			Expect(err).To(BeAssignableToTypeOf(&errs.FieldError{}))
+			return err.(*errs.FieldError)
+		}
+
+		g.It("Should load a single server from JSON", func() {
+			configs, err := load("server.json", `{
+				"host": "10.0.0.1",
+				"port": 7778,
+				"password": "secret",
+				"conn_timeout": "3s",
+				"tags": {"region": "eu"}
+			}`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configs).To(HaveLen(1))
+
+			Expect(configs[0].Name).To(Equal("10.0.0.1:7778"))
+			Expect(configs[0].Config.Host).To(Equal("10.0.0.1"))
+			Expect(configs[0].Config.Port).To(Equal(uint16(7778)))
+			Expect(configs[0].Config.Password).To(Equal("secret"))
+			Expect(configs[0].Config.ConnTimeout).To(Equal(time.Second * 3))
+			Expect(configs[0].Tags).To(Equal(map[string]string{"region": "eu"}))
+		})
+
+		g.It("Should load many servers from YAML", func() {
+			configs, err := load("servers.yaml", `
+servers:
+  - name: eu-1
+    host: 10.0.0.1
+    port: 7778
+    password: secret
+    reconnect:
+      max_attempts: 3
+  - name: us-1
+    host: 10.0.1.1
+    port: 7779
+    password: secret
+    query_port: 27015
+`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configs).To(HaveLen(2))
+
+			Expect(configs[0].Name).To(Equal("eu-1"))
+			Expect(configs[0].Config.ReconnectPolicy.MaxAttempts).To(Equal(3))
+			Expect(configs[1].Name).To(Equal("us-1"))
+			Expect(configs[1].Config.QueryPort).To(Equal(uint16(27015)))
+		})
+
+		g.It("Should load many servers from TOML", func() {
+			configs, err := load("servers.toml", `
+[[servers]]
+name = "eu-1"
+host = "10.0.0.1"
+port = 7778
+password = "secret"
+queue_read_timeout = "500ms"
+
+[servers.tags]
+region = "eu"
+
+[[servers]]
+name = "us-1"
+host = "10.0.1.1"
+port = 7779
+password = "secret"
+`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configs).To(HaveLen(2))
+
+			Expect(configs[0].Config.QueueReadTimeout).To(Equal(time.Millisecond * 500))
+			Expect(configs[0].Tags).To(Equal(map[string]string{"region": "eu"}))
+			Expect(configs[1].Config.Host).To(Equal("10.0.1.1"))
+		})
+
+		g.It("Should name the field of an invalid server entry", func() {
+			_, err := load("servers.json", `{"servers": [
+				{"name": "eu-1", "host": "10.0.0.1", "port": 7778, "password": "secret"},
+				{"name": "us-1", "host": "10.0.1.1", "port": 70000, "password": "secret"}
+			]}`)
+			Expect(fieldError(err).Field).To(Equal("servers[1].port"))
+
+			_, err = load("server.yaml", "host: 10.0.0.1\nport: 7778\npassword: secret\nquery_port: -1\n")
+			Expect(fieldError(err).Field).To(Equal("query_port"))
+			Expect(fieldError(err).Reason).To(Equal("must be 0 (unset) or a valid port"))
+		})
+
+		g.It("Should reject unknown keys", func() {
+			_, err := load("server.json", `{"host": "10.0.0.1", "port": 7778, "pasword": "secret"}`)
+			Expect(fieldError(err).Field).To(Equal("pasword"))
+
+			_, err = load("server.yaml", "host: 10.0.0.1\nport: 7778\npassword: secret\nquer_port: 27015\n")
+			Expect(fieldError(err).Field).To(Equal("quer_port"))
+
+			_, err = load("servers.toml", "[[servers]]\nhost = \"10.0.0.1\"\nport = 7778\npasword = \"secret\"\n")
+			Expect(fieldError(err).Field).To(Equal("servers.pasword"))
+		})
+
+		g.It("Should reject top-level fields next to a server list", func() {
+			_, err := load("servers.json", `{
+				"host": "10.0.0.1",
+				"servers": [{"host": "10.0.0.1", "port": 7778, "password": "secret"}]
+			}`)
+			Expect(fieldError(err).Field).To(Equal("servers"))
+		})
+	})
+
+	g.Describe("LoadConfigFromEnv", func() {
+		setEnv := func(vars map[string]string) func() {
+			for k, v := range vars {
+				Expect(os.Setenv(k, v)).To(Succeed())
+			}
+
+			return func() {
+				for k := range vars {
+					_ = os.Unsetenv(k)
+				}
+			}
+		}
+
+		g.It("Should load a single server using the prefix", func() {
+			defer setEnv(map[string]string{
+				"RCON_TEST_HOST":                  "10.0.0.1",
+				"RCON_TEST_PORT":                  "7778",
+				"RCON_TEST_PASSWORD":              "secret",
+				"RCON_TEST_DIAL_TIMEOUT":          "2s",
+				"RCON_TEST_RESTRICTED_PACKET_IDS": "1, 2",
+				"RCON_TEST_TAGS":                  "region=eu,game=mordhau",
+			})()
+
+			configs, err := LoadConfigFromEnv("rcon_test_")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configs).To(HaveLen(1))
+
+			Expect(configs[0].Config.Host).To(Equal("10.0.0.1"))
+			Expect(configs[0].Config.Port).To(Equal(uint16(7778)))
+			Expect(configs[0].Config.DialTimeout).To(Equal(time.Second * 2))
+			Expect(configs[0].Config.RestrictedPacketIDs).To(Equal([]int32{1, 2}))
+			Expect(configs[0].Tags).To(Equal(map[string]string{"region": "eu", "game": "mordhau"}))
+		})
+
+		g.It("Should load many servers and name the offending variable", func() {
+			defer setEnv(map[string]string{
+				"RCON_TEST_SERVERS":      "eu1, us1",
+				"RCON_TEST_EU1_HOST":     "10.0.0.1",
+				"RCON_TEST_EU1_PORT":     "7778",
+				"RCON_TEST_EU1_PASSWORD": "secret",
+				"RCON_TEST_US1_HOST":     "10.0.1.1",
+				"RCON_TEST_US1_PORT":     "7779",
+				"RCON_TEST_US1_PASSWORD": "secret",
+			})()
+
+			configs, err := LoadConfigFromEnv("RCON_TEST")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configs).To(HaveLen(2))
+			Expect(configs[0].Name).To(Equal("eu1"))
+			Expect(configs[1].Name).To(Equal("us1"))
+			Expect(configs[1].Config.Port).To(Equal(uint16(7779)))
+
+			defer setEnv(map[string]string{"RCON_TEST_US1_PORT": "0"})()
+
+			_, err = LoadConfigFromEnv("RCON_TEST")
+			Expect(err).To(BeAssignableToTypeOf(&errs.FieldError{}))
+			Expect(err.(*errs.FieldError).Field).To(Equal("RCON_TEST_US1_PORT"))
+		})
+	})
+}
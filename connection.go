@@ -5,45 +5,54 @@ import (
 	"github.com/pkg/errors"
 	"github.com/refractorgscm/rcon/errs"
 	"github.com/refractorgscm/rcon/packet"
+	"net"
 	"strings"
+	"sync"
 	"time"
 )
 
-func (c *Client) sendPacket(p packet.Packet) error {
-	out, err := p.Build()
-	if err != nil {
-		return errors.Wrap(err, "could not build packet")
+// framing returns the packet framing of the active dialect, using the client's EndianMode unless the dialect sets one.
+func (c *Client) framing() packet.Framing {
+	framing := packet.SourceFraming
+	framing.Mode = nil
+
+	if f := c.Dialect().Framing; f != nil {
+		framing = *f
 	}
 
-	if err := c.write(out); err != nil {
-		return errors.Wrap(err, "could not send authentication packet")
+	if framing.Mode == nil {
+		framing.Mode = c.EndianMode
 	}
 
-	return nil
+	return framing
 }
 
-func (c *Client) readPacket() (packet.Packet, error) {
-	if c.conn == nil {
-		return nil, errs.ErrNotConnected
-	}
+func (c *Client) sendPacket(p packet.Packet) error {
+	return c.sendPacketTo(c.conn, p)
+}
 
-	if err := c.conn.SetDeadline(time.Time{}); err != nil {
-		if strings.HasSuffix(err.Error(), "use of closed network connection") {
-			return nil, errs.ErrNotConnected
-		}
+// sendPacketTo writes a packet to conn. The connection routines use it with the connection they were started for, since
+// c.conn may already belong to a newer connection.
+func (c *Client) sendPacketTo(conn net.Conn, p packet.Packet) error {
+	out, err := c.framing().Build(p)
+	if err != nil {
+		return errors.Wrap(err, "could not build packet")
+	}
 
-		return nil, errors.Wrap(err, "could not set connection deadline")
+	if err := c.write(conn, out); err != nil {
+		return errors.Wrap(err, "could not send authentication packet")
 	}
 
-	reader := bufio.NewReader(c.conn)
+	c.stats.recordPacketSent()
 
-	res, err := packet.DecodeClientPacket(c.EndianMode, reader)
-	if err != nil {
-		if strings.HasSuffix(err.Error(), "use of closed network connection") {
-			return nil, errs.ErrNotConnected
-		}
+	return nil
+}
 
-		return nil, errors.Wrap(err, "could not read packet")
+// readPacket blocks until a packet is read from conn. Like sendPacketTo, it is used by the connection routines.
+func (c *Client) readPacket(conn net.Conn, reader *bufio.Reader) (packet.Packet, error) {
+	res, err := c.readPacketFrom(conn, reader, time.Time{})
+	if err != nil {
+		return nil, err
 	}
 
 	c.log.Debug("Read packet ID: ", res.ID(), ", Body: ", string(res.Body()))
@@ -52,11 +61,21 @@ func (c *Client) readPacket() (packet.Packet, error) {
 }
 
 func (c *Client) readPacketTimeout() (packet.Packet, error) {
-	if c.conn == nil {
+	return c.readPacketDeadline(time.Now().Add(c.ConnTimeout))
+}
+
+// readPacketDeadline reads a single packet from the current connection. If deadline is the zero value, the read blocks
+// until a packet is received or the connection is closed.
+func (c *Client) readPacketDeadline(deadline time.Time) (packet.Packet, error) {
+	return c.readPacketFrom(c.conn, c.reader, deadline)
+}
+
+func (c *Client) readPacketFrom(conn net.Conn, reader *bufio.Reader, deadline time.Time) (packet.Packet, error) {
+	if conn == nil {
 		return nil, errs.ErrNotConnected
 	}
 
-	if err := c.conn.SetDeadline(time.Now().Add(c.ConnTimeout)); err != nil {
+	if err := conn.SetDeadline(deadline); err != nil {
 		if strings.HasSuffix(err.Error(), "use of closed network connection") {
 			return nil, errs.ErrNotConnected
 		}
@@ -64,9 +83,26 @@ func (c *Client) readPacketTimeout() (packet.Packet, error) {
 		return nil, errors.Wrap(err, "could not set connection deadline")
 	}
 
-	reader := bufio.NewReader(c.conn)
+	var res *packet.ClientPacket
+	var err error
+
+	if c.StrictProtocol {
+		res, err = c.framing().DecodeStrict(reader, c.MaxResponseSize)
+
+		if v, ok := err.(*packet.Violation); ok {
+			c.events.emit(ProtocolViolationEvent{Violation: v})
+		}
+	} else if c.LenientParsing {
+		var warnings []string
+		res, warnings, err = c.framing().DecodeLenient(reader, c.MaxResponseSize)
+
+		for _, warning := range warnings {
+			c.log.Info("Protocol warning: ", warning)
+		}
+	} else {
+		res, err = c.framing().Decode(reader, c.MaxResponseSize)
+	}
 
-	res, err := packet.DecodeClientPacket(c.EndianMode, reader)
 	if err != nil {
 		if strings.HasSuffix(err.Error(), "use of closed network connection") {
 			return nil, errs.ErrNotConnected
@@ -75,16 +111,92 @@ func (c *Client) readPacketTimeout() (packet.Packet, error) {
 		return nil, errors.Wrap(err, "could not read packet")
 	}
 
+	c.stats.recordPacketReceived()
+
 	return res, nil
 }
 
-func (c *Client) write(data []byte) error {
+func (c *Client) write(conn net.Conn, data []byte) error {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
 
-	if _, err := c.conn.Write(data); err != nil {
+	if conn == nil {
+		return errs.ErrNotConnected
+	}
+
+	n, err := conn.Write(data)
+	c.stats.recordBytesSent(n)
+	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// packetIDOffset is the offset of the ID field in an encoded packet.
+const packetIDOffset = 4
+
+// packetTypeOffset returns the offset of the type field in a packet encoded with the active framing.
+func (c *Client) packetTypeOffset() int {
+	if c.framing().ID64 {
+		return packetIDOffset + 8
+	}
+
+	return packetIDOffset + 4
+}
+
+// protocolViolation emits a ProtocolViolationEvent for a decoded packet if StrictProtocol is set, and returns true if
+// the packet should be dropped.
+func (c *Client) protocolViolation(p packet.Packet, offset int, expected, found string) bool {
+	if !c.StrictProtocol {
+		return false
+	}
+
+	data, _ := c.framing().Build(p)
+	v := &packet.Violation{Offset: offset, Expected: expected, Found: found, Data: data}
+
+	c.log.Info("Protocol violation: ", v)
+	c.stats.recordError(v)
+	c.events.emit(ProtocolViolationEvent{Violation: v})
+
+	return true
+}
+
+// arrivedPacket is a received packet along with its arrival sequence number, see Config.ArrivalSequence.
+type arrivedPacket struct {
+	packet.Packet
+	seq uint64
+}
+
+// arrivalSeq returns the arrival sequence number of a received packet, or zero if it wasn't numbered.
+func arrivalSeq(p packet.Packet) uint64 {
+	if a, ok := p.(arrivedPacket); ok {
+		return a.seq
+	}
+
+	return 0
+}
+
+// nextArrivalSeq returns the arrival sequence number of the next packet read, or zero if ArrivalSequence isn't set.
+func (c *Client) nextArrivalSeq() uint64 {
+	if !c.ArrivalSequence {
+		return 0
+	}
+
+	return c.arrivals.next()
+}
+
+// arrivalCounter hands out arrival sequence numbers.
+type arrivalCounter struct {
+	lock sync.Mutex
+	seq  uint64
+}
+
+func (a *arrivalCounter) next() uint64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.seq++
+
+	return a.seq
+}
@@ -0,0 +1,52 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/packet"
+	"github.com/refractorgscm/rcon/rcontest"
+	"testing"
+)
+
+func TestConnection(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("ArrivalSequence", func() {
+		g.It("Should number responses and broadcasts in arrival order", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{
+				ArrivalSequence: true,
+				HistorySize:     10,
+				BroadcastChecker: func(p packet.Packet) bool {
+					return p.ID() == rcontest.BroadcastID
+				},
+			})
+			broadcasts := make(chan Broadcast, 2)
+			c.AddBroadcastListener(func(b Broadcast) {
+				broadcasts <- b
+			})
+
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			var before, after Broadcast
+			s.Broadcast("before")
+			Eventually(broadcasts).Should(Receive(&before))
+
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+
+			s.Broadcast("after")
+			Eventually(broadcasts).Should(Receive(&after))
+
+			history := c.History()
+			response := history[len(history)-1].ArrivalSeq
+			Expect(before.ArrivalSeq).To(BeNumerically("<", response))
+			Expect(after.ArrivalSeq).To(BeNumerically(">", response))
+		})
+	})
+}
@@ -0,0 +1,226 @@
+package rcon
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultCvarGetFormat and DefaultCvarSetFormat are the formats of the commands reading and writing cvars if the
+// dialect doesn't set them.
+const (
+	DefaultCvarGetFormat = "%s"
+	DefaultCvarSetFormat = "%s %s"
+)
+
+// CvarParser extracts the value of the cvar name from the response to the dialect's CvarGetFormat command. Since
+// GetCvars may batch several reads into one command, the response can hold the values of other cvars too. If the cvar
+// isn't found, an error wrapping ErrUnknownCvar should be returned.
+type CvarParser func(name, response string) (string, error)
+
+// ParseSourceCvar parses the value of a cvar as printed by Source engine consoles, such as
+//
+//	"sv_gravity" = "800" ( def. "800" ) min. 0.000000 game replicated
+//
+// Unquoted values as printed by newer engines, such as "sv_cheats = false", are supported too.
+func ParseSourceCvar(name, response string) (string, error) {
+	re := regexp.MustCompile(`(?m)^\s*"?` + regexp.QuoteMeta(name) + `"?\s*(?:=|is)\s*(?:"((?:[^"\\]|\\.)*)"|(\S*))`)
+
+	m := re.FindStringSubmatch(response)
+	if m == nil {
+		return "", errors.Wrapf(errs.ErrUnknownCvar, "%s not found in response", name)
+	}
+
+	if m[2] != "" {
+		return m[2], nil
+	}
+
+	return unquoteCvar(m[1]), nil
+}
+
+// unquoteCvar replaces the escape sequences of a quoted cvar value.
+func unquoteCvar(value string) string {
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(value)
+}
+
+// quoteCvar quotes a cvar value if it is empty or contains whitespace or command separators.
+func quoteCvar(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t;") {
+		return `"` + value + `"`
+	}
+
+	return value
+}
+
+// validateCvarName returns ErrInvalidCvar if name can't be used in a command, for example because it contains spaces.
+func validateCvarName(name string) error {
+	if name == "" || strings.ContainsAny(name, " \t\r\n;\"") {
+		return errors.Wrapf(errs.ErrInvalidCvar, "invalid name %q", name)
+	}
+
+	return nil
+}
+
+// cvarFormats returns the dialect's cvar command formats and parser, falling back to the defaults.
+func (d *Dialect) cvarFormats() (get, set string, parse CvarParser) {
+	get, set, parse = d.CvarGetFormat, d.CvarSetFormat, d.ParseCvar
+	if get == "" {
+		get = DefaultCvarGetFormat
+	}
+
+	if set == "" {
+		set = DefaultCvarSetFormat
+	}
+
+	if parse == nil {
+		parse = ParseSourceCvar
+	}
+
+	return get, set, parse
+}
+
+// GetCvar returns the value of a server cvar, using the dialect's CvarGetFormat and ParseCvar.
+func (c *Client) GetCvar(name string, opts ...ExecOption) (string, error) {
+	if err := validateCvarName(name); err != nil {
+		return "", err
+	}
+
+	get, _, parse := c.Dialect().cvarFormats()
+
+	res, err := c.ExecCommand(fmt.Sprintf(get, name), opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return parse(name, res)
+}
+
+// SetCvar sets a server cvar using the dialect's CvarSetFormat. Values which are empty or contain spaces are quoted.
+// Values containing quotes or line breaks can't be passed to the server console safely, and are rejected with
+// ErrInvalidCvar.
+func (c *Client) SetCvar(name, value string, opts ...ExecOption) error {
+	if err := validateCvarName(name); err != nil {
+		return err
+	}
+
+	if strings.ContainsAny(value, "\"\r\n") {
+		return errors.Wrapf(errs.ErrInvalidCvar, "invalid value for %s", name)
+	}
+
+	_, set, _ := c.Dialect().cvarFormats()
+
+	_, err := c.ExecCommand(fmt.Sprintf(set, name, quoteCvar(value)), opts...)
+
+	return err
+}
+
+// GetCvars returns the values of many server cvars, keyed by name. If the dialect has a CvarBatchSeparator, reads are
+// joined into as few commands as MaxPayloadSize allows. Otherwise, they are sent at once without waiting for each
+// other's responses.
+//
+// If any cvar can't be read, the values which could be read are returned along with the error of the first failed cvar
+// in name order.
+func (c *Client) GetCvars(names []string, opts ...ExecOption) (map[string]string, error) {
+	for _, name := range names {
+		if err := validateCvarName(name); err != nil {
+			return nil, err
+		}
+	}
+
+	d := c.Dialect()
+	get, _, parse := d.cvarFormats()
+
+	var batches [][]string
+	if d.CvarBatchSeparator == "" {
+		for _, name := range names {
+			batches = append(batches, []string{name})
+		}
+	} else {
+		batches = batchCvars(names, get, d.CvarBatchSeparator, d.MaxPayloadSize)
+	}
+
+	values := map[string]string{}
+	failed := map[string]error{}
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, batch := range batches {
+		commands := make([]string, len(batch))
+		for i, name := range batch {
+			commands[i] = fmt.Sprintf(get, name)
+		}
+
+		wg.Add(1)
+		go func(batch []string, command string) {
+			defer wg.Done()
+
+			res, err := c.ExecCommand(command, opts...)
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			for _, name := range batch {
+				if err != nil {
+					failed[name] = err
+					continue
+				}
+
+				if value, perr := parse(name, res); perr != nil {
+					failed[name] = perr
+				} else {
+					values[name] = value
+				}
+			}
+		}(batch, strings.Join(commands, d.CvarBatchSeparator))
+	}
+
+	wg.Wait()
+
+	if len(failed) > 0 {
+		failedNames := make([]string, 0, len(failed))
+		for name := range failed {
+			failedNames = append(failedNames, name)
+		}
+		sort.Strings(failedNames)
+
+		first := failedNames[0]
+		return values, errors.Wrapf(failed[first], "could not get %d of %d cvars, first %s", len(failed), len(names),
+			first)
+	}
+
+	return values, nil
+}
+
+// batchCvars splits names into batches whose joined read commands fit into max bytes. If max is zero, all names are put
+// into a single batch.
+func batchCvars(names []string, format, separator string, max int) [][]string {
+	var batches [][]string
+	var batch []string
+	size := 0
+
+	for _, name := range names {
+		length := len(fmt.Sprintf(format, name))
+		if len(batch) > 0 {
+			length += len(separator)
+		}
+
+		if max > 0 && len(batch) > 0 && size+length > max {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+			length -= len(separator)
+		}
+
+		batch = append(batch, name)
+		size += length
+	}
+
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
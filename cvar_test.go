@@ -0,0 +1,44 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"testing"
+)
+
+func TestCvar(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Cvars", func() {
+		g.It("Should parse quoted and unquoted cvar values", func() {
+			response := `"sv_gravity" = "800" ( def. "800" ) min. 0.000000 game replicated
+"hostname" = "My \"best\" server"
+sv_cheats = false`
+
+			Expect(ParseSourceCvar("sv_gravity", response)).To(Equal("800"))
+			Expect(ParseSourceCvar("hostname", response)).To(Equal(`My "best" server`))
+			Expect(ParseSourceCvar("sv_cheats", response)).To(Equal("false"))
+
+			_, err := ParseSourceCvar("sv", response)
+			Expect(errors.Cause(err)).To(Equal(errs.ErrUnknownCvar))
+		})
+
+		g.It("Should batch reads within the payload limit", func() {
+			Expect(batchCvars([]string{"a", "bb", "cc"}, "%s", ";", 4)).To(Equal([][]string{{"a", "bb"}, {"cc"}}))
+			Expect(batchCvars([]string{"a", "bb", "cc"}, "%s", ";", 0)).To(Equal([][]string{{"a", "bb", "cc"}}))
+		})
+
+		g.It("Should reject names and values which can't be sent safely", func() {
+			c := NewClient(&Config{}, nil)
+
+			Expect(errors.Cause(c.SetCvar("sv_cheats; quit", "1"))).To(Equal(errs.ErrInvalidCvar))
+			Expect(errors.Cause(c.SetCvar("hostname", "a\"b"))).To(Equal(errs.ErrInvalidCvar))
+			Expect(quoteCvar("My server")).To(Equal(`"My server"`))
+		})
+	})
+}
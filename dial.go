@@ -0,0 +1,56 @@
+package rcon
+
+import (
+	"context"
+	"github.com/refractorgscm/rcon/errs"
+	"net"
+	"strconv"
+)
+
+// Dial creates a client for the server at address, connects and authenticates it, and returns it ready to execute
+// commands. It is a shortcut for programs which only execute commands and don't need broadcasts. address is in
+// host:port form, or a local address using UnixScheme or NamedPipeScheme.
+//
+// The client uses the default settings of NewClient, except that broadcasts are discarded rather than buffered. opts
+// change the config before the client is created, for example to set a Dialect or ReconnectPolicy. Invalid arguments
+// are returned as *errs.FieldError.
+//
+// If ctx is done before the client connected, the connection attempt is aborted and ctx.Err() is returned.
+func Dial(ctx context.Context, address, password string, opts ...ConfigOption) (*Client, error) {
+	config := &Config{
+		Password:         password,
+		BroadcastHandler: NopBroadcastHandler,
+	}
+
+	if isLocalAddress(address) {
+		config.Host = address
+	} else {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, &errs.FieldError{Field: "address", Reason: err.Error()}
+		}
+
+		n, err := strconv.ParseUint(port, 10, 16)
+		if host == "" || err != nil || n == 0 {
+			return nil, &errs.FieldError{Field: "address", Reason: "must be host:port with a port between 1 and 65535"}
+		}
+
+		config.Host, config.Port = host, uint16(n)
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	c := NewClient(config, nil)
+
+	if c.Password == "" && c.authMode() == AuthPassword {
+		return nil, &errs.FieldError{Field: "password", Reason: "is required unless AuthMode is empty or none"}
+	}
+
+	if err := c.ConnectContext(ctx); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,78 @@
+package rcon
+
+import (
+	"context"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/errs"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDial(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Dial", func() {
+		g.It("Should dial ready clients", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			host, port := s.Addr()
+			address := net.JoinHostPort(host, strconv.Itoa(int(port)))
+
+			c, err := Dial(context.Background(), address, testPassword)
+			Expect(err).ToNot(HaveOccurred())
+			defer c.Close()
+
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+
+			_, err = Dial(context.Background(), host, testPassword)
+			Expect(err).To(BeAssignableToTypeOf(&errs.FieldError{}))
+
+			_, err = Dial(context.Background(), address, "")
+			Expect(err).To(BeAssignableToTypeOf(&errs.FieldError{}))
+
+			_, err = Dial(context.Background(), address, "wrong")
+			Expect(err).To(HaveOccurred())
+		})
+
+		g.It("Should abort the connection attempt once ctx is done", func() {
+			// The listener accepts a connection but never answers the auth request.
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			defer l.Close()
+
+			accepted := make(chan net.Conn, 1)
+			go func() {
+				conn, err := l.Accept()
+				if err == nil {
+					accepted <- conn
+				}
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+			defer cancel()
+
+			_, err = Dial(ctx, l.Addr().String(), testPassword, func(config *Config) {
+				config.ConnTimeout = time.Second * 10
+			})
+			Expect(err).To(Equal(context.DeadlineExceeded))
+
+			var conn net.Conn
+			Eventually(accepted).Should(Receive(&conn))
+			defer conn.Close()
+
+			// The abandoned connection is closed rather than left to authenticate in the background.
+			_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+			_, err = io.Copy(ioutil.Discard, conn)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+}
@@ -0,0 +1,243 @@
+package rcon
+
+import (
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/packet"
+	"strings"
+	"time"
+)
+
+// Dialect describes the quirks of a particular RCON implementation. Many games implement RCON slightly differently
+// from Valve's specification, so the client adjusts its behavior based on the dialect in use.
+//
+// Dialects for some games can be found in the presets package. If DetectDialect is set in the client config, the
+// client probes the server on connect and adjusts the dialect to match the server's behavior.
+type Dialect struct {
+	Name string
+
+	// SupportsFragmentation is true if the server mirrors an empty SERVERDATA_RESPONSE_VALUE packet back to the
+	// client. This can be used to detect the end of a response which was split across multiple packets.
+	SupportsFragmentation bool
+
+	// AuthResponsePreamble is true if the server sends an empty SERVERDATA_RESPONSE_VALUE packet before the auth
+	// response.
+	AuthResponsePreamble bool
+
+	// ProbeResponse is the start of the body the server answers the dialect detection probe with, if it doesn't mirror
+	// the probe, such as the error message servers send for unknown packet types. It tells dialects apart which
+	// otherwise behave the same during detection.
+	ProbeResponse string
+
+	// SupportsBroadcasts is true if the server sends broadcast messages over RCON. Servers of dialects with
+	// UnsolicitedPacketTypes are assumed to send broadcasts too.
+	SupportsBroadcasts bool
+
+	// RequiresSingleConnection is true if the server only accepts a single RCON connection at a time.
+	RequiresSingleConnection bool
+
+	// MaxPayloadSize is the maximum size of a command body the server accepts, in bytes. Commands larger than this are
+	// rejected with ErrPayloadTooLarge before being sent. If zero, command size is not limited.
+	MaxPayloadSize int
+
+	// SayFormat is the format of the command which sends a message to the server's chat. It must contain a single %s
+	// verb, which is replaced with the message. If empty, DefaultSayFormat is used.
+	SayFormat string
+
+	// MaxSayLength is the maximum number of characters of a single chat message. Longer messages are truncated by the
+	// server, so SayChunked splits them. If zero, chat messages are not split.
+	MaxSayLength int
+
+	// ArgQuoting is how the server's console quotes command arguments containing whitespace. It is used by
+	// ExecTemplate and ListSync. If empty, arguments are quoted with QuoteDouble.
+	ArgQuoting ArgQuoting
+
+	// FingerprintCommand is the command which reports the server's game, version and map, such as "status". Its
+	// response is parsed with ParseFingerprint. If empty, servers of this dialect can't be fingerprinted.
+	FingerprintCommand string
+	ParseFingerprint   FingerprintParser
+
+	// ResponseMappings map localized responses to their canonical form. They are applied to every command response,
+	// except for those of ExecCommandRaw, before any response parser sees them.
+	ResponseMappings []ResponseMapping
+
+	// CvarGetFormat and CvarSetFormat are the formats of the commands reading and writing server cvars. CvarGetFormat
+	// must contain a single %s verb, which is replaced with the cvar name, and CvarSetFormat two, which are replaced
+	// with the name and value. Responses are parsed with ParseCvar. If empty, DefaultCvarGetFormat,
+	// DefaultCvarSetFormat and ParseSourceCvar are used.
+	CvarGetFormat string
+	CvarSetFormat string
+	ParseCvar     CvarParser
+
+	// CvarBatchSeparator joins several commands into one, such as ";" for Source engine consoles. GetCvars uses it to
+	// read many cvars with few commands. If empty, each cvar is read with its own command.
+	CvarBatchSeparator string
+
+	// RecentLogCommand is the command which returns the server's recent log lines, which BackfillBroadcasts uses to
+	// recover broadcasts missed while disconnected. Its response is split into messages with ParseRecentLog, or
+	// SplitLogLines if nil. If empty, broadcasts of this dialect can't be backfilled.
+	RecentLogCommand string
+	ParseRecentLog   LogParser
+
+	// ResponseTerminator detects the end of responses split across several packets. If nil, each response is a single
+	// packet.
+	ResponseTerminator *ResponseTerminator
+
+	// Framing describes how packets of this dialect are laid out on the wire. If nil, the Source RCON framing is used.
+	// If the framing's Mode is nil, the client's EndianMode is used.
+	Framing *packet.Framing
+
+	// UnsolicitedPacketTypes is the list of packet types the server sends without them being requested, such as
+	// broadcast messages.
+	UnsolicitedPacketTypes []packet.PacketType
+}
+
+// DefaultDialect is the dialect of servers which follow Valve's Source RCON specification. It is used if no dialect is
+// set in the client config.
+var DefaultDialect = &Dialect{
+	Name:                  "source",
+	SupportsFragmentation: true,
+	AuthResponsePreamble:  true,
+	FingerprintCommand:    "status",
+	ParseFingerprint:      ParseSourceStatus,
+	CvarBatchSeparator:    ";",
+}
+
+// DefaultProbeTimeout is the default amount of time the dialect detection probe waits for responses.
+const DefaultProbeTimeout = time.Second
+
+// Dialect returns the dialect currently in use by the client.
+func (c *Client) Dialect() *Dialect {
+	c.dialectLock.Lock()
+	defer c.dialectLock.Unlock()
+
+	return c.dialect
+}
+
+func (c *Client) setDialect(d *Dialect) {
+	c.dialectLock.Lock()
+	c.dialect = d
+	c.dialectLock.Unlock()
+}
+
+// probeResult holds the server behavior observed by the dialect detection probe.
+type probeResult struct {
+	authPreamble     bool
+	fragmentation    bool
+	response         string
+	unsolicitedTypes []packet.PacketType
+}
+
+// detectDialect probes the server and selects or adjusts the dialect to match its behavior. The maximum payload size
+// cannot be probed safely, so it is taken from the matched candidate or the configured dialect. It must be called after
+// authenticating but before the reader and writer routines are started, since it reads from the connection directly.
+func (c *Client) detectDialect() error {
+	res, err := c.probe()
+	if err != nil {
+		return err
+	}
+
+	// If exactly one of the candidate dialects matches the observed behavior, use it.
+	if d := matchDialect(c.CandidateDialects, res); d != nil {
+		c.log.Debug("Detected dialect: ", d.Name)
+		c.setDialect(d)
+		return nil
+	}
+
+	// Otherwise, adjust a copy of the configured dialect to match the observed behavior.
+	detected := *c.Dialect()
+	detected.SupportsFragmentation = res.fragmentation
+	detected.AuthResponsePreamble = res.authPreamble
+	detected.UnsolicitedPacketTypes = res.unsolicitedTypes
+
+	c.log.Debug("No single candidate dialect matched, adjusted dialect: ", detected.Name)
+	c.setDialect(&detected)
+
+	return nil
+}
+
+// matchDialect returns the candidate matching the observed behavior. Candidates with a ProbeResponse matching the
+// probe's response are preferred over those without one. If no candidate or several candidates match, nil is returned,
+// since dialects can't be told apart by behavior they share.
+func matchDialect(candidates []*Dialect, res probeResult) *Dialect {
+	var matches, responseMatches []*Dialect
+	for _, d := range candidates {
+		if d.SupportsFragmentation != res.fragmentation || d.AuthResponsePreamble != res.authPreamble {
+			continue
+		}
+
+		if d.ProbeResponse == "" {
+			matches = append(matches, d)
+		} else if res.response != "" && strings.HasPrefix(res.response, d.ProbeResponse) {
+			responseMatches = append(responseMatches, d)
+		}
+	}
+
+	if len(responseMatches) > 0 {
+		matches = responseMatches
+	}
+
+	if len(matches) != 1 {
+		return nil
+	}
+
+	return matches[0]
+}
+
+// probe checks whether the server mirrors empty SERVERDATA_RESPONSE_VALUE packets, and records the types of any
+// unsolicited packets received while waiting for the response.
+func (c *Client) probe() (probeResult, error) {
+	res := probeResult{
+		authPreamble: c.authPreamble,
+	}
+
+	p := c.newClientPacket(packet.TypeCommandRes, "")
+	if err := c.sendPacket(p); err != nil {
+		return res, err
+	}
+
+	// Read until the probe timeout expires. Servers which support fragmentation may respond with more than one packet,
+	// so we keep reading after the mirrored packet is received to make sure they are all consumed.
+	deadline := time.Now().Add(c.ProbeTimeout)
+	for {
+		rp, err := c.readPacketDeadline(deadline)
+		if err != nil {
+			break
+		}
+
+		if rp.ID() == p.ID() {
+			// Servers which don't support fragmentation may respond with an error message instead of an empty packet.
+			if rp.Type() == packet.TypeCommandRes && len(rp.Body()) <= 1 {
+				res.fragmentation = true
+			} else if res.response == "" && !res.fragmentation {
+				body := rp.Body()
+				res.response = string(body[:len(body)-1])
+			}
+
+			continue
+		}
+
+		res.unsolicitedTypes = appendPacketType(res.unsolicitedTypes, rp.Type())
+
+		// Unsolicited packets may be broadcasts, so don't drop them.
+		if c.BroadcastChecker(rp) {
+			body := rp.Body()
+			c.handleBroadcast(string(body[:len(body)-1]))
+		}
+	}
+
+	if err := c.conn.SetDeadline(time.Time{}); err != nil {
+		return res, errors.Wrap(err, "could not clear connection deadline")
+	}
+
+	return res, nil
+}
+
+func appendPacketType(types []packet.PacketType, t packet.PacketType) []packet.PacketType {
+	for _, v := range types {
+		if v == t {
+			return types
+		}
+	}
+
+	return append(types, t)
+}
@@ -0,0 +1,92 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/rcontest"
+	"testing"
+	"time"
+)
+
+func TestDialect(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("matchDialect", func() {
+		source := &Dialect{Name: "source", SupportsFragmentation: true, AuthResponsePreamble: true}
+		minecraft := &Dialect{Name: "minecraft", ProbeResponse: "Unknown request"}
+		mordhau := &Dialect{Name: "mordhau"}
+		factorio := &Dialect{Name: "factorio"}
+
+		g.It("Should match candidates by behavior and probe response", func() {
+			tests := []struct {
+				name       string
+				candidates []*Dialect
+				res        probeResult
+				expected   *Dialect
+			}{
+				{"source", []*Dialect{source, minecraft, mordhau}, probeResult{fragmentation: true, authPreamble: true}, source},
+				{"probe response", []*Dialect{source, mordhau, minecraft, factorio},
+					probeResult{response: "Unknown request 0"}, minecraft},
+				{"ambiguous", []*Dialect{source, mordhau, minecraft, factorio}, probeResult{}, nil},
+				{"single without response", []*Dialect{source, mordhau, minecraft}, probeResult{}, mordhau},
+				{"other response", []*Dialect{source, minecraft}, probeResult{response: "Unknown command"}, nil},
+				{"no match", []*Dialect{source}, probeResult{authPreamble: true}, nil},
+				{"no candidates", nil, probeResult{}, nil},
+			}
+
+			for _, test := range tests {
+				Expect(matchDialect(test.candidates, test.res)).To(Equal(test.expected), test.name)
+			}
+		})
+	})
+
+	g.Describe("Dialect detection", func() {
+		candidates := []*Dialect{
+			{Name: "source", SupportsFragmentation: true, AuthResponsePreamble: true},
+			{Name: "minecraft", ProbeResponse: "Unknown request"},
+			{Name: "mordhau"},
+			{Name: "factorio"},
+		}
+
+		detect := func(config rcontest.Config) *Dialect {
+			config.Password, config.Handler = testPassword, echoHandler
+			s, err := rcontest.NewServer(config)
+			Expect(err).ToNot(HaveOccurred())
+			defer s.Close()
+
+			c := newTestClient(s, &Config{
+				DetectDialect:     true,
+				CandidateDialects: candidates,
+				ProbeTimeout:      time.Millisecond * 100,
+			})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+
+			return c.Dialect()
+		}
+
+		g.It("Should detect servers mirroring the probe", func() {
+			Expect(detect(rcontest.Config{}).Name).To(Equal("source"))
+		})
+
+		g.It("Should detect servers by their probe response", func() {
+			Expect(detect(rcontest.Config{
+				NoAuthPreamble:      true,
+				UnknownTypeResponse: "Unknown request 0",
+			}).Name).To(Equal("minecraft"))
+		})
+
+		g.It("Should adjust the configured dialect if several candidates match", func() {
+			d := detect(rcontest.Config{NoAuthPreamble: true, IgnoreResponseValues: true})
+			Expect(d.Name).To(Equal(DefaultDialect.Name))
+			Expect(d).ToNot(Equal(DefaultDialect))
+			Expect(d.SupportsFragmentation).To(BeFalse())
+			Expect(d.AuthResponsePreamble).To(BeFalse())
+		})
+	})
+}
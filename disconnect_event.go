@@ -0,0 +1,59 @@
+package rcon
+
+import (
+	"time"
+)
+
+// DisconnectEvent describes a disconnect and, if automatic reconnection is enabled, the outcome of reconnecting. It can
+// be used to track availability and build alerting without parsing log output.
+type DisconnectEvent struct {
+	// Address is the address of the server the client was connected to.
+	Address string
+
+	// Cause is the error which caused the disconnect. It is nil for expected disconnects.
+	Cause error
+
+	// Expected is true if the disconnect was caused by calling Close or Reconnect.
+	Expected bool
+
+	// ReconnectAttempted is true if the client tried to reconnect automatically.
+	ReconnectAttempted bool
+
+	// ReconnectSucceeded is true if the client reconnected automatically.
+	ReconnectSucceeded bool
+
+	// ReconnectAttempts is the number of reconnect attempts made.
+	ReconnectAttempts int
+
+	// DisconnectedAt is the time the client was disconnected.
+	DisconnectedAt time.Time
+
+	// Downtime is the time between the disconnect and the event being sent. If the client reconnected, this is the time
+	// it was disconnected for.
+	Downtime time.Duration
+}
+
+// newDisconnectEvent creates a disconnect event for the current address.
+func (c *Client) newDisconnectEvent(cause error) DisconnectEvent {
+	return DisconnectEvent{
+		Address:        c.addressString(),
+		Cause:          cause,
+		Expected:       cause == nil,
+		DisconnectedAt: time.Now(),
+	}
+}
+
+// sendDisconnectEvent sends an event to DisconnectEvents without blocking. If the channel is full, the event is dropped.
+func (c *Client) sendDisconnectEvent(event DisconnectEvent) {
+	if c.DisconnectEvents == nil {
+		return
+	}
+
+	event.Downtime = time.Since(event.DisconnectedAt)
+
+	select {
+	case c.DisconnectEvents <- event:
+	default:
+		c.log.Error("Disconnect event channel is full, dropping event")
+	}
+}
@@ -0,0 +1,104 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDisconnectEvents(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("DisconnectEvents", func() {
+		g.It("Should report expected disconnects", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			events := make(chan DisconnectEvent, 1)
+			c := newTestClient(s, &Config{DisconnectEvents: events})
+			Expect(c.Connect()).To(Succeed())
+			Expect(c.Close()).To(Succeed())
+
+			var event DisconnectEvent
+			Expect(events).To(Receive(&event))
+
+			host, port := s.Addr()
+			Expect(event.Address).To(Equal(net.JoinHostPort(host, strconv.Itoa(int(port)))))
+			Expect(event.Cause).ToNot(HaveOccurred())
+			Expect(event.Expected).To(BeTrue())
+			Expect(event.ReconnectAttempted).To(BeFalse())
+			Expect(event.DisconnectedAt).To(BeTemporally("~", time.Now(), time.Second))
+		})
+
+		g.It("Should report unexpected disconnects once the client reconnected", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			events := make(chan DisconnectEvent, 1)
+			c := newTestClient(s, &Config{
+				DisconnectEvents: events,
+				ReconnectPolicy:  &ReconnectPolicy{InitialDelay: time.Millisecond * 50},
+			})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			s.CloseConnections()
+
+			var event DisconnectEvent
+			Eventually(events, time.Second).Should(Receive(&event))
+			Expect(c.State()).To(Equal(StateConnected))
+			Expect(event.Cause).To(HaveOccurred())
+			Expect(event.Expected).To(BeFalse())
+			Expect(event.ReconnectAttempted).To(BeTrue())
+			Expect(event.ReconnectSucceeded).To(BeTrue())
+			Expect(event.ReconnectAttempts).To(Equal(1))
+			Expect(event.Downtime).To(BeNumerically(">=", time.Millisecond*50))
+		})
+
+		g.It("Should report unexpected disconnects without reconnecting", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			events := make(chan DisconnectEvent, 1)
+			c := newTestClient(s, &Config{DisconnectEvents: events})
+			Expect(c.Connect()).To(Succeed())
+
+			s.CloseConnections()
+
+			var event DisconnectEvent
+			Eventually(events).Should(Receive(&event))
+			Expect(event.Cause).To(HaveOccurred())
+			Expect(event.Expected).To(BeFalse())
+			Expect(event.ReconnectAttempted).To(BeFalse())
+		})
+
+		g.It("Should drop events instead of blocking if the channel is full", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			events := make(chan DisconnectEvent, 1)
+			c := newTestClient(s, &Config{DisconnectEvents: events})
+
+			Expect(c.Connect()).To(Succeed())
+			Expect(c.Close()).To(Succeed())
+
+			// Nothing reads the first event, so the second one doesn't fit.
+			Expect(c.Connect()).To(Succeed())
+			closed := make(chan error, 1)
+			go func() {
+				closed <- c.Close()
+			}()
+			Eventually(closed).Should(Receive(BeNil()))
+
+			Expect(events).To(HaveLen(1))
+			Expect(events).To(Receive())
+			Expect(events).ToNot(Receive())
+		})
+	})
+}
@@ -0,0 +1,90 @@
+package rcon
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+)
+
+// Drain gracefully shuts the connection down. New commands are rejected with ErrDraining, while commands which were
+// already submitted are given until ctx is done to receive their responses. The client is then closed.
+//
+// If ctx is done before every command completed, the client is closed anyway and the context's error is returned. The
+// remaining commands can't receive their responses anymore, so they fail once QueueReadTimeout has passed. Commands in
+// the offline queue stay queued.
+func (c *Client) Drain(ctx context.Context) error {
+	if !c.isConnected() {
+		return errs.ErrNotConnected
+	}
+
+	c.drainLock.Lock()
+	c.draining = true
+	drained := make(chan struct{})
+	if c.inflight == 0 {
+		close(drained)
+	} else {
+		c.drained = drained
+	}
+	c.drainLock.Unlock()
+
+	c.log.Info("Draining client")
+
+	// The client can be connected again once it is drained.
+	defer func() {
+		c.drainLock.Lock()
+		c.draining = false
+		c.drained = nil
+		c.drainLock.Unlock()
+	}()
+
+	var err error
+
+	select {
+	case <-drained:
+		c.log.Debug("All in-flight commands completed")
+	case <-ctx.Done():
+		err = ctx.Err()
+		c.log.Info("Drain deadline reached with commands in flight")
+	}
+
+	if closeErr := c.Close(); closeErr != nil && errors.Cause(closeErr) != errs.ErrNotConnected && err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// Draining returns true while Drain is waiting for in-flight commands.
+func (c *Client) Draining() bool {
+	c.drainLock.Lock()
+	defer c.drainLock.Unlock()
+
+	return c.draining
+}
+
+// beginCommand registers an in-flight command. It returns false if the client is draining, in which case the command
+// must be rejected.
+func (c *Client) beginCommand() bool {
+	c.drainLock.Lock()
+	defer c.drainLock.Unlock()
+
+	if c.draining {
+		return false
+	}
+
+	c.inflight++
+
+	return true
+}
+
+// endCommand must be called once a command registered with beginCommand completed.
+func (c *Client) endCommand() {
+	c.drainLock.Lock()
+	defer c.drainLock.Unlock()
+
+	c.inflight--
+	if c.inflight == 0 && c.drained != nil {
+		close(c.drained)
+		c.drained = nil
+	}
+}
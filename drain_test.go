@@ -0,0 +1,109 @@
+package rcon
+
+import (
+	"context"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"github.com/refractorgscm/rcon/rcontest"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Drain", func() {
+		var started, release chan struct{}
+		var s *rcontest.Server
+		var c *Client
+
+		// "slow" commands are answered once release is closed.
+		g.BeforeEach(func() {
+			started = make(chan struct{}, 1)
+			release = make(chan struct{})
+			s = newTestServer(func(command string) string {
+				if command == "slow" {
+					started <- struct{}{}
+					<-release
+				}
+
+				return "echo: " + command
+			})
+
+			c = newTestClient(s, &Config{QueueReadTimeout: time.Millisecond * 300})
+			Expect(c.Connect()).To(Succeed())
+		})
+
+		g.AfterEach(func() {
+			select {
+			case <-release:
+			default:
+				close(release)
+			}
+
+			_ = c.Close()
+			_ = s.Close()
+		})
+
+		// execSlow executes a slow command in the background, and returns once the server received it.
+		execSlow := func() chan error {
+			result := make(chan error, 1)
+			go func() {
+				res, err := c.ExecCommand("slow")
+				if err == nil && res != "echo: slow" {
+					err = errors.New("unexpected response: " + res)
+				}
+				result <- err
+			}()
+
+			Eventually(started).Should(Receive())
+
+			return result
+		}
+
+		g.It("Should wait for in-flight commands and reject new ones", func() {
+			result := execSlow()
+
+			drained := make(chan error, 1)
+			go func() {
+				drained <- c.Drain(context.Background())
+			}()
+
+			Eventually(c.Draining).Should(BeTrue())
+			_, err := c.ExecCommand("status")
+			Expect(errors.Cause(err)).To(Equal(errs.ErrDraining))
+			Consistently(drained, time.Millisecond*50).ShouldNot(Receive())
+
+			close(release)
+			Eventually(result).Should(Receive(BeNil()))
+			Eventually(drained).Should(Receive(BeNil()))
+			Expect(c.Done()).To(BeClosed())
+			Expect(c.Err()).ToNot(HaveOccurred())
+			Expect(c.Draining()).To(BeFalse())
+		})
+
+		g.It("Should close the client once the deadline is reached", func() {
+			result := execSlow()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+			defer cancel()
+
+			Expect(c.Drain(ctx)).To(Equal(context.DeadlineExceeded))
+			Expect(c.State()).To(Equal(StateDisconnected))
+
+			var err error
+			Eventually(result).Should(Receive(&err))
+			Expect(errors.Cause(err)).To(Equal(errs.ErrReadTimeout))
+		})
+
+		g.It("Should fail if the client isn't connected", func() {
+			Expect(c.Close()).To(Succeed())
+			Expect(c.Drain(context.Background())).To(Equal(errs.ErrNotConnected))
+		})
+	})
+}
@@ -0,0 +1,46 @@
+package rcon
+
+import "github.com/refractorgscm/rcon/errs"
+
+// AttrDryRun is the span attribute set on commands which were executed in dry-run mode.
+const AttrDryRun = "rcon.dry_run"
+
+// DryRunResponder synthesizes the response to a command executed in dry-run mode.
+type DryRunResponder func(command string) string
+
+// WithDryRun sets whether a single call is executed in dry-run mode, overriding the client's DryRun option.
+func WithDryRun(dryRun bool) ExecOption {
+	return func(o *execOptions) {
+		o.dryRun = &dryRun
+	}
+}
+
+// isDryRun reports whether a call with the provided options is executed in dry-run mode.
+func (c *Client) isDryRun(o *execOptions) bool {
+	if o.dryRun != nil {
+		return *o.dryRun
+	}
+
+	return c.DryRun
+}
+
+// execDryRun validates a command like execCommand, but returns a synthesized response instead of sending it. The
+// client doesn't have to be connected.
+func (c *Client) execDryRun(command string, priority Priority) (string, error) {
+	if !c.beginCommand() {
+		return "", errs.ErrDraining
+	}
+	defer c.endCommand()
+
+	if _, err := c.commandPayload(command); err != nil {
+		return "", err
+	}
+
+	c.log.Info("Dry run, not executing command: ", c.CommandRedactor(command), " Priority: ", priority)
+
+	if c.DryRunResponder == nil {
+		return "", nil
+	}
+
+	return c.DryRunResponder(command), nil
+}
@@ -0,0 +1,40 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Dry run", func() {
+		g.It("Should validate and record commands without sending them", func() {
+			c := NewClient(&Config{
+				DryRun:      true,
+				HistorySize: 10,
+				Dialect:     &Dialect{Name: "limited", MaxPayloadSize: 16},
+				DryRunResponder: func(command string) string {
+					return "would execute " + command
+				},
+			}, nil)
+
+			Expect(c.ExecCommand("kick Bob", WithInitiator("admin:1"))).To(Equal("would execute kick Bob"))
+			Expect(c.History()).To(HaveLen(1))
+			Expect(c.History()[0].DryRun).To(BeTrue())
+
+			_, err := c.ExecCommand(strings.Repeat("x", 17))
+			Expect(errors.Cause(err)).To(Equal(errs.ErrPayloadTooLarge))
+
+			_, err = c.ExecCommand("status", WithDryRun(false))
+			Expect(err).To(Equal(errs.ErrNotConnected))
+		})
+	})
+}
@@ -0,0 +1,125 @@
+package rcon
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"sort"
+	"time"
+)
+
+// AddEndpointGroup registers a group of servers which are RCON endpoints of the same cluster, so that commands can be
+// executed on whichever endpoint is fastest using ExecOnGroup. The servers must already be registered. If a group with
+// the same name exists, ErrGroupExists is returned.
+func (m *Manager) AddEndpointGroup(group string, servers ...string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.groups[group]; ok {
+		return errors.Wrapf(errs.ErrGroupExists, "group %s", group)
+	}
+
+	for _, name := range servers {
+		if _, ok := m.servers[name]; !ok {
+			return errors.Wrapf(errs.ErrServerNotFound, "server %s", name)
+		}
+	}
+
+	m.groups[group] = append([]string{}, servers...)
+
+	return nil
+}
+
+// RemoveEndpointGroup unregisters an endpoint group. Its servers stay registered. It is a no-op if no group of that
+// name is registered.
+func (m *Manager) RemoveEndpointGroup(group string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.groups, group)
+}
+
+// Endpoints returns the healthy servers of an endpoint group, fastest first. Servers are healthy while their client is
+// connected, and ranked by their average round trip time. Servers without RTT samples are ranked after the others.
+// Servers removed from the manager are skipped. If no group of that name is registered, ErrGroupNotFound is returned.
+func (m *Manager) Endpoints(group string) ([]*ManagedServer, error) {
+	m.lock.RLock()
+	names, ok := m.groups[group]
+	var servers []*ManagedServer
+	for _, name := range names {
+		if server, ok := m.servers[name]; ok {
+			servers = append(servers, server)
+		}
+	}
+	m.lock.RUnlock()
+
+	if !ok {
+		return nil, errors.Wrapf(errs.ErrGroupNotFound, "group %s", group)
+	}
+
+	type endpoint struct {
+		server *ManagedServer
+		rtt    time.Duration
+	}
+
+	var healthy []endpoint
+	for _, server := range servers {
+		if server.Client.State() != StateConnected {
+			continue
+		}
+
+		healthy = append(healthy, endpoint{server: server, rtt: server.Client.Stats().AverageRTT})
+	}
+
+	sort.SliceStable(healthy, func(i, j int) bool {
+		a, b := healthy[i].rtt, healthy[j].rtt
+		if a == 0 || b == 0 {
+			return b == 0 && a != 0
+		}
+
+		return a < b
+	})
+
+	endpoints := make([]*ManagedServer, len(healthy))
+	for i, e := range healthy {
+		endpoints[i] = e.server
+	}
+
+	return endpoints, nil
+}
+
+// ExecOnGroup executes a command on the fastest healthy endpoint of a group, see Endpoints. If the command fails, it
+// fails over to the next endpoint, until it succeeded or every endpoint was tried. The errors of the endpoints which
+// were tried are returned in an AttemptsError. If no endpoint is healthy, ErrNoHealthyEndpoint is returned.
+//
+// Failing over executes the command again, so it only happens if the command is idempotent, see WithIdempotent and
+// Config.IsIdempotent, or if the failed attempt never reached the server, for example because the endpoint
+// disconnected in the meantime.
+func (m *Manager) ExecOnGroup(ctx context.Context, group, command string, opts ...ExecOption) (string, error) {
+	endpoints, err := m.Endpoints(group)
+	if err != nil {
+		return "", err
+	}
+
+	if len(endpoints) == 0 {
+		return "", errors.Wrapf(errs.ErrNoHealthyEndpoint, "group %s", group)
+	}
+
+	_, o := applyOptions(ctx, opts)
+	attempts := &errs.AttemptsError{}
+
+	for _, server := range endpoints {
+		res := m.execOnServer(ctx, server, command, opts)
+		if res.Err == nil {
+			return res.Response, nil
+		}
+
+		attempts.Errors = append(attempts.Errors, errors.Wrapf(res.Err, "server %s", server.Name))
+
+		if ctx.Err() != nil || !(server.Client.isIdempotent(command, o) || notSent(res.Err)) {
+			break
+		}
+	}
+
+	return "", attempts
+}
@@ -0,0 +1,152 @@
+package rcon
+
+import (
+	"context"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"github.com/refractorgscm/rcon/rcontest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEndpoints(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Endpoint groups", func() {
+		g.It("Should reject unknown endpoint groups and unhealthy endpoints", func() {
+			m := NewManager(ManagerConfig{})
+			Expect(m.Add("eu-1", NewClient(&Config{}, nil))).To(Succeed())
+
+			Expect(errors.Cause(m.AddEndpointGroup("eu", "eu-1", "eu-2"))).To(Equal(errs.ErrServerNotFound))
+			Expect(m.AddEndpointGroup("eu", "eu-1")).To(Succeed())
+			Expect(errors.Cause(m.AddEndpointGroup("eu", "eu-1"))).To(Equal(errs.ErrGroupExists))
+
+			_, err := m.ExecOnGroup(context.Background(), "us", "status")
+			Expect(errors.Cause(err)).To(Equal(errs.ErrGroupNotFound))
+
+			_, err = m.ExecOnGroup(context.Background(), "eu", "status")
+			Expect(errors.Cause(err)).To(Equal(errs.ErrNoHealthyEndpoint))
+		})
+
+		g.It("Should route group commands to the fastest healthy endpoint", func() {
+			fast := newTestServer(echoHandler)
+			defer fast.Close()
+
+			slow, err := rcontest.NewServer(rcontest.Config{
+				Password: testPassword,
+				Handler:  echoHandler,
+				Chaos:    &rcontest.Chaos{Latency: time.Millisecond * 20},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			defer slow.Close()
+
+			m := NewManager(ManagerConfig{})
+			for name, s := range map[string]*rcontest.Server{"fast": fast, "slow": slow} {
+				c := newTestClient(s, &Config{})
+				Expect(m.Add(name, c)).To(Succeed())
+				Expect(c.Connect()).To(Succeed())
+				defer c.Close()
+
+				Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+			}
+
+			Expect(m.AddEndpointGroup("cluster", "slow", "fast")).To(Succeed())
+
+			endpoints, err := m.Endpoints("cluster")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(endpoints).To(HaveLen(2))
+			Expect(endpoints[0].Name).To(Equal("fast"))
+
+			fast.Close()
+			Eventually(endpoints[0].Client.State).Should(Equal(StateDisconnected))
+			Expect(m.ExecOnGroup(context.Background(), "cluster", "status")).To(Equal("echo: status"))
+		})
+	})
+	g.Describe("Endpoint failover", func() {
+		var m *Manager
+		var calls func(server string) int
+		var cleanup []func()
+
+		// The first endpoint of the group answers slower than its client waits for, the second one right away.
+		g.BeforeEach(func() {
+			var lock sync.Mutex
+			counts := map[string]int{}
+			handler := func(server string, delay time.Duration) rcontest.Handler {
+				return func(command string) string {
+					lock.Lock()
+					counts[server]++
+					lock.Unlock()
+
+					time.Sleep(delay)
+
+					return server + ": " + command
+				}
+			}
+
+			calls = func(server string) int {
+				lock.Lock()
+				defer lock.Unlock()
+
+				return counts[server]
+			}
+
+			m = NewManager(ManagerConfig{})
+			cleanup = nil
+			for _, server := range []struct {
+				name  string
+				delay time.Duration
+			}{{"eu-1", time.Millisecond * 150}, {"eu-2", 0}} {
+				s := newTestServer(handler(server.name, server.delay))
+				c := newTestClient(s, &Config{QueueReadTimeout: time.Millisecond * 100})
+				Expect(c.Connect()).To(Succeed())
+				Expect(m.Add(server.name, c)).To(Succeed())
+
+				cleanup = append(cleanup, func() {
+					_ = c.Close()
+					_ = s.Close()
+				})
+			}
+
+			Expect(m.AddEndpointGroup("eu", "eu-1", "eu-2")).To(Succeed())
+		})
+
+		g.AfterEach(func() {
+			for _, fn := range cleanup {
+				fn()
+			}
+		})
+
+		g.It("Should not execute commands which aren't idempotent twice", func() {
+			_, err := m.ExecOnGroup(context.Background(), "eu", "ban Bob")
+			Expect(err).To(BeAssignableToTypeOf(&errs.AttemptsError{}))
+			Expect(err.(*errs.AttemptsError).Errors).To(HaveLen(1))
+			Expect(errors.Cause(err)).To(Equal(errs.ErrReadTimeout))
+			Expect(calls("eu-2")).To(Equal(0))
+		})
+
+		g.It("Should fail over idempotent commands", func() {
+			Expect(m.ExecOnGroup(context.Background(), "eu", "status", WithIdempotent(true))).To(Equal("eu-2: status"))
+			Expect(calls("eu-1")).To(Equal(1))
+		})
+
+		g.It("Should fail over commands which never reached the endpoint", func() {
+			c := m.servers["eu-1"].Client
+			c.drainLock.Lock()
+			c.draining = true
+			c.drainLock.Unlock()
+
+			Expect(m.ExecOnGroup(context.Background(), "eu", "ban Bob")).To(Equal("eu-2: ban Bob"))
+			Expect(calls("eu-1")).To(Equal(0))
+
+			c.drainLock.Lock()
+			c.draining = false
+			c.drainLock.Unlock()
+		})
+	})
+}
@@ -0,0 +1,95 @@
+package rcon
+
+import (
+	"github.com/pkg/errors"
+	"time"
+)
+
+// DefaultErrorHistorySize is the default ErrorHistorySize.
+const DefaultErrorHistorySize = 50
+
+// ErrorRecord is an error encountered by the client, as kept in the error history.
+type ErrorRecord struct {
+	Err  error
+	Time time.Time
+
+	// Cause is the cause of Err, see errors.Cause. It can be compared to the errors of the errs package.
+	Cause error
+
+	// Connection is the number of the connection the error occurred on, counting successful connects from 1. Errors
+	// which occurred while connecting belong to the connection that was being established. It is zero if the client
+	// never connected.
+	Connection uint64
+}
+
+// ErrorSummary is a summary of the errors recently encountered by a client. It is returned by Client.ErrorsSummary.
+type ErrorSummary struct {
+	// Recent holds the most recent errors, oldest first. At most ErrorHistorySize errors are kept.
+	Recent []ErrorRecord
+
+	// Counts is the number of recent errors of each cause, keyed by the cause's message.
+	Counts map[string]int
+
+	// Total is the number of errors encountered since the client was created, including those no longer kept.
+	Total uint64
+}
+
+// errorHistory keeps the most recent errors. It is part of clientStats and protected by its lock.
+type errorHistory struct {
+	size    int
+	records []ErrorRecord
+	next    int
+	total   uint64
+}
+
+func (h *errorHistory) add(record ErrorRecord) {
+	h.total++
+
+	if h.size <= 0 {
+		return
+	}
+
+	if len(h.records) < h.size {
+		h.records = append(h.records, record)
+		return
+	}
+
+	h.records[h.next] = record
+	h.next = (h.next + 1) % h.size
+}
+
+func (h *errorHistory) summary() ErrorSummary {
+	summary := ErrorSummary{
+		Recent: make([]ErrorRecord, 0, len(h.records)),
+		Counts: map[string]int{},
+		Total:  h.total,
+	}
+
+	summary.Recent = append(summary.Recent, h.records[h.next:]...)
+	summary.Recent = append(summary.Recent, h.records[:h.next]...)
+
+	for _, record := range summary.Recent {
+		summary.Counts[record.Cause.Error()]++
+	}
+
+	return summary
+}
+
+// ErrorsSummary returns the errors the client encountered most recently, such as failed commands, connection losses and
+// authentication failures, so that tooling can show recent problems without subscribing to events.
+func (c *Client) ErrorsSummary() ErrorSummary {
+	c.stats.Lock()
+	defer c.stats.Unlock()
+
+	return c.stats.errors.summary()
+}
+
+// newErrorRecord creates the record of an error which occurred on the connection with the provided number.
+func newErrorRecord(err error, connection uint64) ErrorRecord {
+	return ErrorRecord{
+		Err:        err,
+		Time:       time.Now(),
+		Cause:      errors.Cause(err),
+		Connection: connection,
+	}
+}
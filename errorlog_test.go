@@ -0,0 +1,33 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"testing"
+)
+
+func TestErrorLog(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("ErrorsSummary", func() {
+		g.It("Should keep the most recent errors with their cause", func() {
+			c := NewClient(&Config{ErrorHistorySize: 2}, nil)
+
+			c.stats.recordError(errors.New("first"))
+			c.stats.recordError(errs.ErrReadTimeout)
+			c.stats.recordCommand(0, errors.Wrap(errs.ErrReadTimeout, "could not get command response"))
+
+			summary := c.ErrorsSummary()
+			Expect(summary.Total).To(Equal(uint64(3)))
+			Expect(summary.Recent).To(HaveLen(2))
+			Expect(summary.Recent[1].Cause).To(Equal(errs.ErrReadTimeout))
+			Expect(summary.Recent[1].Connection).To(Equal(uint64(1)))
+			Expect(summary.Counts).To(Equal(map[string]int{errs.ErrReadTimeout.Error(): 2}))
+		})
+	})
+}
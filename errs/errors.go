@@ -1,8 +1,125 @@
 package errs
 
-import "github.com/pkg/errors"
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+	"strings"
+)
 
 var ErrNotConnected = errors.New("not connected")
 var ErrAuthentication = errors.New("authentication failed")
 var ErrQueueTimeout = errors.New("queue timeout")
 var ErrReadTimeout = errors.New("read timeout")
+var ErrNoOfflineQueue = errors.New("no offline queue configured")
+var ErrPayloadTooLarge = errors.New("payload too large")
+var ErrResponseTooLarge = errors.New("response too large")
+var ErrConnectInProgress = errors.New("connect already in progress")
+var ErrCircuitOpen = errors.New("reconnect circuit open")
+var ErrCommandRedacted = errors.New("command was redacted")
+var ErrUnsignedPacket = errors.New("packet is not signed")
+var ErrBadSignature = errors.New("packet signature is invalid")
+var ErrHeartbeatFailed = errors.New("heartbeat failed")
+var ErrBroadcastInactive = errors.New("no broadcasts received")
+var ErrUnsupportedTransport = errors.New("unsupported transport")
+var ErrPaused = errors.New("client is paused")
+var ErrDraining = errors.New("client is draining")
+var ErrSharedClientConflict = errors.New("shared client config conflict")
+var ErrServerExists = errors.New("server already exists")
+var ErrServerNotFound = errors.New("server not found")
+var ErrInvalidSelector = errors.New("invalid tag selector")
+var ErrUnresolvedSecret = errors.New("secret reference could not be resolved")
+var ErrMissingListener = errors.New("no listener for subscription")
+var ErrPageLimit = errors.New("page limit reached before the last page")
+var ErrNotJSON = errors.New("message is not JSON")
+var ErrUnknownMacro = errors.New("unknown macro")
+var ErrInvalidMacro = errors.New("invalid macro")
+var ErrInjectedFault = errors.New("injected fault")
+var ErrSessionClosed = errors.New("raw session closed")
+var ErrInvalidPattern = errors.New("invalid broadcast pattern")
+var ErrUnknownCvar = errors.New("unknown cvar")
+var ErrInvalidCvar = errors.New("invalid cvar name or value")
+var ErrGroupExists = errors.New("endpoint group already exists")
+var ErrGroupNotFound = errors.New("endpoint group not found")
+var ErrNoHealthyEndpoint = errors.New("no healthy endpoint")
+var ErrInvalidTemplate = errors.New("invalid command template")
+var ErrSayPrefixTooLong = errors.New("say prefix leaves no room for the message")
+
+// AttemptsError is returned when an operation failed after multiple attempts. It holds the error of each attempt.
+type AttemptsError struct {
+	Errors []error
+}
+
+func (e *AttemptsError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = fmt.Sprintf("attempt %d: %v", i+1, err)
+	}
+
+	return fmt.Sprintf("all %d attempts failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Cause returns the error of the last attempt, so that errors.Cause can be used to inspect it.
+func (e *AttemptsError) Cause() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+
+	return errors.Cause(e.Errors[len(e.Errors)-1])
+}
+
+func (e *AttemptsError) Unwrap() error {
+	return e.Cause()
+}
+
+// FieldError is returned when a config field is invalid.
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("invalid config field %s: %s", e.Field, e.Reason)
+}
+
+// MismatchError is returned when the response to a command didn't match what was expected.
+type MismatchError struct {
+	Command  string
+	Pattern  string
+	Response string
+
+	// Negated is true if the response was expected not to match Pattern.
+	Negated bool
+}
+
+func (e *MismatchError) Error() string {
+	if e.Negated {
+		return fmt.Sprintf("response to %q matched %q but was expected not to: %q", e.Command, e.Pattern, e.Response)
+	}
+
+	return fmt.Sprintf("response to %q did not match %q: %q", e.Command, e.Pattern, e.Response)
+}
+
+// PartialFailureError is returned when a command executed on many servers failed on some of them. It holds the error of
+// each failed server, keyed by server name.
+type PartialFailureError struct {
+	Errors map[string]error
+
+	// Total is the number of servers the command was executed on.
+	Total int
+}
+
+func (e *PartialFailureError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("%s: %v", name, e.Errors[name])
+	}
+
+	return fmt.Sprintf("failed on %d of %d servers: %s", len(e.Errors), e.Total, strings.Join(msgs, "; "))
+}
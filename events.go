@@ -0,0 +1,252 @@
+package rcon
+
+import (
+	"github.com/refractorgscm/rcon/packet"
+	"sync"
+	"time"
+)
+
+// EventType identifies the type of an Event.
+type EventType string
+
+const (
+	EventConnected          = EventType("connected")
+	EventAuthFailed         = EventType("auth_failed")
+	EventDisconnected       = EventType("disconnected")
+	EventReconnectScheduled = EventType("reconnect_scheduled")
+	EventReconnectAttempt   = EventType("reconnect_attempt")
+	EventReconnectFailed    = EventType("reconnect_failed")
+	EventHeartbeatMissed    = EventType("heartbeat_missed")
+	EventBroadcastDropped   = EventType("broadcast_dropped")
+	EventCommandFailed      = EventType("command_failed")
+	EventPlayerJoined       = EventType("player_joined")
+	EventPlayerLeft         = EventType("player_left")
+	EventChatMessage        = EventType("chat_message")
+	EventBroadcastInactive  = EventType("broadcast_inactive")
+	EventFingerprintChanged = EventType("fingerprint_changed")
+	EventProtocolViolation  = EventType("protocol_violation")
+)
+
+// Event is an event emitted by a client's EventBus. Use a type switch to access the fields of a specific event.
+type Event interface {
+	Type() EventType
+}
+
+// ConnectedEvent is emitted when the client has connected and authenticated.
+type ConnectedEvent struct {
+	Address string
+}
+
+// AuthFailedEvent is emitted when the server rejected the password.
+type AuthFailedEvent struct {
+	Address string
+	Err     error
+}
+
+// DisconnectedEvent is emitted when the client disconnects. Err is nil if the disconnect was expected.
+type DisconnectedEvent struct {
+	Address  string
+	Err      error
+	Expected bool
+}
+
+// ReconnectScheduledEvent is emitted when the client schedules an automatic reconnect attempt. The attempt is made at
+// At, after Delay has passed. MaxAttempts is zero if the client never gives up, and LastErr is the error which caused
+// the disconnect or made the previous attempt fail.
+type ReconnectScheduledEvent struct {
+	Attempt     int
+	MaxAttempts int
+	Delay       time.Duration
+	At          time.Time
+	LastErr     error
+}
+
+// ReconnectAttemptEvent is emitted when the client starts an automatic reconnect attempt.
+type ReconnectAttemptEvent struct {
+	Attempt     int
+	MaxAttempts int
+}
+
+// ReconnectFailedEvent is emitted when an automatic reconnect attempt failed. GaveUp is true if it was the last
+// attempt allowed by the reconnect policy.
+type ReconnectFailedEvent struct {
+	Attempt     int
+	MaxAttempts int
+	Err         error
+	GaveUp      bool
+}
+
+// HeartbeatMissedEvent is emitted when the server didn't respond to a heartbeat in time.
+type HeartbeatMissedEvent struct {
+	// Missed is the number of consecutive missed heartbeats.
+	Missed int
+	Err    error
+}
+
+// BroadcastDroppedEvent is emitted when a broadcast was dropped by the BroadcastFilter or a runtime filter pattern.
+type BroadcastDroppedEvent struct {
+	Message string
+}
+
+// CommandFailedEvent is emitted when a command failed. Command is redacted using the CommandRedactor.
+type CommandFailedEvent struct {
+	Command  string
+	Metadata CallMetadata
+	Err      error
+}
+
+// PlayerJoinedEvent is emitted when a BroadcastParser recognised a player joining. Time is the local time the broadcast
+// was received at.
+type PlayerJoinedEvent struct {
+	ID   string
+	Name string
+	Time time.Time
+}
+
+// PlayerLeftEvent is emitted when a BroadcastParser recognised a player leaving. Time is the local time the broadcast
+// was received at.
+type PlayerLeftEvent struct {
+	ID   string
+	Name string
+	Time time.Time
+}
+
+// ChatMessageEvent is emitted when a BroadcastParser recognised an in-game chat message. Time is the local time the
+// broadcast was received at.
+type ChatMessageEvent struct {
+	PlayerID string
+	Name     string
+	Message  string
+	Time     time.Time
+}
+
+func (ConnectedEvent) Type() EventType          { return EventConnected }
+func (AuthFailedEvent) Type() EventType         { return EventAuthFailed }
+func (DisconnectedEvent) Type() EventType       { return EventDisconnected }
+func (ReconnectScheduledEvent) Type() EventType { return EventReconnectScheduled }
+func (ReconnectAttemptEvent) Type() EventType   { return EventReconnectAttempt }
+func (ReconnectFailedEvent) Type() EventType    { return EventReconnectFailed }
+func (HeartbeatMissedEvent) Type() EventType    { return EventHeartbeatMissed }
+func (BroadcastDroppedEvent) Type() EventType   { return EventBroadcastDropped }
+func (CommandFailedEvent) Type() EventType      { return EventCommandFailed }
+func (PlayerJoinedEvent) Type() EventType       { return EventPlayerJoined }
+func (PlayerLeftEvent) Type() EventType         { return EventPlayerLeft }
+func (ChatMessageEvent) Type() EventType        { return EventChatMessage }
+func (BroadcastInactiveEvent) Type() EventType  { return EventBroadcastInactive }
+func (FingerprintChangedEvent) Type() EventType { return EventFingerprintChanged }
+func (ProtocolViolationEvent) Type() EventType  { return EventProtocolViolation }
+
+// InactivityAction is the action taken by the broadcast watchdog when no broadcasts were received for a while.
+type InactivityAction string
+
+const (
+	// InactivityResubscribe means the SubscribeCommands are executed again.
+	InactivityResubscribe = InactivityAction("resubscribe")
+
+	// InactivityReconnect means the client disconnects, so that it can reconnect.
+	InactivityReconnect = InactivityAction("reconnect")
+)
+
+// BroadcastInactiveEvent is emitted when no broadcast was received for BroadcastInactivityTimeout. Idle is the time
+// since the last broadcast, or since the watchdog started if none was received.
+type BroadcastInactiveEvent struct {
+	Idle   time.Duration
+	Action InactivityAction
+}
+
+// FingerprintChangedEvent is emitted when the fingerprint of the server differs from the one seen on the previous
+// connection, which indicates a server update or migration.
+type FingerprintChangedEvent struct {
+	Previous ServerFingerprint
+	Current  ServerFingerprint
+}
+
+// ProtocolViolationEvent is emitted in StrictProtocol mode when the server sent a packet which violates the protocol
+// spec. The packet is dropped. For violations found after decoding, such as unexpected types, Violation.Data holds the
+// packet encoded again rather than the bytes which were read.
+type ProtocolViolationEvent struct {
+	Violation *packet.Violation
+}
+
+// EventHandler is a function which is called with events emitted by an EventBus.
+type EventHandler func(event Event)
+
+// EventBus delivers client events to any number of subscribers. Handlers are called synchronously in the order they
+// subscribed, so they should return quickly.
+type EventBus struct {
+	lock   sync.RWMutex
+	nextID int
+	subs   []subscription
+
+	// callHandler calls handlers with panic recovery. It is set by the client owning the bus.
+	callHandler func(source string, fn func())
+}
+
+type subscription struct {
+	id      int
+	types   map[EventType]bool
+	handler EventHandler
+}
+
+// Subscribe registers a handler for events of the provided types. If no types are provided, the handler receives all
+// events. The returned function removes the subscription.
+func (b *EventBus) Subscribe(handler EventHandler, types ...EventType) (unsubscribe func()) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	sub := subscription{
+		id:      b.nextID,
+		handler: handler,
+	}
+	b.nextID++
+
+	if len(types) > 0 {
+		sub.types = map[EventType]bool{}
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.subs = append(b.subs, sub)
+
+	return func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+
+		for i, s := range b.subs {
+			if s.id == sub.id {
+				b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// emit delivers an event to all subscribers interested in it.
+func (b *EventBus) emit(event Event) {
+	b.lock.RLock()
+	var handlers []EventHandler
+	for _, sub := range b.subs {
+		if sub.types == nil || sub.types[event.Type()] {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	b.lock.RUnlock()
+
+	for _, handler := range handlers {
+		if b.callHandler == nil {
+			handler(event)
+			continue
+		}
+
+		b.callHandler("event handler", func() {
+			handler(event)
+		})
+	}
+}
+
+// Events returns the client's event bus, which can be used to subscribe to connection, reconnect, broadcast and
+// command events.
+func (c *Client) Events() *EventBus {
+	return &c.events
+}
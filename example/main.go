@@ -48,5 +48,5 @@ func main() {
 		_ = client.Close()
 	}()
 
-	client.WaitGroup().Wait()
+	<-client.Done()
 }
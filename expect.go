@@ -0,0 +1,36 @@
+package rcon
+
+import (
+	"github.com/refractorgscm/rcon/errs"
+	"regexp"
+)
+
+// ExecExpect executes a command and checks that the response matches expect. If it doesn't, an *errs.MismatchError
+// holding the actual response is returned.
+func (c *Client) ExecExpect(command string, expect *regexp.Regexp, opts ...ExecOption) error {
+	return c.execExpect(command, expect, false, opts)
+}
+
+// ExecExpectNot executes a command and checks that the response doesn't match expect. If it does, an
+// *errs.MismatchError holding the actual response is returned.
+func (c *Client) ExecExpectNot(command string, expect *regexp.Regexp, opts ...ExecOption) error {
+	return c.execExpect(command, expect, true, opts)
+}
+
+func (c *Client) execExpect(command string, expect *regexp.Regexp, negate bool, opts []ExecOption) error {
+	res, err := c.ExecCommand(command, opts...)
+	if err != nil {
+		return err
+	}
+
+	if expect.MatchString(res) == negate {
+		return &errs.MismatchError{
+			Command:  command,
+			Pattern:  expect.String(),
+			Response: res,
+			Negated:  negate,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"regexp"
+	"testing"
+)
+
+func TestExpect(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("ExecExpect", func() {
+		g.It("Should succeed if the response matches", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(c.ExecExpect("kick bob", regexp.MustCompile(`^echo: kick \w+$`))).To(Succeed())
+			Expect(c.ExecExpectNot("kick bob", regexp.MustCompile(`not found`))).To(Succeed())
+		})
+
+		g.It("Should return the response if it doesn't match", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			err := c.ExecExpect("kick bob", regexp.MustCompile(`^kicked`))
+			Expect(err).To(Equal(&errs.MismatchError{
+				Command:  "kick bob",
+				Pattern:  "^kicked",
+				Response: "echo: kick bob",
+			}))
+
+			err = c.ExecExpectNot("kick bob", regexp.MustCompile(`bob`))
+			Expect(err).To(Equal(&errs.MismatchError{
+				Command:  "kick bob",
+				Pattern:  "bob",
+				Response: "echo: kick bob",
+				Negated:  true,
+			}))
+		})
+
+		g.It("Should return the error of the command", func() {
+			c := NewClient(&Config{}, nil)
+
+			err := c.ExecExpect("kick bob", regexp.MustCompile(`.*`))
+			Expect(errors.Cause(err)).To(Equal(errs.ErrNotConnected))
+
+			err = c.ExecExpectNot("kick bob", regexp.MustCompile(`.*`))
+			Expect(errors.Cause(err)).To(Equal(errs.ErrNotConnected))
+		})
+	})
+}
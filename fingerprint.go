@@ -0,0 +1,97 @@
+package rcon
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ServerFingerprint identifies the software a server is running. Fields which the server didn't report are empty.
+type ServerFingerprint struct {
+	Game       string
+	Version    string
+	Map        string
+	MaxPlayers int
+}
+
+// FingerprintParser parses the response to a dialect's FingerprintCommand.
+type FingerprintParser func(response string) (ServerFingerprint, error)
+
+var (
+	sourceStatusField   = regexp.MustCompile(`(?m)^(\w+)\s*:\s*(.*?)\s*$`)
+	sourceStatusPlayers = regexp.MustCompile(`\((\d+)(?:/\d+)? max\)`)
+)
+
+// ParseSourceStatus parses the response to the "status" command of Source engine servers. The game can't be told from
+// the response, so it is left empty.
+func ParseSourceStatus(response string) (ServerFingerprint, error) {
+	var fp ServerFingerprint
+
+	for _, m := range sourceStatusField.FindAllStringSubmatch(response, -1) {
+		switch m[1] {
+		case "version":
+			// The version is followed by the build number and flags, e.g. "1.39.7.5/13975 9842 secure".
+			fp.Version = strings.Fields(m[2] + " ")[0]
+		case "map":
+			fp.Map = strings.Fields(m[2] + " ")[0]
+		case "players":
+			if pm := sourceStatusPlayers.FindStringSubmatch(m[2]); pm != nil {
+				fp.MaxPlayers, _ = strconv.Atoi(pm[1])
+			}
+		}
+	}
+
+	return fp, nil
+}
+
+// Fingerprint returns the fingerprint of the server, and false if it hasn't been determined. It is only determined if
+// DetectFingerprint is set and the dialect has a FingerprintCommand.
+func (c *Client) Fingerprint() (ServerFingerprint, bool) {
+	c.fingerprintLock.Lock()
+	defer c.fingerprintLock.Unlock()
+
+	return c.fingerprint, c.fingerprintKnown
+}
+
+// detectFingerprint executes the dialect's FingerprintCommand and records the parsed fingerprint. If it differs from
+// the fingerprint of a previous connection, a FingerprintChangedEvent is emitted.
+func (c *Client) detectFingerprint(ctx context.Context) error {
+	d := c.Dialect()
+	if d.FingerprintCommand == "" || d.ParseFingerprint == nil {
+		return nil
+	}
+
+	res, err := c.execCommandTraced(ctx, d.FingerprintCommand, PriorityHigh, nil)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.log.Error("Could not fingerprint server. Error: ", err)
+		}
+		return nil
+	}
+
+	fp, err := d.ParseFingerprint(res)
+	if err != nil {
+		c.log.Error("Could not parse server fingerprint. Error: ", err)
+		c.stats.recordError(err)
+		return nil
+	}
+
+	if fp.Game == "" {
+		fp.Game = d.Name
+	}
+
+	c.fingerprintLock.Lock()
+	previous, known := c.fingerprint, c.fingerprintKnown
+	c.fingerprint, c.fingerprintKnown = fp, true
+	c.fingerprintLock.Unlock()
+
+	c.log.Debug("Server fingerprint: ", fp)
+
+	if known && previous != fp {
+		c.log.Info("Server fingerprint changed from ", previous, " to ", fp)
+		c.events.emit(FingerprintChangedEvent{Previous: previous, Current: fp})
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("ParseSourceStatus", func() {
+		g.It("Should parse the version, map and player limit", func() {
+			fp, err := ParseSourceStatus("hostname: Test\nversion : 1.39.7.5/13975 9842 secure  public\n" +
+				"map     : de_dust2\nplayers : 0 humans, 0 bots (20/0 max) (hibernating)\n")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fp).To(Equal(ServerFingerprint{Version: "1.39.7.5/13975", Map: "de_dust2", MaxPlayers: 20}))
+		})
+	})
+}
@@ -3,7 +3,9 @@ module github.com/refractorgscm/rcon
 go 1.15
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/franela/goblin v0.0.0-20211003143422-0a4f594942bf
 	github.com/onsi/gomega v1.16.0
 	github.com/pkg/errors v0.9.1
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,75 @@
+package rcon
+
+import (
+	"context"
+	"sync"
+)
+
+// routineGroup runs the routines belonging to a single connection. It works like errgroup.Group: the first routine to
+// return an error cancels the group's context, which stops its siblings, and Wait blocks until every routine returned.
+//
+// Each connection gets its own group, so routines belonging to a previous connection can never be confused with those
+// of a new one.
+type routineGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+
+	// legacy is the client-wide WaitGroup returned by Client.WaitGroup, which is kept up to date for compatibility.
+	legacy *sync.WaitGroup
+}
+
+func newRoutineGroup(legacy *sync.WaitGroup) *routineGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &routineGroup{
+		ctx:    ctx,
+		cancel: cancel,
+		legacy: legacy,
+	}
+}
+
+// Go runs fn on a new goroutine. If fn returns an error, the group is cancelled with it.
+func (g *routineGroup) Go(fn func(ctx context.Context) error) {
+	go g.wrap(fn)()
+}
+
+// wrap adds fn to the group and returns a function running it, for routines which are run on a goroutine not started
+// by the group. The returned function must be called exactly once, or Wait blocks forever.
+func (g *routineGroup) wrap(fn func(ctx context.Context) error) func() {
+	g.wg.Add(1)
+	g.legacy.Add(1)
+
+	return func() {
+		defer g.legacy.Done()
+		defer g.wg.Done()
+
+		if err := fn(g.ctx); err != nil {
+			g.stop(err)
+		}
+	}
+}
+
+// stop cancels the group. If err is not nil and the group wasn't cancelled before, err is recorded as the cause.
+func (g *routineGroup) stop(err error) {
+	g.errOnce.Do(func() {
+		g.err = err
+	})
+
+	g.cancel()
+}
+
+// Err returns the error the group was cancelled with, or nil if it was stopped without an error or is still running.
+func (g *routineGroup) Err() error {
+	<-g.ctx.Done()
+
+	return g.err
+}
+
+// Wait blocks until every routine in the group returned.
+func (g *routineGroup) Wait() {
+	g.wg.Wait()
+}
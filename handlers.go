@@ -0,0 +1,68 @@
+package rcon
+
+import "sync"
+
+// DefaultBroadcastBufferSize is the default BroadcastBufferSize.
+const DefaultBroadcastBufferSize = 256
+
+// NopBroadcastHandler discards broadcasts. Setting it as the BroadcastHandler stops broadcasts from being buffered
+// when nothing else consumes them.
+func NopBroadcastHandler(string) {}
+
+// NopDisconnectHandler ignores disconnects. It is equivalent to a nil DisconnectHandler, since disconnects are always
+// emitted on the event bus as DisconnectedEvent.
+func NopDisconnectHandler(error, bool) {}
+
+// ChannelBroadcastHandler returns a BroadcastHandler which sends broadcasts to ch. Since handlers run on the reader
+// routine, broadcasts are dropped instead of blocking if ch is full, so ch should be buffered.
+func ChannelBroadcastHandler(ch chan<- string) BroadcastHandler {
+	return func(message string) {
+		select {
+		case ch <- message:
+		default:
+		}
+	}
+}
+
+// broadcastBuffer keeps the most recent broadcasts which were received while nothing consumed them.
+type broadcastBuffer struct {
+	lock     sync.Mutex
+	messages []string
+	size     int
+}
+
+func (b *broadcastBuffer) add(message string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.size <= 0 {
+		return
+	}
+
+	if len(b.messages) >= b.size {
+		b.messages = b.messages[1:]
+	}
+
+	b.messages = append(b.messages, message)
+}
+
+func (b *broadcastBuffer) drain() []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	messages := b.messages
+	b.messages = nil
+
+	return messages
+}
+
+// hasBroadcastConsumer returns true if a BroadcastHandler, sink or listener receives broadcasts.
+func (c *Client) hasBroadcastConsumer() bool {
+	return c.BroadcastHandler != nil || len(c.BroadcastSinks) > 0 || len(c.BroadcastListeners) > 0
+}
+
+// DrainBroadcasts returns and clears the broadcasts received while no BroadcastHandler, sink or listener was set, oldest
+// first. At most BroadcastBufferSize broadcasts are kept, older ones are dropped.
+func (c *Client) DrainBroadcasts() []string {
+	return c.broadcastBuffer.drain()
+}
@@ -0,0 +1,33 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func TestHandlers(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Broadcast buffer", func() {
+		g.It("Should buffer broadcasts only while nothing consumes them", func() {
+			c := NewClient(&Config{BroadcastBufferSize: 2}, nil)
+
+			c.handleBroadcast("one")
+			c.handleBroadcast("two")
+			c.handleBroadcast("three")
+			Expect(c.DrainBroadcasts()).To(Equal([]string{"two", "three"}))
+			Expect(c.DrainBroadcasts()).To(BeEmpty())
+
+			ch := make(chan string, 1)
+			c.SetBroadcastHandler(ChannelBroadcastHandler(ch))
+			c.handleBroadcast("four")
+			c.handleBroadcast("five")
+			Expect(ch).To(Receive(Equal("four")))
+			Expect(c.DrainBroadcasts()).To(BeEmpty())
+		})
+	})
+}
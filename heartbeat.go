@@ -0,0 +1,81 @@
+package rcon
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"github.com/refractorgscm/rcon/packet"
+	"time"
+)
+
+// DefaultHeartbeatFailureThreshold is the number of consecutive missed heartbeats after which the client disconnects,
+// if HeartbeatFailureThreshold isn't set.
+const DefaultHeartbeatFailureThreshold = 3
+
+// runHeartbeat sends heartbeats until the connection is torn down. It returns an error if too many consecutive heartbeats
+// were missed, which tears down the connection.
+func (c *Client) runHeartbeat(ctx context.Context) error {
+	defer c.log.Debug("Heartbeat routine terminated")
+
+	ticker := time.NewTicker(c.HeartbeatInterval)
+	defer ticker.Stop()
+
+	missed := 0
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			c.log.Debug("Heartbeat routine received termination signal")
+			return nil
+		}
+
+		if c.Paused() {
+			missed = 0
+			continue
+		}
+
+		if err := c.heartbeat(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			missed++
+			c.log.Error("Heartbeat missed (", missed, "/", c.HeartbeatFailureThreshold, "). Error: ", err)
+			c.events.emit(HeartbeatMissedEvent{Missed: missed, Err: err})
+
+			// A single hiccup shouldn't trigger a full reconnect cycle, so we only give up on the connection after
+			// several consecutive failures.
+			if missed >= c.HeartbeatFailureThreshold {
+				return errors.Wrapf(errs.ErrHeartbeatFailed, "%d consecutive heartbeats missed", missed)
+			}
+
+			continue
+		}
+
+		missed = 0
+	}
+}
+
+// isHeartbeatResponse returns true if p is the response to a heartbeat and heartbeat responses are kept out of the
+// broadcast path. Heartbeats are always sent with KeepalivePacketID, which is never allocated to other packets.
+func (c *Client) isHeartbeatResponse(p packet.Packet) bool {
+	return !c.BroadcastHeartbeatResponses && c.HeartbeatInterval > 0 && p.ID() == packet.KeepalivePacketID &&
+		p.Type() == packet.TypeCommandRes
+}
+
+// heartbeat sends a single heartbeat and waits for its response. It returns early if ctx is cancelled.
+func (c *Client) heartbeat(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.HeartbeatTimeout)
+	defer cancel()
+
+	p := c.newClientPacketWithID(packet.TypeCommand, c.HeartbeatCommand, packet.KeepalivePacketID)
+
+	if err := c.enqueuePacket(p, PriorityHigh, true); err != nil {
+		return err
+	}
+
+	_, err := c.getResponse(ctx, p.ID())
+
+	return err
+}
@@ -0,0 +1,126 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/endian"
+	"github.com/refractorgscm/rcon/errs"
+	"github.com/refractorgscm/rcon/packet"
+	"github.com/refractorgscm/rcon/rcontest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Heartbeat responses", func() {
+		g.It("Should keep heartbeat responses out of the broadcast path", func() {
+			alive := packet.NewClientPacketWithID(endian.Little, packet.TypeCommandRes, "Alive", packet.KeepalivePacketID)
+			reply := packet.NewClientPacketWithID(endian.Little, packet.TypeCommandRes, "Alive", 3)
+
+			c := NewClient(&Config{HeartbeatInterval: time.Second}, nil)
+			Expect(c.isHeartbeatResponse(alive)).To(BeTrue())
+			Expect(c.isHeartbeatResponse(reply)).To(BeFalse())
+
+			c = NewClient(&Config{HeartbeatInterval: time.Second, BroadcastHeartbeatResponses: true}, nil)
+			Expect(c.isHeartbeatResponse(alive)).To(BeFalse())
+		})
+	})
+	g.Describe("Heartbeats", func() {
+		// newServer starts a server which holds back its response to the first slow heartbeats for delay, and answers
+		// all other commands right away. The server can't answer anything while a heartbeat is held back, like a
+		// server which hangs. Closing release answers held back heartbeats.
+		newServer := func(slow int, delay time.Duration, release chan struct{}) *rcontest.Server {
+			var lock sync.Mutex
+			heartbeats := 0
+
+			return newTestServer(func(command string) string {
+				if command != "alive" {
+					return "echo: " + command
+				}
+
+				lock.Lock()
+				heartbeats++
+				hold := heartbeats <= slow
+				lock.Unlock()
+
+				if hold {
+					select {
+					case <-time.After(delay):
+					case <-release:
+					}
+				}
+
+				return "alive"
+			})
+		}
+
+		newClient := func(s *rcontest.Server) *Client {
+			return newTestClient(s, &Config{
+				HeartbeatInterval:         time.Millisecond * 20,
+				HeartbeatTimeout:          time.Millisecond * 40,
+				HeartbeatFailureThreshold: 3,
+				HeartbeatCommand:          "alive",
+			})
+		}
+
+		g.It("Should keep healthy connections open", func() {
+			s := newServer(0, 0, nil)
+			defer s.Close()
+
+			c := newClient(s)
+			events := recordEvents(c, EventHeartbeatMissed)
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Consistently(c.State, time.Millisecond*200).Should(Equal(StateConnected))
+			Expect(events()).To(BeEmpty())
+		})
+
+		g.It("Should tolerate fewer missed heartbeats than the threshold", func() {
+			// The late response to the held back heartbeat is taken as the response to the next one, since the server
+			// is alive after all.
+			s := newServer(1, time.Millisecond*70, nil)
+			defer s.Close()
+
+			c := newClient(s)
+			events := recordEvents(c, EventHeartbeatMissed)
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Eventually(events).ShouldNot(BeEmpty())
+			Consistently(c.State, time.Millisecond*300).Should(Equal(StateConnected))
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+
+			for _, e := range events() {
+				Expect(e.(HeartbeatMissedEvent).Missed).To(BeNumerically("<", 3))
+			}
+		})
+
+		g.It("Should disconnect once the threshold is reached", func() {
+			release := make(chan struct{})
+			s := newServer(1, time.Minute, release)
+			defer s.Close()
+			defer close(release)
+
+			c := newClient(s)
+			events := recordEvents(c, EventHeartbeatMissed)
+			Expect(c.Connect()).To(Succeed())
+
+			Eventually(c.Done(), time.Second*2).Should(BeClosed())
+			Expect(errors.Cause(c.Err())).To(Equal(errs.ErrHeartbeatFailed))
+
+			missed := events()
+			Expect(missed).To(HaveLen(3))
+			for i, e := range missed {
+				Expect(e.(HeartbeatMissedEvent).Missed).To(Equal(i + 1))
+			}
+		})
+	})
+}
@@ -0,0 +1,147 @@
+package rcon
+
+import (
+	"context"
+	"github.com/refractorgscm/rcon/errs"
+	"sync"
+	"time"
+)
+
+// HistoryEntry is a command which was executed by the client along with its response.
+type HistoryEntry struct {
+	Command  string
+	Response string
+	Err      error
+	Priority Priority
+	Metadata CallMetadata
+	Time     time.Time
+	Duration time.Duration
+
+	// Redacted is true if the HistoryRedactor changed the command. Redacted commands can't be replayed.
+	Redacted bool
+
+	// DryRun is true if the command was executed in dry-run mode, and never sent to the server.
+	DryRun bool
+
+	// ArrivalSeq is the arrival sequence number of the response if ArrivalSequence is set. Broadcasts with a lower
+	// Broadcast.ArrivalSeq arrived before the response, and those with a higher one after it. It is zero if no
+	// response was received.
+	ArrivalSeq uint64
+}
+
+// HistoryRedactor is a function which takes a history entry and returns a version of it which is safe to keep in the
+// command history.
+type HistoryRedactor func(entry HistoryEntry) HistoryEntry
+
+// commandHistory is a fixed size ring of the most recently executed commands.
+type commandHistory struct {
+	sync.Mutex
+	entries []HistoryEntry
+	next    int
+	full    bool
+}
+
+func (h *commandHistory) add(size int, entry HistoryEntry) {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.entries == nil {
+		h.entries = make([]HistoryEntry, size)
+	}
+
+	h.entries[h.next] = entry
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list returns the entries in the order they were added.
+func (h *commandHistory) list() []HistoryEntry {
+	h.Lock()
+	defer h.Unlock()
+
+	if !h.full {
+		return append([]HistoryEntry{}, h.entries[:h.next]...)
+	}
+
+	return append(append([]HistoryEntry{}, h.entries[h.next:]...), h.entries[:h.next]...)
+}
+
+// recordHistory redacts an executed command and adds it to the command history and the AuditSinks.
+func (c *Client) recordHistory(entry HistoryEntry) {
+	if c.HistorySize <= 0 && len(c.AuditSinks) == 0 {
+		return
+	}
+
+	if c.HistoryRedactor != nil {
+		command := entry.Command
+		entry = c.HistoryRedactor(entry)
+		entry.Redacted = entry.Redacted || entry.Command != command
+	}
+
+	c.recordAudit(entry)
+
+	if c.HistorySize > 0 {
+		c.history.add(c.HistorySize, entry)
+	}
+}
+
+// History returns the most recently executed commands, oldest first. At most HistorySize commands are kept. If
+// HistorySize is zero, History always returns an empty slice.
+func (c *Client) History() []HistoryEntry {
+	return c.history.list()
+}
+
+// ReplayLast executes the last n commands in the history again, in the order they were originally executed and with
+// their original priority, metadata and dry-run mode. The new history entries are returned. Replayed commands are added
+// to the history.
+//
+// If n is larger than the history, the whole history is replayed. If n is zero or negative, nothing is replayed. If any
+// of the commands was redacted, errs.ErrCommandRedacted is returned and no commands are executed. If a command fails,
+// the entries executed so far are returned along with the error.
+func (c *Client) ReplayLast(n int) ([]HistoryEntry, error) {
+	history := c.History()
+	if n > len(history) {
+		n = len(history)
+	}
+
+	if n < 0 {
+		n = 0
+	}
+
+	toReplay := history[len(history)-n:]
+	for _, entry := range toReplay {
+		if entry.Redacted {
+			return nil, errs.ErrCommandRedacted
+		}
+	}
+
+	replayed := make([]HistoryEntry, 0, n)
+	for _, entry := range toReplay {
+		start := time.Now()
+		// Commands which were executed in dry-run mode are replayed in dry-run mode too.
+		opts := entry.Metadata.options()
+		if entry.DryRun {
+			opts = append(opts, WithDryRun(true))
+		}
+
+		res, err := c.execCommandTraced(context.Background(), entry.Command, entry.Priority, opts)
+
+		replayed = append(replayed, HistoryEntry{
+			Command:  entry.Command,
+			Response: res,
+			Err:      err,
+			Priority: entry.Priority,
+			Metadata: entry.Metadata,
+			Time:     start,
+			Duration: time.Since(start),
+			DryRun:   entry.DryRun || c.DryRun,
+		})
+		if err != nil {
+			return replayed, err
+		}
+	}
+
+	return replayed, nil
+}
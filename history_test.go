@@ -0,0 +1,166 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/errs"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	commands := func(entries []HistoryEntry) []string {
+		var commands []string
+		for _, e := range entries {
+			commands = append(commands, e.Command)
+		}
+
+		return commands
+	}
+
+	g.Describe("History", func() {
+		g.It("Should keep the most recent commands, oldest first", func() {
+			c := NewClient(&Config{DryRun: true, HistorySize: 3}, nil)
+			Expect(c.History()).To(BeEmpty())
+
+			for i := 1; i <= 5; i++ {
+				_, err := c.ExecCommand("command " + strconv.Itoa(i))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			Expect(commands(c.History())).To(Equal([]string{"command 3", "command 4", "command 5"}))
+		})
+
+		g.It("Should not keep commands if HistorySize is zero", func() {
+			c := NewClient(&Config{DryRun: true}, nil)
+			_, err := c.ExecCommand("status")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.History()).To(BeEmpty())
+		})
+
+		g.It("Should redact commands", func() {
+			c := NewClient(&Config{
+				DryRun:      true,
+				HistorySize: 10,
+				HistoryRedactor: func(entry HistoryEntry) HistoryEntry {
+					if strings.HasPrefix(entry.Command, "rcon_password ") {
+						entry.Command = "rcon_password ***"
+					}
+
+					return entry
+				},
+			}, nil)
+
+			_, err := c.ExecCommand("status")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = c.ExecCommand("rcon_password hunter2")
+			Expect(err).ToNot(HaveOccurred())
+
+			history := c.History()
+			Expect(commands(history)).To(Equal([]string{"status", "rcon_password ***"}))
+			Expect(history[0].Redacted).To(BeFalse())
+			Expect(history[1].Redacted).To(BeTrue())
+		})
+	})
+
+	g.Describe("ReplayLast", func() {
+		var lock sync.Mutex
+		var executed []string
+
+		newClient := func(config *Config) (*Client, func()) {
+			lock.Lock()
+			executed = nil
+			lock.Unlock()
+
+			s := newTestServer(func(command string) string {
+				lock.Lock()
+				executed = append(executed, command)
+				lock.Unlock()
+
+				return "echo: " + command
+			})
+
+			config.HistorySize = 10
+			c := newTestClient(s, config)
+			Expect(c.Connect()).To(Succeed())
+
+			return c, func() {
+				_ = c.Close()
+				_ = s.Close()
+			}
+		}
+
+		g.It("Should execute the last commands again in order", func() {
+			c, cleanup := newClient(&Config{})
+			defer cleanup()
+
+			for _, command := range []string{"first", "second", "third"} {
+				_, err := c.ExecCommandPriority(command, PriorityHigh, WithInitiator("admin:1"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			replayed, err := c.ReplayLast(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(commands(replayed)).To(Equal([]string{"second", "third"}))
+			Expect(replayed[0].Response).To(Equal("echo: second"))
+			Expect(replayed[0].Priority).To(Equal(PriorityHigh))
+			Expect(replayed[0].Metadata.Initiator).To(Equal("admin:1"))
+			Expect(replayed[0].Time).To(BeTemporally("~", time.Now(), time.Second))
+
+			lock.Lock()
+			Expect(executed).To(Equal([]string{"first", "second", "third", "second", "third"}))
+			lock.Unlock()
+			Expect(c.History()).To(HaveLen(5))
+		})
+
+		g.It("Should clamp the number of commands to the history", func() {
+			c, cleanup := newClient(&Config{})
+			defer cleanup()
+
+			_, err := c.ExecCommand("status")
+			Expect(err).ToNot(HaveOccurred())
+
+			replayed, err := c.ReplayLast(5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(commands(replayed)).To(Equal([]string{"status"}))
+
+			for _, n := range []int{0, -1} {
+				replayed, err = c.ReplayLast(n)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(replayed).To(BeEmpty())
+			}
+
+			Expect(c.History()).To(HaveLen(2))
+		})
+
+		g.It("Should refuse to replay redacted commands", func() {
+			c, cleanup := newClient(&Config{
+				HistoryRedactor: func(entry HistoryEntry) HistoryEntry {
+					entry.Command = strings.Replace(entry.Command, "hunter2", "***", -1)
+					return entry
+				},
+			})
+			defer cleanup()
+
+			_, err := c.ExecCommand("rcon_password hunter2")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = c.ExecCommand("status")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = c.ReplayLast(2)
+			Expect(err).To(Equal(errs.ErrCommandRedacted))
+
+			lock.Lock()
+			Expect(executed).To(HaveLen(2))
+			lock.Unlock()
+		})
+	})
+}
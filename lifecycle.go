@@ -0,0 +1,179 @@
+package rcon
+
+import (
+	"context"
+	"sync"
+)
+
+// State is the connection state of a client.
+type State uint8
+
+const (
+	StateDisconnected State = iota
+	StateConnecting
+	StateConnected
+)
+
+func (s State) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	default:
+		return "unknown"
+	}
+}
+
+// State returns the current connection state of the client.
+func (c *Client) State() State {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	return c.state
+}
+
+func (c *Client) isConnected() bool {
+	return c.State() == StateConnected
+}
+
+// Done returns a channel which is closed when the client stops running: Close was called, the connection was lost and no
+// reconnect is attempted, or reconnecting gave up. Once the client has connected again, Done returns a new channel.
+func (c *Client) Done() <-chan struct{} {
+	c.doneLock.Lock()
+	defer c.doneLock.Unlock()
+
+	return c.done
+}
+
+// Err returns the error the client stopped running with once the channel returned by Done is closed. It is nil if the
+// client was stopped using Close or is still running.
+func (c *Client) Err() error {
+	c.doneLock.Lock()
+	defer c.doneLock.Unlock()
+
+	return c.doneErr
+}
+
+// markDone closes the channel returned by Done with the provided error, if it isn't closed yet.
+func (c *Client) markDone(err error) {
+	c.doneLock.Lock()
+	defer c.doneLock.Unlock()
+
+	select {
+	case <-c.done:
+	default:
+		c.doneErr = err
+		close(c.done)
+	}
+}
+
+// renewDone replaces the channel returned by Done if it was closed, since the client is running again.
+func (c *Client) renewDone() {
+	c.doneLock.Lock()
+	defer c.doneLock.Unlock()
+
+	select {
+	case <-c.done:
+		c.done = make(chan struct{})
+		c.doneErr = nil
+	default:
+	}
+}
+
+// ListenAndServeBroadcasts connects to the server if the client isn't connected yet, and runs the read loop of the
+// connection on the calling goroutine, so broadcasts are delivered to handlers on it. After automatic reconnects and
+// calls to Reconnect or SetAddress, the read loop of the new connection is run on the calling goroutine too. It is an
+// alternative to calling Connect and letting the client run in the background, for programs which manage lifecycles
+// using their own supervision. If the client was connected before, or another call is already serving, the read loop
+// keeps running on the client's own goroutine and this call only waits for the client to stop.
+//
+// ctx also applies while connecting: if it is cancelled while dialing or authenticating, connecting is aborted and
+// ctx.Err() is returned.
+//
+// This only returns once Close is called, the connection is lost and not recovered, or ctx is cancelled. If ctx is
+// cancelled, the client is closed and ctx.Err() is returned. Otherwise, the error the client stopped with is returned,
+// which is nil if Close was called.
+func (c *Client) ListenAndServeBroadcasts(ctx context.Context) error {
+	loops := make(chan func(), 1)
+
+	c.readLoopsLock.Lock()
+	if c.readLoops == nil {
+		c.readLoops = loops
+	}
+	c.readLoopsLock.Unlock()
+
+	var once sync.Once
+	stopServing := func() {
+		once.Do(func() {
+			c.readLoopsLock.Lock()
+			if c.readLoops == loops {
+				c.readLoops = nil
+			}
+			c.readLoopsLock.Unlock()
+
+			// A read loop handed over after the last one was picked up still has to run.
+			select {
+			case loop := <-loops:
+				go loop()
+			default:
+			}
+		})
+	}
+	defer stopServing()
+
+	if err := c.ConnectContext(ctx); err != nil {
+		return err
+	}
+
+	done := c.Done()
+
+	for {
+		select {
+		case loop := <-loops:
+			// The read loop only returns once the connection is closed, so ctx is watched on another goroutine.
+			stop := make(chan struct{})
+			go func() {
+				select {
+				case <-ctx.Done():
+					_ = c.Close()
+				case <-stop:
+				}
+			}()
+
+			loop()
+			close(stop)
+		case <-done:
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			return c.Err()
+		case <-ctx.Done():
+			// Close waits for the read loop, which must not be left waiting for this goroutine.
+			stopServing()
+			_ = c.Close()
+
+			return ctx.Err()
+		}
+	}
+}
+
+// startReadLoop runs the read loop of a new connection on the goroutine of ListenAndServeBroadcasts if it is serving,
+// or on a new goroutine otherwise.
+func (c *Client) startReadLoop(loop func()) {
+	c.readLoopsLock.Lock()
+	defer c.readLoopsLock.Unlock()
+
+	if c.readLoops != nil {
+		select {
+		case c.readLoops <- loop:
+			return
+		default:
+		}
+	}
+
+	go loop()
+}
@@ -0,0 +1,137 @@
+package rcon
+
+import (
+	"context"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/packet"
+	"github.com/refractorgscm/rcon/rcontest"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifecycle(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("ListenAndServeBroadcasts", func() {
+		g.It("Should deliver broadcasts on the calling goroutine, also after reconnecting", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			// The handler reports whether it was called from ListenAndServeBroadcasts.
+			onCaller := make(chan bool, 2)
+			c := newTestClient(s, &Config{
+				ReconnectPolicy: &ReconnectPolicy{InitialDelay: time.Millisecond},
+				BroadcastChecker: func(p packet.Packet) bool {
+					return p.ID() == rcontest.BroadcastID
+				},
+				BroadcastHandler: func(string) {
+					stack := make([]byte, 64*1024)
+					stack = stack[:runtime.Stack(stack, false)]
+					onCaller <- strings.Contains(string(stack), "ListenAndServeBroadcasts")
+				},
+			})
+
+			connected := recordEvents(c, EventConnected)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			result := make(chan error, 1)
+			go func() {
+				result <- c.ListenAndServeBroadcasts(ctx)
+			}()
+
+			Eventually(c.State).Should(Equal(StateConnected))
+			s.Broadcast("before")
+			Eventually(onCaller).Should(Receive(BeTrue()))
+
+			s.CloseConnections()
+			Eventually(func() int { return len(connected()) }, time.Second).Should(Equal(2))
+			Eventually(c.State).Should(Equal(StateConnected))
+
+			s.Broadcast("after")
+			Eventually(onCaller).Should(Receive(BeTrue()))
+			Consistently(result, time.Millisecond*50).ShouldNot(Receive())
+
+			cancel()
+			Eventually(result).Should(Receive(Equal(context.Canceled)))
+		})
+
+		g.It("Should return the error the connection was lost with", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+
+			result := make(chan error, 1)
+			go func() {
+				result <- c.ListenAndServeBroadcasts(context.Background())
+			}()
+
+			Eventually(c.State).Should(Equal(StateConnected))
+			s.CloseConnections()
+
+			var err error
+			Eventually(result, time.Second).Should(Receive(&err))
+			Expect(err).To(HaveOccurred())
+			Expect(c.Err()).To(Equal(err))
+		})
+
+		g.It("Should return nil once the client is closed", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+
+			result := make(chan error, 1)
+			go func() {
+				result <- c.ListenAndServeBroadcasts(context.Background())
+			}()
+
+			Eventually(c.State).Should(Equal(StateConnected))
+			Expect(c.Close()).To(Succeed())
+			Eventually(result).Should(Receive(BeNil()))
+		})
+
+		g.It("Should return the connect error if the server is unreachable", func() {
+			s := newTestServer(echoHandler)
+			c := newTestClient(s, &Config{})
+			Expect(s.Close()).To(Succeed())
+
+			Expect(c.ListenAndServeBroadcasts(context.Background())).ToNot(Succeed())
+		})
+
+		g.It("Should stop authenticating once ctx is cancelled", func() {
+			// The listener accepts connections but never answers the auth request.
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			defer l.Close()
+
+			go func() {
+				for {
+					conn, err := l.Accept()
+					if err != nil {
+						return
+					}
+					defer conn.Close()
+				}
+			}()
+
+			addr := l.Addr().(*net.TCPAddr)
+			c := NewClient(&Config{Host: "127.0.0.1", Port: uint16(addr.Port), Password: testPassword, ConnTimeout: time.Second * 10}, nil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+			defer cancel()
+
+			start := time.Now()
+			Expect(c.ListenAndServeBroadcasts(ctx)).To(Equal(context.DeadlineExceeded))
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+			Expect(c.State()).To(Equal(StateDisconnected))
+		})
+	})
+}
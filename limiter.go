@@ -0,0 +1,173 @@
+package rcon
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// commandLimiter limits the number of commands in flight. Waiting commands are queued per caller, and callers are
+// admitted round robin, so that a caller issuing many commands can't starve the others. A nil limiter doesn't limit
+// anything.
+type commandLimiter struct {
+	lock     sync.Mutex
+	limit    int
+	inFlight int
+	waiting  int
+
+	// queues holds the waiting commands of each caller, oldest first. order holds the callers with waiting commands, in
+	// the order in which they are admitted next.
+	queues map[string][]chan struct{}
+	order  []string
+
+	waits   []time.Duration
+	waitIdx int
+}
+
+func newCommandLimiter(limit int) *commandLimiter {
+	return &commandLimiter{
+		limit:  limit,
+		queues: map[string][]chan struct{}{},
+	}
+}
+
+// acquire waits until a command of caller may be sent. If ctx is done first, its error is returned. release must be
+// called once the command completed if acquire returned nil.
+func (l *commandLimiter) acquire(ctx context.Context, caller string) error {
+	if l == nil {
+		return nil
+	}
+
+	l.lock.Lock()
+	if l.inFlight < l.limit && l.waiting == 0 {
+		l.inFlight++
+		l.recordWait(0)
+		l.lock.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	if len(l.queues[caller]) == 0 {
+		l.order = append(l.order, caller)
+	}
+	l.queues[caller] = append(l.queues[caller], ready)
+	l.waiting++
+	l.lock.Unlock()
+
+	start := time.Now()
+
+	select {
+	case <-ready:
+		l.lock.Lock()
+		l.recordWait(time.Since(start))
+		l.lock.Unlock()
+
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	select {
+	case <-ready:
+		// The command was admitted while the context was done, so its slot has to be passed on.
+		l.inFlight--
+		l.admit()
+	default:
+		l.dequeue(caller, ready)
+	}
+
+	return ctx.Err()
+}
+
+// release frees the slot of a completed command and admits the next waiting command.
+func (l *commandLimiter) release() {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.inFlight--
+	l.admit()
+}
+
+// admit admits waiting commands while slots are free, taking one command of each caller in turn. It must be called
+// with the lock held.
+func (l *commandLimiter) admit() {
+	for l.inFlight < l.limit && len(l.order) > 0 {
+		caller := l.order[0]
+		l.order = l.order[1:]
+
+		queue := l.queues[caller]
+		ready := queue[0]
+
+		if len(queue) > 1 {
+			l.queues[caller] = queue[1:]
+			l.order = append(l.order, caller)
+		} else {
+			delete(l.queues, caller)
+		}
+
+		l.waiting--
+		l.inFlight++
+		close(ready)
+	}
+}
+
+// dequeue removes a command which stopped waiting. It must be called with the lock held.
+func (l *commandLimiter) dequeue(caller string, ready chan struct{}) {
+	queue := l.queues[caller]
+	for i, r := range queue {
+		if r == ready {
+			queue = append(queue[:i:i], queue[i+1:]...)
+			break
+		}
+	}
+
+	l.waiting--
+
+	if len(queue) > 0 {
+		l.queues[caller] = queue
+		return
+	}
+
+	delete(l.queues, caller)
+	for i, c := range l.order {
+		if c == caller {
+			l.order = append(l.order[:i:i], l.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// recordWait must be called with the lock held.
+func (l *commandLimiter) recordWait(wait time.Duration) {
+	if len(l.waits) < latencySampleSize {
+		l.waits = append(l.waits, wait)
+	} else {
+		l.waits[l.waitIdx] = wait
+		l.waitIdx = (l.waitIdx + 1) % latencySampleSize
+	}
+}
+
+// addStats adds the queue length and wait times to stats.
+func (l *commandLimiter) addStats(stats *Stats) {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	stats.CommandsWaiting = l.waiting
+
+	if len(l.waits) > 0 {
+		sorted, average := sortSamples(l.waits)
+
+		stats.AverageCommandWait = average
+		stats.P95CommandWait = percentile(sorted, 95)
+	}
+}
@@ -0,0 +1,59 @@
+package rcon
+
+import (
+	"context"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("commandLimiter", func() {
+		g.It("Should admit waiting callers round robin", func() {
+			l := newCommandLimiter(1)
+			Expect(l.acquire(context.Background(), "poller")).To(Succeed())
+
+			admitted := make(chan string, 4)
+			for i, caller := range []string{"poller", "poller", "poller", "user"} {
+				caller := caller
+				go func() {
+					_ = l.acquire(context.Background(), caller)
+					admitted <- caller
+				}()
+
+				// Wait for the command to be queued, so that the queue order is deterministic.
+				Eventually(func() int {
+					var stats Stats
+					l.addStats(&stats)
+					return stats.CommandsWaiting
+				}).Should(Equal(i + 1))
+			}
+
+			var order []string
+			for i := 0; i < 4; i++ {
+				l.release()
+				order = append(order, <-admitted)
+			}
+
+			Expect(order).To(Equal([]string{"poller", "user", "poller", "poller"}))
+		})
+
+		g.It("Should give up waiting when the context is done", func() {
+			l := newCommandLimiter(1)
+			Expect(l.acquire(context.Background(), "")).To(Succeed())
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+			defer cancel()
+
+			Expect(l.acquire(ctx, "")).To(Equal(context.DeadlineExceeded))
+			Expect(l.waiting).To(BeZero())
+			Expect(l.order).To(BeEmpty())
+		})
+	})
+}
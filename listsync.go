@@ -0,0 +1,179 @@
+package rcon
+
+import (
+	"github.com/pkg/errors"
+	"sort"
+	"strings"
+)
+
+// ListParser parses the response to a command listing entries, such as the IDs on a ban list.
+type ListParser func(response string) ([]string, error)
+
+// ParseListLines is a ListParser treating each non-empty line of the response as an entry.
+func ParseListLines(response string) ([]string, error) {
+	var entries []string
+
+	for _, line := range strings.Split(response, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+
+	return entries, nil
+}
+
+// ListSyncConfig describes how a list of entries on the server, such as a ban or mute list, is read and changed.
+type ListSyncConfig struct {
+	// ListCommand is the command listing the entries on the server, e.g. "listid". Its response is parsed with
+	// ParseList, or ParseListLines if nil.
+	ListCommand string
+	ParseList   ListParser
+
+	// AddFormat and RemoveFormat are the formats of the commands adding and removing an entry, e.g. "banid 0 %s" and
+	// "removeid %s". They must contain a single %s verb, which is replaced with the entry, quoted using the dialect's
+	// ArgQuoting.
+	AddFormat    string
+	RemoveFormat string
+
+	// CommitCommand is executed after changes were applied, for servers which need to be told to persist the list, e.g.
+	// "writeid". If empty, nothing is executed.
+	CommitCommand string
+
+	// KeepUnlisted keeps entries which are on the server but not in the desired list, such as bans issued by the
+	// server's own moderators. If false, they are removed.
+	KeepUnlisted bool
+}
+
+// ListSyncResult reports the changes made by ListSync.Sync.
+type ListSyncResult struct {
+	Added   []string
+	Removed []string
+
+	// Failed holds the error of each entry which could not be added or removed.
+	Failed map[string]error
+}
+
+// ListSync synchronizes a list of entries on the server, such as a ban or mute list, with a desired list supplied by
+// the caller. It is meant for panels which maintain global lists across many servers.
+type ListSync struct {
+	client *Client
+	config ListSyncConfig
+}
+
+// NewListSync creates a ListSync for a client.
+func NewListSync(c *Client, config ListSyncConfig) *ListSync {
+	if config.ParseList == nil {
+		config.ParseList = ParseListLines
+	}
+
+	return &ListSync{
+		client: c,
+		config: config,
+	}
+}
+
+// Current returns the entries currently on the server.
+func (s *ListSync) Current(opts ...ExecOption) ([]string, error) {
+	res, err := s.client.ExecCommand(s.config.ListCommand, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list entries")
+	}
+
+	entries, err := s.config.ParseList(res)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse entries")
+	}
+
+	return entries, nil
+}
+
+// Sync reads the entries on the server, and adds and removes entries until they match desired. Changes are applied one
+// by one, and failed changes don't stop the remaining ones. If any change failed, the result is returned along with the
+// error of the first failed entry in sorted order.
+//
+// In dry-run mode, the changes are computed and recorded in the history without being applied. The list is still read
+// from the server.
+func (s *ListSync) Sync(desired []string, opts ...ExecOption) (ListSyncResult, error) {
+	var result ListSyncResult
+
+	current, err := s.Current(append(append([]ExecOption{}, opts...), WithDryRun(false))...)
+	if err != nil {
+		return result, err
+	}
+
+	add, remove := DiffList(current, desired)
+	if s.config.KeepUnlisted {
+		remove = nil
+	}
+
+	result.Failed = map[string]error{}
+
+	apply := func(format, entry string, applied *[]string) {
+		if _, err := s.client.ExecTemplate(format, []string{entry}, opts...); err != nil {
+			result.Failed[entry] = err
+			return
+		}
+
+		*applied = append(*applied, entry)
+	}
+
+	for _, entry := range remove {
+		apply(s.config.RemoveFormat, entry, &result.Removed)
+	}
+
+	for _, entry := range add {
+		apply(s.config.AddFormat, entry, &result.Added)
+	}
+
+	if s.config.CommitCommand != "" && len(result.Added)+len(result.Removed) > 0 {
+		if _, err := s.client.ExecCommand(s.config.CommitCommand, opts...); err != nil {
+			return result, errors.Wrap(err, "could not commit changes")
+		}
+	}
+
+	if len(result.Failed) > 0 {
+		failed := make([]string, 0, len(result.Failed))
+		for entry := range result.Failed {
+			failed = append(failed, entry)
+		}
+		sort.Strings(failed)
+
+		return result, errors.Wrapf(result.Failed[failed[0]], "could not apply %d of %d changes, first %s",
+			len(failed), len(add)+len(remove), failed[0])
+	}
+
+	return result, nil
+}
+
+// DiffList returns the entries of desired which are missing from current, and the entries of current which aren't in
+// desired. Both are sorted and free of duplicates.
+func DiffList(current, desired []string) (add, remove []string) {
+	inCurrent := toSet(current)
+	inDesired := toSet(desired)
+
+	for entry := range inDesired {
+		if !inCurrent[entry] {
+			add = append(add, entry)
+		}
+	}
+
+	for entry := range inCurrent {
+		if !inDesired[entry] {
+			remove = append(remove, entry)
+		}
+	}
+
+	sort.Strings(add)
+	sort.Strings(remove)
+
+	return add, remove
+}
+
+func toSet(entries []string) map[string]bool {
+	set := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		set[entry] = true
+	}
+
+	return set
+}
@@ -0,0 +1,34 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"testing"
+)
+
+func TestListSync(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("ListSync", func() {
+		g.It("Should diff the current list against the desired list", func() {
+			add, remove := DiffList([]string{"a", "b", "b"}, []string{"c", "b", "c"})
+			Expect(add).To(Equal([]string{"c"}))
+			Expect(remove).To(Equal([]string{"a"}))
+		})
+
+		g.It("Should read the list from the server even in dry-run mode", func() {
+			c := NewClient(&Config{DryRun: true, HistorySize: 10}, nil)
+			sync := NewListSync(c, ListSyncConfig{ListCommand: "banlist", AddFormat: "ban %s", RemoveFormat: "unban %s"})
+
+			_, err := sync.Sync([]string{"a"})
+			Expect(errors.Cause(err)).To(Equal(errs.ErrNotConnected))
+			Expect(c.History()).To(HaveLen(1))
+			Expect(c.History()[0].DryRun).To(BeFalse())
+		})
+	})
+}
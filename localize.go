@@ -0,0 +1,58 @@
+package rcon
+
+import (
+	"regexp"
+	"sync"
+)
+
+// ResponseMapping maps a localized response to its canonical form, so that code checking responses, such as ExecExpect
+// or the response parsers of a dialect, works regardless of the server's language. For example, a German server's
+// "Spieler nicht gefunden" can be mapped to "Player not found".
+type ResponseMapping struct {
+	// Pattern matches the localized response, or the parts of it to replace.
+	Pattern *regexp.Regexp
+
+	// Canonical replaces each match of Pattern. It may refer to the capture groups of Pattern, such as $1 or ${name},
+	// to keep the variable parts of the response.
+	Canonical string
+}
+
+// responseMappings holds the ResponseMappings registered on a client at runtime.
+type responseMappings struct {
+	lock     sync.Mutex
+	mappings []ResponseMapping
+}
+
+func (m *responseMappings) add(mapping ResponseMapping) {
+	m.lock.Lock()
+	m.mappings = append(m.mappings, mapping)
+	m.lock.Unlock()
+}
+
+func (m *responseMappings) get() []ResponseMapping {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.mappings
+}
+
+// AddResponseMapping registers a ResponseMapping, which is applied to responses after those of the dialect. Game
+// adapters can use it to add mappings for the language the server is running in.
+func (c *Client) AddResponseMapping(mapping ResponseMapping) {
+	c.responseMappings.add(mapping)
+}
+
+// canonicalResponse applies the ResponseMappings of the dialect and those registered on the client to a response, in
+// order.
+func (c *Client) canonicalResponse(response string) string {
+	apply := func(mappings []ResponseMapping) {
+		for _, m := range mappings {
+			response = m.Pattern.ReplaceAllString(response, m.Canonical)
+		}
+	}
+
+	apply(c.Dialect().ResponseMappings)
+	apply(c.responseMappings.get())
+
+	return response
+}
@@ -0,0 +1,28 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"regexp"
+	"testing"
+)
+
+func TestLocalize(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Response mappings", func() {
+		g.It("Should map localized responses to their canonical form", func() {
+			c := NewClient(&Config{Dialect: &Dialect{ResponseMappings: []ResponseMapping{{
+				Pattern:   regexp.MustCompile(`^Spieler (\S+) nicht gefunden$`),
+				Canonical: "Player $1 not found",
+			}}}}, nil)
+			c.AddResponseMapping(ResponseMapping{Pattern: regexp.MustCompile(`gekickt`), Canonical: "kicked"})
+
+			Expect(c.canonicalResponse("Spieler Bob nicht gefunden")).To(Equal("Player Bob not found"))
+			Expect(c.canonicalResponse("Bob wurde gekickt")).To(Equal("Bob wurde kicked"))
+		})
+	})
+}
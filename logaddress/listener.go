@@ -0,0 +1,210 @@
+// Package logaddress provides a broadcast source which receives Source engine UDP log streams. Games such as CS:GO and
+// TF2 deliver events through "logaddress_add" log forwarding rather than over the RCON connection.
+//
+// The game server must be configured to send its logs to the listener, for example:
+//
+//	logaddress_add 203.0.113.10:27500
+//	sv_logsecret 123456
+package logaddress
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"net"
+	"strings"
+	"sync"
+)
+
+const (
+	typeNoSecret = 'R'
+	typeSecret   = 'S'
+)
+
+// maxPacketSize is the largest log packet the Source engine sends.
+const maxPacketSize = 65535
+
+var header = []byte{0xFF, 0xFF, 0xFF, 0xFF}
+
+var (
+	ErrMalformedPacket = errors.New("malformed log packet")
+	ErrBadSecret       = errors.New("log packet secret mismatch")
+	ErrUnknownSender   = errors.New("log packet from unknown sender")
+)
+
+// Listener receives UDP log packets and emits each log line. It implements rcon.BroadcastSource.
+type Listener struct {
+	// Address is the UDP address to listen on, for example ":27500".
+	Address string
+
+	// Secret is the game server's sv_logsecret. If set, packets without a matching secret are dropped.
+	Secret string
+
+	// AllowedIPs is the list of addresses packets are accepted from. If empty, packets from any address are accepted.
+	AllowedIPs []net.IP
+
+	// ErrorHandler is called when a packet is dropped or the socket could not be read. If nil, errors are ignored.
+	ErrorHandler func(error)
+
+	mu   sync.Mutex
+	conn *net.UDPConn
+	done chan struct{}
+}
+
+func New(address string) *Listener {
+	return &Listener{
+		Address: address,
+	}
+}
+
+// Start starts listening for log packets in a new goroutine.
+func (l *Listener) Start(emit func(message string)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		return errors.New("listener already started")
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", l.Address)
+	if err != nil {
+		return errors.Wrap(err, "could not resolve listen address")
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return errors.Wrap(err, "could not listen for log packets")
+	}
+
+	l.conn = conn
+	l.done = make(chan struct{})
+
+	go l.run(conn, emit, l.done)
+
+	return nil
+}
+
+// Stop closes the socket and waits for the listening goroutine to exit.
+func (l *Listener) Stop() error {
+	l.mu.Lock()
+	conn, done := l.conn, l.done
+	l.conn, l.done = nil, nil
+	l.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	err := conn.Close()
+	<-done
+
+	return err
+}
+
+// LocalAddr returns the address the listener is bound to, or nil if it is not started.
+func (l *Listener) LocalAddr() net.Addr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+
+	return l.conn.LocalAddr()
+}
+
+func (l *Listener) run(conn *net.UDPConn, emit func(string), done chan struct{}) {
+	defer close(done)
+
+	buf := make([]byte, maxPacketSize)
+
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// The socket is closed when the listener is stopped.
+			if strings.HasSuffix(err.Error(), "use of closed network connection") {
+				return
+			}
+
+			l.handleError(errors.Wrap(err, "could not read log packet"))
+			continue
+		}
+
+		if !l.allowed(from.IP) {
+			l.handleError(errors.Wrapf(ErrUnknownSender, "packet from %s", from.IP))
+			continue
+		}
+
+		line, err := ParsePacket(buf[:n], l.Secret)
+		if err != nil {
+			l.handleError(err)
+			continue
+		}
+
+		emit(line)
+	}
+}
+
+func (l *Listener) allowed(ip net.IP) bool {
+	if len(l.AllowedIPs) == 0 {
+		return true
+	}
+
+	for _, allowed := range l.AllowedIPs {
+		if allowed.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *Listener) handleError(err error) {
+	if l.ErrorHandler != nil {
+		l.ErrorHandler(err)
+	}
+}
+
+// ParsePacket parses a Source engine log packet and returns the log line it contains, without the leading "L " marker.
+//
+// If secret is not empty, the packet must contain the matching secret.
+func ParsePacket(data []byte, secret string) (string, error) {
+	if len(data) < len(header)+1 || !bytes.HasPrefix(data, header) {
+		return "", ErrMalformedPacket
+	}
+
+	pType := data[len(header)]
+	body := data[len(header)+1:]
+
+	switch pType {
+	case typeSecret:
+		if secret != "" && !bytes.HasPrefix(body, []byte(secret)) {
+			return "", ErrBadSecret
+		}
+
+		// The secret is directly followed by the line, which always begins with "L ".
+		idx := bytes.Index(body, []byte("L "))
+		if idx < 0 {
+			return "", ErrMalformedPacket
+		}
+
+		if secret != "" && idx != len(secret) {
+			return "", ErrBadSecret
+		}
+
+		body = body[idx:]
+	case typeNoSecret:
+		if secret != "" {
+			return "", ErrBadSecret
+		}
+	default:
+		return "", ErrMalformedPacket
+	}
+
+	if !bytes.HasPrefix(body, []byte("L ")) {
+		return "", ErrMalformedPacket
+	}
+
+	body = bytes.TrimRight(body[2:], "\x00\r\n")
+
+	return string(body), nil
+}
@@ -0,0 +1,125 @@
+package logaddress
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"net"
+	"testing"
+)
+
+// packet builds a log packet of the provided type.
+func packet(pType byte, body string) []byte {
+	return append(append(append([]byte{}, header...), pType), body...)
+}
+
+func Test(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	const line = `10/01/2021 - 12:00:00: "Bob<2><STEAM_1:0:1><CT>" say "hello"`
+
+	g.Describe("ParsePacket", func() {
+		g.It("Should parse log packets", func() {
+			tests := []struct {
+				name   string
+				data   []byte
+				secret string
+				line   string
+				err    error
+			}{
+				{"without secret", packet(typeNoSecret, "L "+line+"\n\x00"), "", line, nil},
+				{"with secret", packet(typeSecret, "123456L "+line+"\x00"), "123456", line, nil},
+				{"unchecked secret", packet(typeSecret, "123456L "+line), "", line, nil},
+				{"empty line", packet(typeNoSecret, "L \x00"), "", "", nil},
+
+				{"wrong secret", packet(typeSecret, "654321L "+line), "123456", "", ErrBadSecret},
+				{"secret prefix", packet(typeSecret, "123456L "+line), "123", "", ErrBadSecret},
+				{"longer secret", packet(typeSecret, "123L "+line), "123456", "", ErrBadSecret},
+				{"missing secret", packet(typeNoSecret, "L "+line), "123456", "", ErrBadSecret},
+
+				{"empty", nil, "", "", ErrMalformedPacket},
+				{"truncated header", header[:2], "", "", ErrMalformedPacket},
+				{"header only", header, "", "", ErrMalformedPacket},
+				{"wrong header", append([]byte{0xFE, 0xFF, 0xFF, 0xFF, typeNoSecret}, "L "+line...), "", "",
+					ErrMalformedPacket},
+				{"unknown type", packet('X', "L "+line), "", "", ErrMalformedPacket},
+				{"truncated marker", packet(typeNoSecret, "L"), "", "", ErrMalformedPacket},
+				{"missing marker", packet(typeNoSecret, line), "", "", ErrMalformedPacket},
+				{"truncated after secret", packet(typeSecret, "123456"), "123456", "", ErrMalformedPacket},
+			}
+
+			for _, test := range tests {
+				parsed, err := ParsePacket(test.data, test.secret)
+				if test.err == nil {
+					Expect(err).ToNot(HaveOccurred(), test.name)
+				} else {
+					Expect(err).To(Equal(test.err), test.name)
+				}
+				Expect(parsed).To(Equal(test.line), test.name)
+			}
+		})
+	})
+
+	g.Describe("Listener", func() {
+		g.It("Should emit the lines of valid packets and report others", func() {
+			l := New("127.0.0.1:0")
+			l.Secret = "123456"
+
+			errs := make(chan error, 10)
+			l.ErrorHandler = func(err error) {
+				errs <- err
+			}
+
+			lines := make(chan string, 10)
+			Expect(l.Start(func(message string) {
+				lines <- message
+			})).To(Succeed())
+			defer l.Stop()
+
+			conn, err := net.Dial("udp", l.LocalAddr().String())
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+
+			_, err = conn.Write(packet(typeSecret, "654321L wrong secret"))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = conn.Write(packet(typeSecret, "123456L "+line))
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(errs).Should(Receive(Equal(ErrBadSecret)))
+			Eventually(lines).Should(Receive(Equal(line)))
+
+			Expect(l.Stop()).To(Succeed())
+			Expect(l.LocalAddr()).To(BeNil())
+		})
+
+		g.It("Should only accept packets from allowed addresses", func() {
+			l := New("127.0.0.1:0")
+			l.AllowedIPs = []net.IP{net.ParseIP("203.0.113.10")}
+
+			errs := make(chan error, 10)
+			l.ErrorHandler = func(err error) {
+				errs <- err
+			}
+
+			lines := make(chan string, 10)
+			Expect(l.Start(func(message string) {
+				lines <- message
+			})).To(Succeed())
+			defer l.Stop()
+
+			conn, err := net.Dial("udp", l.LocalAddr().String())
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+
+			_, err = conn.Write(packet(typeNoSecret, "L "+line))
+			Expect(err).ToNot(HaveOccurred())
+
+			var received error
+			Eventually(errs).Should(Receive(&received))
+			Expect(received).To(MatchError(ContainSubstring(ErrUnknownSender.Error())))
+			Consistently(lines, "100ms").ShouldNot(Receive())
+		})
+	})
+}
@@ -0,0 +1,255 @@
+package logtail
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FTPFile is a Fetcher which reads a log file from an FTP server. Many game server hosts only provide file access over
+// FTP. A new FTP session is opened for every request, so no connection is held open between polls.
+//
+// The server must support the SIZE and REST commands.
+type FTPFile struct {
+	// Address is the host:port of the FTP server.
+	Address  string
+	Username string
+	Password string
+
+	// Path is the path of the log file on the FTP server.
+	Path string
+
+	// Timeout is used for dialing, for each command on the control connection and for each read from the data
+	// connection, so transfers may take longer as long as the server keeps sending data.
+	//
+	// Default: 10s
+	Timeout time.Duration
+}
+
+const defaultFTPTimeout = time.Second * 10
+
+func (f *FTPFile) Size() (int64, error) {
+	c, err := f.login()
+	if err != nil {
+		return 0, err
+	}
+	defer c.quit()
+
+	if _, _, err := c.cmd(200, "TYPE I"); err != nil {
+		return 0, err
+	}
+
+	_, msg, err := c.cmd(213, "SIZE %s", f.Path)
+	if err != nil {
+		if tpErr, ok := errors.Cause(err).(*textproto.Error); ok && tpErr.Code == 550 {
+			// The log file does not exist yet.
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	size, err := strconv.ParseInt(strings.TrimSpace(msg), 10, 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid SIZE response")
+	}
+
+	return size, nil
+}
+
+func (f *FTPFile) ReadFrom(offset int64) (io.ReadCloser, error) {
+	c, err := f.login()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := f.retrieve(c, offset)
+	if err != nil {
+		c.quit()
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (f *FTPFile) retrieve(c *ftpConn, offset int64) (io.ReadCloser, error) {
+	if _, _, err := c.cmd(200, "TYPE I"); err != nil {
+		return nil, err
+	}
+
+	addr, err := c.passive()
+	if err != nil {
+		return nil, err
+	}
+
+	dataConn, err := net.DialTimeout("tcp", addr, f.timeout())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open ftp data connection")
+	}
+
+	if _, _, err := c.cmd(350, "REST %d", offset); err != nil {
+		_ = dataConn.Close()
+		return nil, err
+	}
+
+	// 1xx is a positive preliminary reply, which is sent before the transfer starts.
+	if _, _, err := c.cmd(1, "RETR %s", f.Path); err != nil {
+		_ = dataConn.Close()
+		return nil, err
+	}
+
+	return &ftpReader{conn: c, data: dataConn}, nil
+}
+
+func (f *FTPFile) timeout() time.Duration {
+	if f.Timeout <= 0 {
+		return defaultFTPTimeout
+	}
+
+	return f.Timeout
+}
+
+func (f *FTPFile) login() (*ftpConn, error) {
+	conn, err := net.DialTimeout("tcp", f.Address, f.timeout())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to ftp server")
+	}
+	c := &ftpConn{
+		conn:    conn,
+		text:    textproto.NewConn(conn),
+		timeout: f.timeout(),
+	}
+
+	c.extendDeadline()
+	if _, _, err := c.text.ReadResponse(220); err != nil {
+		c.close()
+		return nil, errors.Wrap(err, "unexpected ftp greeting")
+	}
+
+	code, _, err := c.cmd(0, "USER %s", f.Username)
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+
+	if code == 331 {
+		if _, _, err := c.cmd(230, "PASS %s", f.Password); err != nil {
+			c.close()
+			return nil, errors.Wrap(err, "ftp login failed")
+		}
+	} else if code != 230 {
+		c.close()
+		return nil, fmt.Errorf("ftp login failed: unexpected response code %d", code)
+	}
+
+	return c, nil
+}
+
+type ftpConn struct {
+	conn    net.Conn
+	text    *textproto.Conn
+	timeout time.Duration
+}
+
+// extendDeadline gives the next command on the control connection the full timeout.
+func (c *ftpConn) extendDeadline() {
+	_ = c.conn.SetDeadline(time.Now().Add(c.timeout))
+}
+
+// cmd sends a command and reads its response. expectCode is interpreted as in textproto.Conn.ReadResponse, and 0
+// accepts any response code.
+func (c *ftpConn) cmd(expectCode int, format string, args ...interface{}) (int, string, error) {
+	c.extendDeadline()
+
+	id, err := c.text.Cmd(format, args...)
+	if err != nil {
+		return 0, "", errors.Wrap(err, "could not send ftp command")
+	}
+
+	c.text.StartResponse(id)
+	defer c.text.EndResponse(id)
+
+	code, msg, err := c.text.ReadResponse(expectCode)
+	if err != nil {
+		return code, msg, errors.Wrapf(err, "ftp command %s failed", strings.Fields(format)[0])
+	}
+
+	return code, msg, nil
+}
+
+// passive enters passive mode and returns the address of the data connection. EPSV is tried first, then PASV.
+func (c *ftpConn) passive() (string, error) {
+	host, _, err := net.SplitHostPort(c.conn.RemoteAddr().String())
+	if err != nil {
+		return "", err
+	}
+
+	if _, msg, err := c.cmd(229, "EPSV"); err == nil {
+		// 229 Entering Extended Passive Mode (|||port|)
+		start, end := strings.Index(msg, "(|||"), strings.LastIndex(msg, "|)")
+		if start >= 0 && end > start+4 {
+			return net.JoinHostPort(host, msg[start+4:end]), nil
+		}
+	}
+
+	_, msg, err := c.cmd(227, "PASV")
+	if err != nil {
+		return "", err
+	}
+
+	// 227 Entering Passive Mode (h1,h2,h3,h4,p1,p2)
+	start, end := strings.Index(msg, "("), strings.Index(msg, ")")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("invalid PASV response: %s", msg)
+	}
+
+	parts := strings.Split(msg[start+1:end], ",")
+	if len(parts) != 6 {
+		return "", fmt.Errorf("invalid PASV response: %s", msg)
+	}
+
+	p1, err1 := strconv.Atoi(parts[4])
+	p2, err2 := strconv.Atoi(parts[5])
+	if err1 != nil || err2 != nil {
+		return "", fmt.Errorf("invalid PASV response: %s", msg)
+	}
+
+	// The host in the PASV response is ignored as it is often a private address when the server is behind NAT.
+	return net.JoinHostPort(host, strconv.Itoa(p1*256+p2)), nil
+}
+
+func (c *ftpConn) quit() {
+	_, _, _ = c.cmd(221, "QUIT")
+	c.close()
+}
+
+func (c *ftpConn) close() {
+	_ = c.text.Close()
+}
+
+// ftpReader reads a file transfer from the data connection. Closing it completes the transfer and ends the session.
+type ftpReader struct {
+	conn *ftpConn
+	data net.Conn
+}
+
+func (r *ftpReader) Read(p []byte) (int, error) {
+	_ = r.data.SetReadDeadline(time.Now().Add(r.conn.timeout))
+	return r.data.Read(p)
+}
+
+func (r *ftpReader) Close() error {
+	err := r.data.Close()
+
+	// Read the transfer complete response before ending the session.
+	r.conn.extendDeadline()
+	_, _, _ = r.conn.text.ReadResponse(2)
+	r.conn.quit()
+
+	return err
+}
@@ -0,0 +1,273 @@
+package logtail
+
+import (
+	"fmt"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ftpServer serves the parts of the FTP protocol used by FTPFile from the local filesystem. Transfers are sent in
+// chunks of chunkSize bytes, chunkDelay apart.
+type ftpServer struct {
+	listener   net.Listener
+	chunkSize  int
+	chunkDelay time.Duration
+
+	// stall makes the server stop responding after login.
+	stall bool
+}
+
+func newFTPServer() *ftpServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).To(BeNil())
+
+	s := &ftpServer{listener: l, chunkSize: 1024}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *ftpServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *ftpServer) Close() {
+	_ = s.listener.Close()
+}
+
+func (s *ftpServer) serve(conn net.Conn) {
+	text := textproto.NewConn(conn)
+	defer text.Close()
+
+	reply := func(code int, format string, args ...interface{}) error {
+		return text.PrintfLine("%d %s", code, fmt.Sprintf(format, args...))
+	}
+
+	if reply(220, "ready") != nil {
+		return
+	}
+
+	var data net.Listener
+	var offset int64
+	defer func() {
+		if data != nil {
+			_ = data.Close()
+		}
+	}()
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command, arg := line, ""
+		if i := strings.Index(line, " "); i >= 0 {
+			command, arg = line[:i], line[i+1:]
+		}
+
+		if s.stall && command != "USER" && command != "PASS" {
+			// Leave the command unanswered until the client gives up.
+			_, _ = text.ReadLine()
+			return
+		}
+
+		switch command {
+		case "USER":
+			err = reply(331, "password required")
+		case "PASS":
+			if arg == "secret" {
+				err = reply(230, "logged in")
+			} else {
+				err = reply(530, "login incorrect")
+			}
+		case "TYPE":
+			err = reply(200, "type set")
+		case "SIZE":
+			info, statErr := os.Stat(arg)
+			if statErr != nil {
+				err = reply(550, "no such file")
+			} else {
+				err = reply(213, "%d", info.Size())
+			}
+		case "EPSV":
+			data, err = net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				return
+			}
+			err = reply(229, "Entering Extended Passive Mode (|||%d|)", data.Addr().(*net.TCPAddr).Port)
+		case "REST":
+			offset, _ = strconv.ParseInt(arg, 10, 64)
+			err = reply(350, "restarting at %d", offset)
+		case "RETR":
+			err = s.retrieve(reply, data, arg, offset)
+		case "QUIT":
+			_ = reply(221, "bye")
+			return
+		default:
+			err = reply(502, "not implemented")
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *ftpServer) retrieve(reply func(int, string, ...interface{}) error, data net.Listener, path string,
+	offset int64) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return reply(550, "no such file")
+	}
+
+	conn, err := data.Accept()
+	if err != nil {
+		return err
+	}
+
+	if err := reply(150, "opening data connection"); err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	contents = contents[offset:]
+	for len(contents) > 0 {
+		n := s.chunkSize
+		if n > len(contents) {
+			n = len(contents)
+		}
+
+		time.Sleep(s.chunkDelay)
+		if _, err := conn.Write(contents[:n]); err != nil {
+			break
+		}
+		contents = contents[n:]
+	}
+
+	_ = conn.Close()
+
+	return reply(226, "transfer complete")
+}
+
+func TestFTPFile(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("FTPFile", func() {
+		var dir string
+		var server *ftpServer
+		var fetcher *FTPFile
+
+		g.BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "logtail")
+			Expect(err).To(BeNil())
+
+			path := filepath.Join(dir, "server.log")
+			Expect(ioutil.WriteFile(path, []byte("first line\nsecond line\n"), 0644)).To(BeNil())
+
+			server = newFTPServer()
+			fetcher = &FTPFile{
+				Address:  server.Addr(),
+				Username: "admin",
+				Password: "secret",
+				Path:     path,
+				Timeout:  time.Second,
+			}
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+			_ = os.RemoveAll(dir)
+		})
+
+		g.It("Should return the size of the file", func() {
+			size, err := fetcher.Size()
+			Expect(err).To(BeNil())
+			Expect(size).To(Equal(int64(23)))
+		})
+
+		g.It("Should return a size of 0 if the file does not exist", func() {
+			fetcher.Path = filepath.Join(dir, "missing.log")
+
+			size, err := fetcher.Size()
+			Expect(err).To(BeNil())
+			Expect(size).To(Equal(int64(0)))
+		})
+
+		g.It("Should return an error if the login fails", func() {
+			fetcher.Password = "wrong"
+
+			_, err := fetcher.Size()
+			Expect(err).ToNot(BeNil())
+		})
+
+		g.It("Should read the file from the offset", func() {
+			r, err := fetcher.ReadFrom(11)
+			Expect(err).To(BeNil())
+
+			data, err := ioutil.ReadAll(r)
+			Expect(err).To(BeNil())
+			Expect(string(data)).To(Equal("second line\n"))
+			Expect(r.Close()).To(BeNil())
+		})
+
+		g.It("Should allow transfers taking longer than the timeout", func() {
+			fetcher.Timeout = time.Millisecond * 100
+			server.chunkSize = 4
+			server.chunkDelay = time.Millisecond * 30
+
+			r, err := fetcher.ReadFrom(0)
+			Expect(err).To(BeNil())
+
+			data, err := ioutil.ReadAll(r)
+			Expect(err).To(BeNil())
+			Expect(string(data)).To(Equal("first line\nsecond line\n"))
+			Expect(r.Close()).To(BeNil())
+		})
+
+		g.It("Should give up on a server which stops responding", func() {
+			fetcher.Timeout = time.Millisecond * 100
+			server.stall = true
+
+			start := time.Now()
+			_, err := fetcher.Size()
+			Expect(err).ToNot(BeNil())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+
+		g.It("Should be usable by a Tailer", func() {
+			tailer := New(fetcher)
+			tailer.PollInterval = time.Millisecond * 10
+			tailer.FromStart = true
+			defer tailer.Stop()
+
+			lines := make(chan string, 10)
+			Expect(tailer.Start(func(line string) { lines <- line })).To(BeNil())
+
+			Eventually(lines).Should(Receive(Equal("first line")))
+			Eventually(lines).Should(Receive(Equal("second line")))
+		})
+	})
+}
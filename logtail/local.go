@@ -0,0 +1,39 @@
+package logtail
+
+import (
+	"io"
+	"os"
+)
+
+// LocalFile is a Fetcher which reads a log file on the local filesystem.
+type LocalFile struct {
+	Path string
+}
+
+func (f *LocalFile) Size() (int64, error) {
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// The log file may not have been created yet, or it may be in the middle of being rotated.
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+func (f *LocalFile) ReadFrom(offset int64) (io.ReadCloser, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
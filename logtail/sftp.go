@@ -0,0 +1,387 @@
+package logtail
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"time"
+)
+
+// SFTPFile is a Fetcher which reads a log file over SFTP. Like FTPFile, a new SFTP session is opened for every request,
+// so no connection is held open between polls.
+//
+// Only the SFTP protocol is implemented here. The SSH connection is established by Connect, so this package doesn't
+// depend on golang.org/x/crypto/ssh.
+type SFTPFile struct {
+	// Connect opens a session on the SSH server and requests the sftp subsystem. The returned stream carries the stdin
+	// and stdout of the session, and closing it must close the session and the SSH connection. For example:
+	//
+	//	func() (io.ReadWriteCloser, error) {
+	//		client, err := ssh.Dial("tcp", "game.example.com:22", config)
+	//		if err != nil {
+	//			return nil, err
+	//		}
+	//		session, _ := client.NewSession()
+	//		stdin, _ := session.StdinPipe()
+	//		stdout, _ := session.StdoutPipe()
+	//		if err := session.RequestSubsystem("sftp"); err != nil {
+	//			client.Close()
+	//			return nil, err
+	//		}
+	//		return &logtail.SFTPStream{Reader: stdout, Writer: stdin, Closer: client}, nil
+	//	}
+	Connect func() (io.ReadWriteCloser, error)
+
+	// Path is the path of the log file on the SFTP server.
+	Path string
+
+	// Timeout is how long to wait for the server to answer each request. SSH sessions don't support deadlines, so the
+	// session is closed if the server doesn't answer in time.
+	//
+	// Default: 10s
+	Timeout time.Duration
+}
+
+const defaultSFTPTimeout = time.Second * 10
+
+// SFTPStream combines the stdout and stdin of an SSH session into the stream returned by SFTPFile.Connect.
+type SFTPStream struct {
+	io.Reader
+	io.Writer
+
+	// Closer is closed when the stream is closed, which is usually the *ssh.Client.
+	Closer io.Closer
+}
+
+func (s *SFTPStream) Close() error {
+	return s.Closer.Close()
+}
+
+// SFTP protocol version 3 packet types and status codes, as used by OpenSSH.
+const (
+	sftpInit    = 1
+	sftpVersion = 2
+	sftpOpen    = 3
+	sftpClose   = 4
+	sftpRead    = 5
+	sftpStat    = 17
+	sftpStatus  = 101
+	sftpHandle  = 102
+	sftpData    = 103
+	sftpAttrs   = 105
+
+	sftpStatusOK         = 0
+	sftpStatusEOF        = 1
+	sftpStatusNoSuchFile = 2
+
+	sftpProtocolVersion = 3
+	sftpFlagRead        = 1
+	sftpAttrSize        = 1
+
+	// sftpReadSize is the number of bytes requested by each read. OpenSSH limits reads to 256KiB, and many other
+	// servers to 32KiB.
+	sftpReadSize = 32 * 1024
+)
+
+// sftpStatusError is a status response which is not SSH_FX_OK.
+type sftpStatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *sftpStatusError) Error() string {
+	return fmt.Sprintf("sftp status %d: %s", e.Code, e.Message)
+}
+
+func (f *SFTPFile) Size() (int64, error) {
+	c, err := f.open()
+	if err != nil {
+		return 0, err
+	}
+	defer c.close()
+
+	typ, payload, err := c.request(sftpStat, sftpString(f.Path))
+	if err != nil {
+		if statusErr, ok := errors.Cause(err).(*sftpStatusError); ok && statusErr.Code == sftpStatusNoSuchFile {
+			// The log file does not exist yet.
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	if typ != sftpAttrs {
+		return 0, fmt.Errorf("unexpected sftp response type %d to STAT", typ)
+	}
+
+	flags, payload, ok := sftpUint32(payload)
+	if !ok || flags&sftpAttrSize == 0 || len(payload) < 8 {
+		return 0, errors.New("sftp server did not return the file size")
+	}
+
+	return int64(binary.BigEndian.Uint64(payload)), nil
+}
+
+func (f *SFTPFile) ReadFrom(offset int64) (io.ReadCloser, error) {
+	c, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+
+	// The flags are followed by empty attributes.
+	typ, payload, err := c.request(sftpOpen, sftpString(f.Path), sftpUint32Bytes(sftpFlagRead), sftpUint32Bytes(0))
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+
+	handle, _, ok := sftpReadString(payload)
+	if typ != sftpHandle || !ok {
+		c.close()
+		return nil, fmt.Errorf("unexpected sftp response type %d to OPEN", typ)
+	}
+
+	return &sftpReader{conn: c, handle: handle, offset: uint64(offset)}, nil
+}
+
+func (f *SFTPFile) open() (*sftpConn, error) {
+	if f.Connect == nil {
+		return nil, errors.New("SFTPFile.Connect is not set")
+	}
+
+	stream, err := f.Connect()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to sftp server")
+	}
+
+	timeout := f.Timeout
+	if timeout <= 0 {
+		timeout = defaultSFTPTimeout
+	}
+
+	c := &sftpConn{stream: stream, timeout: timeout}
+
+	var typ byte
+	err = c.withTimeout(func() error {
+		if err := c.send(sftpInit, sftpUint32Bytes(sftpProtocolVersion)); err != nil {
+			return err
+		}
+
+		typ, _, err = c.receive()
+		return err
+	})
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+
+	if typ != sftpVersion {
+		c.close()
+		return nil, fmt.Errorf("unexpected sftp response type %d to INIT", typ)
+	}
+
+	return c, nil
+}
+
+// sftpConn is an SFTP session. Requests are sent one at a time, so responses don't need to be matched to requests by
+// ID beyond checking that they belong to the last request.
+type sftpConn struct {
+	stream  io.ReadWriteCloser
+	timeout time.Duration
+	id      uint32
+}
+
+// request sends a request and returns the type and payload of its response, without the request ID. Status responses
+// other than SSH_FX_OK are returned as an *sftpStatusError.
+func (c *sftpConn) request(typ byte, fields ...[]byte) (byte, []byte, error) {
+	c.id++
+
+	var resType byte
+	var payload []byte
+	err := c.withTimeout(func() (err error) {
+		if err := c.send(typ, append([][]byte{sftpUint32Bytes(c.id)}, fields...)...); err != nil {
+			return err
+		}
+
+		resType, payload, err = c.receive()
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	id, payload, ok := sftpUint32(payload)
+	if !ok || id != c.id {
+		return 0, nil, errors.New("sftp response does not match the request")
+	}
+
+	if resType == sftpStatus {
+		code, rest, _ := sftpUint32(payload)
+		if code == sftpStatusOK {
+			return resType, nil, nil
+		}
+
+		msg, _, _ := sftpReadString(rest)
+		return resType, nil, &sftpStatusError{Code: code, Message: string(msg)}
+	}
+
+	return resType, payload, nil
+}
+
+// withTimeout runs fn, closing the stream if it doesn't return within the timeout so that a stalled server can't block
+// it forever.
+func (c *sftpConn) withTimeout(fn func() error) error {
+	if c.timeout <= 0 {
+		return fn()
+	}
+
+	timer := time.AfterFunc(c.timeout, c.close)
+	err := fn()
+
+	if !timer.Stop() {
+		return errors.New("sftp server did not respond in time")
+	}
+
+	return err
+}
+
+func (c *sftpConn) send(typ byte, fields ...[]byte) error {
+	length := 1
+	for _, field := range fields {
+		length += len(field)
+	}
+
+	buf := make([]byte, 0, 4+length)
+	buf = append(buf, sftpUint32Bytes(uint32(length))...)
+	buf = append(buf, typ)
+	for _, field := range fields {
+		buf = append(buf, field...)
+	}
+
+	if _, err := c.stream.Write(buf); err != nil {
+		return errors.Wrap(err, "could not send sftp packet")
+	}
+
+	return nil
+}
+
+func (c *sftpConn) receive() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.stream, header[:]); err != nil {
+		return 0, nil, errors.Wrap(err, "could not read sftp packet")
+	}
+
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > sftpReadSize+1024 {
+		return 0, nil, fmt.Errorf("invalid sftp packet length %d", length)
+	}
+
+	payload := make([]byte, length-1)
+	if _, err := io.ReadFull(c.stream, payload); err != nil {
+		return 0, nil, errors.Wrap(err, "could not read sftp packet")
+	}
+
+	return header[4], payload, nil
+}
+
+func (c *sftpConn) close() {
+	_ = c.stream.Close()
+}
+
+// sftpReader reads an open file until the server reports the end of the file. Closing it closes the file and ends the
+// session.
+type sftpReader struct {
+	conn   *sftpConn
+	handle []byte
+	offset uint64
+	buf    []byte
+	eof    bool
+}
+
+func (r *sftpReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+
+	return n, nil
+}
+
+// fill reads the next chunk of the file into the buffer. Servers shouldn't answer a READ with empty data, but if one
+// does, it is treated as the end of the file rather than sending the same READ again forever.
+func (r *sftpReader) fill() error {
+	typ, payload, err := r.conn.request(sftpRead, sftpString(string(r.handle)), sftpUint64Bytes(r.offset),
+		sftpUint32Bytes(sftpReadSize))
+	if err != nil {
+		if statusErr, ok := err.(*sftpStatusError); ok && statusErr.Code == sftpStatusEOF {
+			r.eof = true
+			return io.EOF
+		}
+
+		return err
+	}
+
+	data, _, ok := sftpReadString(payload)
+	if typ != sftpData || !ok {
+		return fmt.Errorf("unexpected sftp response type %d to READ", typ)
+	}
+
+	if len(data) == 0 {
+		r.eof = true
+		return io.EOF
+	}
+
+	r.buf = data
+	r.offset += uint64(len(data))
+
+	return nil
+}
+
+func (r *sftpReader) Close() error {
+	_, _, err := r.conn.request(sftpClose, sftpString(string(r.handle)))
+	r.conn.close()
+
+	return err
+}
+
+func sftpUint32Bytes(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func sftpUint64Bytes(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func sftpString(s string) []byte {
+	return append(sftpUint32Bytes(uint32(len(s))), s...)
+}
+
+func sftpUint32(b []byte) (uint32, []byte, bool) {
+	if len(b) < 4 {
+		return 0, b, false
+	}
+
+	return binary.BigEndian.Uint32(b), b[4:], true
+}
+
+func sftpReadString(b []byte) ([]byte, []byte, bool) {
+	length, rest, ok := sftpUint32(b)
+	if !ok || uint32(len(rest)) < length {
+		return nil, b, false
+	}
+
+	return rest[:length], rest[length:], true
+}
@@ -0,0 +1,223 @@
+package logtail
+
+import (
+	"encoding/binary"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// serveSFTP serves the parts of the SFTP protocol used by SFTPFile from the local filesystem. If emptyEOF is set, the end
+// of a file is reported using an empty DATA response instead of an EOF status, like some noncompliant servers do.
+func serveSFTP(conn net.Conn, emptyEOF bool) {
+	defer conn.Close()
+
+	c := &sftpConn{stream: conn}
+	files := map[string]*os.File{}
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+
+	status := func(id []byte, code uint32) error {
+		return c.send(sftpStatus, id, sftpUint32Bytes(code), sftpString("status"), sftpString(""))
+	}
+
+	for {
+		typ, payload, err := c.receive()
+		if err != nil {
+			return
+		}
+
+		if typ == sftpInit {
+			if c.send(sftpVersion, sftpUint32Bytes(sftpProtocolVersion)) != nil {
+				return
+			}
+			continue
+		}
+
+		id, payload := payload[:4], payload[4:]
+		arg, rest, _ := sftpReadString(payload)
+
+		switch typ {
+		case sftpStat:
+			info, err := os.Stat(string(arg))
+			if err != nil {
+				err = status(id, sftpStatusNoSuchFile)
+			} else {
+				err = c.send(sftpAttrs, id, sftpUint32Bytes(sftpAttrSize), sftpUint64Bytes(uint64(info.Size())))
+			}
+			if err != nil {
+				return
+			}
+		case sftpOpen:
+			f, err := os.Open(string(arg))
+			if err != nil {
+				err = status(id, sftpStatusNoSuchFile)
+			} else {
+				files[f.Name()] = f
+				err = c.send(sftpHandle, id, sftpString(f.Name()))
+			}
+			if err != nil {
+				return
+			}
+		case sftpRead:
+			offset := binary.BigEndian.Uint64(rest)
+			length := binary.BigEndian.Uint32(rest[8:])
+
+			// Return less than was requested to check that short reads are continued.
+			if length > 4 {
+				length = 4
+			}
+
+			buf := make([]byte, length)
+			n, err := files[string(arg)].ReadAt(buf, int64(offset))
+			if n == 0 && err == io.EOF && !emptyEOF {
+				err = status(id, sftpStatusEOF)
+			} else {
+				err = c.send(sftpData, id, sftpString(string(buf[:n])))
+			}
+			if err != nil {
+				return
+			}
+		case sftpClose:
+			_ = files[string(arg)].Close()
+			delete(files, string(arg))
+			if status(id, sftpStatusOK) != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func TestSFTPFile(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("SFTPFile", func() {
+		var dir string
+		var path string
+		var fetcher *SFTPFile
+		var sessions int
+		var emptyEOF bool
+
+		g.BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "logtail")
+			Expect(err).To(BeNil())
+
+			path = filepath.Join(dir, "server.log")
+			Expect(ioutil.WriteFile(path, []byte("first line\nsecond line\n"), 0644)).To(BeNil())
+
+			sessions = 0
+			emptyEOF = false
+			fetcher = &SFTPFile{
+				Connect: func() (io.ReadWriteCloser, error) {
+					sessions++
+					client, server := net.Pipe()
+					go serveSFTP(server, emptyEOF)
+					return client, nil
+				},
+				Path: path,
+			}
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(dir)
+		})
+
+		g.It("Should return the size of the file", func() {
+			size, err := fetcher.Size()
+			Expect(err).To(BeNil())
+			Expect(size).To(Equal(int64(23)))
+		})
+
+		g.It("Should return a size of 0 if the file does not exist", func() {
+			fetcher.Path = filepath.Join(dir, "missing.log")
+
+			size, err := fetcher.Size()
+			Expect(err).To(BeNil())
+			Expect(size).To(Equal(int64(0)))
+		})
+
+		g.It("Should read the file from the offset", func() {
+			r, err := fetcher.ReadFrom(11)
+			Expect(err).To(BeNil())
+
+			data, err := ioutil.ReadAll(r)
+			Expect(err).To(BeNil())
+			Expect(string(data)).To(Equal("second line\n"))
+			Expect(r.Close()).To(BeNil())
+		})
+
+		g.It("Should treat an empty DATA response as the end of the file", func() {
+			emptyEOF = true
+
+			r, err := fetcher.ReadFrom(11)
+			Expect(err).To(BeNil())
+
+			data, err := ioutil.ReadAll(r)
+			Expect(err).To(BeNil())
+			Expect(string(data)).To(Equal("second line\n"))
+			Expect(r.Close()).To(BeNil())
+		})
+
+		g.It("Should return an error if the file cannot be opened", func() {
+			fetcher.Path = filepath.Join(dir, "missing.log")
+
+			_, err := fetcher.ReadFrom(0)
+			Expect(err).ToNot(BeNil())
+		})
+
+		g.It("Should give up on a server which stops responding", func() {
+			fetcher.Timeout = time.Millisecond * 50
+			fetcher.Connect = func() (io.ReadWriteCloser, error) {
+				client, server := net.Pipe()
+				go func() {
+					_, _ = io.Copy(ioutil.Discard, server)
+				}()
+				return client, nil
+			}
+
+			start := time.Now()
+			_, err := fetcher.Size()
+			Expect(err).ToNot(BeNil())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+
+		g.It("Should open a new session for every request", func() {
+			_, err := fetcher.Size()
+			Expect(err).To(BeNil())
+
+			r, err := fetcher.ReadFrom(0)
+			Expect(err).To(BeNil())
+			Expect(r.Close()).To(BeNil())
+
+			Expect(sessions).To(Equal(2))
+		})
+
+		g.It("Should be usable by a Tailer", func() {
+			tailer := New(fetcher)
+			tailer.PollInterval = time.Millisecond * 10
+			tailer.FromStart = true
+			defer tailer.Stop()
+
+			lines := make(chan string, 10)
+			Expect(tailer.Start(func(line string) { lines <- line })).To(BeNil())
+
+			Eventually(lines).Should(Receive(Equal("first line")))
+			Eventually(lines).Should(Receive(Equal("second line")))
+		})
+	})
+}
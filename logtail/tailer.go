@@ -0,0 +1,185 @@
+// Package logtail provides a broadcast source which tails a game server's log file. It is intended for games which only
+// emit events to a log file while their RCON implementation is command-only.
+//
+// A Tailer reads the log file through a Fetcher, so the file can be local (LocalFile) or on a remote host (FTPFile or
+// SFTPFile).
+package logtail
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// Fetcher provides access to a log file which is only ever appended to, truncated or replaced.
+type Fetcher interface {
+	// Size returns the current size of the log file.
+	Size() (int64, error)
+
+	// ReadFrom returns a reader which reads the log file from the provided offset to its end.
+	ReadFrom(offset int64) (io.ReadCloser, error)
+}
+
+const DefaultPollInterval = time.Second
+
+// Tailer polls a log file for new lines and emits each complete line. It implements rcon.BroadcastSource.
+//
+// If the log file shrinks, it is assumed to have been truncated or rotated and is read again from the start.
+type Tailer struct {
+	fetcher Fetcher
+
+	// PollInterval is how often the log file is checked for new lines.
+	//
+	// Default: 1s
+	PollInterval time.Duration
+
+	// FromStart controls whether the existing contents of the log file are emitted when the tailer is first started.
+	// By default, only lines written after the tailer was started are emitted.
+	FromStart bool
+
+	// ErrorHandler is called if the log file could not be read. If nil, errors are ignored.
+	ErrorHandler func(error)
+
+	mu          sync.Mutex
+	initialized bool
+	offset      int64
+	partial     []byte
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+func New(fetcher Fetcher) *Tailer {
+	return &Tailer{
+		fetcher:      fetcher,
+		PollInterval: DefaultPollInterval,
+	}
+}
+
+// Start starts polling the log file in a new goroutine. The read position is kept between a Stop and the next Start,
+// so lines written while the tailer was stopped are emitted once it is started again.
+func (t *Tailer) Start(emit func(message string)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stop != nil {
+		return errors.New("tailer already started")
+	}
+
+	if !t.initialized {
+		if !t.FromStart {
+			size, err := t.fetcher.Size()
+			if err != nil {
+				return errors.Wrap(err, "could not get log file size")
+			}
+
+			t.offset = size
+		}
+
+		t.initialized = true
+	}
+
+	interval := t.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	t.stop = make(chan struct{})
+	t.done = make(chan struct{})
+
+	go t.run(emit, interval, t.stop, t.done)
+
+	return nil
+}
+
+// Stop stops polling the log file and waits for the polling goroutine to exit.
+func (t *Tailer) Stop() error {
+	t.mu.Lock()
+	stop, done := t.stop, t.done
+	t.stop, t.done = nil, nil
+	t.mu.Unlock()
+
+	if stop == nil {
+		return nil
+	}
+
+	close(stop)
+	<-done
+
+	return nil
+}
+
+func (t *Tailer) run(emit func(string), interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.poll(emit); err != nil && t.ErrorHandler != nil {
+			t.ErrorHandler(err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (t *Tailer) poll(emit func(string)) error {
+	size, err := t.fetcher.Size()
+	if err != nil {
+		return errors.Wrap(err, "could not get log file size")
+	}
+
+	if size < t.offset {
+		// The file was truncated or rotated, so start reading it from the beginning again.
+		t.offset = 0
+		t.partial = nil
+	}
+
+	if size == t.offset {
+		return nil
+	}
+
+	r, err := t.fetcher.ReadFrom(t.offset)
+	if err != nil {
+		return errors.Wrap(err, "could not open log file")
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	t.offset += int64(len(data))
+	t.emitLines(data, emit)
+
+	if err != nil {
+		return errors.Wrap(err, "could not read log file")
+	}
+
+	return nil
+}
+
+// emitLines emits each complete line in data. Any trailing incomplete line is kept until the rest of it is read.
+func (t *Tailer) emitLines(data []byte, emit func(string)) {
+	data = append(t.partial, data...)
+
+	for {
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			break
+		}
+
+		line := bytes.TrimRight(data[:idx], "\r")
+		data = data[idx+1:]
+
+		if len(line) > 0 {
+			emit(string(line))
+		}
+	}
+
+	t.partial = append([]byte(nil), data...)
+}
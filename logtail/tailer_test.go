@@ -0,0 +1,87 @@
+package logtail
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Tailer", func() {
+		var dir string
+		var path string
+		var tailer *Tailer
+		var lines chan string
+
+		g.BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "logtail")
+			Expect(err).To(BeNil())
+
+			path = filepath.Join(dir, "server.log")
+			Expect(ioutil.WriteFile(path, []byte("existing line\n"), 0644)).To(BeNil())
+
+			tailer = New(&LocalFile{Path: path})
+			tailer.PollInterval = time.Millisecond * 10
+			lines = make(chan string, 10)
+		})
+
+		g.AfterEach(func() {
+			_ = tailer.Stop()
+			_ = os.RemoveAll(dir)
+		})
+
+		appendToFile := func(data string) {
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+			Expect(err).To(BeNil())
+			_, err = f.WriteString(data)
+			Expect(err).To(BeNil())
+			Expect(f.Close()).To(BeNil())
+		}
+
+		emit := func(line string) {
+			lines <- line
+		}
+
+		g.It("Should only emit lines written after starting", func() {
+			Expect(tailer.Start(emit)).To(BeNil())
+
+			appendToFile("new line\n")
+			Eventually(lines).Should(Receive(Equal("new line")))
+			Consistently(lines, time.Millisecond*50).ShouldNot(Receive())
+		})
+
+		g.It("Should emit existing lines if FromStart is set", func() {
+			tailer.FromStart = true
+			Expect(tailer.Start(emit)).To(BeNil())
+
+			Eventually(lines).Should(Receive(Equal("existing line")))
+		})
+
+		g.It("Should wait for incomplete lines to be completed", func() {
+			Expect(tailer.Start(emit)).To(BeNil())
+
+			appendToFile("partial")
+			Consistently(lines, time.Millisecond*50).ShouldNot(Receive())
+
+			appendToFile(" line\r\n")
+			Eventually(lines).Should(Receive(Equal("partial line")))
+		})
+
+		g.It("Should read from the start after the file is truncated", func() {
+			Expect(tailer.Start(emit)).To(BeNil())
+
+			Expect(ioutil.WriteFile(path, []byte("rotated\n"), 0644)).To(BeNil())
+			Eventually(lines).Should(Receive(Equal("rotated")))
+		})
+	})
+}
@@ -0,0 +1,130 @@
+package rcon
+
+import (
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// macroParam matches the parameters of macro commands: $1 to $9 are the arguments the macro was invoked with, and $*
+// is every argument joined by spaces.
+var macroParam = regexp.MustCompile(`\$([1-9*])`)
+
+// RegisterMacro registers a macro which expands into the provided commands, replacing any macro of the same name. The
+// commands may reference the macro's arguments as $1 to $9, and all arguments as $*. For example, a "kickspam" macro
+// could consist of "kick $1 spamming" followed by "say $1 was kicked for spamming".
+func (c *Client) RegisterMacro(name string, commands ...string) error {
+	if name == "" || strings.ContainsAny(name, " \t\r\n") {
+		return errors.Wrapf(errs.ErrInvalidMacro, "invalid name %q", name)
+	}
+
+	if len(commands) == 0 {
+		return errors.Wrapf(errs.ErrInvalidMacro, "macro %s has no commands", name)
+	}
+
+	c.macroLock.Lock()
+	defer c.macroLock.Unlock()
+
+	if c.macros == nil {
+		c.macros = map[string][]string{}
+	}
+	c.macros[name] = append([]string{}, commands...)
+
+	return nil
+}
+
+// RegisteredMacros returns a copy of the registered macros, keyed by name.
+func (c *Client) RegisteredMacros() map[string][]string {
+	c.macroLock.Lock()
+	defer c.macroLock.Unlock()
+
+	macros := make(map[string][]string, len(c.macros))
+	for name, commands := range c.macros {
+		macros[name] = append([]string{}, commands...)
+	}
+
+	return macros
+}
+
+// RemoveMacro removes a macro. It is a no-op if no macro of that name is registered.
+func (c *Client) RemoveMacro(name string) {
+	c.macroLock.Lock()
+	defer c.macroLock.Unlock()
+
+	delete(c.macros, name)
+}
+
+// ExpandMacro expands an invocation such as "kickspam Bob" into the commands of the macro, with its parameters
+// substituted. An error wrapping errs.ErrUnknownMacro is returned if no such macro is registered, and one wrapping
+// errs.ErrInvalidMacro if a parameter wasn't provided.
+func (c *Client) ExpandMacro(invocation string) ([]string, error) {
+	fields := strings.Fields(invocation)
+	if len(fields) == 0 {
+		return nil, errors.Wrap(errs.ErrUnknownMacro, "empty invocation")
+	}
+
+	name, args := fields[0], fields[1:]
+
+	c.macroLock.Lock()
+	templates, ok := c.macros[name]
+	c.macroLock.Unlock()
+
+	if !ok {
+		return nil, errors.Wrapf(errs.ErrUnknownMacro, "macro %s", name)
+	}
+
+	commands := make([]string, len(templates))
+	for i, template := range templates {
+		var missing error
+
+		commands[i] = macroParam.ReplaceAllStringFunc(template, func(param string) string {
+			if param == "$*" {
+				return strings.Join(args, " ")
+			}
+
+			n, _ := strconv.Atoi(param[1:])
+			if n > len(args) {
+				missing = errors.Wrapf(errs.ErrInvalidMacro, "macro %s needs argument %d", name, n)
+				return param
+			}
+
+			return args[n-1]
+		})
+
+		if missing != nil {
+			return nil, missing
+		}
+	}
+
+	return commands, nil
+}
+
+// ExecMacro expands a macro invocation and executes the resulting commands in order. Each command goes through
+// ExecCommand, so it is traced, recorded in the command history and subject to dry-run mode like any other command.
+//
+// If a command fails, execution stops and the results of the commands executed so far are returned along with the
+// error. Result.Line is the position of the command within the macro, starting at 1.
+func (c *Client) ExecMacro(invocation string, opts ...ExecOption) ([]Result, error) {
+	commands, err := c.ExpandMacro(invocation)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(commands))
+	for i, command := range commands {
+		res, err := c.ExecCommand(command, opts...)
+		if err != nil {
+			return results, errors.Wrapf(err, "macro command %d", i+1)
+		}
+
+		results = append(results, Result{
+			Line:     i + 1,
+			Command:  command,
+			Response: res,
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,44 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"testing"
+)
+
+func TestMacro(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Macros", func() {
+		g.It("Should expand parameters and execute every command", func() {
+			c := NewClient(&Config{
+				DryRun:      true,
+				HistorySize: 10,
+				Macros: map[string][]string{
+					"kickspam": {"kick $1 spamming", "say $1 was kicked: $*"},
+				},
+			}, nil)
+
+			Expect(c.ExpandMacro("kickspam Bob again")).To(Equal([]string{
+				"kick Bob spamming",
+				"say Bob was kicked: Bob again",
+			}))
+
+			results, err := c.ExecMacro("kickspam Bob")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+			Expect(c.History()[1].Command).To(Equal("say Bob was kicked: Bob"))
+
+			_, err = c.ExpandMacro("kickspam")
+			Expect(errors.Cause(err)).To(Equal(errs.ErrInvalidMacro))
+
+			_, err = c.ExecMacro("banspam Bob")
+			Expect(errors.Cause(err)).To(Equal(errs.ErrUnknownMacro))
+		})
+	})
+}
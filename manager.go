@@ -0,0 +1,397 @@
+package rcon
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultFanOutConcurrency is the default number of servers a Manager executes a fan-out command on at once.
+const DefaultFanOutConcurrency = 10
+
+// ManagerConfig configures a Manager.
+type ManagerConfig struct {
+	// Concurrency is the maximum number of servers a fan-out command is executed on at once.
+	//
+	// Default: DefaultFanOutConcurrency
+	Concurrency int
+
+	// Timeout is the amount of time each server gets to respond to a fan-out command. If zero, only the clients'
+	// QueueReadTimeout applies.
+	Timeout time.Duration
+}
+
+// Manager manages clients for a fleet of servers, identified by name. It is used to execute commands on many servers
+// at once, and to listen for the broadcasts of many servers. Servers can be tagged, for example with their region, so
+// that commands and subscriptions can target a group of servers using ParseTagSelector.
+type Manager struct {
+	config ManagerConfig
+
+	lock    sync.RWMutex
+	servers map[string]*ManagedServer
+
+	// nextRegistration identifies each call to add, so that the broadcast listeners of removed servers can be told
+	// apart from those of servers later registered under the same name.
+	nextRegistration uint64
+
+	// groups maps endpoint group names to the names of their servers.
+	groups map[string][]string
+
+	subsLock   sync.RWMutex
+	nextSubID  int
+	broadcasts []broadcastSubscription
+}
+
+// ManagedServer is a server registered with a Manager.
+type ManagedServer struct {
+	Name   string
+	Client *Client
+
+	// Tags describe the server, for example {"region": "eu", "game": "mordhau"}. They must not be modified, use
+	// Manager.SetTags instead.
+	Tags map[string]string
+
+	// registration identifies the call to Manager.add that registered the server. It survives SetTags.
+	registration uint64
+}
+
+// ManagedBroadcastListener is called with the broadcasts of the servers a Manager subscription selected.
+type ManagedBroadcastListener func(server *ManagedServer, b Broadcast)
+
+type broadcastSubscription struct {
+	id       int
+	selector Selector
+	listener ManagedBroadcastListener
+
+	// name and expr are set for subscriptions made using SubscribeTagged, which are included in snapshots.
+	name string
+	expr string
+}
+
+// Selector decides whether a fan-out command is executed on a server.
+type Selector func(server *ManagedServer) bool
+
+// ServerResult is the outcome of a fan-out command on a single server.
+type ServerResult struct {
+	Response string
+	Err      error
+	Duration time.Duration
+}
+
+// FanOutResults holds the outcome of a fan-out command on each server, keyed by server name.
+type FanOutResults map[string]ServerResult
+
+// Failed returns the sorted names of the servers the command failed on.
+func (r FanOutResults) Failed() []string {
+	var names []string
+	for name, res := range r {
+		if res.Err != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Err returns an *errs.PartialFailureError holding the error of each failed server, or nil if the command succeeded on
+// every server.
+func (r FanOutResults) Err() error {
+	failed := map[string]error{}
+	for name, res := range r {
+		if res.Err != nil {
+			failed[name] = res.Err
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return &errs.PartialFailureError{Errors: failed, Total: len(r)}
+}
+
+// NewManager creates an empty Manager.
+func NewManager(config ManagerConfig) *Manager {
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultFanOutConcurrency
+	}
+
+	return &Manager{
+		config:  config,
+		servers: map[string]*ManagedServer{},
+		groups:  map[string][]string{},
+	}
+}
+
+// Add registers a client under a name. If a server with the same name is already registered, ErrServerExists is
+// returned. The Manager doesn't connect the client, but it adds a broadcast listener to it, so clients should be added
+// before they are connected.
+func (m *Manager) Add(name string, client *Client) error {
+	return m.AddTagged(name, client, nil)
+}
+
+// AddTagged registers a client under a name with tags, like Add.
+func (m *Manager) AddTagged(name string, client *Client, tags map[string]string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.servers[name]; ok {
+		return errors.Wrapf(errs.ErrServerExists, "server %s", name)
+	}
+
+	m.add(name, client, tags)
+
+	return nil
+}
+
+// add must be called with the manager's lock held.
+func (m *Manager) add(name string, client *Client, tags map[string]string) {
+	m.nextRegistration++
+
+	server := &ManagedServer{
+		Name:         name,
+		Client:       client,
+		Tags:         copyTags(tags),
+		registration: m.nextRegistration,
+	}
+	m.servers[name] = server
+
+	client.AddBroadcastListener(func(b Broadcast) {
+		m.relayBroadcast(server, b)
+	})
+}
+
+// AddConfigs creates and registers a client for each server config, such as those returned by LoadConfigFromFile,
+// using the tags from the config. If a server name is already registered, no clients are added.
+func (m *Manager) AddConfigs(configs []*ServerConfig, logger Logger) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, sc := range configs {
+		if _, ok := m.servers[sc.Name]; ok {
+			return errors.Wrapf(errs.ErrServerExists, "server %s", sc.Name)
+		}
+	}
+
+	for _, sc := range configs {
+		m.add(sc.Name, NewClient(sc.Config, logger), sc.Tags)
+	}
+
+	return nil
+}
+
+// Remove unregisters a server and returns its client, or nil if no server with the name is registered. The client is
+// not closed.
+func (m *Manager) Remove(name string) *Client {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	server, ok := m.servers[name]
+	if !ok {
+		return nil
+	}
+
+	delete(m.servers, name)
+
+	return server.Client
+}
+
+// SetTags replaces the tags of a registered server. If no server with the name is registered, ErrServerNotFound is
+// returned.
+func (m *Manager) SetTags(name string, tags map[string]string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	server, ok := m.servers[name]
+	if !ok {
+		return errors.Wrapf(errs.ErrServerNotFound, "server %s", name)
+	}
+
+	// The server is replaced rather than modified, so that ManagedServers passed to selectors and listeners never
+	// change.
+	updated := *server
+	updated.Tags = copyTags(tags)
+	m.servers[name] = &updated
+
+	return nil
+}
+
+// Client returns the client of a registered server.
+func (m *Manager) Client(name string) (*Client, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	server, ok := m.servers[name]
+	if !ok {
+		return nil, false
+	}
+
+	return server.Client, true
+}
+
+// Names returns the sorted names of the registered servers.
+func (m *Manager) Names() []string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	names := make([]string, 0, len(m.servers))
+	for name := range m.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ExecOnAll executes a command on every registered server and returns the outcome for each of them. Use the Err method
+// of the results to check whether the command failed on any server.
+func (m *Manager) ExecOnAll(ctx context.Context, command string, opts ...ExecOption) FanOutResults {
+	return m.execOn(ctx, nil, command, opts)
+}
+
+// ExecOnMatching executes a command on the registered servers the selector returns true for, like ExecOnAll.
+func (m *Manager) ExecOnMatching(ctx context.Context, selector Selector, command string,
+	opts ...ExecOption) FanOutResults {
+	return m.execOn(ctx, selector, command, opts)
+}
+
+// ExecOnTagged executes a command on the registered servers matching a tag expression, like ExecOnAll. See
+// ParseTagSelector for the syntax of tag expressions.
+func (m *Manager) ExecOnTagged(ctx context.Context, expr, command string, opts ...ExecOption) (FanOutResults, error) {
+	selector, err := ParseTagSelector(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.execOn(ctx, selector, command, opts), nil
+}
+
+// SubscribeBroadcasts calls listener with the broadcasts of every registered server the selector returns true for,
+// including servers registered later. If selector is nil, broadcasts of all servers are delivered. Listeners are called
+// on the reader routine of the server's client, so they must not block.
+func (m *Manager) SubscribeBroadcasts(selector Selector, listener ManagedBroadcastListener) (unsubscribe func()) {
+	return m.subscribe("", "", selector, listener)
+}
+
+func (m *Manager) subscribe(name, expr string, selector Selector, listener ManagedBroadcastListener) func() {
+	m.subsLock.Lock()
+	defer m.subsLock.Unlock()
+
+	sub := broadcastSubscription{
+		id:       m.nextSubID,
+		selector: selector,
+		listener: listener,
+		name:     name,
+		expr:     expr,
+	}
+	m.nextSubID++
+
+	m.broadcasts = append(m.broadcasts, sub)
+
+	return func() {
+		m.subsLock.Lock()
+		defer m.subsLock.Unlock()
+
+		for i, s := range m.broadcasts {
+			if s.id == sub.id {
+				m.broadcasts = append(m.broadcasts[:i:i], m.broadcasts[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// relayBroadcast delivers a broadcast of a server to the matching subscriptions.
+func (m *Manager) relayBroadcast(server *ManagedServer, b Broadcast) {
+	// The server may have been removed, re-tagged or removed and registered again since the listener was added. Clients
+	// don't support removing listeners, so the listeners of earlier registrations are ignored here instead.
+	m.lock.RLock()
+	current, ok := m.servers[server.Name]
+	m.lock.RUnlock()
+
+	if !ok || current.registration != server.registration {
+		return
+	}
+
+	m.subsLock.RLock()
+	var listeners []ManagedBroadcastListener
+	for _, sub := range m.broadcasts {
+		if sub.selector == nil || sub.selector(current) {
+			listeners = append(listeners, sub.listener)
+		}
+	}
+	m.subsLock.RUnlock()
+
+	for _, listener := range listeners {
+		current.Client.callHandler("manager broadcast listener", func() {
+			listener(current, b)
+		})
+	}
+}
+
+// execOn executes a command on the selected servers, at most config.Concurrency at a time. If selector is nil, every
+// server is selected.
+func (m *Manager) execOn(ctx context.Context, selector Selector, command string, opts []ExecOption) FanOutResults {
+	m.lock.RLock()
+	var targets []*ManagedServer
+	for _, server := range m.servers {
+		if selector == nil || selector(server) {
+			targets = append(targets, server)
+		}
+	}
+	m.lock.RUnlock()
+
+	results := make(FanOutResults, len(targets))
+	var resultsLock sync.Mutex
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, m.config.Concurrency)
+
+	for _, server := range targets {
+		wg.Add(1)
+
+		go func(server *ManagedServer) {
+			defer wg.Done()
+
+			var res ServerResult
+
+			select {
+			case sem <- struct{}{}:
+				res = m.execOnServer(ctx, server, command, opts)
+				<-sem
+			case <-ctx.Done():
+				res = ServerResult{Err: ctx.Err()}
+			}
+
+			resultsLock.Lock()
+			results[server.Name] = res
+			resultsLock.Unlock()
+		}(server)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// execOnServer executes a command on a single server, applying the per-server timeout.
+func (m *Manager) execOnServer(ctx context.Context, s *ManagedServer, cmd string, opts []ExecOption) ServerResult {
+	if m.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.config.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	res, err := s.Client.ExecCommandContext(ctx, cmd, opts...)
+
+	return ServerResult{
+		Response: res,
+		Err:      err,
+		Duration: time.Since(start),
+	}
+}
@@ -0,0 +1,176 @@
+package rcon
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Manager", func() {
+		g.It("Should report the outcome of fan-out commands per server", func() {
+			m := NewManager(ManagerConfig{Concurrency: 1})
+			Expect(m.Add("eu-1", NewClient(&Config{}, nil))).To(Succeed())
+			Expect(m.Add("us-1", NewClient(&Config{}, nil))).To(Succeed())
+			Expect(errors.Cause(m.Add("eu-1", NewClient(&Config{}, nil)))).To(Equal(errs.ErrServerExists))
+
+			results := m.ExecOnMatching(context.Background(), func(s *ManagedServer) bool {
+				return s.Name == "eu-1"
+			}, "say hi")
+
+			Expect(results).To(HaveLen(1))
+			Expect(results.Failed()).To(Equal([]string{"eu-1"}))
+			Expect(errors.Cause(results["eu-1"].Err)).To(Equal(errs.ErrNotConnected))
+
+			err := m.ExecOnAll(context.Background(), "say hi").Err()
+			Expect(err).To(BeAssignableToTypeOf(&errs.PartialFailureError{}))
+			Expect(err.(*errs.PartialFailureError).Errors).To(HaveLen(2))
+		})
+
+		g.It("Should stop waiting for matching servers once ctx is done", func() {
+			s := newTestServer(func(command string) string {
+				time.Sleep(time.Millisecond * 500)
+				return "echo: " + command
+			})
+			defer s.Close()
+
+			c := newTestClient(s, &Config{QueueReadTimeout: time.Second})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			m := NewManager(ManagerConfig{})
+			Expect(m.Add("eu-1", c)).To(Succeed())
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+			defer cancel()
+
+			results := m.ExecOnMatching(ctx, func(*ManagedServer) bool { return true }, "say hi")
+			Expect(errors.Cause(results["eu-1"].Err)).To(Equal(context.DeadlineExceeded))
+		})
+
+		g.It("Should select servers using tag expressions", func() {
+			m := NewManager(ManagerConfig{})
+			Expect(m.AddTagged("eu-1", NewClient(&Config{}, nil), map[string]string{"region": "eu", "game": "mordhau"})).To(Succeed())
+			Expect(m.AddTagged("eu-2", NewClient(&Config{}, nil), map[string]string{"region": "eu", "maintenance": ""})).To(Succeed())
+			Expect(m.AddTagged("us-1", NewClient(&Config{}, nil), map[string]string{"region": "us", "game": "mordhau"})).To(Succeed())
+
+			selected := func(expr string) []string {
+				results, err := m.ExecOnTagged(context.Background(), expr, "say hi")
+				Expect(err).ToNot(HaveOccurred())
+
+				return results.Failed()
+			}
+
+			Expect(selected("region=eu")).To(Equal([]string{"eu-1", "eu-2"}))
+			Expect(selected("region=eu|us, game=mordhau")).To(Equal([]string{"eu-1", "us-1"}))
+			Expect(selected("region!=us,!maintenance")).To(Equal([]string{"eu-1"}))
+			Expect(selected("maintenance")).To(Equal([]string{"eu-2"}))
+			Expect(selected("")).To(HaveLen(3))
+
+			_, err := m.ExecOnTagged(context.Background(), "region=", "say hi")
+			Expect(errors.Cause(err)).To(Equal(errs.ErrInvalidSelector))
+		})
+
+		g.It("Should relay broadcasts of matching servers", func() {
+			m := NewManager(ManagerConfig{})
+			eu := NewClient(&Config{}, nil)
+			us := NewClient(&Config{}, nil)
+			Expect(m.AddTagged("eu-1", eu, map[string]string{"region": "eu"})).To(Succeed())
+			Expect(m.AddTagged("us-1", us, map[string]string{"region": "us"})).To(Succeed())
+
+			var received []string
+			unsubscribe := m.SubscribeBroadcasts(MustParseTagSelector("region=eu"), func(s *ManagedServer, b Broadcast) {
+				received = append(received, s.Name+": "+b.Message)
+			})
+
+			eu.handleBroadcast("hello")
+			us.handleBroadcast("ignored")
+
+			Expect(m.SetTags("us-1", map[string]string{"region": "eu"})).To(Succeed())
+			us.handleBroadcast("moved")
+
+			unsubscribe()
+			eu.handleBroadcast("unsubscribed")
+
+			Expect(received).To(Equal([]string{"eu-1: hello", "us-1: moved"}))
+		})
+
+		g.It("Should relay broadcasts once after a server is removed and added again", func() {
+			m := NewManager(ManagerConfig{})
+			c := NewClient(&Config{}, nil)
+			Expect(m.Add("eu-1", c)).To(Succeed())
+
+			var received []string
+			m.SubscribeBroadcasts(nil, func(s *ManagedServer, b Broadcast) {
+				received = append(received, s.Name+": "+b.Message)
+			})
+
+			Expect(m.Remove("eu-1")).To(Equal(c))
+			c.handleBroadcast("removed")
+
+			Expect(m.Add("eu-1", c)).To(Succeed())
+			c.handleBroadcast("re-added")
+
+			Expect(m.SetTags("eu-1", map[string]string{"region": "eu"})).To(Succeed())
+			c.handleBroadcast("re-tagged")
+
+			Expect(received).To(Equal([]string{"eu-1: re-added", "eu-1: re-tagged"}))
+		})
+
+		g.It("Should restore snapshots without plaintext passwords", func() {
+			Expect(os.Setenv("RCON_TEST_EU_1_PASSWORD", "secret")).To(Succeed())
+			defer os.Unsetenv("RCON_TEST_EU_1_PASSWORD")
+
+			secrets := EnvSecretStore{Prefix: "RCON_TEST"}
+
+			m := NewManager(ManagerConfig{})
+			Expect(m.AddTagged("eu-1", NewClient(&Config{Host: "10.0.0.1", Port: 7778, Password: "secret"}, nil),
+				map[string]string{"region": "eu"})).To(Succeed())
+
+			listener := func(s *ManagedServer, b Broadcast) {}
+			_, err := m.SubscribeTagged("chat", "region=eu", listener)
+			Expect(err).ToNot(HaveOccurred())
+
+			snapshot, err := m.Snapshot(secrets)
+			Expect(err).ToNot(HaveOccurred())
+
+			data, err := json.Marshal(snapshot)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).ToNot(ContainSubstring("secret"))
+
+			var decoded ManagerSnapshot
+			Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+
+			restored := NewManager(ManagerConfig{})
+			err = restored.Restore(&decoded, RestoreOptions{Secrets: secrets})
+			Expect(errors.Cause(err)).To(Equal(errs.ErrMissingListener))
+			Expect(restored.Names()).To(BeEmpty())
+
+			err = restored.Restore(&decoded, RestoreOptions{
+				Secrets:   secrets,
+				Listeners: map[string]ManagedBroadcastListener{"chat": listener},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			c, ok := restored.Client("eu-1")
+			Expect(ok).To(BeTrue())
+			Expect(c.Password).To(Equal("secret"))
+			Expect(c.addressString()).To(Equal("10.0.0.1:7778"))
+
+			resnapshot, err := restored.Snapshot(secrets)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resnapshot).To(Equal(snapshot))
+		})
+	})
+}
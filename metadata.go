@@ -0,0 +1,141 @@
+package rcon
+
+import (
+	"context"
+	"sort"
+)
+
+// Span attribute keys set from call metadata. Labels are recorded with the AttrLabelPrefix followed by the label key.
+const (
+	AttrInitiator   = "rcon.initiator"
+	AttrReason      = "rcon.reason"
+	AttrLabelPrefix = "rcon.label."
+)
+
+// CallMetadata describes who executed a command and why. It is recorded in traces and the command history, which
+// allows actions to be attributed when many admins share a client.
+type CallMetadata struct {
+	// Initiator identifies who executed the command, for example "admin:42".
+	Initiator string
+
+	// Reason is a free-form explanation of why the command was executed.
+	Reason string
+
+	// Labels are arbitrary key-value pairs, for example a tenant ID.
+	Labels map[string]string
+}
+
+// ExecOption sets per-call options when executing a command.
+type ExecOption func(o *execOptions)
+
+// execOptions holds the options of a single call.
+type execOptions struct {
+	metadata   CallMetadata
+	retry      *RetryPolicy
+	idempotent *bool
+
+	// dryRun overrides the client's DryRun option if set.
+	dryRun *bool
+
+	// arrivalSeq is set to the arrival sequence number of the response once it was received.
+	arrivalSeq uint64
+
+	// noResponse makes the command return once it was queued, without waiting for a response. If confirm is set too,
+	// it returns once the server confirmed processing it.
+	noResponse bool
+	confirm    bool
+
+	// raw makes the command return the response body untrimmed. It is set by ExecCommandRaw.
+	raw bool
+}
+
+// WithInitiator sets who executed the command.
+func WithInitiator(initiator string) ExecOption {
+	return func(o *execOptions) {
+		o.metadata.Initiator = initiator
+	}
+}
+
+// WithReason sets why the command was executed.
+func WithReason(reason string) ExecOption {
+	return func(o *execOptions) {
+		o.metadata.Reason = reason
+	}
+}
+
+// WithLabel adds a label to the call.
+func WithLabel(key, value string) ExecOption {
+	return func(o *execOptions) {
+		if o.metadata.Labels == nil {
+			o.metadata.Labels = map[string]string{}
+		}
+
+		o.metadata.Labels[key] = value
+	}
+}
+
+type metadataKey struct{}
+
+// MetadataFromContext returns the call metadata of the command being executed with ctx. If ctx doesn't carry any
+// metadata, ok will be false.
+func MetadataFromContext(ctx context.Context) (md CallMetadata, ok bool) {
+	md, ok = ctx.Value(metadataKey{}).(CallMetadata)
+	return md, ok
+}
+
+// applyOptions applies opts on top of any metadata already carried by ctx and returns a context carrying the resulting
+// metadata.
+func applyOptions(ctx context.Context, opts []ExecOption) (context.Context, *execOptions) {
+	o := &execOptions{}
+	o.metadata, _ = MetadataFromContext(ctx)
+
+	if len(opts) == 0 {
+		return ctx, o
+	}
+
+	if o.metadata.Labels != nil {
+		labels := make(map[string]string, len(o.metadata.Labels))
+		for k, v := range o.metadata.Labels {
+			labels[k] = v
+		}
+		o.metadata.Labels = labels
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return context.WithValue(ctx, metadataKey{}, o.metadata), o
+}
+
+// attributes returns the span attributes for the metadata.
+func (md CallMetadata) attributes() map[string]string {
+	attrs := map[string]string{}
+
+	if md.Initiator != "" {
+		attrs[AttrInitiator] = md.Initiator
+	}
+
+	if md.Reason != "" {
+		attrs[AttrReason] = md.Reason
+	}
+
+	keys := make([]string, 0, len(md.Labels))
+	for k := range md.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		attrs[AttrLabelPrefix+k] = md.Labels[k]
+	}
+
+	return attrs
+}
+
+// options returns options which recreate the metadata.
+func (md CallMetadata) options() []ExecOption {
+	return []ExecOption{func(o *execOptions) {
+		o.metadata = md
+	}}
+}
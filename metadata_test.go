@@ -0,0 +1,66 @@
+package rcon
+
+import (
+	"context"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"sync"
+	"testing"
+)
+
+// spanRecorder is a Tracer which records the attributes of the spans it starts.
+type spanRecorder struct {
+	lock  sync.Mutex
+	spans map[string]map[string]string
+}
+
+func (r *spanRecorder) StartSpan(ctx context.Context, name string, attributes map[string]string) (context.Context,
+	Span) {
+	r.lock.Lock()
+	r.spans[name] = attributes
+	r.lock.Unlock()
+
+	return ctx, noopSpan{}
+}
+
+func TestMetadata(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("WithReason", func() {
+		g.It("Should record the reason in the history and traces", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			tracer := &spanRecorder{spans: map[string]map[string]string{}}
+			c := newTestClient(s, &Config{HistorySize: 1, Tracer: tracer})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			_, err := c.ExecCommand("kick bob", WithInitiator("admin:1"), WithReason("spamming"))
+			Expect(err).ToNot(HaveOccurred())
+
+			history := c.History()
+			Expect(history).To(HaveLen(1))
+			Expect(history[0].Metadata.Reason).To(Equal("spamming"))
+			Expect(history[0].Metadata.Initiator).To(Equal("admin:1"))
+
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			Expect(tracer.spans[SpanExec]).To(HaveKeyWithValue(AttrReason, "spamming"))
+		})
+
+		g.It("Should override the reason carried by the context", func() {
+			ctx, _ := applyOptions(context.Background(), []ExecOption{WithInitiator("admin:1"), WithReason("first")})
+
+			ctx, o := applyOptions(ctx, []ExecOption{WithReason("second")})
+			Expect(o.metadata).To(Equal(CallMetadata{Initiator: "admin:1", Reason: "second"}))
+
+			md, ok := MetadataFromContext(ctx)
+			Expect(ok).To(BeTrue())
+			Expect(md.Reason).To(Equal("second"))
+		})
+	})
+}
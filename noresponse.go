@@ -0,0 +1,48 @@
+package rcon
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/packet"
+)
+
+// WithNoResponse makes a call return as soon as the command was queued, for commands which never produce a response
+// and would otherwise make the call wait until the read timeout. The returned response is always empty.
+//
+// If confirm is true and the dialect supports fragmentation, an empty SERVERDATA_RESPONSE_VALUE packet is sent after
+// the command. Servers process packets in order and mirror the empty packet, so the call returns once the mirrored
+// packet arrives, which confirms that the command was processed. Confirmation is skipped for other dialects.
+func WithNoResponse(confirm bool) ExecOption {
+	return func(o *execOptions) {
+		o.noResponse = true
+		o.confirm = confirm
+	}
+}
+
+// execNoResponse queues a command packet without opening a mailbox for its response, which is dropped if the server
+// sends one anyway. If confirm is set, it waits for the server to mirror a sentinel packet sent after the command.
+func (c *Client) execNoResponse(ctx context.Context, p packet.Packet, priority Priority, confirm bool) error {
+	if err := c.enqueuePacket(p, priority, false); err != nil {
+		return errors.Wrap(err, "could not enqueue command packet")
+	}
+
+	if !confirm {
+		return nil
+	}
+
+	if !c.Dialect().SupportsFragmentation {
+		c.log.Debug("Dialect doesn't mirror empty packets, not confirming command ", p.ID())
+		return nil
+	}
+
+	sentinel := c.newClientPacket(packet.TypeCommandRes, "")
+	if err := c.enqueuePacket(sentinel, priority, true); err != nil {
+		return errors.Wrap(err, "could not enqueue confirmation packet")
+	}
+
+	if _, err := c.getResponse(ctx, sentinel.ID()); err != nil {
+		return errors.Wrap(err, "could not confirm command")
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/rcontest"
+	"testing"
+	"time"
+)
+
+func TestNoResponse(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("WithNoResponse", func() {
+		g.It("Should return once the command was queued if the server never replies", func() {
+			received := make(chan string, 1)
+			unblock := make(chan struct{})
+			s := newTestServer(func(command string) string {
+				received <- command
+				<-unblock
+				return ""
+			})
+			defer s.Close()
+			defer close(unblock)
+
+			c := newTestClient(s, &Config{QueueReadTimeout: time.Second * 5})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			start := time.Now()
+			Expect(c.ExecCommand("restartmap", WithNoResponse(false))).To(Equal(""))
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+			Eventually(received).Should(Receive(Equal("restartmap")))
+		})
+
+		g.It("Should be used by ExecCommandNoResponse", func() {
+			received := make(chan string, 1)
+			unblock := make(chan struct{})
+			s := newTestServer(func(command string) string {
+				received <- command
+				<-unblock
+				return ""
+			})
+			defer s.Close()
+			defer close(unblock)
+
+			c := newTestClient(s, &Config{QueueReadTimeout: time.Second * 5, HistorySize: 1})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			start := time.Now()
+			Expect(c.ExecCommandNoResponse("restartmap")).To(Succeed())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+			Eventually(received).Should(Receive(Equal("restartmap")))
+			Expect(c.History()).To(HaveLen(1))
+		})
+
+		g.It("Should return once the sentinel confirms the command was processed", func() {
+			processed := make(chan struct{})
+			s := newTestServer(func(command string) string {
+				time.Sleep(time.Millisecond * 100)
+				close(processed)
+				return "ignored"
+			})
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(c.ExecCommand("restartmap", WithNoResponse(true))).To(Equal(""))
+			Expect(processed).To(BeClosed())
+		})
+
+		g.It("Should fail if the sentinel is never mirrored", func() {
+			s, err := rcontest.NewServer(rcontest.Config{Password: testPassword, Handler: echoHandler, IgnoreResponseValues: true})
+			Expect(err).ToNot(HaveOccurred())
+			defer s.Close()
+
+			c := newTestClient(s, &Config{QueueReadTimeout: time.Millisecond * 100})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			_, err = c.ExecCommand("restartmap", WithNoResponse(true))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("could not confirm command"))
+		})
+	})
+}
@@ -0,0 +1,205 @@
+package rcon
+
+import (
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"time"
+)
+
+// QueuedCommand is a command which has been queued for execution by an OfflineQueue.
+type QueuedCommand struct {
+	// ID identifies the command among the commands in the store. It is assigned when the command is queued.
+	ID uint64 `json:"id"`
+
+	Command  string    `json:"command"`
+	Priority Priority  `json:"priority"`
+	QueuedAt time.Time `json:"queued_at"`
+}
+
+// CommandStore is the storage backend of an OfflineQueue. Commands must be returned in the order they were appended.
+//
+// MemoryCommandStore and FileCommandStore are provided, but any implementation can be used.
+type CommandStore interface {
+	// Append adds a command to the back of the store.
+	Append(cmd QueuedCommand) error
+
+	// Front returns the oldest command in the store. If the store is empty, ok will be false.
+	Front() (cmd QueuedCommand, ok bool, err error)
+
+	// RemoveFront removes the oldest command from the store.
+	RemoveFront() error
+
+	// Len returns the number of commands in the store.
+	Len() (int, error)
+}
+
+// OfflineQueue holds commands which were queued using Client.QueueCommand until they can be executed. Queued commands
+// are executed in order as soon as the client is connected, so commands queued while the server is down are flushed
+// once the client reconnects.
+//
+// Queued commands are executed at most once: a command is removed from the queue once it was written to the
+// connection, even if the connection is lost before its response arrives. Only commands which were never written are
+// kept queued and executed after reconnecting. If the process exits after a command was written but before it was
+// removed from a FileCommandStore, it is executed again after a restart.
+type OfflineQueue struct {
+	// Store is where queued commands are kept. Use a FileCommandStore if queued commands should survive restarts.
+	//
+	// Default: MemoryCommandStore
+	Store CommandStore
+
+	// MaxAge is the maximum amount of time a command can be queued for. Commands older than MaxAge are discarded
+	// instead of being executed. If MaxAge is zero, commands never expire.
+	MaxAge time.Duration
+
+	// MaxSize is the maximum number of commands which can be queued. If the queue is full, the oldest command is
+	// discarded to make room for the new one. If MaxSize is zero, the queue size is unbounded.
+	MaxSize int
+
+	lastID uint64
+}
+
+// nextID returns the ID of a newly queued command. IDs are based on the current time so that they are larger than the
+// IDs of commands queued before a restart, and are strictly increasing within a queue.
+func (q *OfflineQueue) nextID() uint64 {
+	id := uint64(time.Now().UnixNano())
+	if id <= q.lastID {
+		id = q.lastID + 1
+	}
+
+	q.lastID = id
+
+	return id
+}
+
+func (q *OfflineQueue) push(cmd QueuedCommand) error {
+	cmd.ID = q.nextID()
+
+	if q.MaxSize > 0 {
+		for {
+			size, err := q.Store.Len()
+			if err != nil {
+				return errors.Wrap(err, "could not get queue size")
+			}
+
+			if size < q.MaxSize {
+				break
+			}
+
+			if err := q.Store.RemoveFront(); err != nil {
+				return errors.Wrap(err, "could not evict oldest queued command")
+			}
+		}
+	}
+
+	if err := q.Store.Append(cmd); err != nil {
+		return errors.Wrap(err, "could not append command to store")
+	}
+
+	return nil
+}
+
+// next returns the oldest queued command which has not expired. Expired commands are removed from the store.
+func (q *OfflineQueue) next() (QueuedCommand, bool, error) {
+	for {
+		cmd, ok, err := q.Store.Front()
+		if err != nil || !ok {
+			return cmd, ok, err
+		}
+
+		if q.MaxAge <= 0 || time.Since(cmd.QueuedAt) <= q.MaxAge {
+			return cmd, true, nil
+		}
+
+		if err := q.Store.RemoveFront(); err != nil {
+			return cmd, false, err
+		}
+	}
+}
+
+// remove removes the command with the given ID if it is still the oldest queued command. It may already have been
+// evicted to make room for newer commands while it was being executed, in which case the store is left unchanged.
+func (q *OfflineQueue) remove(id uint64) error {
+	cmd, ok, err := q.Store.Front()
+	if err != nil || !ok || cmd.ID != id {
+		return err
+	}
+
+	return q.Store.RemoveFront()
+}
+
+// QueueCommand adds a command to the client's offline queue. Queued commands are executed in order once the client is
+// connected, and their responses are discarded. If the client is already connected, the queue is flushed immediately.
+//
+// ErrNoOfflineQueue is returned if OfflineQueue is not set in the client config.
+func (c *Client) QueueCommand(command string, priority Priority) error {
+	if c.OfflineQueue == nil {
+		return errs.ErrNoOfflineQueue
+	}
+
+	c.oqLock.Lock()
+	err := c.OfflineQueue.push(QueuedCommand{
+		Command:  command,
+		Priority: priority,
+		QueuedAt: time.Now(),
+	})
+	c.oqLock.Unlock()
+
+	if err != nil {
+		return errors.Wrap(err, "could not queue command")
+	}
+
+	if c.isConnected() {
+		go c.flushOfflineQueue()
+	}
+
+	return nil
+}
+
+// flushOfflineQueue executes queued commands in order until the queue is empty or the client is disconnected, paused or
+// draining.
+func (c *Client) flushOfflineQueue() {
+	if c.OfflineQueue == nil {
+		return
+	}
+
+	// Only one flush can run at a time so that commands are executed in order.
+	c.oqFlushLock.Lock()
+	defer c.oqFlushLock.Unlock()
+
+	for c.isConnected() && !c.Paused() && !c.Draining() {
+		c.oqLock.Lock()
+		cmd, ok, err := c.OfflineQueue.next()
+		c.oqLock.Unlock()
+
+		if err != nil {
+			c.log.Error("Could not read from offline queue. Error: ", err)
+			c.stats.recordError(err)
+			return
+		}
+
+		if !ok {
+			return
+		}
+
+		if _, err := c.ExecCommandPriority(cmd.Command, cmd.Priority); err != nil {
+			// If the command was never written, for example because we were disconnected, leave it queued so it is
+			// executed after reconnecting. Once it was written, it could have been executed even if its response was
+			// lost, so it is removed rather than risking executing it twice.
+			if notSent(err) {
+				return
+			}
+
+			c.log.Error("Queued command failed: ", cmd.Command, " Error: ", err)
+		}
+
+		c.oqLock.Lock()
+		err = c.OfflineQueue.remove(cmd.ID)
+		c.oqLock.Unlock()
+
+		if err != nil {
+			c.log.Error("Could not remove command from offline queue. Error: ", err)
+			c.stats.recordError(err)
+			return
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/rcontest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOfflineQueue(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("OfflineQueue", func() {
+		g.It("Should evict the oldest commands once full", func() {
+			q := &OfflineQueue{Store: NewMemoryCommandStore(), MaxSize: 2}
+			for _, command := range []string{"first", "second", "third"} {
+				Expect(q.push(QueuedCommand{Command: command})).To(Succeed())
+			}
+
+			Expect(q.Store.Len()).To(Equal(2))
+			cmd, ok, err := q.next()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(cmd.Command).To(Equal("second"))
+		})
+
+		g.It("Should discard expired commands", func() {
+			q := &OfflineQueue{Store: NewMemoryCommandStore(), MaxAge: time.Minute}
+			Expect(q.push(QueuedCommand{Command: "old", QueuedAt: time.Now().Add(-time.Hour)})).To(Succeed())
+			Expect(q.push(QueuedCommand{Command: "new", QueuedAt: time.Now()})).To(Succeed())
+
+			cmd, ok, err := q.next()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(cmd.Command).To(Equal("new"))
+			Expect(q.Store.Len()).To(Equal(1))
+		})
+
+		g.It("Should assign increasing IDs", func() {
+			q := &OfflineQueue{Store: NewMemoryCommandStore(), lastID: uint64(time.Now().Add(time.Hour).UnixNano())}
+			last := q.lastID
+			for i := 0; i < 3; i++ {
+				id := q.nextID()
+				Expect(id).To(BeNumerically(">", last))
+				last = id
+			}
+		})
+
+		g.It("Should not remove another command if the executed one was evicted", func() {
+			q := &OfflineQueue{Store: NewMemoryCommandStore(), MaxSize: 1}
+			Expect(q.push(QueuedCommand{Command: "first"})).To(Succeed())
+
+			executing, _, err := q.next()
+			Expect(err).ToNot(HaveOccurred())
+
+			// While the first command is executed, it is evicted by a newly queued one.
+			Expect(q.push(QueuedCommand{Command: "second"})).To(Succeed())
+			Expect(q.remove(executing.ID)).To(Succeed())
+
+			cmd, ok, err := q.next()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(cmd.Command).To(Equal("second"))
+
+			Expect(q.remove(cmd.ID)).To(Succeed())
+			Expect(q.Store.Len()).To(Equal(0))
+		})
+	})
+
+	g.Describe("QueueCommand", func() {
+		g.It("Should execute queued commands in order once connected", func() {
+			var lock sync.Mutex
+			var executed []string
+			s := newTestServer(func(command string) string {
+				lock.Lock()
+				executed = append(executed, command)
+				lock.Unlock()
+				return ""
+			})
+			defer s.Close()
+
+			c := newTestClient(s, &Config{OfflineQueue: &OfflineQueue{}})
+			Expect(c.QueueCommand("first", PriorityNormal)).To(Succeed())
+			Expect(c.QueueCommand("second", PriorityHigh)).To(Succeed())
+
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(c.QueueCommand("third", PriorityNormal)).To(Succeed())
+
+			Eventually(func() []string {
+				lock.Lock()
+				defer lock.Unlock()
+				return append([]string(nil), executed...)
+			}).Should(Equal([]string{"first", "second", "third"}))
+			Eventually(c.OfflineQueue.Store.Len).Should(Equal(0))
+		})
+
+		g.It("Should not execute a sent command again after reconnecting", func() {
+			var lock sync.Mutex
+			var executed []string
+			var s *rcontest.Server
+			s = newTestServer(func(command string) string {
+				lock.Lock()
+				executed = append(executed, command)
+				lock.Unlock()
+
+				// The connection is lost before the response to the command is sent.
+				if command == "kick bob" {
+					s.CloseConnections()
+				}
+
+				return ""
+			})
+			defer s.Close()
+
+			c := newTestClient(s, &Config{
+				OfflineQueue:     &OfflineQueue{},
+				QueueReadTimeout: time.Millisecond * 100,
+				ReconnectPolicy:  &ReconnectPolicy{InitialDelay: time.Millisecond * 300},
+			})
+			Expect(c.QueueCommand("kick bob", PriorityNormal)).To(Succeed())
+
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Eventually(c.OfflineQueue.Store.Len).Should(Equal(0))
+			Eventually(c.State, time.Second).Should(Equal(StateConnected))
+
+			Expect(c.QueueCommand("say hi", PriorityNormal)).To(Succeed())
+
+			Eventually(func() []string {
+				lock.Lock()
+				defer lock.Unlock()
+				return append([]string(nil), executed...)
+			}).Should(Equal([]string{"kick bob", "say hi"}))
+		})
+	})
+}
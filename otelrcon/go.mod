@@ -0,0 +1,22 @@
+module github.com/refractorgscm/rcon/otelrcon
+
+go 1.21
+
+require (
+	github.com/franela/goblin v0.0.0-20211003143422-0a4f594942bf
+	github.com/onsi/gomega v1.16.0
+	github.com/pkg/errors v0.9.1
+	github.com/refractorgscm/rcon v0.0.0-20261016065740-99d3f2b76bad
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+)
+
+require (
+	github.com/BurntSushi/toml v0.3.1 // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/otel/metric v1.28.0 // indirect
+	golang.org/x/net v0.0.0-20210428140749-89ef3d95e781 // indirect
+	golang.org/x/text v0.3.6 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
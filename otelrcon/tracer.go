@@ -0,0 +1,74 @@
+// Package otelrcon provides an OpenTelemetry implementation of rcon.Tracer.
+//
+// It lives in its own module so that the core client does not depend on OpenTelemetry.
+package otelrcon
+
+import (
+	"context"
+	"github.com/refractorgscm/rcon"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/refractorgscm/rcon/otelrcon"
+
+// Tracer is an rcon.Tracer which records client operations as OpenTelemetry spans.
+type Tracer struct {
+	provider trace.TracerProvider
+	tracer   trace.Tracer
+}
+
+type Option func(*Tracer)
+
+// WithTracerProvider sets the tracer provider used to create spans. By default, the global tracer provider is used.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(t *Tracer) {
+		t.provider = provider
+	}
+}
+
+func NewTracer(opts ...Option) *Tracer {
+	t := &Tracer{
+		provider: otel.GetTracerProvider(),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	t.tracer = t.provider.Tracer(instrumentationName)
+
+	return t
+}
+
+// WithTracing enables OpenTelemetry tracing on the provided client config.
+func WithTracing(config *rcon.Config, opts ...Option) *rcon.Config {
+	config.Tracer = NewTracer(opts...)
+	return config
+}
+
+func (t *Tracer) StartSpan(ctx context.Context, name string, attributes map[string]string) (context.Context, rcon.Span) {
+	attrs := make([]attribute.KeyValue, 0, len(attributes))
+	for k, v := range attributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+
+	ctx, s := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+
+	return ctx, &span{span: s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s *span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+
+	s.span.End()
+}
@@ -0,0 +1,186 @@
+package otelrcon
+
+import (
+	"context"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon"
+	"github.com/refractorgscm/rcon/rcontest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// recordingProvider is a trace.TracerProvider which records the spans started by its tracers, so that tests don't
+// depend on the OpenTelemetry SDK.
+type recordingProvider struct {
+	embedded.TracerProvider
+
+	lock  sync.Mutex
+	spans []*recordedSpan
+}
+
+func (p *recordingProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return &recordingTracer{provider: p}
+}
+
+// Spans returns the spans which have ended so far.
+func (p *recordingProvider) Spans() []*recordedSpan {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var ended []*recordedSpan
+	for _, s := range p.spans {
+		if s.ended {
+			ended = append(ended, s)
+		}
+	}
+
+	return ended
+}
+
+type recordingTracer struct {
+	embedded.Tracer
+	provider *recordingProvider
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string,
+	opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	config := trace.NewSpanStartConfig(opts...)
+
+	s := &recordedSpan{
+		provider:   t.provider,
+		name:       name,
+		kind:       config.SpanKind(),
+		attributes: config.Attributes(),
+		context: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{byte(len(t.provider.spans) + 1)},
+			TraceFlags: trace.FlagsSampled,
+		}),
+	}
+
+	t.provider.lock.Lock()
+	t.provider.spans = append(t.provider.spans, s)
+	t.provider.lock.Unlock()
+
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+type recordedSpan struct {
+	noop.Span
+	provider *recordingProvider
+
+	name              string
+	kind              trace.SpanKind
+	attributes        []attribute.KeyValue
+	context           trace.SpanContext
+	statusCode        codes.Code
+	statusDescription string
+	errors            []error
+	ended             bool
+}
+
+func (s *recordedSpan) SpanContext() trace.SpanContext {
+	return s.context
+}
+
+func (s *recordedSpan) IsRecording() bool {
+	return true
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, description string) {
+	s.statusCode, s.statusDescription = code, description
+}
+
+func (s *recordedSpan) RecordError(err error, _ ...trace.EventOption) {
+	s.errors = append(s.errors, err)
+}
+
+func (s *recordedSpan) End(...trace.SpanEndOption) {
+	s.provider.lock.Lock()
+	s.ended = true
+	s.provider.lock.Unlock()
+}
+
+func Test(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Tracer", func() {
+		var provider *recordingProvider
+
+		g.BeforeEach(func() {
+			provider = &recordingProvider{}
+		})
+
+		g.It("Should record client spans with the provided attributes", func() {
+			tracer := NewTracer(WithTracerProvider(provider))
+
+			ctx, s := tracer.StartSpan(context.Background(), rcon.SpanExec, map[string]string{
+				rcon.AttrCommandName: "status",
+			})
+			Expect(trace.SpanFromContext(ctx).SpanContext().IsValid()).To(BeTrue())
+			s.End(nil)
+
+			spans := provider.Spans()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].name).To(Equal(rcon.SpanExec))
+			Expect(spans[0].kind).To(Equal(trace.SpanKindClient))
+			Expect(spans[0].attributes).To(ConsistOf(attribute.String(rcon.AttrCommandName, "status")))
+			Expect(spans[0].statusCode).To(Equal(codes.Unset))
+			Expect(spans[0].errors).To(BeEmpty())
+		})
+
+		g.It("Should mark spans ended with an error as failed", func() {
+			tracer := NewTracer(WithTracerProvider(provider))
+
+			err := errors.New("connection refused")
+			_, s := tracer.StartSpan(context.Background(), rcon.SpanConnect, nil)
+			s.End(err)
+
+			spans := provider.Spans()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].statusCode).To(Equal(codes.Error))
+			Expect(spans[0].statusDescription).To(Equal("connection refused"))
+			Expect(spans[0].errors).To(ConsistOf(err))
+		})
+
+		g.It("Should trace the operations of a client", func() {
+			server, err := rcontest.NewServer(rcontest.Config{Password: "pw", Handler: func(command string) string {
+				return "echo: " + command
+			}})
+			Expect(err).ToNot(HaveOccurred())
+			defer server.Close()
+
+			host, port := server.Addr()
+			c := rcon.NewClient(WithTracing(&rcon.Config{Host: host, Port: port, Password: "pw"},
+				WithTracerProvider(provider)), nil)
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(c.ExecCommand("kick bob")).To(Equal("echo: kick bob"))
+
+			attrs := []attribute.KeyValue{
+				attribute.String(rcon.AttrServerAddress, host),
+				attribute.String(rcon.AttrServerPort, strconv.Itoa(int(port))),
+			}
+
+			spans := provider.Spans()
+			Expect(spans).To(HaveLen(2))
+			Expect(spans[0].name).To(Equal(rcon.SpanConnect))
+			Expect(spans[0].attributes).To(ConsistOf(attrs))
+			Expect(spans[1].name).To(Equal(rcon.SpanExec))
+			Expect(spans[1].attributes).To(ConsistOf(append(attrs,
+				attribute.String(rcon.AttrCommandName, "kick"), attribute.String(rcon.AttrCommand, "kick"))))
+		})
+	})
+}
@@ -1,22 +1,27 @@
 package packet
 
 import (
-	"bytes"
-	"encoding/binary"
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/refractorgscm/rcon/endian"
+	"github.com/refractorgscm/rcon/errs"
 	"io"
 	"math"
+	"sync"
 )
 
 var nextClientPacketID int32 = 0
+var nextClientPacketIDLock sync.Mutex
 
 type ClientPacket struct {
 	mode  endian.Mode
 	pType PacketType
 	body  []byte
 	id    int32
+
+	// raw is the decoded body before leading and trailing null bytes and newlines were trimmed from it. It is only set
+	// if trimming changed the body.
+	raw []byte
 }
 
 func idInArr(arr []int32, id int32) bool {
@@ -49,13 +54,16 @@ func getNextID(restrictedIDs []int32) int32 {
 }
 
 func NewClientPacket(mode endian.Mode, pType PacketType, body string, restrictedIDs []int32) Packet {
-	nextClientPacketID = getNextID(restrictedIDs)
+	// Packets are created by many goroutines at once, so IDs must be allocated under a lock to stay unique.
+	nextClientPacketIDLock.Lock()
+	id := getNextID(restrictedIDs)
+	nextClientPacketIDLock.Unlock()
 
 	p := &ClientPacket{
 		mode:  mode,
 		pType: pType,
 		body:  []byte(body),
-		id:    nextClientPacketID,
+		id:    id,
 	}
 
 	if len(body) == 0 {
@@ -84,74 +92,44 @@ func (p *ClientPacket) Body() []byte {
 	return append(p.body, byte('\x00'))
 }
 
-func (p *ClientPacket) Build() ([]byte, error) {
-	buffer := bytes.NewBuffer([]byte{})
-
-	order := p.mode
-
-	if err := binary.Write(buffer, order, p.Size()); err != nil {
-		return nil, errors.Wrap(err, "could not write packet size")
-	}
-
-	if err := binary.Write(buffer, order, p.ID()); err != nil {
-		return nil, errors.Wrap(err, "could not write packet size")
-	}
-
-	if err := binary.Write(buffer, order, p.Type()); err != nil {
-		return nil, errors.Wrap(err, "could not write packet size")
+// RawBody returns the body of a decoded packet exactly as it was sent, without its terminating null bytes. Unlike Body,
+// leading and trailing null bytes and newlines are kept, which makes it suitable for binary bodies.
+func (p *ClientPacket) RawBody() []byte {
+	if p.raw != nil {
+		return p.raw
 	}
 
-	if err := binary.Write(buffer, order, p.Body()); err != nil {
-		return nil, errors.Wrap(err, "could not write packet size")
-	}
+	return p.body
+}
 
-	if err := binary.Write(buffer, order, byte('\x00')); err != nil {
-		return nil, errors.Wrap(err, "could not write packet size")
-	}
+func (p *ClientPacket) Build() ([]byte, error) {
+	framing := SourceFraming
+	framing.Mode = p.mode
 
-	return buffer.Bytes(), nil
+	return framing.Build(p)
 }
 
 var malformedPacketErr = fmt.Errorf("malformed packet")
 
 func DecodeClientPacket(mode endian.Mode, reader io.Reader) (*ClientPacket, error) {
-	var size int32
-	var id int32
-	var pType int32
-
-	// Read size
-	if err := binary.Read(reader, mode, &size); err != nil {
-		return nil, err
-	}
+	return DecodeClientPacketLimit(mode, reader, 0)
+}
 
-	// Read ID
-	if err := binary.Read(reader, mode, &id); err != nil {
-		return nil, err
-	}
+// DecodeClientPacketLimit decodes a packet like DecodeClientPacket. If maxBodySize is greater than zero, packets whose
+// declared body size exceeds it are rejected with ErrResponseTooLarge before the body is read, so that a broken or
+// malicious server can't make the client allocate arbitrary amounts of memory. The rest of the packet is left unread,
+// so the stream can't be read any further.
+func DecodeClientPacketLimit(mode endian.Mode, reader io.Reader, maxBodySize int) (*ClientPacket, error) {
+	framing := SourceFraming
+	framing.Mode = mode
 
-	// Read type
-	if err := binary.Read(reader, mode, &pType); err != nil {
-		return nil, err
-	}
-
-	// Read body
-	bodyLen := size - 4 - 4 // size - id bytes - type bytes
-	body := make([]byte, bodyLen)
+	return framing.Decode(reader, maxBodySize)
+}
 
-	_, err := io.ReadFull(reader, body)
-	if err != nil {
-		return nil, err
+func checkBodySize(bodyLen int32, maxBodySize int) error {
+	if maxBodySize > 0 && int64(bodyLen) > int64(maxBodySize) {
+		return errors.Wrapf(errs.ErrResponseTooLarge, "packet body is %d bytes, the maximum is %d", bodyLen, maxBodySize)
 	}
 
-	// Trim unneeded bytes from body
-	body = bytes.Trim(body, "\x00")
-	body = bytes.Trim(body, "\n")
-
-	// Construct and return client packet
-	return &ClientPacket{
-		mode:  mode,
-		pType: PacketType(pType),
-		body:  body,
-		id:    id,
-	}, nil
+	return nil
 }
@@ -0,0 +1,166 @@
+package packet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// Codec compresses and decompresses packet bodies. Gzip is built in. Other algorithms such as zstd can be supported by
+// implementing Codec and registering it with RegisterCodec.
+type Codec interface {
+	// Name identifies the codec in compressed bodies. It must not contain ':'.
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// LimitedDecompressor is implemented by codecs which can stop decompressing once the output exceeds a size limit. It
+// is used by DecompressBodyLimit to protect against small bodies which decompress to huge amounts of data. Codecs
+// which don't implement it are limited after decompressing.
+type LimitedDecompressor interface {
+	// DecompressLimit decompresses data like Codec.Decompress, but returns at most max+1 bytes.
+	DecompressLimit(data []byte, max int) ([]byte, error)
+}
+
+// compressedPrefix marks a compressed body. It starts with a control character which can't appear at the start of a
+// regular command or response, and isn't trimmed by DecodeClientPacket.
+const compressedPrefix = "\x01z:"
+
+var codecs = map[string]Codec{}
+var codecsLock sync.RWMutex
+
+// RegisterCodec makes a codec available for decompressing bodies. Codecs are looked up by name.
+func RegisterCodec(codec Codec) {
+	codecsLock.Lock()
+	defer codecsLock.Unlock()
+
+	codecs[codec.Name()] = codec
+}
+
+// Gzip is a Codec using gzip compression.
+var Gzip Codec = gzipCodec{}
+
+func init() {
+	RegisterCodec(Gzip)
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string {
+	return "gzip"
+}
+
+func (gzipCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+func (gzipCodec) DecompressLimit(data []byte, max int) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(io.LimitReader(r, int64(max)+1))
+}
+
+// CompressBody compresses a packet body using codec. Since bodies are null terminated strings, the compressed data is
+// base64 encoded and prefixed with a marker holding the codec name, so it can be detected by DecompressBody.
+func CompressBody(codec Codec, body []byte) ([]byte, error) {
+	compressed, err := codec.Compress(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not compress body")
+	}
+
+	header := compressedPrefix + codec.Name() + ":"
+	out := make([]byte, len(header)+base64.StdEncoding.EncodedLen(len(compressed)))
+	copy(out, header)
+	base64.StdEncoding.Encode(out[len(header):], compressed)
+
+	return out, nil
+}
+
+// IsCompressed returns true if body was compressed using CompressBody.
+func IsCompressed(body []byte) bool {
+	return bytes.HasPrefix(body, []byte(compressedPrefix))
+}
+
+// DecompressBody decompresses a body compressed using CompressBody. Bodies which aren't compressed are returned as is.
+func DecompressBody(body []byte) ([]byte, error) {
+	return DecompressBodyLimit(body, 0)
+}
+
+// DecompressBodyLimit decompresses a body like DecompressBody. If max is greater than zero, bodies which decompress to
+// more than max bytes are rejected with ErrResponseTooLarge.
+func DecompressBodyLimit(body []byte, max int) ([]byte, error) {
+	if !IsCompressed(body) {
+		return body, nil
+	}
+
+	rest := body[len(compressedPrefix):]
+
+	sep := bytes.IndexByte(rest, ':')
+	if sep < 0 {
+		return nil, errors.New("compressed body is missing the codec name")
+	}
+
+	name := string(rest[:sep])
+
+	codecsLock.RLock()
+	codec, ok := codecs[name]
+	codecsLock.RUnlock()
+
+	if !ok {
+		return nil, errors.Errorf("unknown compression codec %q", name)
+	}
+
+	compressed := make([]byte, base64.StdEncoding.DecodedLen(len(rest)-sep-1))
+	n, err := base64.StdEncoding.Decode(compressed, rest[sep+1:])
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode compressed body")
+	}
+
+	var decompressed []byte
+	if limited, ok := codec.(LimitedDecompressor); ok && max > 0 {
+		decompressed, err = limited.DecompressLimit(compressed[:n], max)
+	} else {
+		decompressed, err = codec.Decompress(compressed[:n])
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decompress body")
+	}
+
+	if max > 0 && len(decompressed) > max {
+		return nil, errors.Wrapf(errs.ErrResponseTooLarge, "decompressed body exceeds the maximum of %d bytes", max)
+	}
+
+	return decompressed, nil
+}
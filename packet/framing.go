@@ -0,0 +1,214 @@
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/endian"
+	"io"
+	"math"
+)
+
+// Framing describes how packets are laid out on the wire. Engines which deviate from Valve's Source RCON framing can
+// be supported by describing their framing instead of forking the codec.
+type Framing struct {
+	// Mode is the byte order of the size, ID and type fields.
+	//
+	// Default: endian.Little
+	Mode endian.Mode
+
+	// Terminators is the number of null bytes following the body. Source RCON terminates the body and an empty second
+	// string, which makes two.
+	Terminators int
+
+	// SizeExcludesHeader is true if the size field only counts the body and its terminators, rather than including the
+	// ID and type fields too.
+	SizeExcludesHeader bool
+
+	// ID64 is true if the ID field is 64 bits wide. IDs must still fit into 32 bits.
+	ID64 bool
+}
+
+// SourceFraming is the framing of Valve's Source RCON protocol, which is used by DecodeClientPacket and
+// ClientPacket.Build.
+var SourceFraming = Framing{Mode: endian.Little, Terminators: 2}
+
+func (f Framing) mode() endian.Mode {
+	if f.Mode == nil {
+		return endian.Little
+	}
+
+	return f.Mode
+}
+
+// headerSize is the size of the ID and type fields.
+func (f Framing) headerSize() int32 {
+	if f.ID64 {
+		return 8 + int32Bytes
+	}
+
+	return int32Bytes + int32Bytes
+}
+
+// Build encodes a packet using the framing.
+func (f Framing) Build(p Packet) ([]byte, error) {
+	// Body includes the body's null terminator, which is replaced by the framing's terminators.
+	body := bytes.TrimSuffix(p.Body(), []byte{'\x00'})
+
+	size := int32(len(body) + f.Terminators)
+	if !f.SizeExcludesHeader {
+		size += f.headerSize()
+	}
+
+	buffer := bytes.NewBuffer(make([]byte, 0, int(size)+int32Bytes))
+	order := f.mode()
+
+	if err := binary.Write(buffer, order, size); err != nil {
+		return nil, errors.Wrap(err, "could not write packet size")
+	}
+
+	var id interface{} = p.ID()
+	if f.ID64 {
+		id = int64(p.ID())
+	}
+
+	if err := binary.Write(buffer, order, id); err != nil {
+		return nil, errors.Wrap(err, "could not write packet ID")
+	}
+
+	if err := binary.Write(buffer, order, p.Type()); err != nil {
+		return nil, errors.Wrap(err, "could not write packet type")
+	}
+
+	buffer.Write(body)
+	buffer.Write(make([]byte, f.Terminators))
+
+	return buffer.Bytes(), nil
+}
+
+// Decode decodes a packet using the framing, rejecting packets whose body exceeds maxBodySize like
+// DecodeClientPacketLimit. The body is trimmed like DecodeClientPacket trims it.
+func (f Framing) Decode(reader io.Reader, maxBodySize int) (*ClientPacket, error) {
+	id, pType, bodyLen, err := f.readHeader(reader, maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, bodyLen)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, err
+	}
+
+	raw := body
+	for i := 0; i < f.Terminators && len(raw) > 0 && raw[len(raw)-1] == '\x00'; i++ {
+		raw = raw[:len(raw)-1]
+	}
+
+	// Trim unneeded bytes from body
+	body = bytes.Trim(body, "\x00")
+	body = bytes.Trim(body, "\n")
+
+	p := &ClientPacket{
+		mode:  f.mode(),
+		pType: pType,
+		body:  body,
+		id:    id,
+	}
+
+	if !bytes.Equal(raw, body) {
+		p.raw = raw
+	}
+
+	return p, nil
+}
+
+// DecodeLenient decodes a packet using the framing, tolerating protocol violations like DecodeClientPacketLenient.
+func (f Framing) DecodeLenient(reader io.Reader, maxBodySize int) (*ClientPacket, []string, error) {
+	var warnings []string
+
+	id, pType, bodyLen, err := f.readHeader(reader, maxBodySize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if int64(bodyLen) > MaxLenientPacketSize {
+		return nil, nil, errors.Wrapf(malformedPacketErr, "invalid packet size %d", bodyLen)
+	}
+
+	body := make([]byte, bodyLen)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, nil, err
+	}
+
+	if end := bytes.IndexByte(body, '\x00'); end < 0 {
+		if f.Terminators > 0 {
+			warnings = append(warnings, "packet body is missing its null terminators")
+		}
+	} else {
+		trailer := body[end:]
+		body = body[:end]
+
+		switch {
+		case len(bytes.Trim(trailer, "\x00")) > 0:
+			warnings = append(warnings, "discarded trailing bytes after packet body")
+		case len(trailer) < f.Terminators:
+			warnings = append(warnings, "packet is missing null terminators")
+		case len(trailer) > f.Terminators:
+			warnings = append(warnings, "packet has extra null padding after its body")
+		}
+	}
+
+	body = bytes.Trim(body, "\n")
+
+	return &ClientPacket{
+		mode:  f.mode(),
+		pType: pType,
+		body:  body,
+		id:    id,
+	}, warnings, nil
+}
+
+// readHeader reads the size, ID and type fields, and returns the number of bytes following them.
+func (f Framing) readHeader(reader io.Reader, maxBodySize int) (int32, PacketType, int32, error) {
+	order := f.mode()
+
+	var size, pType int32
+	if err := binary.Read(reader, order, &size); err != nil {
+		return 0, 0, 0, err
+	}
+
+	bodyLen := size
+	if !f.SizeExcludesHeader {
+		bodyLen -= f.headerSize()
+	}
+
+	if bodyLen < 0 {
+		return 0, 0, 0, errors.Wrapf(malformedPacketErr, "invalid packet size %d", size)
+	}
+
+	var id int32
+	if f.ID64 {
+		var id64 int64
+		if err := binary.Read(reader, order, &id64); err != nil {
+			return 0, 0, 0, err
+		}
+
+		if id64 < math.MinInt32 || id64 > math.MaxInt32 {
+			return 0, 0, 0, errors.Wrapf(malformedPacketErr, "packet ID %d doesn't fit into 32 bits", id64)
+		}
+
+		id = int32(id64)
+	} else if err := binary.Read(reader, order, &id); err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := binary.Read(reader, order, &pType); err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := checkBodySize(bodyLen, maxBodySize); err != nil {
+		return 0, 0, 0, err
+	}
+
+	return id, PacketType(pType), bodyLen, nil
+}
@@ -0,0 +1,85 @@
+package packet
+
+import (
+	"github.com/refractorgscm/rcon/endian"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// IDs reserved by IDAllocator for special packets, so that responses to them can never be confused with responses to
+// commands.
+const (
+	AuthPacketID      int32 = 1
+	KeepalivePacketID int32 = 2
+)
+
+// IDAllocator allocates packet IDs. IDs increase monotonically, skipping reserved and restricted IDs, and wrap around to
+// the first unreserved ID after reaching math.MaxInt32. Negative IDs are never allocated since -1 denotes a failed
+// authentication.
+//
+// Each client should use its own allocator so that the IDs of one connection don't depend on another. Keeping the
+// allocator across reconnects, or randomizing the starting ID, avoids reusing the same IDs after a reconnect, which
+// some servers mishandle.
+type IDAllocator struct {
+	lock sync.Mutex
+	last int32
+}
+
+// NewIDAllocator creates an IDAllocator. The first allocated ID is the first unreserved ID.
+func NewIDAllocator() *IDAllocator {
+	return &IDAllocator{}
+}
+
+// Next allocates the next ID, skipping reserved IDs and the provided restricted IDs.
+func (a *IDAllocator) Next(restrictedIDs []int32) int32 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for {
+		if a.last < 1 || a.last >= math.MaxInt32-1 {
+			a.last = 1
+		} else {
+			a.last++
+		}
+
+		if !isReservedID(a.last) && !idInArr(restrictedIDs, a.last) {
+			return a.last
+		}
+	}
+}
+
+// Seed sets the last allocated ID, so that the next allocated ID is the first allowed ID after it.
+func (a *IDAllocator) Seed(last int32) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.last = last
+}
+
+// Randomize seeds the allocator with a random ID in the lower half of the ID space, leaving plenty of room before the
+// IDs wrap around.
+func (a *IDAllocator) Randomize() {
+	a.Seed(rand.New(rand.NewSource(time.Now().UnixNano())).Int31n(math.MaxInt32 / 2))
+}
+
+func isReservedID(id int32) bool {
+	return id == AuthPacketID || id == KeepalivePacketID
+}
+
+// NewClientPacketWithID creates a packet with the provided ID instead of allocating one.
+func NewClientPacketWithID(mode endian.Mode, pType PacketType, body string, id int32) Packet {
+	p := &ClientPacket{
+		mode:  mode,
+		pType: pType,
+		body:  []byte(body),
+		id:    id,
+	}
+
+	if len(body) == 0 {
+		p.body = []byte{}
+	}
+
+	return p
+}
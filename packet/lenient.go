@@ -0,0 +1,31 @@
+package packet
+
+import (
+	"github.com/refractorgscm/rcon/endian"
+	"io"
+)
+
+// MaxLenientPacketSize is the largest packet size accepted by DecodeClientPacketLenient. Larger sizes are assumed to be
+// garbage rather than a real packet. The spec limits packets to 4096 bytes, but some servers send larger packets.
+const MaxLenientPacketSize = 1 << 16
+
+// DecodeClientPacketLenient decodes a packet like DecodeClientPacket, but tolerates common protocol violations by
+// noncompliant servers instead of failing:
+//
+//   - a missing second null terminator, or both terminators missing
+//   - trailing bytes after the body's null terminator, which are discarded
+//
+// Each tolerated violation is described in the returned warnings. Bytes sent beyond the declared packet size can't be
+// told apart from the next packet reliably, so they aren't tolerated.
+func DecodeClientPacketLenient(mode endian.Mode, reader io.Reader) (*ClientPacket, []string, error) {
+	return DecodeClientPacketLenientLimit(mode, reader, 0)
+}
+
+// DecodeClientPacketLenientLimit decodes a packet like DecodeClientPacketLenient, rejecting packets whose body exceeds
+// maxBody like DecodeClientPacketLimit.
+func DecodeClientPacketLenientLimit(mode endian.Mode, reader io.Reader, maxBody int) (*ClientPacket, []string, error) {
+	framing := SourceFraming
+	framing.Mode = mode
+
+	return framing.DecodeLenient(reader, maxBody)
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"github.com/franela/goblin"
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 	"github.com/refractorgscm/rcon/endian"
+	"github.com/refractorgscm/rcon/errs"
 	"math"
 	"testing"
 )
@@ -108,6 +110,291 @@ func Test(t *testing.T) {
 					Expect(err).To(BeNil())
 					Expect(decoded).To(Equal(packet))
 				})
+
+				g.It("Should keep binary bodies intact", func() {
+					body := string([]byte{0x00, 0xff, 0x01, ' ', '\n', 0x00, 0x80})
+					raw, err := NewClientPacketWithID(packet.mode, TypeCommandRes, body, 7).Build()
+					Expect(err).To(BeNil())
+
+					decoded, err := DecodeClientPacket(packet.mode, bytes.NewReader(raw))
+
+					Expect(err).To(BeNil())
+					Expect(decoded.RawBody()).To(Equal([]byte(body)))
+				})
+
+				g.It("Should reject bodies larger than the limit before reading them", func() {
+					huge := []byte{0xff, 0xff, 0xff, 0x7f, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+
+					_, err := DecodeClientPacketLimit(endian.Little, bytes.NewReader(huge), 4096)
+					Expect(errors.Cause(err)).To(Equal(errs.ErrResponseTooLarge))
+
+					_, err = DecodeClientPacketLimit(packet.mode, bytes.NewReader(rawPacket), 4096)
+					Expect(err).To(BeNil())
+				})
+			})
+		})
+
+		g.Describe("Framing", func() {
+			g.It("Should encode variant framings", func() {
+				p := NewClientPacketWithID(endian.Little, TypeCommand, "hi", 7)
+
+				out, err := Framing{Mode: endian.Big, Terminators: 1, SizeExcludesHeader: true, ID64: true}.Build(p)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(out).To(Equal([]byte{0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 7, 0, 0, 0, 2, 'h', 'i', 0}))
+			})
+
+			g.It("Should round trip packets", func() {
+				framings := []Framing{
+					SourceFraming,
+					{Terminators: 0},
+					{Mode: endian.Big, Terminators: 1, SizeExcludesHeader: true},
+					{Terminators: 2, ID64: true},
+				}
+
+				for _, f := range framings {
+					out, err := f.Build(NewClientPacketWithID(endian.Little, TypeCommandRes, "status", AuthFailedID))
+					Expect(err).ToNot(HaveOccurred())
+
+					decoded, err := f.Decode(bytes.NewReader(out), 0)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(decoded.ID()).To(Equal(int32(AuthFailedID)))
+					Expect(decoded.Type()).To(Equal(TypeCommandRes))
+					Expect(string(decoded.RawBody())).To(Equal("status"))
+
+					decoded, warnings, err := f.DecodeLenient(bytes.NewReader(out), 0)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(warnings).To(BeEmpty())
+					Expect(string(decoded.RawBody())).To(Equal("status"))
+				}
+			})
+
+			g.It("Should reject protocol violations in strict mode", func() {
+				out, err := SourceFraming.Build(NewClientPacketWithID(endian.Little, TypeCommandRes, "status", 5))
+				Expect(err).ToNot(HaveOccurred())
+
+				decoded, err := SourceFraming.DecodeStrict(bytes.NewReader(out), 0)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(decoded.RawBody())).To(Equal("status"))
+
+				out[len(out)-1] = 'x'
+				reader := bytes.NewReader(out)
+				_, err = SourceFraming.DecodeStrict(reader, 0)
+				Expect(err).To(BeAssignableToTypeOf(&Violation{}))
+				Expect(err.(*Violation).Offset).To(Equal(len(out) - 1))
+				Expect(err.(*Violation).Data).To(Equal(out))
+				Expect(reader.Len()).To(BeZero())
+			})
+
+			g.It("Should reject 64 bit IDs which don't fit into 32 bits", func() {
+				out := []byte{12, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0}
+				_, err := Framing{ID64: true}.Decode(bytes.NewReader(out), 0)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		g.Describe("Compression", func() {
+			body := bytes.Repeat([]byte("Player joined the game\n"), 100)
+
+			g.It("Should round trip a body through CompressBody and DecompressBody", func() {
+				compressed, err := CompressBody(Gzip, body)
+				Expect(err).To(BeNil())
+				Expect(IsCompressed(compressed)).To(BeTrue())
+				Expect(len(compressed)).To(BeNumerically("<", len(body)))
+
+				decompressed, err := DecompressBody(compressed)
+				Expect(err).To(BeNil())
+				Expect(decompressed).To(Equal(body))
+			})
+
+			g.It("Should survive being sent in a packet", func() {
+				compressed, err := CompressBody(Gzip, body)
+				Expect(err).To(BeNil())
+
+				raw, err := NewClientPacket(endian.Little, TypeCommand, string(compressed), nil).Build()
+				Expect(err).To(BeNil())
+
+				decoded, err := DecodeClientPacket(endian.Little, bytes.NewReader(raw))
+				Expect(err).To(BeNil())
+
+				decompressed, err := DecompressBody(decoded.body)
+				Expect(err).To(BeNil())
+				Expect(decompressed).To(Equal(body))
+			})
+
+			g.It("Should reject bodies which decompress beyond the limit", func() {
+				compressed, err := CompressBody(Gzip, body)
+				Expect(err).To(BeNil())
+
+				_, err = DecompressBodyLimit(compressed, len(body)-1)
+				Expect(errors.Cause(err)).To(Equal(errs.ErrResponseTooLarge))
+
+				decompressed, err := DecompressBodyLimit(compressed, len(body))
+				Expect(err).To(BeNil())
+				Expect(decompressed).To(Equal(body))
+			})
+
+			g.It("Should return uncompressed bodies as is", func() {
+				decompressed, err := DecompressBody([]byte("plain"))
+				Expect(err).To(BeNil())
+				Expect(decompressed).To(Equal([]byte("plain")))
+			})
+
+			g.It("Should return an error for unknown codecs", func() {
+				_, err := DecompressBody([]byte("\x01z:unknown:AAAA"))
+				Expect(err).ToNot(BeNil())
+			})
+		})
+
+		g.Describe("Signer", func() {
+			body := []byte("Ban PlayerID")
+
+			g.It("Should verify bodies it signed", func() {
+				signer := NewSigner("k1", []byte("secret"))
+
+				verified, err := signer.Verify(TypeCommand, signer.Sign(TypeCommand, body))
+				Expect(err).To(BeNil())
+				Expect(verified).To(Equal(body))
+			})
+
+			g.It("Should reject tampered bodies", func() {
+				signer := NewSigner("k1", []byte("secret"))
+
+				signed := signer.Sign(TypeCommand, body)
+				signed[len(signed)-1] = 'X'
+
+				_, err := signer.Verify(TypeCommand, signed)
+				Expect(err).To(Equal(errs.ErrBadSignature))
+			})
+
+			g.It("Should reject bodies signed for another packet type", func() {
+				signer := NewSigner("k1", []byte("secret"))
+
+				_, err := signer.Verify(TypeAuth, signer.Sign(TypeCommandRes, body))
+				Expect(err).To(Equal(errs.ErrBadSignature))
+			})
+
+			g.It("Should accept old keys until they are removed", func() {
+				signer := NewSigner("k1", []byte("secret"))
+				signed := signer.Sign(TypeCommand, body)
+
+				signer.Rotate("k2", []byte("new secret"))
+				_, err := signer.Verify(TypeCommand, signed)
+				Expect(err).To(BeNil())
+
+				signer.RemoveKey("k1")
+				_, err = signer.Verify(TypeCommand, signed)
+				Expect(err).To(Equal(errs.ErrBadSignature))
+			})
+
+			g.It("Should only reject unsigned bodies in strict mode", func() {
+				signer := NewSigner("k1", []byte("secret"))
+
+				_, err := signer.Verify(TypeCommand, body)
+				Expect(err).To(BeNil())
+
+				signer.Strict = true
+				_, err = signer.Verify(TypeCommand, body)
+				Expect(err).To(Equal(errs.ErrUnsignedPacket))
+			})
+		})
+
+		g.Describe("IDAllocator", func() {
+			g.It("Should skip reserved IDs", func() {
+				a := NewIDAllocator()
+
+				Expect(a.Next(nil)).To(Equal(int32(3)))
+				Expect(a.Next(nil)).To(Equal(int32(4)))
+			})
+
+			g.It("Should skip restricted IDs", func() {
+				a := NewIDAllocator()
+				a.Seed(9)
+
+				Expect(a.Next([]int32{10, 11})).To(Equal(int32(12)))
+			})
+
+			g.It("Should wrap around to the first unreserved ID", func() {
+				a := NewIDAllocator()
+				a.Seed(math.MaxInt32 - 1)
+
+				Expect(a.Next(nil)).To(Equal(int32(3)))
+			})
+
+			g.It("Should never allocate negative IDs", func() {
+				a := NewIDAllocator()
+				a.Seed(-5)
+
+				Expect(a.Next(nil)).To(Equal(int32(3)))
+			})
+
+			g.It("Should start at a random ID when randomized", func() {
+				a := NewIDAllocator()
+				a.Randomize()
+
+				Expect(a.Next(nil)).To(BeNumerically(">", 0))
+			})
+		})
+
+		g.Describe("DecodeClientPacketLenient()", func() {
+			build := func(mode endian.Mode, id int32, body []byte) []byte {
+				buf := make([]byte, 12, 12+len(body))
+				mode.PutUint32(buf[0:], uint32(8+len(body)))
+				mode.PutUint32(buf[4:], uint32(id))
+				mode.PutUint32(buf[8:], uint32(TypeCommandRes))
+				return append(buf, body...)
+			}
+
+			g.It("Should decode compliant packets without warnings", func() {
+				p, warnings, err := DecodeClientPacketLenient(endian.Little, bytes.NewReader(
+					build(endian.Little, 5, []byte("hello\x00\x00"))))
+
+				Expect(err).To(BeNil())
+				Expect(warnings).To(BeEmpty())
+				Expect(p.body).To(Equal([]byte("hello")))
+			})
+
+			g.It("Should tolerate a missing second null terminator", func() {
+				p, warnings, err := DecodeClientPacketLenient(endian.Little, bytes.NewReader(
+					build(endian.Little, 5, []byte("hello\x00"))))
+
+				Expect(err).To(BeNil())
+				Expect(warnings).To(HaveLen(1))
+				Expect(p.body).To(Equal([]byte("hello")))
+			})
+
+			g.It("Should discard trailing bytes after the body", func() {
+				p, warnings, err := DecodeClientPacketLenient(endian.Little, bytes.NewReader(
+					build(endian.Little, 5, []byte("hello\x00garbage"))))
+
+				Expect(err).To(BeNil())
+				Expect(warnings).To(HaveLen(1))
+				Expect(p.body).To(Equal([]byte("hello")))
+			})
+
+			g.It("Should tolerate missing null terminators", func() {
+				p, warnings, err := DecodeClientPacketLenient(endian.Little, bytes.NewReader(
+					build(endian.Little, 5, []byte("hello"))))
+
+				Expect(err).To(BeNil())
+				Expect(warnings).To(HaveLen(1))
+				Expect(p.body).To(Equal([]byte("hello")))
+			})
+
+			g.It("Should reject implausible packet sizes", func() {
+				data := build(endian.Little, 5, []byte("hello\x00\x00"))
+				endian.Little.PutUint32(data, 2)
+
+				_, _, err := DecodeClientPacketLenient(endian.Little, bytes.NewReader(data))
+				Expect(err).ToNot(BeNil())
+			})
+
+			g.It("Should decode big endian packets", func() {
+				p, warnings, err := DecodeClientPacketLenient(endian.Big, bytes.NewReader(
+					build(endian.Big, 5, []byte("hello\x00\x00"))))
+
+				Expect(err).To(BeNil())
+				Expect(warnings).To(BeEmpty())
+				Expect(p.id).To(Equal(int32(5)))
 			})
 		})
 	})
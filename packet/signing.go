@@ -0,0 +1,141 @@
+package packet
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"github.com/refractorgscm/rcon/errs"
+	"sync"
+)
+
+// signedPrefix marks a signed body. The full format is the prefix, the key ID, ':', the base64 encoded signature, ':'
+// and the original body.
+const signedPrefix = "\x01s:"
+
+// Signer signs and verifies packet bodies using HMAC-SHA256, so that the integrity of commands can be verified when
+// TLS isn't available. It is intended for servers and proxies built using this library, since game servers don't
+// understand signed bodies.
+//
+// Signatures cover the packet type and body. They don't protect against replayed packets.
+//
+// Keys are identified by an ID which is included in signed bodies, which allows keys to be rotated: add the new key
+// with Rotate, and remove the old key with RemoveKey once all peers have been updated.
+type Signer struct {
+	// Strict makes Verify reject unsigned bodies.
+	Strict bool
+
+	lock    sync.RWMutex
+	keys    map[string][]byte
+	current string
+}
+
+// NewSigner creates a Signer which signs bodies using the provided key.
+func NewSigner(keyID string, key []byte) *Signer {
+	s := &Signer{
+		keys: map[string][]byte{},
+	}
+
+	s.Rotate(keyID, key)
+
+	return s
+}
+
+// AddKey adds a key which is accepted by Verify without using it for signing.
+func (s *Signer) AddKey(keyID string, key []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.keys[keyID] = key
+}
+
+// Rotate adds a key and uses it for signing from now on. Previous keys are still accepted by Verify until they are
+// removed.
+func (s *Signer) Rotate(keyID string, key []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.keys[keyID] = key
+	s.current = keyID
+}
+
+// RemoveKey removes a key. If it is the key used for signing, bodies are no longer signed until Rotate is called.
+func (s *Signer) RemoveKey(keyID string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.keys, keyID)
+	if s.current == keyID {
+		s.current = ""
+	}
+}
+
+// Sign returns the signed version of body. If the signer has no signing key, body is returned as is.
+func (s *Signer) Sign(pType PacketType, body []byte) []byte {
+	s.lock.RLock()
+	keyID := s.current
+	key := s.keys[keyID]
+	s.lock.RUnlock()
+
+	if keyID == "" {
+		return body
+	}
+
+	sig := base64.StdEncoding.EncodeToString(signature(key, pType, body))
+
+	signed := make([]byte, 0, len(signedPrefix)+len(keyID)+len(sig)+2+len(body))
+	signed = append(signed, signedPrefix...)
+	signed = append(signed, keyID...)
+	signed = append(signed, ':')
+	signed = append(signed, sig...)
+	signed = append(signed, ':')
+	signed = append(signed, body...)
+
+	return signed
+}
+
+// Verify checks the signature of a signed body and returns the original body. Unsigned bodies are returned as is,
+// unless Strict is set, in which case errs.ErrUnsignedPacket is returned. If the signature is invalid or was made with
+// an unknown key, errs.ErrBadSignature is returned.
+func (s *Signer) Verify(pType PacketType, body []byte) ([]byte, error) {
+	if !bytes.HasPrefix(body, []byte(signedPrefix)) {
+		if s.Strict {
+			return nil, errs.ErrUnsignedPacket
+		}
+
+		return body, nil
+	}
+
+	parts := bytes.SplitN(body[len(signedPrefix):], []byte(":"), 3)
+	if len(parts) != 3 {
+		return nil, errs.ErrBadSignature
+	}
+
+	s.lock.RLock()
+	key, ok := s.keys[string(parts[0])]
+	s.lock.RUnlock()
+
+	if !ok {
+		return nil, errs.ErrBadSignature
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(string(parts[1]))
+	if err != nil || !hmac.Equal(sig, signature(key, pType, parts[2])) {
+		return nil, errs.ErrBadSignature
+	}
+
+	return parts[2], nil
+}
+
+func signature(key []byte, pType PacketType, body []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+
+	var typeBytes [4]byte
+	binary.LittleEndian.PutUint32(typeBytes[:], uint32(pType))
+
+	mac.Write(typeBytes[:])
+	mac.Write(body)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,82 @@
+package packet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// Violation is returned by DecodeStrict for packets which don't follow the protocol spec. It holds everything needed to
+// report the violation to the developers of the game server.
+type Violation struct {
+	// Offset is the position in Data at which the violation was found.
+	Offset int
+
+	// Expected describes what the spec requires at Offset, and Found what the server sent instead.
+	Expected string
+	Found    string
+
+	// Data is the packet as it was read from the connection, including its header.
+	Data []byte
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("protocol violation at offset %d: expected %s, found %s\n%s", v.Offset, v.Expected, v.Found,
+		hex.Dump(v.Data))
+}
+
+// DecodeStrict decodes a packet using the framing like Decode, but rejects packets whose body isn't followed by exactly
+// the framing's null terminators, or contains null bytes itself, with a *Violation. The whole packet is read before it
+// is rejected, so the stream can still be read.
+func (f Framing) DecodeStrict(reader io.Reader, maxBodySize int) (*ClientPacket, error) {
+	var header bytes.Buffer
+
+	id, pType, bodyLen, err := f.readHeader(io.TeeReader(reader, &header), maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, bodyLen)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, err
+	}
+
+	violation := func(offset int, expected, found string) *Violation {
+		return &Violation{
+			Offset:   header.Len() + offset,
+			Expected: expected,
+			Found:    found,
+			Data:     append(header.Bytes(), body...),
+		}
+	}
+
+	if len(body) < f.Terminators {
+		return nil, violation(0, fmt.Sprintf("%d null terminators", f.Terminators),
+			fmt.Sprintf("%d bytes", len(body)))
+	}
+
+	text := body[:len(body)-f.Terminators]
+	for i, b := range body[len(text):] {
+		if b != '\x00' {
+			return nil, violation(len(text)+i, "null terminator", fmt.Sprintf("byte 0x%02x", b))
+		}
+	}
+
+	if i := bytes.IndexByte(text, '\x00'); i >= 0 {
+		return nil, violation(i, "body text", "null byte")
+	}
+
+	p := &ClientPacket{
+		mode:  f.mode(),
+		pType: pType,
+		body:  bytes.Trim(text, "\n"),
+		id:    id,
+	}
+
+	if !bytes.Equal(text, p.body) {
+		p.raw = text
+	}
+
+	return p, nil
+}
@@ -1,9 +1,26 @@
 package packet
 
+// PacketType is the type field of a packet. The meaning of a type depends on the direction of the packet: type 2 is an
+// exec command request when sent by the client, but an auth response when sent by the server.
 type PacketType int32
 
-const TypeAuth = PacketType(3)
-const TypeAuthRes = PacketType(2)
-const TypeCommand = PacketType(2)
-const TypeCommandRes = PacketType(0)
+// Packet types using their names from Valve's Source RCON specification.
+const (
+	// TypeServerDataAuth (SERVERDATA_AUTH) is sent by the client to authenticate.
+	TypeServerDataAuth = PacketType(3)
+
+	// TypeServerDataAuthResponse (SERVERDATA_AUTH_RESPONSE) is sent by the server in response to an auth request.
+	TypeServerDataAuthResponse = PacketType(2)
+
+	// TypeServerDataExecCommand (SERVERDATA_EXECCOMMAND) is sent by the client to execute a command.
+	TypeServerDataExecCommand = PacketType(2)
+
+	// TypeServerDataResponseValue (SERVERDATA_RESPONSE_VALUE) is sent by the server in response to a command.
+	TypeServerDataResponseValue = PacketType(0)
+)
+
+const TypeAuth = TypeServerDataAuth
+const TypeAuthRes = TypeServerDataAuthResponse
+const TypeCommand = TypeServerDataExecCommand
+const TypeCommandRes = TypeServerDataResponseValue
 const AuthFailedID = -1
@@ -0,0 +1,121 @@
+package rcon
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"strings"
+)
+
+// DefaultMaxPages is the number of pages requested by a Pager if PagerConfig.MaxPages is zero.
+const DefaultMaxPages = 100
+
+// PagerConfig describes how a paginated listing, such as a ban list, is requested from the server. Games paginate
+// listings differently, so the commands and the terminal marker are configured per listing.
+type PagerConfig struct {
+	// Command returns the command which requests the provided page, starting at 1. The previous page is passed along,
+	// and is empty for the first page, for games whose "next page" command depends on it.
+	Command func(page int, previous string) string
+
+	// IsLast reports whether a response is the last page. If nil, paging stops at the first empty page, which is not
+	// included in the output.
+	IsLast func(response string) bool
+
+	// MaxPages is the maximum number of pages requested, which protects against servers which never send the
+	// terminal marker. If the limit is reached, errs.ErrPageLimit is returned.
+	//
+	// Default: 100
+	MaxPages int
+
+	// Separator is inserted between pages by Pager.All.
+	//
+	// Default: "\n"
+	Separator string
+}
+
+// PageCommand returns a PagerConfig.Command which formats the page number into format, e.g. "banlist %d". The first
+// page is requested with first, which may be the same command without a page number.
+func PageCommand(first, format string) func(page int, previous string) string {
+	return func(page int, _ string) string {
+		if page == 1 && first != "" {
+			return first
+		}
+
+		return fmt.Sprintf(format, page)
+	}
+}
+
+// ContainsMarker returns a PagerConfig.IsLast function which reports a page as the last one if it contains marker.
+func ContainsMarker(marker string) func(response string) bool {
+	return func(response string) bool {
+		return strings.Contains(response, marker)
+	}
+}
+
+// Pager executes the commands of a paginated listing until its last page.
+type Pager struct {
+	client *Client
+	config PagerConfig
+	opts   []ExecOption
+}
+
+// NewPager creates a pager for the listing described by config. The options are applied to every page's command.
+func NewPager(c *Client, config PagerConfig, opts ...ExecOption) *Pager {
+	if config.MaxPages == 0 {
+		config.MaxPages = DefaultMaxPages
+	}
+
+	if config.Separator == "" {
+		config.Separator = "\n"
+	}
+
+	return &Pager{
+		client: c,
+		config: config,
+		opts:   opts,
+	}
+}
+
+// Each requests the pages in order and passes each to fn as soon as it was received. Paging stops if fn returns an
+// error, which is returned.
+func (p *Pager) Each(fn func(page int, response string) error) error {
+	previous := ""
+
+	for page := 1; page <= p.config.MaxPages; page++ {
+		command := p.config.Command(page, previous)
+
+		res, err := p.client.ExecCommand(command, p.opts...)
+		if err != nil {
+			return errors.Wrapf(err, "page %d", page)
+		}
+
+		if p.config.IsLast == nil && strings.TrimSpace(res) == "" {
+			return nil
+		}
+
+		if err := fn(page, res); err != nil {
+			return err
+		}
+
+		if p.config.IsLast != nil && p.config.IsLast(res) {
+			return nil
+		}
+
+		previous = res
+	}
+
+	return errors.Wrapf(errs.ErrPageLimit, "%d pages", p.config.MaxPages)
+}
+
+// All requests every page and returns them joined by the separator. If paging fails, the pages received so far are
+// returned along with the error.
+func (p *Pager) All() (string, error) {
+	var pages []string
+
+	err := p.Each(func(_ int, response string) error {
+		pages = append(pages, response)
+		return nil
+	})
+
+	return strings.Join(pages, p.config.Separator), err
+}
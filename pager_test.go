@@ -0,0 +1,110 @@
+package rcon
+
+import (
+	"fmt"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"strings"
+	"testing"
+)
+
+func TestPager(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	// banList answers "banlist <page>" with two bans per page. The last page ends with marker if it isn't empty.
+	banList := func(bans []string, marker string) func(string) string {
+		return func(command string) string {
+			var page int
+			if _, err := fmt.Sscanf(command, "banlist %d", &page); err != nil {
+				page = 1
+			}
+
+			start := (page - 1) * 2
+			if start >= len(bans) {
+				return ""
+			}
+
+			end := start + 2
+			if end >= len(bans) {
+				return strings.Join(bans[start:], "\n") + marker
+			}
+
+			return strings.Join(bans[start:end], "\n")
+		}
+	}
+
+	g.Describe("Pager", func() {
+		g.It("Should stop at the first empty page", func() {
+			s := newTestServer(banList([]string{"a", "b", "c", "d", "e"}, ""))
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			var pages []int
+			err := NewPager(c, PagerConfig{Command: PageCommand("banlist", "banlist %d")}).Each(func(page int, _ string) error {
+				pages = append(pages, page)
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pages).To(Equal([]int{1, 2, 3}))
+
+			all, err := NewPager(c, PagerConfig{Command: PageCommand("banlist", "banlist %d")}).All()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(all).To(Equal("a\nb\nc\nd\ne"))
+		})
+
+		g.It("Should stop at the partial last page containing the marker", func() {
+			s := newTestServer(banList([]string{"a", "b", "c"}, "\n(end)"))
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			all, err := NewPager(c, PagerConfig{
+				Command:   PageCommand("", "banlist %d"),
+				IsLast:    ContainsMarker("(end)"),
+				Separator: "\n--\n",
+			}).All()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(all).To(Equal("a\nb\n--\nc\n(end)"))
+		})
+
+		g.It("Should return nothing for an empty listing", func() {
+			s := newTestServer(banList(nil, ""))
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			called := false
+			err := NewPager(c, PagerConfig{Command: PageCommand("banlist", "banlist %d")}).Each(func(int, string) error {
+				called = true
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(called).To(BeFalse())
+		})
+
+		g.It("Should stop after MaxPages pages", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			all, err := NewPager(c, PagerConfig{Command: PageCommand("", "page %d"), MaxPages: 2}).All()
+			Expect(errors.Cause(err)).To(Equal(errs.ErrPageLimit))
+			Expect(all).To(Equal("echo: page 1\necho: page 2"))
+		})
+	})
+}
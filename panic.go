@@ -0,0 +1,34 @@
+package rcon
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicHandler is called when a user-provided handler panicked. source names the kind of handler, such as
+// "broadcast handler" or "event handler", and recovered is the value passed to panic.
+type PanicHandler func(source string, recovered interface{}, stack []byte)
+
+// callHandler calls a user-provided handler, recovering from any panic so that a faulty handler can't crash the
+// routine it runs on. Without this, a panicking broadcast handler would take the whole process down, or silently stop
+// broadcast delivery if recovered further up.
+func (c *Client) callHandler(source string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.handlePanic(source, r, debug.Stack())
+		}
+	}()
+
+	fn()
+}
+
+func (c *Client) handlePanic(source string, recovered interface{}, stack []byte) {
+	c.stats.recordError(fmt.Errorf("%s panicked: %v", source, recovered))
+
+	if c.PanicHandler != nil {
+		c.PanicHandler(source, recovered, stack)
+		return
+	}
+
+	c.log.Error("Recovered from panic in ", source, ": ", recovered, "\n", string(stack))
+}
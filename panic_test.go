@@ -0,0 +1,47 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func TestPanic(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Panic recovery", func() {
+		g.It("Should recover from panicking handlers and keep delivering", func() {
+			var sources []string
+			var delivered []string
+
+			c := NewClient(&Config{
+				BroadcastHandler: func(string) {
+					panic("boom")
+				},
+				BroadcastSinks: []BroadcastHandler{func(msg string) {
+					delivered = append(delivered, msg)
+				}},
+				PanicHandler: func(source string, recovered interface{}, stack []byte) {
+					sources = append(sources, source)
+					Expect(recovered).To(Equal("boom"))
+					Expect(stack).NotTo(BeEmpty())
+				},
+			}, nil)
+
+			c.Events().Subscribe(func(Event) {
+				panic("boom")
+			})
+
+			c.handleBroadcast("first")
+			c.events.emit(ConnectedEvent{})
+			c.handleBroadcast("second")
+
+			Expect(delivered).To(Equal([]string{"first", "second"}))
+			Expect(sources).To(Equal([]string{"broadcast handler", "event handler", "broadcast handler"}))
+			Expect(c.Stats().LastError).NotTo(BeNil())
+		})
+	})
+}
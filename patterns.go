@@ -0,0 +1,172 @@
+package rcon
+
+import (
+	"github.com/refractorgscm/rcon/errs"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// NamedPattern is a broadcast filter pattern which can be added and removed at runtime. Patterns are identified by
+// Name and can be enabled and disabled together by Group, such as "noise" or "chat".
+//
+// Like BroadcastPatterns, allow patterns form an allowlist: if any are enabled, only broadcasts matching at least one
+// of them are delivered. Deny patterns drop the broadcasts matching them. Runtime patterns are applied in addition to
+// the BroadcastFilter.
+type NamedPattern struct {
+	Name    string
+	Group   string
+	Pattern *regexp.Regexp
+
+	// Deny makes the pattern drop matching broadcasts instead of allowing them.
+	Deny bool
+}
+
+// patternRegistry holds the runtime patterns of a client. The PatternFilter built from the enabled patterns is rebuilt
+// on every change, so broadcasts are always checked against a consistent set of patterns.
+type patternRegistry struct {
+	lock     sync.RWMutex
+	patterns map[string]NamedPattern
+	disabled map[string]bool
+	filter   *PatternFilter
+}
+
+// rebuild compiles the enabled patterns into a new filter. It must be called with the lock held.
+func (r *patternRegistry) rebuild() {
+	var allow, deny []*regexp.Regexp
+
+	for _, p := range r.sorted() {
+		if r.disabled[p.Group] {
+			continue
+		}
+
+		if p.Deny {
+			deny = append(deny, p.Pattern)
+		} else {
+			allow = append(allow, p.Pattern)
+		}
+	}
+
+	if len(allow) == 0 && len(deny) == 0 {
+		r.filter = nil
+		return
+	}
+
+	r.filter = NewPatternFilter(allow, deny)
+}
+
+// sorted returns the patterns sorted by group and name. It must be called with the lock held.
+func (r *patternRegistry) sorted() []NamedPattern {
+	patterns := make([]NamedPattern, 0, len(r.patterns))
+	for _, p := range r.patterns {
+		patterns = append(patterns, p)
+	}
+
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].Group != patterns[j].Group {
+			return patterns[i].Group < patterns[j].Group
+		}
+
+		return patterns[i].Name < patterns[j].Name
+	})
+
+	return patterns
+}
+
+func (r *patternRegistry) accept(body []byte) bool {
+	r.lock.RLock()
+	filter := r.filter
+	r.lock.RUnlock()
+
+	return filter == nil || filter.Accept(body)
+}
+
+// AddFilterPattern adds a runtime broadcast pattern, replacing any pattern with the same name. ErrInvalidPattern is
+// returned if the pattern has no name or no regexp.
+func (c *Client) AddFilterPattern(p NamedPattern) error {
+	if p.Name == "" || p.Pattern == nil {
+		return errs.ErrInvalidPattern
+	}
+
+	c.patterns.lock.Lock()
+	defer c.patterns.lock.Unlock()
+
+	if c.patterns.patterns == nil {
+		c.patterns.patterns = map[string]NamedPattern{}
+	}
+
+	c.patterns.patterns[p.Name] = p
+	c.patterns.rebuild()
+
+	return nil
+}
+
+// RemoveFilterPattern removes the runtime broadcast pattern with the provided name. It returns false if there was none.
+func (c *Client) RemoveFilterPattern(name string) bool {
+	c.patterns.lock.Lock()
+	defer c.patterns.lock.Unlock()
+
+	if _, ok := c.patterns.patterns[name]; !ok {
+		return false
+	}
+
+	delete(c.patterns.patterns, name)
+	c.patterns.rebuild()
+
+	return true
+}
+
+// RemoveFilterGroup removes all runtime broadcast patterns of a group and returns how many were removed.
+func (c *Client) RemoveFilterGroup(group string) int {
+	c.patterns.lock.Lock()
+	defer c.patterns.lock.Unlock()
+
+	removed := 0
+	for name, p := range c.patterns.patterns {
+		if p.Group == group {
+			delete(c.patterns.patterns, name)
+			removed++
+		}
+	}
+
+	if removed > 0 {
+		c.patterns.rebuild()
+	}
+
+	return removed
+}
+
+// FilterPatterns returns the runtime broadcast patterns sorted by group and name, including those of disabled groups.
+func (c *Client) FilterPatterns() []NamedPattern {
+	c.patterns.lock.RLock()
+	defer c.patterns.lock.RUnlock()
+
+	return c.patterns.sorted()
+}
+
+// SetFilterGroupEnabled enables or disables all runtime broadcast patterns of a group at once. Groups are enabled by
+// default, and stay disabled while their patterns are removed and added again.
+func (c *Client) SetFilterGroupEnabled(group string, enabled bool) {
+	c.patterns.lock.Lock()
+	defer c.patterns.lock.Unlock()
+
+	if c.patterns.disabled == nil {
+		c.patterns.disabled = map[string]bool{}
+	}
+
+	if enabled {
+		delete(c.patterns.disabled, group)
+	} else {
+		c.patterns.disabled[group] = true
+	}
+
+	c.patterns.rebuild()
+}
+
+// FilterGroupEnabled returns false if the group of runtime broadcast patterns was disabled.
+func (c *Client) FilterGroupEnabled(group string) bool {
+	c.patterns.lock.RLock()
+	defer c.patterns.lock.RUnlock()
+
+	return !c.patterns.disabled[group]
+}
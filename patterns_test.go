@@ -0,0 +1,44 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/errs"
+	"regexp"
+	"testing"
+)
+
+func TestPatterns(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Filter patterns", func() {
+		g.It("Should add, remove and toggle patterns by name and group", func() {
+			c := NewClient(&Config{}, nil)
+
+			Expect(c.AddFilterPattern(NamedPattern{Name: "tick", Group: "noise", Pattern: regexp.MustCompile(`^Tick:`),
+				Deny: true})).To(Succeed())
+			Expect(c.AddFilterPattern(NamedPattern{Name: "save", Group: "noise", Pattern: regexp.MustCompile(`saved`),
+				Deny: true})).To(Succeed())
+			Expect(c.AddFilterPattern(NamedPattern{Name: "unnamed"})).To(Equal(errs.ErrInvalidPattern))
+
+			Expect(c.patterns.accept([]byte("Tick: 1"))).To(BeFalse())
+			Expect(c.patterns.accept([]byte("Chat: hi"))).To(BeTrue())
+
+			c.SetFilterGroupEnabled("noise", false)
+			Expect(c.FilterGroupEnabled("noise")).To(BeFalse())
+			Expect(c.patterns.accept([]byte("Tick: 1"))).To(BeTrue())
+			Expect(c.FilterPatterns()).To(HaveLen(2))
+
+			c.SetFilterGroupEnabled("noise", true)
+			Expect(c.RemoveFilterPattern("tick")).To(BeTrue())
+			Expect(c.patterns.accept([]byte("Tick: 1"))).To(BeTrue())
+			Expect(c.patterns.accept([]byte("World saved"))).To(BeFalse())
+
+			Expect(c.RemoveFilterGroup("noise")).To(Equal(1))
+			Expect(c.FilterPatterns()).To(BeEmpty())
+		})
+	})
+}
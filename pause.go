@@ -0,0 +1,71 @@
+package rcon
+
+// Pause puts the client into maintenance mode, for example during a planned server restart. While paused:
+//
+//   - heartbeats are not sent
+//   - disconnects are reported as expected, and automatic reconnect attempts wait until Resume is called
+//   - broadcasts are discarded
+//   - commands queued using QueueCommand are kept queued until Resume is called
+//
+// Commands can still be executed while the client is connected, so that the restart itself can be triggered after
+// pausing. If the client is disconnected, ExecCommand fails with ErrPaused.
+func (c *Client) Pause() {
+	c.pauseLock.Lock()
+	defer c.pauseLock.Unlock()
+
+	if c.resumed != nil {
+		return
+	}
+
+	c.resumed = make(chan struct{})
+	c.log.Info("Client paused")
+}
+
+// Resume leaves maintenance mode. Waiting reconnect attempts are made immediately and the offline queue is flushed once
+// the client is connected.
+func (c *Client) Resume() {
+	c.pauseLock.Lock()
+	if c.resumed == nil {
+		c.pauseLock.Unlock()
+		return
+	}
+
+	close(c.resumed)
+	c.resumed = nil
+	c.pauseLock.Unlock()
+
+	c.log.Info("Client resumed")
+
+	if c.isConnected() {
+		go c.flushOfflineQueue()
+	}
+}
+
+// Paused returns true if the client is paused.
+func (c *Client) Paused() bool {
+	c.pauseLock.Lock()
+	defer c.pauseLock.Unlock()
+
+	return c.resumed != nil
+}
+
+// waitResumed blocks until the client is resumed or stop is closed. It returns immediately if the client isn't paused.
+// It returns false if stop was closed.
+func (c *Client) waitResumed(stop chan struct{}) bool {
+	c.pauseLock.Lock()
+	resumed := c.resumed
+	c.pauseLock.Unlock()
+
+	if resumed == nil {
+		return true
+	}
+
+	c.log.Debug("Waiting for the client to be resumed")
+
+	select {
+	case <-resumed:
+		return true
+	case <-stop:
+		return false
+	}
+}
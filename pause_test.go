@@ -0,0 +1,102 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/errs"
+	"github.com/refractorgscm/rcon/packet"
+	"github.com/refractorgscm/rcon/rcontest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPause(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Pause", func() {
+		g.It("Should hold back reconnect attempts until resumed", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{ReconnectPolicy: &ReconnectPolicy{InitialDelay: time.Millisecond * 10}})
+			events := recordEvents(c, EventDisconnected, EventReconnectAttempt)
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			c.Pause()
+			Expect(c.Paused()).To(BeTrue())
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+
+			s.CloseConnections()
+
+			Eventually(events).Should(HaveLen(1))
+			Expect(events()[0].(DisconnectedEvent).Expected).To(BeTrue())
+			Consistently(events, time.Millisecond*100).Should(HaveLen(1))
+
+			_, err := c.ExecCommand("status")
+			Expect(err).To(Equal(errs.ErrPaused))
+
+			c.Resume()
+			Expect(c.Paused()).To(BeFalse())
+			Eventually(c.State).Should(Equal(StateConnected))
+			Expect(events()[1]).To(Equal(ReconnectAttemptEvent{Attempt: 1}))
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+		})
+
+		g.It("Should discard broadcasts while paused", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			broadcasts := make(chan string, 2)
+			c := newTestClient(s, &Config{
+				BroadcastChecker: func(p packet.Packet) bool {
+					return p.ID() == rcontest.BroadcastID
+				},
+				BroadcastHandler: func(message string) {
+					broadcasts <- message
+				},
+			})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			c.Pause()
+			s.Broadcast("Server restarting")
+			Consistently(broadcasts, time.Millisecond*100).ShouldNot(Receive())
+
+			c.Resume()
+			s.Broadcast("Server restarted")
+			Eventually(broadcasts).Should(Receive(Equal("Server restarted")))
+		})
+
+		g.It("Should keep commands queued until resumed", func() {
+			var lock sync.Mutex
+			var executed []string
+			s := newTestServer(func(command string) string {
+				lock.Lock()
+				executed = append(executed, command)
+				lock.Unlock()
+				return ""
+			})
+			defer s.Close()
+
+			c := newTestClient(s, &Config{OfflineQueue: &OfflineQueue{}})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			c.Pause()
+			Expect(c.QueueCommand("say Back online", PriorityNormal)).To(Succeed())
+			Consistently(c.OfflineQueue.Store.Len, time.Millisecond*100).Should(Equal(1))
+
+			c.Resume()
+			Eventually(c.OfflineQueue.Store.Len).Should(Equal(0))
+
+			lock.Lock()
+			Expect(executed).To(Equal([]string{"say Back online"}))
+			lock.Unlock()
+		})
+	})
+}
@@ -0,0 +1,306 @@
+package rcon
+
+import (
+	"github.com/pkg/errors"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BroadcastParser turns a broadcast into an event, such as a PlayerJoinedEvent. It returns nil if the broadcast isn't
+// recognised. Parsed events are emitted on the client's EventBus.
+type BroadcastParser func(b Broadcast) Event
+
+// NewPlayerEventParser creates a BroadcastParser which emits a PlayerJoinedEvent for broadcasts matching joined and a
+// PlayerLeftEvent for broadcasts matching left. The player's ID and name are taken from the capture groups named "id"
+// and "name". If a pattern has no "id" group, the name is used as the ID. Either pattern may be nil.
+func NewPlayerEventParser(joined, left *regexp.Regexp) BroadcastParser {
+	match := func(pattern *regexp.Regexp, message string) (id, name string, ok bool) {
+		if pattern == nil {
+			return "", "", false
+		}
+
+		m := pattern.FindStringSubmatch(message)
+		if m == nil {
+			return "", "", false
+		}
+
+		if i := pattern.SubexpIndex("name"); i >= 0 {
+			name = m[i]
+		}
+
+		id = name
+		if i := pattern.SubexpIndex("id"); i >= 0 {
+			id = m[i]
+		}
+
+		return id, name, true
+	}
+
+	return func(b Broadcast) Event {
+		if id, name, ok := match(joined, b.Message); ok {
+			return PlayerJoinedEvent{ID: id, Name: name, Time: b.ReceivedAt}
+		}
+
+		if id, name, ok := match(left, b.Message); ok {
+			return PlayerLeftEvent{ID: id, Name: name, Time: b.ReceivedAt}
+		}
+
+		return nil
+	}
+}
+
+// Player is a player on the server, as tracked by a PlayerTracker.
+type Player struct {
+	ID   string
+	Name string
+
+	// JoinedAt is the time the player joined. If the player was already on the server when tracking began, it is the
+	// time the player was first seen and JoinTimeKnown is false.
+	JoinedAt      time.Time
+	JoinTimeKnown bool
+}
+
+// Playtime returns the amount of time the player has been on the server for.
+func (p Player) Playtime() time.Duration {
+	return time.Since(p.JoinedAt)
+}
+
+// PlayerListParser parses the response to the player list command.
+type PlayerListParser func(response string) ([]Player, error)
+
+// PlayerTrackerConfig configures a PlayerTracker.
+type PlayerTrackerConfig struct {
+	// ListCommand is the command which lists the players on the server, e.g. "ListPlayers". If empty, the roster is
+	// never reconciled and only join and leave events are used.
+	ListCommand string
+
+	// ParseList parses the response to ListCommand. Only the ID and Name of the returned players are used.
+	ParseList PlayerListParser
+
+	// ReconcileInterval is how often the roster is reconciled with the player list. The roster is always reconciled
+	// after connecting, since join and leave events are missed while disconnected. If zero, the roster is only
+	// reconciled after connecting.
+	ReconcileInterval time.Duration
+}
+
+// PlayerTracker maintains an in-memory roster of the players on a server using PlayerJoinedEvent and PlayerLeftEvent
+// events, which are emitted by the client's BroadcastParsers. Since events can be missed, the roster is periodically
+// reconciled with the server's player list.
+type PlayerTracker struct {
+	client *Client
+	config PlayerTrackerConfig
+
+	lock    sync.Mutex
+	players map[string]*trackedPlayer
+	// left holds the time of recent leave events, so that a player who left while a reconcile was in flight isn't
+	// added back from its stale player list.
+	left map[string]time.Time
+
+	unsubscribe func()
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+type trackedPlayer struct {
+	Player
+	// updatedAt is the time of the last event or player list which confirmed the player's presence.
+	updatedAt time.Time
+}
+
+// NewPlayerTracker creates a PlayerTracker for a client and starts tracking. Call Stop to stop tracking.
+func NewPlayerTracker(c *Client, config PlayerTrackerConfig) *PlayerTracker {
+	t := &PlayerTracker{
+		client:  c,
+		config:  config,
+		players: map[string]*trackedPlayer{},
+		left:    map[string]time.Time{},
+		stop:    make(chan struct{}),
+	}
+
+	t.unsubscribe = c.Events().Subscribe(t.handleEvent, EventPlayerJoined, EventPlayerLeft, EventConnected)
+
+	if config.ReconcileInterval > 0 && config.ListCommand != "" {
+		go t.reconcileLoop()
+	}
+
+	return t
+}
+
+// Stop stops tracking. The roster is kept as it was.
+func (t *PlayerTracker) Stop() {
+	t.stopOnce.Do(func() {
+		t.unsubscribe()
+		close(t.stop)
+	})
+}
+
+// Players returns the players currently on the server, ordered by join time.
+func (t *PlayerTracker) Players() []Player {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	players := make([]Player, 0, len(t.players))
+	for _, p := range t.players {
+		players = append(players, p.Player)
+	}
+
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].JoinedAt.Before(players[j].JoinedAt)
+	})
+
+	return players
+}
+
+// Player returns the player with the provided ID. ok is false if the player isn't on the server.
+func (t *PlayerTracker) Player(id string) (player Player, ok bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	p, ok := t.players[id]
+	if !ok {
+		return Player{}, false
+	}
+
+	return p.Player, true
+}
+
+func (t *PlayerTracker) handleEvent(event Event) {
+	switch e := event.(type) {
+	case PlayerJoinedEvent:
+		t.join(e.ID, e.Name, e.Time)
+	case PlayerLeftEvent:
+		t.leave(e.ID, e.Time)
+	case ConnectedEvent:
+		if t.config.ListCommand != "" {
+			// ConnectedEvent is emitted from within Connect, so the command is executed separately to avoid blocking it.
+			go t.reconcileAndLog()
+		}
+	}
+}
+
+func (t *PlayerTracker) join(id, name string, at time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.left, id)
+
+	if p, ok := t.players[id]; ok {
+		p.Name = name
+		p.updatedAt = at
+		return
+	}
+
+	t.players[id] = &trackedPlayer{
+		Player: Player{
+			ID:            id,
+			Name:          name,
+			JoinedAt:      at,
+			JoinTimeKnown: true,
+		},
+		updatedAt: at,
+	}
+}
+
+func (t *PlayerTracker) leave(id string, at time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.players, id)
+	t.left[id] = at
+}
+
+// Reconcile fetches the player list and updates the roster to match it. Players who joined or left while the command
+// was in flight are left as they are, since the player list may not reflect their change yet.
+func (t *PlayerTracker) Reconcile() error {
+	if t.config.ListCommand == "" || t.config.ParseList == nil {
+		return errors.New("player tracker has no list command configured")
+	}
+
+	start := time.Now()
+
+	res, err := t.client.ExecCommand(t.config.ListCommand)
+	if err != nil {
+		return errors.Wrap(err, "could not fetch player list")
+	}
+
+	listed, err := t.config.ParseList(res)
+	if err != nil {
+		return errors.Wrap(err, "could not parse player list")
+	}
+
+	t.apply(listed, start)
+
+	return nil
+}
+
+// apply updates the roster to match a player list which was requested at start.
+func (t *PlayerTracker) apply(listed []Player, start time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := time.Now()
+	present := map[string]bool{}
+
+	for _, lp := range listed {
+		present[lp.ID] = true
+
+		if leftAt, ok := t.left[lp.ID]; ok && !leftAt.Before(start) {
+			continue
+		}
+
+		if p, ok := t.players[lp.ID]; ok {
+			p.Name = lp.Name
+			p.updatedAt = now
+			continue
+		}
+
+		t.players[lp.ID] = &trackedPlayer{
+			Player: Player{
+				ID:       lp.ID,
+				Name:     lp.Name,
+				JoinedAt: now,
+			},
+			updatedAt: now,
+		}
+	}
+
+	for id, p := range t.players {
+		if !present[id] && p.updatedAt.Before(start) {
+			delete(t.players, id)
+		}
+	}
+
+	// Leave events older than this reconcile can no longer affect the roster.
+	for id, leftAt := range t.left {
+		if leftAt.Before(start) {
+			delete(t.left, id)
+		}
+	}
+}
+
+func (t *PlayerTracker) reconcileAndLog() {
+	if err := t.Reconcile(); err != nil {
+		t.client.log.Error("Could not reconcile player roster. Error: ", err)
+	}
+}
+
+func (t *PlayerTracker) reconcileLoop() {
+	ticker := time.NewTicker(t.config.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.stop:
+			return
+		}
+
+		if t.client.State() != StateConnected {
+			continue
+		}
+
+		t.reconcileAndLog()
+	}
+}
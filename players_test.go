@@ -0,0 +1,82 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPlayers(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("PlayerTracker", func() {
+		var c *Client
+		var t *PlayerTracker
+
+		g.BeforeEach(func() {
+			c = NewClient(&Config{
+				BroadcastParsers: []BroadcastParser{NewPlayerEventParser(
+					regexp.MustCompile(`^Join: (?P<name>\w+) \((?P<id>\d+)\)`),
+					regexp.MustCompile(`^Leave: (?P<name>\w+) \((?P<id>\d+)\)`),
+				)},
+			}, nil)
+			t = NewPlayerTracker(c, PlayerTrackerConfig{})
+		})
+
+		g.AfterEach(func() {
+			t.Stop()
+		})
+
+		g.It("Should track players using parsed broadcasts", func() {
+			c.handleBroadcast("Join: alice (1)")
+			c.handleBroadcast("Join: bob (2)")
+			c.handleBroadcast("Leave: alice (1)")
+
+			players := t.Players()
+			Expect(players).To(HaveLen(1))
+			Expect(players[0].ID).To(Equal("2"))
+			Expect(players[0].Name).To(Equal("bob"))
+			Expect(players[0].JoinTimeKnown).To(BeTrue())
+		})
+
+		g.It("Should reconcile the roster with the player list", func() {
+			c.handleBroadcast("Join: alice (1)")
+			joined, _ := t.Player("1")
+
+			start := time.Now()
+			t.apply([]Player{{ID: "1", Name: "alice"}, {ID: "3", Name: "carol"}}, start)
+
+			alice, ok := t.Player("1")
+			Expect(ok).To(BeTrue())
+			Expect(alice.JoinedAt).To(Equal(joined.JoinedAt))
+
+			carol, ok := t.Player("3")
+			Expect(ok).To(BeTrue())
+			Expect(carol.JoinTimeKnown).To(BeFalse())
+
+			t.apply([]Player{{ID: "3", Name: "carol"}}, time.Now())
+
+			_, ok = t.Player("1")
+			Expect(ok).To(BeFalse())
+		})
+
+		g.It("Should not apply stale player lists to players who joined or left during a reconcile", func() {
+			c.handleBroadcast("Join: alice (1)")
+			start := time.Now()
+			c.handleBroadcast("Leave: alice (1)")
+			c.handleBroadcast("Join: bob (2)")
+
+			t.apply([]Player{{ID: "1", Name: "alice"}}, start)
+
+			_, ok := t.Player("1")
+			Expect(ok).To(BeFalse())
+			_, ok = t.Player("2")
+			Expect(ok).To(BeTrue())
+		})
+	})
+}
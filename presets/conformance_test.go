@@ -0,0 +1,175 @@
+package presets
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/endian"
+	"github.com/refractorgscm/rcon/packet"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update rewrites the golden files from the encoder instead of checking against them. Only use it for intentional wire
+// format changes, and review the resulting diff.
+var update = flag.Bool("update", false, "update golden files")
+
+// goldenDir holds one directory per dialect, with a golden file per packet.
+const goldenDir = "testdata/conformance"
+
+// conformanceCase is a packet whose wire format is pinned by a golden file.
+type conformanceCase struct {
+	dialect string
+	name    string
+	pType   packet.PacketType
+	id      int32
+	body    string
+}
+
+func (cc conformanceCase) path() string {
+	return filepath.Join(goldenDir, cc.dialect, cc.name+".golden")
+}
+
+var conformanceCases = []conformanceCase{
+	{"source", "auth", packet.TypeServerDataAuth, 1, "password"},
+	{"source", "auth_response", packet.TypeServerDataAuthResponse, 1, ""},
+	{"source", "auth_failed", packet.TypeServerDataAuthResponse, packet.AuthFailedID, ""},
+	{"source", "exec_command", packet.TypeServerDataExecCommand, 42, "status"},
+	{"source", "response_value", packet.TypeServerDataResponseValue, 42, "hostname: test\nmap: de_dust2\n"},
+	{"source", "empty_response", packet.TypeServerDataResponseValue, 42, ""},
+	{"source", "mirror_terminator", packet.TypeServerDataResponseValue, 42, "\x00\x01\x00\x00"},
+
+	{"mordhau", "auth", packet.TypeServerDataAuth, 1, "password"},
+	{"mordhau", "exec_command", packet.TypeServerDataExecCommand, 2, "PlayerList"},
+	{"mordhau", "broadcast", packet.TypeServerDataResponseValue, 54321, "Chat: 76561198000000000, Bob, (0) hello"},
+
+	{"minecraft", "auth", packet.TypeServerDataAuth, 1, "password"},
+	{"minecraft", "exec_command", packet.TypeServerDataExecCommand, 7, "list"},
+	{"minecraft", "response_value", packet.TypeServerDataResponseValue, 7, "There are 0 of a max of 20 players online: "},
+	{"minecraft", "unicode_response", packet.TypeServerDataResponseValue, 8, "§aWelcome, Jöhn ✓"},
+
+	{"factorio", "auth", packet.TypeServerDataAuth, 1, "password"},
+	{"factorio", "exec_command", packet.TypeServerDataExecCommand, 3, "/players online"},
+	{"factorio", "response_value", packet.TypeServerDataResponseValue, 3, "Online players (1):\n  alice (online)\n"},
+}
+
+// formatGolden renders wire bytes as a hex dump with a header describing the packet, so that diffs are reviewable.
+func formatGolden(cc conformanceCase, wire []byte) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "# dialect: %s\n# type: %d, id: %d, body: %q\n", cc.dialect, cc.pType, cc.id, cc.body)
+
+	for i := 0; i < len(wire); i += 16 {
+		end := i + 16
+		if end > len(wire) {
+			end = len(wire)
+		}
+
+		line := make([]string, 0, 16)
+		for _, b := range wire[i:end] {
+			line = append(line, fmt.Sprintf("%02x", b))
+		}
+
+		buf.WriteString(strings.Join(line, " ") + "\n")
+	}
+
+	return buf.Bytes()
+}
+
+// parseGolden reads the wire bytes from a golden file, skipping comments.
+func parseGolden(data []byte) ([]byte, error) {
+	var digits strings.Builder
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		digits.WriteString(strings.Join(strings.Fields(line), ""))
+	}
+
+	return hex.DecodeString(digits.String())
+}
+
+func Test(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Conformance", func() {
+		g.It("Should have fixtures for every dialect", func() {
+			for _, d := range Dialects {
+				found := false
+				for _, cc := range conformanceCases {
+					found = found || cc.dialect == d.Name
+				}
+
+				Expect(found).To(BeTrue(), "no fixtures for dialect %s", d.Name)
+			}
+		})
+
+		for _, cc := range conformanceCases {
+			cc := cc
+
+			g.It(fmt.Sprintf("Should match the golden bytes of %s/%s", cc.dialect, cc.name), func() {
+				wire, err := packet.NewClientPacketWithID(endian.Little, cc.pType, cc.body, cc.id).Build()
+				Expect(err).ToNot(HaveOccurred())
+
+				if *update {
+					Expect(os.MkdirAll(filepath.Dir(cc.path()), 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(cc.path(), formatGolden(cc, wire), 0644)).To(Succeed())
+				}
+
+				data, err := ioutil.ReadFile(cc.path())
+				Expect(err).ToNot(HaveOccurred())
+
+				golden, err := parseGolden(data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(wire).To(Equal(golden), "encoded packet differs from %s", cc.path())
+
+				decoded, err := packet.DecodeClientPacket(endian.Little, bytes.NewReader(golden))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decoded.ID()).To(Equal(cc.id))
+				Expect(decoded.Type()).To(Equal(cc.pType))
+				Expect(string(decoded.RawBody())).To(Equal(cc.body))
+			})
+		}
+
+		g.It("Should recognise Mordhau broadcasts", func() {
+			data, err := ioutil.ReadFile(filepath.Join(goldenDir, "mordhau", "broadcast.golden"))
+			Expect(err).ToNot(HaveOccurred())
+
+			golden, err := parseGolden(data)
+			Expect(err).ToNot(HaveOccurred())
+
+			decoded, err := packet.DecodeClientPacket(endian.Little, bytes.NewReader(golden))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(MordhauBroadcastChecker(decoded)).To(BeTrue())
+		})
+	})
+
+	g.Describe("Argument quoting", func() {
+		expected := map[string]string{
+			"source":    `kick "Some \"Player\"" spam`,
+			"mordhau":   `kick Some "Player" spam`,
+			"minecraft": `kick Some "Player" spam`,
+			"factorio":  `kick Some "Player" spam`,
+		}
+
+		for _, d := range Dialects {
+			d := d
+
+			g.It(fmt.Sprintf("Should quote arguments for %s", d.Name), func() {
+				Expect(d.FormatCommand("kick %s %s", `Some "Player"`, "spam")).To(Equal(expected[d.Name]))
+			})
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package presets
+
+import (
+	"github.com/refractorgscm/rcon"
+	"strings"
+)
+
+// SourceDialect is the dialect of Source engine servers, which follow Valve's specification. Unlike DefaultDialect,
+// it limits command bodies to the 4086 bytes Source servers accept.
+var SourceDialect = &rcon.Dialect{
+	Name:                  "source",
+	SupportsFragmentation: true,
+	AuthResponsePreamble:  true,
+	MaxPayloadSize:        4086,
+	FingerprintCommand:    "status",
+	ParseFingerprint:      rcon.ParseSourceStatus,
+	CvarBatchSeparator:    ";",
+}
+
+// MordhauDialect is the dialect of Mordhau servers. Mordhau sends broadcast messages using the packet IDs in
+// MordhauRestrictedPacketIDs. Its commands identify players by PlayFab ID and take the rest of the line as
+// the last argument, so arguments aren't quoted.
+var MordhauDialect = &rcon.Dialect{
+	Name:               "mordhau",
+	SupportsBroadcasts: true,
+	MaxPayloadSize:     4086,
+	ArgQuoting:         rcon.QuoteNone,
+}
+
+// MinecraftDialect is the dialect of Minecraft servers. Minecraft responds to unknown packet types with an error
+// message rather than mirroring them, and only accepts command bodies of up to 1446 bytes. Player names can't contain
+// spaces and commands such as say take the rest of the line, so arguments aren't quoted.
+var MinecraftDialect = &rcon.Dialect{
+	Name:           "minecraft",
+	ProbeResponse:  "Unknown request",
+	MaxPayloadSize: 1446,
+	SayFormat:      "say %s",
+	MaxSayLength:   256,
+	ArgQuoting:     rcon.QuoteNone,
+}
+
+// FactorioDialect is the dialect of Factorio servers. Factorio commands take the rest of the line as the last argument,
+// and player names can't contain spaces, so arguments aren't quoted.
+var FactorioDialect = &rcon.Dialect{
+	Name:               "factorio",
+	MaxPayloadSize:     4086,
+	FingerprintCommand: "/version",
+	ParseFingerprint:   ParseFactorioVersion,
+	ArgQuoting:         rcon.QuoteNone,
+}
+
+// ParseFactorioVersion parses the response to Factorio's "/version" command, which is the bare version number.
+func ParseFactorioVersion(response string) (rcon.ServerFingerprint, error) {
+	return rcon.ServerFingerprint{
+		Game:    "factorio",
+		Version: strings.TrimSpace(response),
+	}, nil
+}
+
+// Dialects is a list of all dialect presets. It can be used as the CandidateDialects for dialect detection. Mordhau and
+// Factorio servers behave the same during detection, so neither is selected for them.
+var Dialects = []*rcon.Dialect{SourceDialect, MordhauDialect, MinecraftDialect, FactorioDialect}
@@ -0,0 +1,30 @@
+package presets
+
+import (
+	"github.com/refractorgscm/rcon"
+	"regexp"
+)
+
+// SourceBanList describes the SteamID ban list of Source engine servers. Bans added by rcon.ListSync are permanent, and
+// the list is written to banned_user.cfg after changes so that it survives restarts.
+var SourceBanList = rcon.ListSyncConfig{
+	ListCommand:   "listid",
+	ParseList:     ParseSourceListID,
+	AddFormat:     "banid 0 %s",
+	RemoveFormat:  "removeid %s",
+	CommitCommand: "writeid",
+}
+
+var sourceListIDEntry = regexp.MustCompile(`(?m)^\s*\d+\s+(\S+)\s+:`)
+
+// ParseSourceListID parses the response to the "listid" command of Source engine servers, which lists one ban per line
+// after a header, e.g. "1 STEAM_1:0:12345 : permanent".
+func ParseSourceListID(response string) ([]string, error) {
+	var ids []string
+
+	for _, m := range sourceListIDEntry.FindAllStringSubmatch(response, -1) {
+		ids = append(ids, m[1])
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,60 @@
+package rcon
+
+import "github.com/refractorgscm/rcon/packet"
+
+// Priority represents the urgency of a command. Queued commands with a higher priority are always written to the
+// connection before queued commands with a lower priority.
+//
+// The write queues are unbuffered, so a command is only queued while its caller is blocked waiting for the writer, for
+// up to QueueWriteTimeout. Priority therefore only orders commands which are waiting at the same time, which is the
+// case when the client is under load. A command submitted after a lower priority command was already written isn't
+// reordered.
+//
+// For example, moderation actions such as kicks and bans should use PriorityHigh so that they aren't stuck behind a
+// backlog of PriorityLow polling queries when the client is under load.
+type Priority uint8
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+)
+
+const priorityLevels = int(PriorityHigh) + 1
+
+// normalize clamps unknown priority values to the nearest valid priority.
+func (p Priority) normalize() Priority {
+	if p > PriorityHigh {
+		return PriorityHigh
+	}
+
+	return p
+}
+
+// dequeuePacket blocks until a packet is available on one of the write queues and returns it. Higher priority queues
+// are always checked before lower priority queues, which only has an effect if senders are blocked on several queues at
+// once, since the queues are unbuffered. If done is closed, ok will be false.
+func (c *Client) dequeuePacket(done <-chan struct{}) (p packet.Packet, ok bool) {
+	select {
+	case p = <-c.writeQueue[PriorityHigh]:
+		return p, true
+	default:
+	}
+
+	select {
+	case p = <-c.writeQueue[PriorityNormal]:
+		return p, true
+	default:
+	}
+
+	select {
+	case p = <-c.writeQueue[PriorityHigh]:
+		return p, true
+	case p = <-c.writeQueue[PriorityNormal]:
+		return p, true
+	case p = <-c.writeQueue[PriorityLow]:
+		return p, true
+	case <-done:
+		return nil, false
+	}
+}
@@ -0,0 +1,56 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/packet"
+	"testing"
+	"time"
+)
+
+func TestPriority(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Priority", func() {
+		g.It("Should clamp unknown priorities", func() {
+			Expect(PriorityLow.normalize()).To(Equal(PriorityLow))
+			Expect(PriorityHigh.normalize()).To(Equal(PriorityHigh))
+			Expect(Priority(10).normalize()).To(Equal(PriorityHigh))
+		})
+
+		g.It("Should write waiting high priority commands first", func() {
+			// Without a connection, nothing dequeues packets, so every sender waits until the test dequeues them.
+			c := NewClient(&Config{QueueWriteTimeout: time.Second}, nil)
+
+			queue := func(body string, priority Priority) {
+				go func() {
+					_ = c.enqueuePacket(c.newClientPacket(packet.TypeCommand, body), priority, false)
+				}()
+
+				// Give the sender time to block on the queue, so that the commands are submitted in order.
+				time.Sleep(time.Millisecond * 20)
+			}
+
+			queue("low", PriorityLow)
+			queue("normal", PriorityNormal)
+			queue("high", PriorityHigh)
+
+			done := make(chan struct{})
+			var bodies []string
+			for i := 0; i < 3; i++ {
+				p, ok := c.dequeuePacket(done)
+				Expect(ok).To(BeTrue())
+				bodies = append(bodies, string(p.Body()[:len(p.Body())-1]))
+			}
+
+			Expect(bodies).To(Equal([]string{"high", "normal", "low"}))
+
+			close(done)
+			_, ok := c.dequeuePacket(done)
+			Expect(ok).To(BeFalse())
+		})
+	})
+}
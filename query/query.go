@@ -0,0 +1,295 @@
+// Package query implements the Steam server query protocol (A2S), which can be used to get live server information,
+// players and rules alongside RCON control.
+//
+// See https://developer.valvesoftware.com/wiki/Server_queries for the protocol specification.
+package query
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon"
+	"github.com/refractorgscm/rcon/endian"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrMalformedResponse  = errors.New("malformed query response")
+	ErrUnexpectedResponse = errors.New("unexpected query response type")
+)
+
+const (
+	headerSingle = -1
+	headerSplit  = -2
+
+	requestInfo    = 'T'
+	requestPlayers = 'U'
+	requestRules   = 'V'
+
+	responseInfo      = 'I'
+	responsePlayers   = 'D'
+	responseRules     = 'E'
+	responseChallenge = 'A'
+
+	// maxPacketSize is the maximum size of a single query response packet.
+	maxPacketSize = 1400
+
+	// maxChallenges is the number of challenges we accept before giving up. Some servers issue a new challenge in
+	// response to a challenged A2S_INFO request before responding.
+	maxChallenges = 3
+
+	// maxDecompressedSize is the maximum decompressed size of a compressed response. A response is made of at most 255
+	// packets, so anything larger than this can only come from a broken or malicious server.
+	maxDecompressedSize = 1 << 20
+)
+
+var infoPayload = append([]byte("Source Engine Query"), 0)
+
+// noChallenge is sent in place of a challenge number to request one.
+var noChallenge = []byte{0xFF, 0xFF, 0xFF, 0xFF}
+
+// Client sends A2S queries to a single game server.
+type Client struct {
+	address string
+	timeout time.Duration
+}
+
+// NewClient creates a query client for the server at address, which must be in host:port form. The timeout applies to
+// each query.
+func NewClient(address string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = rcon.DefaultTimeout
+	}
+
+	return &Client{
+		address: address,
+		timeout: timeout,
+	}
+}
+
+// NewClientFromConfig creates a query client for the server described by an RCON client config. The config's Host,
+// QueryPort and ConnTimeout are used. If QueryPort is not set, Port is used.
+func NewClientFromConfig(config *rcon.Config) *Client {
+	port := config.QueryPort
+	if port == 0 {
+		port = config.Port
+	}
+
+	return NewClient(net.JoinHostPort(config.Host, strconv.Itoa(int(port))), config.ConnTimeout)
+}
+
+// Info sends an A2S_INFO query and returns the server's information.
+func (c *Client) Info() (*Info, error) {
+	data, err := c.query(requestInfo, infoPayload, true, responseInfo)
+	if err != nil {
+		return nil, errors.Wrap(err, "info query failed")
+	}
+
+	return parseInfo(data)
+}
+
+// Players sends an A2S_PLAYER query and returns the players currently on the server.
+func (c *Client) Players() ([]Player, error) {
+	data, err := c.query(requestPlayers, nil, false, responsePlayers)
+	if err != nil {
+		return nil, errors.Wrap(err, "player query failed")
+	}
+
+	return parsePlayers(data)
+}
+
+// Rules sends an A2S_RULES query and returns the server's rules (cvars).
+func (c *Client) Rules() (map[string]string, error) {
+	data, err := c.query(requestRules, nil, false, responseRules)
+	if err != nil {
+		return nil, errors.Wrap(err, "rules query failed")
+	}
+
+	return parseRules(data)
+}
+
+// query sends a request and returns the payload of the response, without its header byte. If the server responds with
+// a challenge, the request is sent again with the challenge number. If appendChallenge is true, the challenge number is
+// appended to the payload, otherwise it replaces it.
+func (c *Client) query(requestType byte, payload []byte, appendChallenge bool, responseType byte) ([]byte, error) {
+	conn, err := net.DialTimeout("udp", c.address, c.timeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not dial server")
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return nil, errors.Wrap(err, "could not set deadline")
+	}
+
+	challenge := noChallenge
+	if appendChallenge {
+		challenge = nil
+	}
+
+	for i := 0; i <= maxChallenges; i++ {
+		req := append([]byte{0xFF, 0xFF, 0xFF, 0xFF, requestType}, payload...)
+		req = append(req, challenge...)
+
+		if _, err := conn.Write(req); err != nil {
+			return nil, errors.Wrap(err, "could not send request")
+		}
+
+		res, err := readResponse(conn)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(res) < 1 {
+			return nil, ErrMalformedResponse
+		}
+
+		switch res[0] {
+		case responseType:
+			return res[1:], nil
+		case responseChallenge:
+			if len(res) < 5 {
+				return nil, ErrMalformedResponse
+			}
+
+			challenge = res[1:5]
+		default:
+			return nil, errors.Wrapf(ErrUnexpectedResponse, "got response type %q", res[0])
+		}
+	}
+
+	return nil, errors.New("server kept responding with challenges")
+}
+
+// readResponse reads a full response from conn, reassembling split responses, and returns it without the leading
+// single packet header.
+func readResponse(conn net.Conn) ([]byte, error) {
+	buf := make([]byte, maxPacketSize)
+
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read response")
+	}
+
+	if n < 4 {
+		return nil, ErrMalformedResponse
+	}
+
+	switch int32(endian.Little.Uint32(buf)) {
+	case headerSingle:
+		return buf[4:n], nil
+	case headerSplit:
+		return readSplitResponse(conn, buf[:n])
+	default:
+		return nil, ErrMalformedResponse
+	}
+}
+
+type splitHeader struct {
+	id     int32
+	total  int
+	number int
+}
+
+func parseSplitHeader(data []byte) (splitHeader, []byte, error) {
+	// Header (4), ID (4), total (1), number (1), size (2)
+	if len(data) < 12 {
+		return splitHeader{}, nil, ErrMalformedResponse
+	}
+
+	h := splitHeader{
+		id:     int32(endian.Little.Uint32(data[4:8])),
+		total:  int(data[8]),
+		number: int(data[9]),
+	}
+
+	if h.total == 0 || h.number >= h.total {
+		return splitHeader{}, nil, ErrMalformedResponse
+	}
+
+	return h, data[12:], nil
+}
+
+// readSplitResponse reads the remaining packets of a split response and reassembles it. first is the first packet
+// which was received, which is not necessarily the first packet of the response.
+func readSplitResponse(conn net.Conn, first []byte) ([]byte, error) {
+	h, payload, err := parseSplitHeader(first)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([][]byte, h.total)
+	parts[h.number] = append([]byte(nil), payload...)
+	received := 1
+
+	buf := make([]byte, maxPacketSize)
+	for received < h.total {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read split response")
+		}
+
+		ph, payload, err := parseSplitHeader(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+
+		if ph.id != h.id || ph.total != h.total {
+			// A packet belonging to a different response, ignore it.
+			continue
+		}
+
+		if parts[ph.number] == nil {
+			parts[ph.number] = append([]byte(nil), payload...)
+			received++
+		}
+	}
+
+	data := bytes.Join(parts, nil)
+
+	// If the most significant bit of the ID is set, the response is bzip2 compressed.
+	if uint32(h.id)&0x80000000 != 0 {
+		data, err = decompress(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(data) < 4 || int32(endian.Little.Uint32(data)) != headerSingle {
+		return nil, ErrMalformedResponse
+	}
+
+	return data[4:], nil
+}
+
+// decompress decompresses a compressed split response payload, which starts with the decompressed size and CRC32.
+func decompress(data []byte) ([]byte, error) {
+	if len(data) < 8 {
+		return nil, ErrMalformedResponse
+	}
+
+	size := endian.Little.Uint32(data[0:4])
+	checksum := endian.Little.Uint32(data[4:8])
+
+	if size > maxDecompressedSize {
+		return nil, errors.Wrapf(ErrMalformedResponse, "decompressed size of %d bytes exceeds the limit", size)
+	}
+
+	// Reading one byte more than the advertised size is enough to tell that the payload doesn't match it, without
+	// decompressing all of it.
+	out, err := ioutil.ReadAll(io.LimitReader(bzip2.NewReader(bytes.NewReader(data[8:])), int64(size)+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decompress response")
+	}
+
+	if uint32(len(out)) != size || crc32.ChecksumIEEE(out) != checksum {
+		return nil, errors.Wrap(ErrMalformedResponse, "decompressed response failed validation")
+	}
+
+	return out, nil
+}
@@ -0,0 +1,181 @@
+package query
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/endian"
+	"hash/crc32"
+	"net"
+	"testing"
+	"time"
+)
+
+func Test(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Query", func() {
+		g.Describe("decompress()", func() {
+			// "hello world", compressed with bzip2.
+			compressed := []byte{
+				0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x44, 0xf7, 0x13, 0x78, 0x00, 0x00,
+				0x01, 0x91, 0x80, 0x40, 0x00, 0x06, 0x44, 0x90, 0x80, 0x20, 0x00, 0x22, 0x03, 0x34, 0x84, 0x30,
+				0x21, 0xb6, 0x81, 0x54, 0x27, 0x8b, 0xb9, 0x22, 0x9c, 0x28, 0x48, 0x22, 0x7b, 0x89, 0xbc, 0x00,
+			}
+
+			payload := func(size uint32) []byte {
+				data := make([]byte, 8, 8+len(compressed))
+				endian.Little.PutUint32(data[0:4], size)
+				endian.Little.PutUint32(data[4:8], crc32.ChecksumIEEE([]byte("hello world")))
+
+				return append(data, compressed...)
+			}
+
+			g.It("Should decompress and validate the payload", func() {
+				out, err := decompress(payload(11))
+				Expect(err).To(BeNil())
+				Expect(string(out)).To(Equal("hello world"))
+			})
+
+			g.It("Should return an error if the payload is larger than advertised", func() {
+				_, err := decompress(payload(5))
+				Expect(errors.Cause(err)).To(Equal(ErrMalformedResponse))
+			})
+
+			g.It("Should reject sizes above the limit without decompressing", func() {
+				_, err := decompress(payload(maxDecompressedSize + 1))
+				Expect(errors.Cause(err)).To(Equal(ErrMalformedResponse))
+				Expect(err.Error()).To(ContainSubstring("exceeds the limit"))
+			})
+		})
+
+		g.Describe("parseInfo()", func() {
+			var raw []byte
+
+			g.BeforeEach(func() {
+				raw = []byte{0x11}
+				raw = append(raw, "My Server\x00de_dust2\x00csgo\x00Counter-Strike\x00"...)
+				raw = append(raw, 0xDA, 0x02) // app ID 730
+				raw = append(raw, 5, 10, 1, 'd', 'l', 0, 1)
+				raw = append(raw, "1.38.0.0\x00"...)
+			})
+
+			g.It("Should parse the required fields", func() {
+				info, err := parseInfo(raw)
+
+				Expect(err).To(BeNil())
+				Expect(info.Name).To(Equal("My Server"))
+				Expect(info.Map).To(Equal("de_dust2"))
+				Expect(info.Folder).To(Equal("csgo"))
+				Expect(info.Game).To(Equal("Counter-Strike"))
+				Expect(info.AppID).To(Equal(uint16(730)))
+				Expect(info.Players).To(Equal(uint8(5)))
+				Expect(info.MaxPlayers).To(Equal(uint8(10)))
+				Expect(info.Bots).To(Equal(uint8(1)))
+				Expect(info.ServerType).To(Equal(ServerTypeDedicated))
+				Expect(info.Environment).To(Equal(EnvironmentLinux))
+				Expect(info.Password).To(BeFalse())
+				Expect(info.VAC).To(BeTrue())
+				Expect(info.Version).To(Equal("1.38.0.0"))
+			})
+
+			g.It("Should parse the extra data fields", func() {
+				raw = append(raw, edfPort|edfKeywords, 0x87, 0x69)
+				raw = append(raw, "empty,secure\x00"...)
+
+				info, err := parseInfo(raw)
+
+				Expect(err).To(BeNil())
+				Expect(info.Port).To(Equal(uint16(27015)))
+				Expect(info.Keywords).To(Equal("empty,secure"))
+				Expect(info.SourceTV).To(BeNil())
+			})
+
+			g.It("Should return an error for a truncated response", func() {
+				_, err := parseInfo(raw[:20])
+
+				Expect(err).To(Equal(ErrMalformedResponse))
+			})
+		})
+
+		g.Describe("parsePlayers()", func() {
+			g.It("Should parse all players", func() {
+				raw := []byte{2}
+				raw = append(raw, 0)
+				raw = append(raw, "Alice\x00"...)
+				raw = append(raw, 10, 0, 0, 0, 0x00, 0x00, 0x70, 0x42) // score 10, 60 seconds
+				raw = append(raw, 1)
+				raw = append(raw, "Bob\x00"...)
+				raw = append(raw, 0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0x00) // score -1, 0 seconds
+
+				players, err := parsePlayers(raw)
+
+				Expect(err).To(BeNil())
+				Expect(players).To(Equal([]Player{
+					{Index: 0, Name: "Alice", Score: 10, Duration: time.Minute},
+					{Index: 1, Name: "Bob", Score: -1},
+				}))
+			})
+		})
+
+		g.Describe("parseRules()", func() {
+			g.It("Should parse all rules", func() {
+				raw := []byte{2, 0}
+				raw = append(raw, "mp_timelimit\x0030\x00sv_cheats\x000\x00"...)
+
+				rules, err := parseRules(raw)
+
+				Expect(err).To(BeNil())
+				Expect(rules).To(Equal(map[string]string{"mp_timelimit": "30", "sv_cheats": "0"}))
+			})
+		})
+
+		g.Describe("Players()", func() {
+			var server *net.UDPConn
+
+			g.BeforeEach(func() {
+				var err error
+				server, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+				Expect(err).To(BeNil())
+			})
+
+			g.AfterEach(func() {
+				_ = server.Close()
+			})
+
+			g.It("Should answer the server's challenge", func() {
+				go func() {
+					buf := make([]byte, 1400)
+
+					// The first request has no challenge, so respond with one.
+					_, from, err := server.ReadFromUDP(buf)
+					if err != nil {
+						return
+					}
+					_, _ = server.WriteToUDP([]byte{0xFF, 0xFF, 0xFF, 0xFF, 'A', 1, 2, 3, 4}, from)
+
+					// The second request must include the challenge.
+					n, from, err := server.ReadFromUDP(buf)
+					if err != nil {
+						return
+					}
+
+					res := []byte{0xFF, 0xFF, 0xFF, 0xFF, 'D', 0}
+					if string(buf[:n]) != string([]byte{0xFF, 0xFF, 0xFF, 0xFF, 'U', 1, 2, 3, 4}) {
+						res = []byte{0xFF, 0xFF, 0xFF, 0xFF, 'X'}
+					}
+					_, _ = server.WriteToUDP(res, from)
+				}()
+
+				client := NewClient(server.LocalAddr().String(), time.Second)
+				players, err := client.Players()
+
+				Expect(err).To(BeNil())
+				Expect(players).To(BeEmpty())
+			})
+		})
+	})
+}
@@ -0,0 +1,102 @@
+package query
+
+import (
+	"bytes"
+	"github.com/refractorgscm/rcon/endian"
+	"math"
+)
+
+// reader reads the little endian values used by A2S responses. Once a read fails, all further reads return zero values
+// and err is set, so a response can be parsed without checking for an error after every field.
+type reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func newReader(data []byte) *reader {
+	return &reader{data: data}
+}
+
+func (r *reader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if r.pos+n > len(r.data) {
+		r.err = ErrMalformedResponse
+		return nil
+	}
+
+	out := r.data[r.pos : r.pos+n]
+	r.pos += n
+
+	return out
+}
+
+func (r *reader) remaining() int {
+	return len(r.data) - r.pos
+}
+
+func (r *reader) uint8() uint8 {
+	b := r.take(1)
+	if b == nil {
+		return 0
+	}
+
+	return b[0]
+}
+
+func (r *reader) uint16() uint16 {
+	b := r.take(2)
+	if b == nil {
+		return 0
+	}
+
+	return endian.Little.Uint16(b)
+}
+
+func (r *reader) int32() int32 {
+	b := r.take(4)
+	if b == nil {
+		return 0
+	}
+
+	return int32(endian.Little.Uint32(b))
+}
+
+func (r *reader) uint64() uint64 {
+	b := r.take(8)
+	if b == nil {
+		return 0
+	}
+
+	return endian.Little.Uint64(b)
+}
+
+func (r *reader) float32() float32 {
+	b := r.take(4)
+	if b == nil {
+		return 0
+	}
+
+	return math.Float32frombits(endian.Little.Uint32(b))
+}
+
+// string reads a null terminated string.
+func (r *reader) string() string {
+	if r.err != nil {
+		return ""
+	}
+
+	idx := bytes.IndexByte(r.data[r.pos:], 0)
+	if idx < 0 {
+		r.err = ErrMalformedResponse
+		return ""
+	}
+
+	s := string(r.data[r.pos : r.pos+idx])
+	r.pos += idx + 1
+
+	return s
+}
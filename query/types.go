@@ -0,0 +1,189 @@
+package query
+
+import "time"
+
+// Info is the server information returned by an A2S_INFO query.
+type Info struct {
+	Protocol    uint8
+	Name        string
+	Map         string
+	Folder      string
+	Game        string
+	AppID       uint16
+	Players     uint8
+	MaxPlayers  uint8
+	Bots        uint8
+	ServerType  ServerType
+	Environment Environment
+	Password    bool
+	VAC         bool
+	Version     string
+
+	// The following fields are only set if the server included them in its response.
+	Port     uint16
+	SteamID  uint64
+	SourceTV *SourceTVInfo
+	Keywords string
+	GameID   uint64
+}
+
+// SourceTVInfo holds the SourceTV details of a server.
+type SourceTVInfo struct {
+	Port uint16
+	Name string
+}
+
+type ServerType byte
+
+const (
+	ServerTypeDedicated    = ServerType('d')
+	ServerTypeNonDedicated = ServerType('l')
+	ServerTypeProxy        = ServerType('p')
+)
+
+type Environment byte
+
+const (
+	EnvironmentLinux   = Environment('l')
+	EnvironmentWindows = Environment('w')
+	EnvironmentMac     = Environment('m')
+)
+
+// Player is a single player returned by an A2S_PLAYER query.
+type Player struct {
+	Index    uint8
+	Name     string
+	Score    int32
+	Duration time.Duration
+}
+
+// Extra data flags of an A2S_INFO response.
+const (
+	edfPort     = 0x80
+	edfSteamID  = 0x10
+	edfSourceTV = 0x40
+	edfKeywords = 0x20
+	edfGameID   = 0x01
+)
+
+// theShipAppID is the app ID of The Ship, which includes extra fields in its A2S_INFO responses.
+const theShipAppID = 2400
+
+// parseInfo parses the payload of an A2S_INFO response, starting after the response header byte.
+func parseInfo(data []byte) (*Info, error) {
+	r := newReader(data)
+
+	info := &Info{
+		Protocol: r.uint8(),
+		Name:     r.string(),
+		Map:      r.string(),
+		Folder:   r.string(),
+		Game:     r.string(),
+		AppID:    r.uint16(),
+	}
+
+	info.Players = r.uint8()
+	info.MaxPlayers = r.uint8()
+	info.Bots = r.uint8()
+	info.ServerType = ServerType(r.uint8())
+	info.Environment = Environment(r.uint8())
+	info.Password = r.uint8() == 1
+	info.VAC = r.uint8() == 1
+
+	if info.AppID == theShipAppID {
+		// Mode, witnesses and duration are not exposed.
+		r.take(3)
+	}
+
+	info.Version = r.string()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if r.remaining() == 0 {
+		return info, nil
+	}
+
+	edf := r.uint8()
+
+	if edf&edfPort != 0 {
+		info.Port = r.uint16()
+	}
+
+	if edf&edfSteamID != 0 {
+		info.SteamID = r.uint64()
+	}
+
+	if edf&edfSourceTV != 0 {
+		info.SourceTV = &SourceTVInfo{
+			Port: r.uint16(),
+			Name: r.string(),
+		}
+	}
+
+	if edf&edfKeywords != 0 {
+		info.Keywords = r.string()
+	}
+
+	if edf&edfGameID != 0 {
+		info.GameID = r.uint64()
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return info, nil
+}
+
+// parsePlayers parses the payload of an A2S_PLAYER response, starting after the response header byte.
+func parsePlayers(data []byte) ([]Player, error) {
+	r := newReader(data)
+
+	count := int(r.uint8())
+	players := make([]Player, 0, count)
+
+	for i := 0; i < count; i++ {
+		p := Player{
+			Index: r.uint8(),
+			Name:  r.string(),
+			Score: r.int32(),
+		}
+		p.Duration = time.Duration(float64(r.float32()) * float64(time.Second))
+
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		players = append(players, p)
+	}
+
+	return players, nil
+}
+
+// parseRules parses the payload of an A2S_RULES response, starting after the response header byte.
+func parseRules(data []byte) (map[string]string, error) {
+	r := newReader(data)
+
+	count := int(r.uint16())
+	rules := make(map[string]string, count)
+
+	for i := 0; i < count; i++ {
+		name := r.string()
+		value := r.string()
+
+		if r.err != nil {
+			// Some servers truncate large rule lists, so return what was read.
+			if len(rules) > 0 {
+				return rules, nil
+			}
+
+			return nil, r.err
+		}
+
+		rules[name] = value
+	}
+
+	return rules, nil
+}
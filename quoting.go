@@ -0,0 +1,109 @@
+package rcon
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"strings"
+)
+
+// ArgQuoting describes how a dialect's console quotes command arguments which contain whitespace, such as player names
+// with spaces.
+type ArgQuoting string
+
+const (
+	// QuoteDouble wraps arguments containing whitespace, quotes or command separators in double quotes, escaping
+	// quotes and backslashes with a backslash. Empty arguments are quoted too. It is used by Source engine consoles.
+	QuoteDouble = ArgQuoting("double")
+
+	// QuoteEscape escapes whitespace and backslashes with a backslash.
+	QuoteEscape = ArgQuoting("escape")
+
+	// QuoteNone passes arguments on unchanged, for consoles which take the rest of the line as the last argument.
+	QuoteNone = ArgQuoting("none")
+)
+
+// Quote quotes a single command argument.
+func (q ArgQuoting) Quote(arg string) string {
+	switch q {
+	case QuoteEscape:
+		var b strings.Builder
+		for _, r := range arg {
+			if r == '\\' || r == ' ' || r == '\t' {
+				b.WriteRune('\\')
+			}
+			b.WriteRune(r)
+		}
+
+		return b.String()
+	case QuoteNone:
+		return arg
+	default:
+		if arg != "" && !strings.ContainsAny(arg, " \t\";") {
+			return arg
+		}
+
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+	}
+}
+
+// QuoteArg quotes a command argument using the dialect's ArgQuoting.
+func (d *Dialect) QuoteArg(arg string) string {
+	return d.ArgQuoting.Quote(arg)
+}
+
+// FormatCommand formats a command like fmt.Sprintf, after quoting each argument using the dialect's ArgQuoting. The
+// format should use a %s verb for each argument.
+func (d *Dialect) FormatCommand(format string, args ...string) string {
+	quoted := make([]interface{}, len(args))
+	for i, arg := range args {
+		quoted[i] = d.QuoteArg(arg)
+	}
+
+	return fmt.Sprintf(format, quoted...)
+}
+
+// ExecTemplate executes a command formatted with the dialect's FormatCommand, so that arguments such as player names
+// with spaces are quoted the way the server expects:
+//
+//	client.ExecTemplate("kick %s %s", []string{"Some Player", "team killing"})
+//
+// If format uses verbs other than %s and %%, or the number of %s verbs doesn't match the number of arguments,
+// ErrInvalidTemplate is returned and nothing is executed.
+func (c *Client) ExecTemplate(format string, args []string, opts ...ExecOption) (string, error) {
+	if err := checkTemplate(format, len(args)); err != nil {
+		return "", err
+	}
+
+	return c.ExecCommand(c.Dialect().FormatCommand(format, args...), opts...)
+}
+
+// checkTemplate checks that format only uses %s and %% verbs, with one %s verb for each of n arguments.
+func checkTemplate(format string, n int) error {
+	verbs := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+
+		i++
+		if i == len(format) {
+			return errors.Wrapf(errs.ErrInvalidTemplate, "%q ends with %%", format)
+		}
+
+		switch format[i] {
+		case '%':
+		case 's':
+			verbs++
+		default:
+			return errors.Wrapf(errs.ErrInvalidTemplate, "%q uses %%%c, only %%s is supported", format, format[i])
+		}
+	}
+
+	if verbs != n {
+		return errors.Wrapf(errs.ErrInvalidTemplate, "%q has %d arguments, got %d", format, verbs, n)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"sync"
+	"testing"
+)
+
+func TestQuoting(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("ArgQuoting", func() {
+		g.It("Should quote arguments containing whitespace", func() {
+			Expect(QuoteDouble.Quote("alice")).To(Equal("alice"))
+			Expect(QuoteDouble.Quote("")).To(Equal(`""`))
+			Expect(QuoteDouble.Quote(`a b\c`)).To(Equal(`"a b\\c"`))
+			Expect(QuoteEscape.Quote(`a b\c`)).To(Equal(`a\ b\\c`))
+			Expect(QuoteNone.Quote("a b")).To(Equal("a b"))
+		})
+	})
+
+	g.Describe("ExecTemplate", func() {
+		g.It("Should send arguments quoted for the dialect", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			for quoting, expected := range map[ArgQuoting]string{
+				QuoteDouble: `echo: kick "Some \"Player\"" "team killing"`,
+				QuoteEscape: `echo: kick Some\ "Player" team\ killing`,
+				QuoteNone:   `echo: kick Some "Player" team killing`,
+			} {
+				dialect := *DefaultDialect
+				dialect.ArgQuoting = quoting
+
+				c := newTestClient(s, &Config{Dialect: &dialect})
+				Expect(c.Connect()).To(Succeed())
+
+				res, err := c.ExecTemplate("kick %s %s", []string{`Some "Player"`, "team killing"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res).To(Equal(expected))
+
+				Expect(c.Close()).To(Succeed())
+			}
+		})
+
+		g.It("Should reject malformed templates without executing them", func() {
+			var lock sync.Mutex
+			var executed []string
+			s := newTestServer(func(command string) string {
+				lock.Lock()
+				executed = append(executed, command)
+				lock.Unlock()
+				return ""
+			})
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			for _, format := range []string{"kick %s %s", "kick %d", "kick %s %", "kick"} {
+				_, err := c.ExecTemplate(format, []string{"bob"})
+				Expect(errors.Cause(err)).To(Equal(errs.ErrInvalidTemplate))
+			}
+
+			_, err := c.ExecTemplate("say 100%% %s", []string{"done"})
+			Expect(err).ToNot(HaveOccurred())
+
+			lock.Lock()
+			defer lock.Unlock()
+			Expect(executed).To(Equal([]string{"say 100% done"}))
+		})
+	})
+}
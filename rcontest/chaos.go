@@ -0,0 +1,126 @@
+package rcontest
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// Chaos makes a Server simulate an unreliable network and server, so that reconnect and error handling can be tested
+// against realistic failure modes. Rates are probabilities between 0 and 1, applied to each packet sent by the server.
+type Chaos struct {
+	// Latency delays every packet. A random delay of up to Jitter is added on top.
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// DropRate is the probability of a packet not being sent at all.
+	DropRate float64
+
+	// SplitRate is the probability of a packet being split into two TCP segments, with a short pause between them.
+	SplitRate float64
+
+	// DuplicateRate is the probability of a packet being sent twice.
+	DuplicateRate float64
+
+	// CloseRate is the probability of the connection being closed instead of a command being answered.
+	CloseRate float64
+
+	// Seed seeds the random number generator, so that failures can be reproduced. If zero, a random seed is used.
+	Seed int64
+}
+
+// splitPause is the pause between the segments of a split packet. It is long enough for the segments to arrive
+// separately.
+const splitPause = time.Millisecond * 5
+
+type chaosState struct {
+	config Chaos
+
+	lock sync.Mutex
+	rand *rand.Rand
+}
+
+func newChaosState(config Chaos) *chaosState {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &chaosState{
+		config: config,
+		rand:   rand.New(rand.NewSource(seed)),
+	}
+}
+
+// roll returns true with the probability rate.
+func (c *chaosState) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.rand.Float64() < rate
+}
+
+func (c *chaosState) delay() time.Duration {
+	d := c.config.Latency
+
+	if c.config.Jitter > 0 {
+		c.lock.Lock()
+		d += time.Duration(c.rand.Int63n(int64(c.config.Jitter)))
+		c.lock.Unlock()
+	}
+
+	return d
+}
+
+func (c *chaosState) close() bool {
+	return c.roll(c.config.CloseRate)
+}
+
+// write sends data to conn, applying latency, drops, splits and duplicates.
+func (c *chaosState) write(conn net.Conn, data []byte) error {
+	if d := c.delay(); d > 0 {
+		time.Sleep(d)
+	}
+
+	if c.roll(c.config.DropRate) {
+		return nil
+	}
+
+	times := 1
+	if c.roll(c.config.DuplicateRate) {
+		times = 2
+	}
+
+	for i := 0; i < times; i++ {
+		if err := c.writePacket(conn, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *chaosState) writePacket(conn net.Conn, data []byte) error {
+	if len(data) < 2 || !c.roll(c.config.SplitRate) {
+		_, err := conn.Write(data)
+		return err
+	}
+
+	c.lock.Lock()
+	at := 1 + c.rand.Intn(len(data)-1)
+	c.lock.Unlock()
+
+	if _, err := conn.Write(data[:at]); err != nil {
+		return err
+	}
+
+	time.Sleep(splitPause)
+
+	_, err := conn.Write(data[at:])
+	return err
+}
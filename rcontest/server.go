@@ -0,0 +1,274 @@
+// Package rcontest provides an in-process RCON server for testing code built on the rcon package. The server follows
+// Valve's Source RCON specification, and can simulate unreliable networks and servers using Chaos.
+package rcontest
+
+import (
+	"bufio"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/endian"
+	"github.com/refractorgscm/rcon/packet"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// BroadcastID is the packet ID of broadcasts sent by Server.Broadcast. Clients can recognise them using a
+// BroadcastChecker such as:
+//
+//	func(p packet.Packet) bool { return p.ID() == rcontest.BroadcastID }
+const BroadcastID int32 = 0
+
+// Handler returns the response to a command.
+type Handler func(command string) string
+
+// Config configures a Server.
+type Config struct {
+	// Password is the password clients must authenticate with.
+	Password string
+
+	// Handler responds to commands. If nil, commands are answered with an empty response.
+	Handler Handler
+
+	// ResponseChunkSize splits responses into packets with bodies of at most this many bytes, like servers do with
+	// long responses. If zero, each response is sent as a single packet.
+	ResponseChunkSize int
+
+	// Chaos makes the server simulate an unreliable network. If nil, the server behaves reliably.
+	Chaos *Chaos
+
+	// NoAuthPreamble stops the server from sending an empty response value packet before the auth response, like the
+	// servers of many games other than Source engine ones.
+	NoAuthPreamble bool
+
+	// UnknownTypeResponse is the body response value packets are answered with instead of being mirrored, like
+	// Minecraft servers do. If empty, response value packets are mirrored, unless IgnoreResponseValues is set.
+	UnknownTypeResponse string
+
+	// IgnoreResponseValues stops the server from answering response value packets at all.
+	IgnoreResponseValues bool
+
+	// IgnoreReAuth stops the server from answering auth packets on connections which are already authenticated, like
+	// servers which only accept auth packets at the start of a connection.
+	IgnoreReAuth bool
+}
+
+// Server is an RCON server listening on a local TCP port.
+type Server struct {
+	config Config
+	ln     net.Listener
+	chaos  *chaosState
+
+	lock  sync.Mutex
+	conns map[*serverConn]struct{}
+	wg    sync.WaitGroup
+}
+
+type serverConn struct {
+	conn   net.Conn
+	authed bool
+
+	// writeLock serializes writes, so that packets of concurrent writers aren't interleaved.
+	writeLock sync.Mutex
+}
+
+// NewServer starts a server listening on a random local port.
+func NewServer(config Config) (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not listen")
+	}
+
+	s := &Server{
+		config: config,
+		ln:     ln,
+		conns:  map[*serverConn]struct{}{},
+	}
+
+	if config.Chaos != nil {
+		s.chaos = newChaosState(*config.Chaos)
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// Addr returns the host and port the server is listening on, for use in rcon.Config.
+func (s *Server) Addr() (string, uint16) {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	n, _ := strconv.Atoi(port)
+
+	return host, uint16(n)
+}
+
+// Broadcast sends a message to every authenticated client. Broadcasts are subject to Chaos like any other packet.
+func (s *Server) Broadcast(message string) {
+	s.lock.Lock()
+	conns := make([]*serverConn, 0, len(s.conns))
+	for sc := range s.conns {
+		if sc.authed {
+			conns = append(conns, sc)
+		}
+	}
+	s.lock.Unlock()
+
+	for _, sc := range conns {
+		_ = s.send(sc, BroadcastID, packet.TypeServerDataResponseValue, message)
+	}
+}
+
+// CloseConnections closes every open connection without stopping the server, simulating a server restart.
+func (s *Server) CloseConnections() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for sc := range s.conns {
+		_ = sc.conn.Close()
+	}
+}
+
+// Close stops the server, closes every open connection and waits for them to be torn down.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.CloseConnections()
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		sc := &serverConn{conn: conn}
+
+		s.lock.Lock()
+		s.conns[sc] = struct{}{}
+		s.lock.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(sc)
+	}
+}
+
+func (s *Server) handle(sc *serverConn) {
+	defer s.wg.Done()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.conns, sc)
+		s.lock.Unlock()
+
+		_ = sc.conn.Close()
+	}()
+
+	reader := bufio.NewReader(sc.conn)
+
+	for {
+		p, err := packet.DecodeClientPacket(endian.Little, reader)
+		if err != nil {
+			return
+		}
+
+		if err := s.respond(sc, p); err != nil {
+			return
+		}
+	}
+}
+
+// respond answers a single packet. It returns an error if the connection should be closed.
+func (s *Server) respond(sc *serverConn, p *packet.ClientPacket) error {
+	body := string(p.RawBody())
+
+	switch p.Type() {
+	case packet.TypeServerDataAuth:
+		s.lock.Lock()
+		authed := sc.authed
+		s.lock.Unlock()
+
+		if authed && s.config.IgnoreReAuth {
+			return nil
+		}
+
+		// Source servers send an empty response value packet before the auth response.
+		if !s.config.NoAuthPreamble {
+			if err := s.send(sc, p.ID(), packet.TypeServerDataResponseValue, ""); err != nil {
+				return err
+			}
+		}
+
+		if body != s.config.Password {
+			return s.send(sc, packet.AuthFailedID, packet.TypeServerDataAuthResponse, "")
+		}
+
+		s.lock.Lock()
+		sc.authed = true
+		s.lock.Unlock()
+
+		return s.send(sc, p.ID(), packet.TypeServerDataAuthResponse, "")
+	case packet.TypeServerDataResponseValue:
+		if s.config.IgnoreResponseValues {
+			return nil
+		}
+
+		if s.config.UnknownTypeResponse != "" {
+			return s.send(sc, p.ID(), packet.TypeServerDataResponseValue, s.config.UnknownTypeResponse)
+		}
+
+		// Source servers mirror empty response value packets, followed by a packet with an unusual body. Clients use
+		// this to find the end of a response split across several packets.
+		if err := s.send(sc, p.ID(), packet.TypeServerDataResponseValue, ""); err != nil {
+			return err
+		}
+
+		return s.send(sc, p.ID(), packet.TypeServerDataResponseValue, "\x00\x01\x00\x00")
+	}
+
+	s.lock.Lock()
+	authed := sc.authed
+	s.lock.Unlock()
+
+	if !authed {
+		return errors.New("command sent before authenticating")
+	}
+
+	if s.chaos != nil && s.chaos.close() {
+		return errors.New("connection closed by chaos")
+	}
+
+	response := ""
+	if s.config.Handler != nil {
+		response = s.config.Handler(body)
+	}
+
+	for size := s.config.ResponseChunkSize; size > 0 && len(response) > size; response = response[size:] {
+		if err := s.send(sc, p.ID(), packet.TypeServerDataResponseValue, response[:size]); err != nil {
+			return err
+		}
+	}
+
+	return s.send(sc, p.ID(), packet.TypeServerDataResponseValue, response)
+}
+
+func (s *Server) send(sc *serverConn, id int32, pType packet.PacketType, body string) error {
+	data, err := packet.NewClientPacketWithID(endian.Little, pType, body, id).Build()
+	if err != nil {
+		return err
+	}
+
+	sc.writeLock.Lock()
+	defer sc.writeLock.Unlock()
+
+	if s.chaos == nil {
+		_, err := sc.conn.Write(data)
+		return err
+	}
+
+	return s.chaos.write(sc.conn, data)
+}
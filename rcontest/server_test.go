@@ -0,0 +1,124 @@
+package rcontest
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon"
+	"github.com/refractorgscm/rcon/packet"
+	"github.com/refractorgscm/rcon/presets"
+	"testing"
+	"time"
+)
+
+func Test(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	echo := func(command string) string {
+		return "echo: " + command
+	}
+
+	newClient := func(s *Server, password string) *rcon.Client {
+		host, port := s.Addr()
+
+		return rcon.NewClient(&rcon.Config{
+			Host:     host,
+			Port:     port,
+			Password: password,
+			BroadcastChecker: func(p packet.Packet) bool {
+				return p.ID() == BroadcastID
+			},
+		}, nil)
+	}
+
+	g.Describe("Server", func() {
+		g.It("Should authenticate clients and answer commands", func() {
+			s, err := NewServer(Config{Password: "pw", Handler: echo})
+			Expect(err).ToNot(HaveOccurred())
+			defer s.Close()
+
+			Expect(newClient(s, "wrong").Connect()).ToNot(Succeed())
+
+			c := newClient(s, "pw")
+			broadcasts := make(chan string, 1)
+			c.SetBroadcastHandler(func(message string) {
+				broadcasts <- message
+			})
+
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+
+			s.Broadcast("Player joined")
+			Eventually(broadcasts).Should(Receive(Equal("Player joined")))
+		})
+
+		g.It("Should keep working with split, duplicated and delayed packets", func() {
+			s, err := NewServer(Config{Password: "pw", Handler: echo, Chaos: &Chaos{
+				Latency:       time.Millisecond,
+				Jitter:        time.Millisecond,
+				SplitRate:     0.5,
+				DuplicateRate: 0.2,
+				Seed:          1,
+			}})
+			Expect(err).ToNot(HaveOccurred())
+			defer s.Close()
+
+			c := newClient(s, "pw")
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			for i := 0; i < 10; i++ {
+				Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+			}
+		})
+
+		g.It("Should close connections randomly", func() {
+			s, err := NewServer(Config{Password: "pw", Handler: echo, Chaos: &Chaos{CloseRate: 1}})
+			Expect(err).ToNot(HaveOccurred())
+			defer s.Close()
+
+			c := newClient(s, "pw")
+			Expect(c.Connect()).To(Succeed())
+
+			_, err = c.ExecCommand("status")
+			Expect(err).To(HaveOccurred())
+			Eventually(c.Done()).Should(BeClosed())
+		})
+
+		g.It("Should let dialect detection tell presets apart", func() {
+			detect := func(config Config) *rcon.Dialect {
+				config.Password, config.Handler = "pw", echo
+				s, err := NewServer(config)
+				Expect(err).ToNot(HaveOccurred())
+				defer s.Close()
+
+				host, port := s.Addr()
+				c := rcon.NewClient(&rcon.Config{
+					Host:              host,
+					Port:              port,
+					Password:          "pw",
+					DetectDialect:     true,
+					CandidateDialects: presets.Dialects,
+					ProbeTimeout:      time.Millisecond * 100,
+				}, nil)
+				Expect(c.Connect()).To(Succeed())
+				defer c.Close()
+
+				return c.Dialect()
+			}
+
+			Expect(detect(Config{})).To(Equal(presets.SourceDialect))
+			Expect(detect(Config{NoAuthPreamble: true, UnknownTypeResponse: "Unknown request 0"})).
+				To(Equal(presets.MinecraftDialect))
+
+			// Mordhau and Factorio servers ignore the probe, so neither preset is selected.
+			d := detect(Config{NoAuthPreamble: true, IgnoreResponseValues: true})
+			Expect(d.Name).To(Equal(rcon.DefaultDialect.Name))
+			Expect(d.AuthResponsePreamble).To(BeFalse())
+		})
+	})
+}
@@ -0,0 +1,113 @@
+package rcon
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"github.com/refractorgscm/rcon/packet"
+)
+
+// ReAuthenticate sends the auth packet again on the current connection. Some servers expire authentication after a
+// period of time, or after the password was changed with a grace period for existing sessions. Re-authenticating keeps
+// the connection, its routines and the offline queue intact, which is cheaper than a full reconnect.
+//
+// The password is read from the client's config, so it can be changed before calling ReAuthenticate. If the server
+// rejects the password, an AuthFailedEvent is emitted and an error wrapping ErrAuthentication is returned. If the server
+// doesn't answer, which is the case for servers that only accept auth packets at the start of a connection, the client
+// falls back to reconnecting. With AuthNone, ReAuthenticate does nothing.
+//
+// If ResubscribeOnReAuth is set, broadcast subscriptions are re-established after re-authenticating successfully.
+func (c *Client) ReAuthenticate() error {
+	if !c.isConnected() {
+		return errs.ErrNotConnected
+	}
+
+	if c.authMode() == AuthNone {
+		return nil
+	}
+
+	// Auth responses are all delivered to the same mailbox, so only one re-authentication can be in flight.
+	c.authLock.Lock()
+	defer c.authLock.Unlock()
+
+	c.log.Debug("Re-authenticating")
+
+	err := c.reauthenticate()
+	if err == nil {
+		c.log.Debug("Re-authenticated successfully")
+
+		if c.ResubscribeOnReAuth {
+			c.resubscribeBroadcasts()
+		}
+
+		return nil
+	}
+
+	c.stats.recordError(err)
+
+	if errors.Cause(err) == errs.ErrAuthentication {
+		c.events.emit(AuthFailedEvent{Address: c.addressString(), Err: err})
+		return err
+	}
+
+	if errors.Cause(err) != errs.ErrReadTimeout {
+		return err
+	}
+
+	c.log.Info("Server did not answer re-authentication, reconnecting")
+
+	return c.Reconnect()
+}
+
+func (c *Client) reauthenticate() error {
+	p := c.newClientPacketWithID(packet.TypeAuth, c.authPassword(), packet.AuthPacketID)
+
+	// The mailbox has room for two packets since some servers send an empty response value packet before the auth
+	// response, see authenticate.
+	c.rqLock.Lock()
+	mailbox := make(chan packet.Packet, 2)
+	c.readQueue[packet.AuthPacketID] = mailbox
+	c.rqLock.Unlock()
+
+	defer c.closeMailbox(packet.AuthPacketID)
+
+	if err := c.enqueuePacket(p, PriorityHigh, false); err != nil {
+		return errors.Wrap(err, "could not enqueue auth packet")
+	}
+
+	for {
+		res, err := c.waitMailbox(context.Background(), packet.AuthPacketID, mailbox)
+		if err != nil {
+			return errors.Wrap(err, "could not get auth response")
+		}
+
+		if res.Type() != packet.TypeAuthRes {
+			c.log.Debug("Skipping response preceding auth response")
+			continue
+		}
+
+		if res.ID() == packet.AuthFailedID {
+			return errors.Wrap(errs.ErrAuthentication, "authentication failed")
+		}
+
+		return nil
+	}
+}
+
+// resubscribeBroadcasts restarts the BroadcastSources and executes the SubscribeCommands again, for servers which bind
+// broadcast subscriptions to the authenticated session.
+func (c *Client) resubscribeBroadcasts() {
+	c.stateLock.Lock()
+	if c.state != StateConnected {
+		c.stateLock.Unlock()
+		return
+	}
+	ctx := c.routines.ctx
+	c.stateLock.Unlock()
+
+	c.log.Debug("Re-establishing broadcast subscriptions after re-authenticating")
+
+	c.stopBroadcastSources()
+	c.startBroadcastSources()
+	c.runSubscribeCommands(ctx)
+}
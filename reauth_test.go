@@ -0,0 +1,118 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"github.com/refractorgscm/rcon/rcontest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReAuth(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("ReAuthenticate", func() {
+		g.It("Should fail if the client isn't connected", func() {
+			c := NewClient(&Config{}, nil)
+			Expect(c.ReAuthenticate()).To(Equal(errs.ErrNotConnected))
+		})
+
+		g.It("Should re-authenticate on the existing connection", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			events := recordEvents(c, EventDisconnected, EventConnected)
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(c.ReAuthenticate()).To(Succeed())
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+			Expect(events()).To(HaveLen(1))
+		})
+
+		g.It("Should report rejected passwords", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			events := recordEvents(c, EventAuthFailed)
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			c.Password = "wrong"
+			err := c.ReAuthenticate()
+			Expect(errors.Cause(err)).To(Equal(errs.ErrAuthentication))
+			Expect(events()).To(HaveLen(1))
+			Expect(c.State()).To(Equal(StateConnected))
+		})
+
+		g.It("Should reconnect if the server doesn't answer", func() {
+			s, err := rcontest.NewServer(rcontest.Config{Password: testPassword, Handler: echoHandler, IgnoreReAuth: true})
+			Expect(err).ToNot(HaveOccurred())
+			defer s.Close()
+
+			c := newTestClient(s, &Config{QueueReadTimeout: time.Millisecond * 100})
+			events := recordEvents(c, EventDisconnected, EventConnected)
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+			done := c.Done()
+
+			Expect(c.ReAuthenticate()).To(Succeed())
+			Expect(done).ToNot(BeClosed())
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+
+			Expect(events()).To(HaveLen(3))
+			Expect(events()[1].(DisconnectedEvent).Expected).To(BeTrue())
+		})
+	})
+	g.Describe("ResubscribeOnReAuth", func() {
+		g.It("Should execute the subscribe commands again after re-authenticating", func() {
+			var lock sync.Mutex
+			subscribes := 0
+			s := newTestServer(func(command string) string {
+				if command == "listen chat" {
+					lock.Lock()
+					subscribes++
+					lock.Unlock()
+				}
+
+				return ""
+			})
+			defer s.Close()
+
+			newClient := func(resubscribe bool) *Client {
+				return newTestClient(s, &Config{
+					SubscribeCommands:   []string{"listen chat"},
+					ResubscribeOnReAuth: resubscribe,
+				})
+			}
+			count := func() int {
+				lock.Lock()
+				defer lock.Unlock()
+
+				return subscribes
+			}
+
+			c := newClient(false)
+			Expect(c.Connect()).To(Succeed())
+			Eventually(count).Should(Equal(1))
+			Expect(c.ReAuthenticate()).To(Succeed())
+			Consistently(count, time.Millisecond*100).Should(Equal(1))
+			Expect(c.Close()).To(Succeed())
+
+			c = newClient(true)
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+			Eventually(count).Should(Equal(2))
+			Expect(c.ReAuthenticate()).To(Succeed())
+			Eventually(count).Should(Equal(3))
+		})
+	})
+}
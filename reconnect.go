@@ -0,0 +1,415 @@
+package rcon
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"math"
+	"sync"
+	"time"
+)
+
+// SpanReconnect is the name of the span covering a reconnect cycle, from the disconnect until the client reconnected or
+// gave up.
+const SpanReconnect = "rcon.reconnect"
+
+// ReconnectPolicy configures automatic reconnection after an unexpected disconnect. Reconnect attempts are spaced out
+// using exponential backoff, and a circuit breaker stops the client from hammering a server which is down.
+type ReconnectPolicy struct {
+	// InitialDelay is the delay before the first reconnect attempt.
+	//
+	// Default: 1s
+	InitialDelay time.Duration
+
+	// MaxDelay is the maximum delay between reconnect attempts.
+	//
+	// Default: 1m
+	MaxDelay time.Duration
+
+	// Multiplier is the factor the delay is multiplied by after each failed attempt.
+	//
+	// Default: 2
+	Multiplier float64
+
+	// MaxAttempts is the number of attempts made before giving up. If zero, the client never gives up. Authentication
+	// failures are not retried.
+	MaxAttempts int
+
+	// BreakerThreshold is the number of consecutive failed attempts after which the circuit breaker opens. While the
+	// circuit is open, no reconnect attempts are made and commands fail immediately with ErrCircuitOpen. If zero, the
+	// circuit breaker is disabled.
+	BreakerThreshold int
+
+	// BreakerCooldown is how long the circuit stays open before a single trial attempt is allowed.
+	//
+	// Default: 5m
+	BreakerCooldown time.Duration
+}
+
+func (p *ReconnectPolicy) setDefaults() {
+	if p.InitialDelay <= 0 {
+		p.InitialDelay = time.Second
+	}
+
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = time.Minute
+	}
+
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+
+	if p.BreakerCooldown <= 0 {
+		p.BreakerCooldown = time.Minute * 5
+	}
+}
+
+// Delay returns the delay before the provided attempt number, starting at 1.
+func (p *ReconnectPolicy) Delay(attempt int) time.Duration {
+	return backoffDelay(p.InitialDelay, p.MaxDelay, p.Multiplier, attempt)
+}
+
+// backoffDelay returns the exponential backoff delay before the provided attempt number, starting at 1.
+func backoffDelay(initial, max time.Duration, multiplier float64, attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if delay > float64(max) {
+		return max
+	}
+
+	return time.Duration(delay)
+}
+
+// BreakerState is the state of the reconnect circuit breaker.
+type BreakerState uint8
+
+const (
+	// BreakerClosed means reconnect attempts are allowed.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen means too many consecutive reconnect attempts failed, and no attempts are made until the cooldown
+	// has passed.
+	BreakerOpen
+
+	// BreakerHalfOpen means the cooldown has passed and a single trial attempt is allowed. If it succeeds the circuit
+	// closes, otherwise it opens again.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerStateHandler is a function which is called whenever the state of the reconnect circuit breaker changes.
+type BreakerStateHandler func(state BreakerState)
+
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	onChange  func(BreakerState)
+}
+
+// wait returns how long to wait until an attempt is allowed. If the cooldown has passed, the circuit becomes half-open.
+func (b *circuitBreaker) wait() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != BreakerOpen {
+		return 0
+	}
+
+	if remaining := b.cooldown - time.Since(b.openedAt); remaining > 0 {
+		return remaining
+	}
+
+	b.setState(BreakerHalfOpen)
+
+	return 0
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	b.failures = 0
+	b.setState(BreakerClosed)
+	b.mu.Unlock()
+}
+
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+
+	if b.threshold > 0 && (b.state == BreakerHalfOpen || b.failures >= b.threshold) {
+		b.openedAt = time.Now()
+		b.setState(BreakerOpen)
+	}
+}
+
+func (b *circuitBreaker) current() (BreakerState, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state, b.failures
+}
+
+// setState must be called with the lock held.
+func (b *circuitBreaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+
+	b.state = state
+
+	if b.onChange != nil {
+		go b.onChange(state)
+	}
+}
+
+// startReconnect starts the reconnect routine after an unexpected disconnect, if reconnection is enabled. It returns
+// true if the routine was started, in which case the routine sends the disconnect event. It must be called with the
+// state lock held.
+func (c *Client) startReconnect(event DisconnectEvent) bool {
+	if c.ReconnectPolicy == nil || c.reconnectStop != nil {
+		return false
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	c.reconnectStop = stop
+	c.reconnectDone = done
+
+	go c.reconnectLoop(event, stop, done)
+
+	return true
+}
+
+// NextRetryAt returns the time of the next automatic reconnect attempt, and false if no attempt is scheduled. Together
+// with ReconnectScheduledEvent, it lets UIs show a countdown instead of a plain disconnected state.
+func (c *Client) NextRetryAt() (time.Time, bool) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	return c.nextRetryAt, !c.nextRetryAt.IsZero()
+}
+
+func (c *Client) setNextRetryAt(at time.Time) {
+	c.stateLock.Lock()
+	c.nextRetryAt = at
+	c.stateLock.Unlock()
+}
+
+// stopReconnect stops the reconnect routine if it is running and waits for it to return. It returns true if a reconnect
+// routine was stopped.
+func (c *Client) stopReconnect() bool {
+	c.stateLock.Lock()
+	if c.reconnectStop == nil {
+		c.stateLock.Unlock()
+		return false
+	}
+
+	close(c.reconnectStop)
+	done := c.reconnectDone
+	c.reconnectStop = nil
+	c.reconnectDone = nil
+	c.stateLock.Unlock()
+
+	<-done
+
+	return true
+}
+
+// releaseReconnect unregisters the reconnect routine owning stop, if it is still registered, so that it is no longer
+// stopped by stopReconnect and a new routine can be started. It must be called with the state lock held.
+func (c *Client) releaseReconnect(stop chan struct{}) {
+	if c.reconnectStop == stop {
+		c.reconnectStop = nil
+		c.reconnectDone = nil
+	}
+}
+
+func (c *Client) reconnectLoop(event DisconnectEvent, stop chan struct{}, done chan struct{}) {
+	_, span := c.startSpan(context.Background(), SpanReconnect, nil)
+	err := event.Cause
+
+	defer func() {
+		c.setNextRetryAt(time.Time{})
+
+		c.stateLock.Lock()
+		c.releaseReconnect(stop)
+		c.stateLock.Unlock()
+
+		c.sendDisconnectEvent(event)
+
+		if !event.ReconnectSucceeded {
+			c.markDone(err)
+		}
+
+		close(done)
+	}()
+
+	maxAttempts := c.ReconnectPolicy.MaxAttempts
+
+	for attempt := 1; maxAttempts == 0 || attempt <= maxAttempts; attempt++ {
+		// No attempts are made during maintenance, so that a planned restart doesn't exhaust the policy or open the
+		// circuit breaker.
+		if !c.waitResumed(stop) {
+			c.log.Debug("Reconnect routine stopped")
+			span.End(nil)
+			err = nil
+			return
+		}
+
+		delay := c.ReconnectPolicy.Delay(attempt)
+		if wait := c.breaker.wait(); wait > delay {
+			c.log.Info("Reconnect circuit is open, waiting ", wait, " before the next attempt")
+			delay = wait
+		}
+
+		at := time.Now().Add(delay)
+		c.setNextRetryAt(at)
+		c.events.emit(ReconnectScheduledEvent{
+			Attempt:     attempt,
+			MaxAttempts: maxAttempts,
+			Delay:       delay,
+			At:          at,
+			LastErr:     err,
+		})
+
+		select {
+		case <-time.After(delay):
+		case <-stop:
+			c.log.Debug("Reconnect routine stopped")
+			span.End(nil)
+			err = nil
+			return
+		}
+
+		// The cooldown may not have passed if the delay was shorter than it.
+		if c.breaker.wait() > 0 {
+			attempt--
+			continue
+		}
+
+		c.log.Info("Reconnect attempt ", attempt)
+		c.setNextRetryAt(time.Time{})
+		c.events.emit(ReconnectAttemptEvent{Attempt: attempt, MaxAttempts: maxAttempts})
+		event.ReconnectAttempted = true
+		event.ReconnectAttempts++
+
+		// The routine is finished once the client is connected, so it is released right away. Otherwise, Close or
+		// Reconnect could still stop it, and a loss of the new connection couldn't start another routine.
+		err = c.connectOnceThen(context.Background(), func() {
+			c.releaseReconnect(stop)
+		})
+		if err != nil {
+			c.log.Error("Reconnect attempt ", attempt, " failed. Error: ", err)
+			c.breaker.failure()
+
+			// Like Connect with RetryInitialConnect, retrying won't fix a wrong password.
+			authFailed := errors.Cause(err) == errs.ErrAuthentication
+			c.events.emit(ReconnectFailedEvent{
+				Attempt:     attempt,
+				MaxAttempts: maxAttempts,
+				Err:         err,
+				GaveUp:      attempt == maxAttempts || authFailed,
+			})
+
+			if authFailed {
+				break
+			}
+
+			continue
+		}
+
+		c.breaker.success()
+		event.ReconnectSucceeded = true
+		c.log.Info("Reconnected after ", attempt, " attempts")
+		span.End(nil)
+
+		// If Close was called before we were released, honour it. Close is waiting for us to return, so we can only
+		// disconnect here.
+		select {
+		case <-stop:
+			c.disconnect(nil)
+		default:
+		}
+
+		return
+	}
+
+	err = errors.Wrap(err, "giving up on reconnecting")
+	c.log.Error("Reconnect attempts exhausted. Error: ", err)
+	c.stats.recordError(err)
+	span.End(err)
+}
+
+// DefaultConnectAttempts is the number of attempts made by Connect when RetryInitialConnect is set and the reconnect
+// policy doesn't limit the number of attempts.
+const DefaultConnectAttempts = 5
+
+func (c *Client) connectWithRetry(ctx context.Context) error {
+	policy := c.ReconnectPolicy
+	if policy == nil {
+		policy = &ReconnectPolicy{}
+		policy.setDefaults()
+	}
+
+	attempts := policy.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultConnectAttempts
+	}
+
+	attemptsErr := &errs.AttemptsError{}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err := c.connectOnce(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		// Another Connect call is already taking care of connecting.
+		cause := errors.Cause(err)
+		if cause == errs.ErrConnectInProgress {
+			return err
+		}
+
+		c.log.Error("Connect attempt ", attempt, " failed. Error: ", err)
+		attemptsErr.Errors = append(attemptsErr.Errors, err)
+
+		// Retrying won't fix a wrong password.
+		if cause == errs.ErrAuthentication {
+			break
+		}
+
+		if attempt < attempts {
+			select {
+			case <-time.After(policy.Delay(attempt)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	return attemptsErr
+}
@@ -0,0 +1,300 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"github.com/refractorgscm/rcon/rcontest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReconnect(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("ReconnectPolicy", func() {
+		g.It("Should back off exponentially up to the maximum delay", func() {
+			p := &ReconnectPolicy{InitialDelay: time.Second, MaxDelay: time.Second * 5, Multiplier: 2}
+
+			Expect(p.Delay(0)).To(Equal(time.Second))
+			Expect(p.Delay(1)).To(Equal(time.Second))
+			Expect(p.Delay(2)).To(Equal(time.Second * 2))
+			Expect(p.Delay(3)).To(Equal(time.Second * 4))
+			Expect(p.Delay(4)).To(Equal(time.Second * 5))
+			Expect(p.Delay(100)).To(Equal(time.Second * 5))
+		})
+
+		g.It("Should reconnect after the connection was lost", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{ReconnectPolicy: &ReconnectPolicy{InitialDelay: time.Millisecond * 10}})
+			events := recordEvents(c, EventReconnectScheduled, EventReconnectAttempt)
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+			done := c.Done()
+
+			s.CloseConnections()
+
+			Eventually(events).Should(HaveLen(2))
+			Eventually(c.State).Should(Equal(StateConnected))
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+			Expect(done).ToNot(BeClosed())
+
+			scheduled := events()[0].(ReconnectScheduledEvent)
+			Expect(scheduled.Attempt).To(Equal(1))
+			Expect(scheduled.Delay).To(Equal(time.Millisecond * 10))
+			Expect(scheduled.LastErr).To(HaveOccurred())
+			Expect(events()[1]).To(Equal(ReconnectAttemptEvent{Attempt: 1}))
+		})
+
+		g.It("Should give up after the maximum number of attempts", func() {
+			s := newTestServer(echoHandler)
+
+			c := newTestClient(s, &Config{ReconnectPolicy: &ReconnectPolicy{
+				InitialDelay: time.Millisecond * 10,
+				MaxAttempts:  2,
+			}})
+			events := recordEvents(c, EventReconnectScheduled, EventReconnectFailed)
+			Expect(c.Connect()).To(Succeed())
+
+			Expect(s.Close()).To(Succeed())
+
+			Eventually(c.Done()).Should(BeClosed())
+			Expect(c.Err()).To(MatchError(ContainSubstring("giving up on reconnecting")))
+
+			var delays []time.Duration
+			var failed []ReconnectFailedEvent
+			for _, e := range events() {
+				switch e := e.(type) {
+				case ReconnectScheduledEvent:
+					delays = append(delays, e.Delay)
+				case ReconnectFailedEvent:
+					failed = append(failed, e)
+				}
+			}
+
+			Expect(delays).To(Equal([]time.Duration{time.Millisecond * 10, time.Millisecond * 20}))
+			Expect(failed).To(HaveLen(2))
+			Expect(failed[0].GaveUp).To(BeFalse())
+			Expect(failed[1].GaveUp).To(BeTrue())
+		})
+
+		g.It("Should open the circuit breaker after consecutive failures", func() {
+			s := newTestServer(echoHandler)
+
+			states := make(chan BreakerState, 10)
+			c := newTestClient(s, &Config{
+				ReconnectPolicy: &ReconnectPolicy{
+					InitialDelay:     time.Millisecond * 10,
+					MaxDelay:         time.Millisecond * 10,
+					BreakerThreshold: 2,
+					BreakerCooldown:  time.Millisecond * 200,
+				},
+				BreakerStateHandler: func(state BreakerState) {
+					states <- state
+				},
+			})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(s.Close()).To(Succeed())
+
+			Eventually(states).Should(Receive(Equal(BreakerOpen)))
+			_, err := c.ExecCommand("status")
+			Expect(errors.Cause(err)).To(Equal(errs.ErrCircuitOpen))
+
+			// Let the trial attempt after the cooldown reach a server which is up again.
+			s = newTestServer(echoHandler)
+			defer s.Close()
+			host, port := s.Addr()
+			c.addrLock.Lock()
+			c.Host, c.Port = host, port
+			c.addrLock.Unlock()
+
+			Eventually(states).Should(Receive(Equal(BreakerHalfOpen)))
+			Eventually(states).Should(Receive(Equal(BreakerClosed)))
+			Eventually(c.State).Should(Equal(StateConnected))
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+		})
+
+		g.It("Should report the progress of reconnecting", func() {
+			s := newTestServer(echoHandler)
+			up := newTestServer(echoHandler)
+			defer up.Close()
+
+			c := newTestClient(s, &Config{ReconnectPolicy: &ReconnectPolicy{InitialDelay: time.Millisecond * 50}})
+			events := recordEvents(c, EventReconnectScheduled, EventReconnectAttempt, EventReconnectFailed,
+				EventConnected)
+
+			// The second attempt reaches a server which is up.
+			c.Events().Subscribe(func(Event) {
+				host, port := up.Addr()
+				c.addrLock.Lock()
+				c.Host, c.Port = host, port
+				c.addrLock.Unlock()
+			}, EventReconnectFailed)
+
+			_, scheduled := c.NextRetryAt()
+			Expect(scheduled).To(BeFalse())
+
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(s.Close()).To(Succeed())
+
+			Eventually(func() bool {
+				_, scheduled := c.NextRetryAt()
+				return scheduled
+			}).Should(BeTrue())
+			at, _ := c.NextRetryAt()
+			Expect(at).To(BeTemporally("~", time.Now().Add(time.Millisecond*50), time.Millisecond*50))
+
+			Eventually(func() int { return len(events()) }, time.Second).Should(Equal(7))
+			Eventually(c.State).Should(Equal(StateConnected))
+			_, scheduled = c.NextRetryAt()
+			Expect(scheduled).To(BeFalse())
+
+			recorded := events()
+			Expect(recorded[0]).To(BeAssignableToTypeOf(ConnectedEvent{}))
+
+			first := recorded[1].(ReconnectScheduledEvent)
+			Expect(first.Attempt).To(Equal(1))
+			Expect(first.At).To(Equal(at))
+			Expect(recorded[2]).To(Equal(ReconnectAttemptEvent{Attempt: 1}))
+
+			failed := recorded[3].(ReconnectFailedEvent)
+			Expect(failed.Attempt).To(Equal(1))
+			Expect(failed.Err).To(HaveOccurred())
+			Expect(failed.GaveUp).To(BeFalse())
+
+			second := recorded[4].(ReconnectScheduledEvent)
+			Expect(second.Attempt).To(Equal(2))
+			Expect(second.Delay).To(Equal(time.Millisecond * 100))
+			Expect(second.LastErr).To(Equal(failed.Err))
+			Expect(recorded[5]).To(Equal(ReconnectAttemptEvent{Attempt: 2}))
+			Expect(recorded[6]).To(BeAssignableToTypeOf(ConnectedEvent{}))
+		})
+
+		g.It("Should stop reconnecting after an authentication failure", func() {
+			s := newTestServer(echoHandler)
+
+			// The server comes back with another password.
+			changed, err := rcontest.NewServer(rcontest.Config{Password: "changed", Handler: echoHandler})
+			Expect(err).ToNot(HaveOccurred())
+			defer changed.Close()
+
+			c := newTestClient(s, &Config{ReconnectPolicy: &ReconnectPolicy{InitialDelay: time.Millisecond}})
+			events := recordEvents(c, EventReconnectFailed)
+			Expect(c.Connect()).To(Succeed())
+
+			host, port := changed.Addr()
+			c.addrLock.Lock()
+			c.Host, c.Port = host, port
+			c.addrLock.Unlock()
+			Expect(s.Close()).To(Succeed())
+
+			Eventually(c.Done()).Should(BeClosed())
+			Expect(errors.Cause(c.Err())).To(Equal(errs.ErrAuthentication))
+			Expect(events()).To(HaveLen(1))
+			Expect(events()[0].(ReconnectFailedEvent).GaveUp).To(BeTrue())
+		})
+
+		g.It("Should reconnect again if the connection is lost right after reconnecting", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{ReconnectPolicy: &ReconnectPolicy{InitialDelay: time.Millisecond}})
+
+			// The connection made by the first reconnect is lost before the reconnect routine returns.
+			var lock sync.Mutex
+			connects := 0
+			c.Events().Subscribe(func(Event) {
+				lock.Lock()
+				connects++
+				first := connects == 2
+				lock.Unlock()
+
+				if first {
+					s.CloseConnections()
+					Eventually(c.State).Should(Equal(StateDisconnected))
+				}
+			}, EventConnected)
+
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			s.CloseConnections()
+
+			Eventually(func() int {
+				lock.Lock()
+				defer lock.Unlock()
+
+				return connects
+			}, time.Second).Should(Equal(3))
+			Eventually(c.State).Should(Equal(StateConnected))
+			Expect(c.Done()).ToNot(BeClosed())
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+		})
+	})
+	g.Describe("RetryInitialConnect", func() {
+		g.It("Should retry until the server is reachable", func() {
+			down := newTestServer(echoHandler)
+			Expect(down.Close()).To(Succeed())
+
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(down, &Config{
+				RetryInitialConnect: true,
+				ReconnectPolicy:     &ReconnectPolicy{InitialDelay: time.Millisecond * 100, MaxAttempts: 3},
+			})
+
+			// Bring the server up while the client waits between attempts.
+			go func() {
+				time.Sleep(time.Millisecond * 30)
+				host, port := s.Addr()
+				c.addrLock.Lock()
+				c.Host, c.Port = host, port
+				c.addrLock.Unlock()
+			}()
+
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+		})
+
+		g.It("Should return the error of every attempt", func() {
+			s := newTestServer(echoHandler)
+			c := newTestClient(s, &Config{
+				RetryInitialConnect: true,
+				ReconnectPolicy:     &ReconnectPolicy{InitialDelay: time.Millisecond, MaxAttempts: 3},
+			})
+			Expect(s.Close()).To(Succeed())
+
+			err := c.Connect()
+			Expect(err).To(BeAssignableToTypeOf(&errs.AttemptsError{}))
+			Expect(err.(*errs.AttemptsError).Errors).To(HaveLen(3))
+		})
+
+		g.It("Should not retry authentication failures", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{
+				RetryInitialConnect: true,
+				ReconnectPolicy:     &ReconnectPolicy{InitialDelay: time.Millisecond, MaxAttempts: 3},
+			})
+			c.Password = "wrong"
+
+			err := c.Connect()
+			Expect(err.(*errs.AttemptsError).Errors).To(HaveLen(1))
+			Expect(errors.Cause(err)).To(Equal(errs.ErrAuthentication))
+		})
+	})
+}
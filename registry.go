@@ -0,0 +1,131 @@
+package rcon
+
+import (
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// Registry shares clients between components of a process, so that components talking to the same server use a single
+// authenticated connection instead of opening one each. Clients are deduplicated by address and reference counted: the
+// connection is only closed once every component which acquired the client has closed its handle.
+//
+// Using a registry is opt-in. Clients created with NewClient are never shared.
+type Registry struct {
+	lock    sync.Mutex
+	clients map[string]*registryEntry
+}
+
+type registryEntry struct {
+	client   *Client
+	password string
+	refs     int
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		clients: map[string]*registryEntry{},
+	}
+}
+
+var defaultRegistry = NewRegistry()
+
+// Shared acquires a client from the package-level registry. See Registry.Acquire.
+func Shared(config *Config, logger Logger) (*SharedClient, error) {
+	return defaultRegistry.Acquire(config, logger)
+}
+
+// Acquire returns a handle to the client for the config's address, creating the client if none exists yet. If a client
+// already exists, config and logger are ignored, apart from the password, which must match to prevent components from
+// piggybacking on a connection they couldn't authenticate themselves.
+//
+// The returned client is not connected automatically. Calling Connect on a client which is already connected is a
+// no-op, so every component can call it.
+func (r *Registry) Acquire(config *Config, logger Logger) (*SharedClient, error) {
+	key := registryKey(config.Host, config.Port)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	entry, ok := r.clients[key]
+	if ok {
+		if entry.password != config.Password {
+			return nil, errors.Wrapf(errs.ErrSharedClientConflict, "password differs from the shared client for %s", key)
+		}
+	} else {
+		entry = &registryEntry{
+			client:   NewClient(config, logger),
+			password: config.Password,
+		}
+		r.clients[key] = entry
+	}
+
+	entry.refs++
+
+	return &SharedClient{
+		Client:   entry.client,
+		registry: r,
+		key:      key,
+	}, nil
+}
+
+// release drops a reference to the client for key. It returns true if it was the last reference, in which case the
+// client is removed from the registry.
+func (r *Registry) release(key string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	entry, ok := r.clients[key]
+	if !ok {
+		return false
+	}
+
+	entry.refs--
+	if entry.refs > 0 {
+		return false
+	}
+
+	delete(r.clients, key)
+
+	return true
+}
+
+// registryKey returns the key under which clients for an address are registered.
+func registryKey(host string, port uint16) string {
+	if isLocalAddress(host) {
+		return host
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+// SharedClient is a handle to a client acquired from a Registry. Its Close method releases the handle, and only closes
+// the connection once all handles have been released.
+type SharedClient struct {
+	*Client
+
+	registry  *Registry
+	key       string
+	closeOnce sync.Once
+}
+
+// Close releases the handle. If it was the last handle to the client, the client is closed. Calling Close more than once
+// has no effect.
+func (s *SharedClient) Close() error {
+	var err error
+
+	s.closeOnce.Do(func() {
+		if !s.registry.release(s.key) {
+			return
+		}
+
+		if err = s.Client.Close(); errors.Cause(err) == errs.ErrNotConnected {
+			err = nil
+		}
+	})
+
+	return err
+}
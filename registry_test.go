@@ -0,0 +1,49 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Registry", func() {
+		g.It("Should share clients by address until every handle is closed", func() {
+			r := NewRegistry()
+
+			a, err := r.Acquire(&Config{Host: "127.0.0.1", Port: 27015, Password: "pw"}, nil)
+			Expect(err).To(BeNil())
+			b, err := r.Acquire(&Config{Host: "127.0.0.1", Port: 27015, Password: "pw"}, nil)
+			Expect(err).To(BeNil())
+			other, err := r.Acquire(&Config{Host: "127.0.0.1", Port: 27016, Password: "pw"}, nil)
+			Expect(err).To(BeNil())
+
+			Expect(a.Client).To(BeIdenticalTo(b.Client))
+			Expect(a.Client).NotTo(BeIdenticalTo(other.Client))
+
+			Expect(a.Close()).To(BeNil())
+			Expect(a.Close()).To(BeNil())
+			Expect(r.clients).To(HaveKey("127.0.0.1:27015"))
+
+			Expect(b.Close()).To(BeNil())
+			Expect(r.clients).NotTo(HaveKey("127.0.0.1:27015"))
+		})
+
+		g.It("Should refuse to share a client with a different password", func() {
+			r := NewRegistry()
+
+			_, err := r.Acquire(&Config{Host: "127.0.0.1", Port: 27015, Password: "pw"}, nil)
+			Expect(err).To(BeNil())
+
+			_, err = r.Acquire(&Config{Host: "127.0.0.1", Port: 27015, Password: "other"}, nil)
+			Expect(errors.Cause(err)).To(Equal(errs.ErrSharedClientConflict))
+		})
+	})
+}
@@ -0,0 +1,139 @@
+package rcon
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"time"
+)
+
+// RetryPolicy configures retries of failed commands. Commands are only retried if they are idempotent, since a
+// command which timed out may still have been executed by the server. Retrying a ban or restart could apply it twice.
+type RetryPolicy struct {
+	// MaxRetries is the number of times a failed command is retried.
+	MaxRetries int
+
+	// InitialDelay is the delay before the first retry.
+	//
+	// Default: 100ms
+	InitialDelay time.Duration
+
+	// MaxDelay is the maximum delay between retries.
+	//
+	// Default: 5s
+	MaxDelay time.Duration
+
+	// Multiplier is the factor the delay is multiplied by after each retry.
+	//
+	// Default: 2
+	Multiplier float64
+
+	// RetryOn decides which errors are retried.
+	//
+	// Default: IsTransient
+	RetryOn func(err error) bool
+}
+
+func (p *RetryPolicy) setDefaults() {
+	if p.InitialDelay <= 0 {
+		p.InitialDelay = time.Millisecond * 100
+	}
+
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = time.Second * 5
+	}
+
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+
+	if p.RetryOn == nil {
+		p.RetryOn = IsTransient
+	}
+}
+
+// Delay returns the delay before the provided retry number, starting at 1.
+func (p *RetryPolicy) Delay(retry int) time.Duration {
+	return backoffDelay(p.InitialDelay, p.MaxDelay, p.Multiplier, retry)
+}
+
+// IsTransient is the default RetryPolicy.RetryOn. It returns true for errors which are likely to go away by themselves:
+// timeouts and the client being disconnected, for example while it is reconnecting.
+func IsTransient(err error) bool {
+	switch errors.Cause(err) {
+	case errs.ErrReadTimeout, errs.ErrQueueTimeout, errs.ErrNotConnected:
+		return true
+	}
+
+	return false
+}
+
+// notSent returns true for errors which are returned before a command was written to the connection, so executing it
+// elsewhere can't execute it twice.
+func notSent(err error) bool {
+	switch errors.Cause(err) {
+	case errs.ErrNotConnected, errs.ErrQueueTimeout, errs.ErrPaused, errs.ErrDraining, errs.ErrCircuitOpen:
+		return true
+	}
+
+	return false
+}
+
+// IdempotencyChecker is a function which returns true if a command can safely be executed more than once, such as
+// commands which only query the server.
+type IdempotencyChecker func(command string) bool
+
+// WithRetry sets the retry policy of a single call, overriding the client's CommandRetryPolicy.
+func WithRetry(policy *RetryPolicy) ExecOption {
+	return func(o *execOptions) {
+		o.retry = policy
+	}
+}
+
+// WithIdempotent marks a command as idempotent or not, overriding the client's IsIdempotent hint.
+func WithIdempotent(idempotent bool) ExecOption {
+	return func(o *execOptions) {
+		o.idempotent = &idempotent
+	}
+}
+
+// isIdempotent returns true if a command can safely be executed more than once. The WithIdempotent option takes
+// precedence over the client's IsIdempotent hint.
+func (c *Client) isIdempotent(command string, o *execOptions) bool {
+	if o.idempotent != nil {
+		return *o.idempotent
+	}
+
+	return c.IsIdempotent != nil && c.IsIdempotent(command)
+}
+
+// execWithRetry executes a command, retrying it according to the retry policy if it is idempotent.
+func (c *Client) execWithRetry(ctx context.Context, command string, priority Priority, o *execOptions) (string, error) {
+	policy := o.retry
+	if policy == nil {
+		policy = c.CommandRetryPolicy
+	}
+
+	res, err := c.execCommand(ctx, command, priority, o)
+	if policy == nil || !c.isIdempotent(command, o) {
+		return res, err
+	}
+
+	// The policy may be a per-call policy which hasn't had its defaults set.
+	p := *policy
+	p.setDefaults()
+
+	for retry := 1; err != nil && retry <= p.MaxRetries && p.RetryOn(err); retry++ {
+		c.log.Debug("Retrying command. Retry: ", retry, " Error: ", err)
+
+		select {
+		case <-time.After(p.Delay(retry)):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+
+		res, err = c.execCommand(ctx, command, priority, o)
+	}
+
+	return res, err
+}
@@ -0,0 +1,102 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Command retries", func() {
+		var c *Client
+		var calls func(command string) int
+		var cleanup func()
+
+		// The first execution of every command is slower than the client waits for, so it fails with a transient
+		// ErrReadTimeout. Retries are delayed until the server is done with it, so they succeed.
+		g.BeforeEach(func() {
+			var lock sync.Mutex
+			counts := map[string]int{}
+			s := newTestServer(func(command string) string {
+				lock.Lock()
+				counts[command]++
+				first := counts[command] == 1
+				lock.Unlock()
+
+				if first {
+					time.Sleep(time.Millisecond * 150)
+				}
+
+				return "echo: " + command
+			})
+
+			calls = func(command string) int {
+				lock.Lock()
+				defer lock.Unlock()
+
+				return counts[command]
+			}
+
+			c = newTestClient(s, &Config{
+				QueueReadTimeout:   time.Millisecond * 100,
+				CommandRetryPolicy: &RetryPolicy{MaxRetries: 2, InitialDelay: time.Millisecond * 100},
+				IsIdempotent: func(command string) bool {
+					return command == "status"
+				},
+			})
+			Expect(c.Connect()).To(Succeed())
+
+			cleanup = func() {
+				_ = c.Close()
+				_ = s.Close()
+			}
+		})
+
+		g.AfterEach(func() {
+			cleanup()
+		})
+
+		g.It("Should retry idempotent commands", func() {
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+			Expect(calls("status")).To(Equal(2))
+		})
+
+		g.It("Should retry commands marked idempotent", func() {
+			Expect(c.ExecCommand("players", WithIdempotent(true))).To(Equal("echo: players"))
+			Expect(calls("players")).To(Equal(2))
+		})
+
+		g.It("Should never retry commands which aren't idempotent", func() {
+			_, err := c.ExecCommand("ban Bob")
+			Expect(errors.Cause(err)).To(Equal(errs.ErrReadTimeout))
+
+			_, err = c.ExecCommand("status", WithIdempotent(false))
+			Expect(errors.Cause(err)).To(Equal(errs.ErrReadTimeout))
+
+			Consistently(func() int {
+				return calls("ban Bob") + calls("status")
+			}, time.Millisecond*300).Should(Equal(2))
+		})
+
+		g.It("Should use the per-call policy", func() {
+			_, err := c.ExecCommand("status", WithRetry(&RetryPolicy{
+				RetryOn: func(error) bool {
+					return false
+				},
+			}))
+			Expect(errors.Cause(err)).To(Equal(errs.ErrReadTimeout))
+			Consistently(func() int {
+				return calls("status")
+			}, time.Millisecond*300).Should(Equal(1))
+		})
+	})
+}
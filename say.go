@@ -0,0 +1,110 @@
+package rcon
+
+import (
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultSayFormat is the format of the chat command used by SayChunked if the dialect doesn't set one.
+const DefaultSayFormat = "say %s"
+
+// DefaultSayDelay is the default amount of time SayChunked waits between chunks.
+const DefaultSayDelay = time.Second
+
+// SayChunked sends a message to the server's chat using the dialect's SayFormat. Messages longer than the dialect's
+// MaxSayLength are split at word boundaries into several chat messages, which are sent SayDelay apart. SayPrefix is
+// prepended to every chunk and counts towards the limit. If SayPrefix leaves no room for the message,
+// ErrSayPrefixTooLong is returned. Chunks are formatted with the dialect's SayCommand, so the message may contain text
+// written by players.
+//
+// If sending a chunk fails, the remaining chunks are not sent.
+func (c *Client) SayChunked(msg string, opts ...ExecOption) error {
+	d := c.Dialect()
+
+	limit := d.MaxSayLength - utf8.RuneCountInString(c.SayPrefix)
+	if d.MaxSayLength > 0 && limit <= 0 {
+		return errors.Wrapf(errs.ErrSayPrefixTooLong, "%q with a limit of %d characters", c.SayPrefix, d.MaxSayLength)
+	}
+
+	chunks := splitChunks(msg, limit)
+
+	for i, chunk := range chunks {
+		if i > 0 {
+			time.Sleep(c.SayDelay)
+		}
+
+		if _, err := c.ExecCommand(d.SayCommand("", c.SayPrefix+chunk), opts...); err != nil {
+			return errors.Wrapf(err, "could not send chunk %d of %d", i+1, len(chunks))
+		}
+	}
+
+	return nil
+}
+
+// SayCommand returns the command which sends msg to the server's chat using format, or the dialect's SayFormat if
+// format is empty. Line breaks and other control characters in msg are replaced with spaces, and msg is quoted with
+// the dialect's QuoteArg, so that text written by players can't inject further commands.
+func (d *Dialect) SayCommand(format, msg string) string {
+	if format == "" {
+		format = d.SayFormat
+	}
+	if format == "" {
+		format = DefaultSayFormat
+	}
+
+	msg = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+
+		return r
+	}, msg)
+
+	return d.FormatCommand(format, msg)
+}
+
+// splitChunks splits s into chunks of at most limit characters, breaking at whitespace where possible. Words longer than
+// limit are split across chunks. If limit is not positive, s is returned as a single chunk.
+func splitChunks(s string, limit int) []string {
+	s = strings.TrimSpace(s)
+	if limit <= 0 || utf8.RuneCountInString(s) <= limit {
+		return []string{s}
+	}
+
+	var chunks []string
+	var current []rune
+
+	flush := func() {
+		if len(current) > 0 {
+			chunks = append(chunks, string(current))
+			current = current[:0]
+		}
+	}
+
+	for _, word := range strings.Fields(s) {
+		w := []rune(word)
+
+		if len(current) > 0 && len(current)+1+len(w) > limit {
+			flush()
+		}
+
+		for len(w) > limit {
+			flush()
+			chunks = append(chunks, string(w[:limit]))
+			w = w[limit:]
+		}
+
+		if len(current) > 0 {
+			current = append(current, ' ')
+		}
+		current = append(current, w...)
+	}
+
+	flush()
+
+	return chunks
+}
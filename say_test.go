@@ -0,0 +1,96 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSay(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("splitChunks", func() {
+		g.It("Should not split messages within the limit", func() {
+			Expect(splitChunks(" hello world ", 11)).To(Equal([]string{"hello world"}))
+			Expect(splitChunks("hello world", 0)).To(Equal([]string{"hello world"}))
+		})
+
+		g.It("Should split at word boundaries", func() {
+			Expect(splitChunks("the quick brown fox jumps", 10)).To(Equal([]string{"the quick", "brown fox", "jumps"}))
+		})
+
+		g.It("Should split words longer than the limit", func() {
+			Expect(splitChunks("a abcdefghij b", 4)).To(Equal([]string{"a", "abcd", "efgh", "ij b"}))
+		})
+
+		g.It("Should count characters rather than bytes", func() {
+			Expect(splitChunks("äöü äöü", 3)).To(Equal([]string{"äöü", "äöü"}))
+		})
+	})
+
+	g.Describe("SayChunked", func() {
+		g.It("Should send one chat command per chunk at the dialect limit", func() {
+			var lock sync.Mutex
+			var received []string
+			s := newTestServer(func(command string) string {
+				lock.Lock()
+				received = append(received, command)
+				lock.Unlock()
+				return ""
+			})
+			defer s.Close()
+
+			dialect := *DefaultDialect
+			dialect.SayFormat = "ServerSay %s"
+			dialect.MaxSayLength = 16
+
+			c := newTestClient(s, &Config{Dialect: &dialect, SayPrefix: "[bot] ", SayDelay: time.Millisecond})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(c.SayChunked("the server restarts in five minutes")).To(Succeed())
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			Expect(received).To(Equal([]string{
+				`ServerSay "[bot] the server"`,
+				`ServerSay "[bot] restarts"`,
+				`ServerSay "[bot] in five"`,
+				`ServerSay "[bot] minutes"`,
+			}))
+
+			for _, command := range received {
+				Expect(len(strings.Trim(strings.TrimPrefix(command, "ServerSay "), `"`))).
+					To(BeNumerically("<=", dialect.MaxSayLength))
+			}
+		})
+
+		g.It("Should return an error if the prefix leaves no room for the message", func() {
+			dialect := *DefaultDialect
+			dialect.MaxSayLength = 6
+
+			c := NewClient(&Config{Dialect: &dialect, SayPrefix: "[bot] "}, nil)
+			Expect(errors.Cause(c.SayChunked("hello"))).To(Equal(errs.ErrSayPrefixTooLong))
+		})
+	})
+
+	g.Describe("SayCommand", func() {
+		g.It("Should keep messages from injecting commands", func() {
+			Expect(DefaultDialect.SayCommand("", "hi; quit\nkick admin")).To(Equal(`say "hi; quit kick admin"`))
+			Expect(DefaultDialect.SayCommand("", `say "hi"`)).To(Equal(`say "say \"hi\""`))
+
+			dialect := *DefaultDialect
+			dialect.ArgQuoting = QuoteNone
+			Expect(dialect.SayCommand("broadcast %s", "hi\r\nquit")).To(Equal("broadcast hi  quit"))
+		})
+	})
+}
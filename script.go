@@ -0,0 +1,59 @@
+package rcon
+
+import (
+	"bufio"
+	"github.com/pkg/errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of a single command executed by ExecScript.
+type Result struct {
+	// Line is the line number of the command in the script, starting at 1.
+	Line     int
+	Command  string
+	Response string
+}
+
+// ExecScript reads newline separated commands from r and executes them in order, waiting ScriptDelay between commands.
+// Blank lines and comments starting with "//" or "#" are skipped, which allows .cfg style scripts to be applied.
+//
+// If a command fails, execution stops and the results of the commands executed so far are returned along with the
+// error, which names the offending line.
+func (c *Client) ExecScript(r io.Reader, opts ...ExecOption) ([]Result, error) {
+	var results []Result
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		command := strings.TrimSpace(scanner.Text())
+		if command == "" || strings.HasPrefix(command, "//") || strings.HasPrefix(command, "#") {
+			continue
+		}
+
+		if len(results) > 0 && c.ScriptDelay > 0 {
+			time.Sleep(c.ScriptDelay)
+		}
+
+		res, err := c.ExecCommand(command, opts...)
+		if err != nil {
+			return results, errors.Wrapf(err, "line %d", line)
+		}
+
+		results = append(results, Result{
+			Line:     line,
+			Command:  command,
+			Response: res,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return results, errors.Wrap(err, "could not read script")
+	}
+
+	return results, nil
+}
@@ -0,0 +1,71 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/rcontest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScript(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("ExecScript", func() {
+		g.It("Should skip blank lines and comments", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			results, err := c.ExecScript(strings.NewReader("// setup\nmp_timelimit 30\n\n  # rotation\n  changelevel de_dust2  \n"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal([]Result{
+				{Line: 2, Command: "mp_timelimit 30", Response: "echo: mp_timelimit 30"},
+				{Line: 5, Command: "changelevel de_dust2", Response: "echo: changelevel de_dust2"},
+			}))
+		})
+
+		g.It("Should stop at the first failed command and return the results so far", func() {
+			var lock sync.Mutex
+			var executed []string
+			var s *rcontest.Server
+			s = newTestServer(func(command string) string {
+				lock.Lock()
+				executed = append(executed, command)
+				lock.Unlock()
+
+				// The connection is lost instead of answering, which fails the command.
+				if command == "fail" {
+					s.CloseConnections()
+				}
+
+				return "ok"
+			})
+			defer s.Close()
+
+			c := newTestClient(s, &Config{QueueReadTimeout: time.Millisecond * 200})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			results, err := c.ExecScript(strings.NewReader("first\nsecond\n\nfail\nnever\n"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("line 4: "))
+			Expect(results).To(Equal([]Result{
+				{Line: 1, Command: "first", Response: "ok"},
+				{Line: 2, Command: "second", Response: "ok"},
+			}))
+
+			lock.Lock()
+			defer lock.Unlock()
+			Expect(executed).To(Equal([]string{"first", "second", "fail"}))
+		})
+	})
+}
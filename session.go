@@ -0,0 +1,155 @@
+package rcon
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"github.com/refractorgscm/rcon/packet"
+	"io"
+	"sync"
+)
+
+// rawSessionBuffer is the number of received packets a raw session buffers before further packets are dropped.
+const rawSessionBuffer = 64
+
+// rawSession is the io.ReadWriteCloser returned by Client.RawSession.
+type rawSession struct {
+	c   *Client
+	id  int32
+	ctx context.Context
+
+	mailbox chan packet.Packet
+	closed  chan struct{}
+	once    sync.Once
+
+	readLock sync.Mutex
+	pending  []byte
+}
+
+// RawSession returns an io.ReadWriteCloser bound to the current authenticated connection, for integrations which tunnel
+// their own protocol over RCON, such as modded servers exposing a custom command channel.
+//
+// Each Write sends its bytes as the body of a single command packet, so writes larger than the dialect's
+// MaxPayloadSize fail with ErrPayloadTooLarge. Read returns the bodies of packets the server sends back with the
+// session's packet ID, in order. A body which doesn't fit into the buffer passed to Read is returned by the following
+// calls. Bodies are not signed, compressed or recorded in the command history.
+//
+// The session ends when it is closed or when the connection it was opened on is lost, even if the client reconnects.
+// Once the connection was lost, Read returns the packets which were already received and then io.EOF. After the
+// session ends, writes fail with ErrSessionClosed, as do reads once it was closed. If the session isn't read from,
+// packets beyond a small buffer are dropped.
+func (c *Client) RawSession() (io.ReadWriteCloser, error) {
+	c.stateLock.Lock()
+	if c.state != StateConnected {
+		c.stateLock.Unlock()
+		return nil, errs.ErrNotConnected
+	}
+	ctx := c.routines.ctx
+	c.stateLock.Unlock()
+
+	s := &rawSession{
+		c:       c,
+		id:      c.PacketIDs.Next(c.RestrictedPacketIDs),
+		ctx:     ctx,
+		mailbox: make(chan packet.Packet, rawSessionBuffer),
+		closed:  make(chan struct{}),
+	}
+
+	c.rqLock.Lock()
+	c.readQueue[s.id] = s.mailbox
+	c.rqLock.Unlock()
+
+	// Sessions which are never closed still release their mailbox once the connection is lost, so that packets sent
+	// with the same ID on a later connection aren't routed to it.
+	go func() {
+		select {
+		case <-s.closed:
+		case <-s.ctx.Done():
+			s.removeMailbox()
+		}
+	}()
+
+	return s, nil
+}
+
+func (s *rawSession) Write(b []byte) (int, error) {
+	if s.isClosed() {
+		return 0, errs.ErrSessionClosed
+	}
+
+	if max := s.c.Dialect().MaxPayloadSize; max > 0 && len(b) > max {
+		return 0, errors.Wrapf(errs.ErrPayloadTooLarge, "write is %d bytes, the maximum is %d", len(b), max)
+	}
+
+	p := packet.NewClientPacketWithID(s.c.EndianMode, packet.TypeCommand, string(b), s.id)
+	if err := s.c.enqueuePacket(p, PriorityNormal, false); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+func (s *rawSession) Read(b []byte) (int, error) {
+	s.readLock.Lock()
+	defer s.readLock.Unlock()
+
+	select {
+	case <-s.closed:
+		return 0, errs.ErrSessionClosed
+	default:
+	}
+
+	if len(s.pending) == 0 {
+		select {
+		case p := <-s.mailbox:
+			s.pending = packetBody(p, true)
+		case <-s.closed:
+			return 0, errs.ErrSessionClosed
+		case <-s.ctx.Done():
+			// Packets which were received before the connection was lost are still returned.
+			select {
+			case p := <-s.mailbox:
+				s.pending = packetBody(p, true)
+			default:
+				return 0, io.EOF
+			}
+		}
+	}
+
+	n := copy(b, s.pending)
+	s.pending = s.pending[n:]
+
+	return n, nil
+}
+
+// Close ends the session. Packets for the session received afterwards are dropped.
+func (s *rawSession) Close() error {
+	s.once.Do(func() {
+		close(s.closed)
+		s.removeMailbox()
+	})
+
+	return nil
+}
+
+// removeMailbox removes the session's mailbox from the read queue. Once the connection was lost, the session's packet ID
+// may have been reused by another command, whose mailbox is left alone.
+func (s *rawSession) removeMailbox() {
+	s.c.rqLock.Lock()
+	defer s.c.rqLock.Unlock()
+
+	if s.c.readQueue[s.id] == s.mailbox {
+		delete(s.c.readQueue, s.id)
+	}
+}
+
+func (s *rawSession) isClosed() bool {
+	select {
+	case <-s.closed:
+		return true
+	case <-s.ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
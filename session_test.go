@@ -0,0 +1,90 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/errs"
+	"io/ioutil"
+	"testing"
+)
+
+func TestRawSession(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("RawSession", func() {
+		g.It("Should tunnel raw sessions over the authenticated connection", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			session, err := c.RawSession()
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = session.Write([]byte("hello"))
+			Expect(err).ToNot(HaveOccurred())
+
+			buf := make([]byte, 6)
+			n, err := session.Read(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(buf[:n])).To(Equal("echo: "))
+
+			n, err = session.Read(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(buf[:n])).To(Equal("hello"))
+
+			Expect(session.Close()).To(Succeed())
+			_, err = session.Write([]byte("hello"))
+			Expect(err).To(Equal(errs.ErrSessionClosed))
+		})
+
+		g.It("Should end with io.EOF and release its mailbox once the connection is lost", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			session, err := c.RawSession()
+			Expect(err).ToNot(HaveOccurred())
+			id := session.(*rawSession).id
+
+			_, err = session.Write([]byte("hello"))
+			Expect(err).ToNot(HaveOccurred())
+
+			// The response is delivered to the session before the connection is lost.
+			Eventually(func() int {
+				return len(session.(*rawSession).mailbox)
+			}).Should(Equal(1))
+
+			s.CloseConnections()
+			Eventually(c.State).Should(Equal(StateDisconnected))
+
+			mailboxOpen := func() bool {
+				c.rqLock.Lock()
+				defer c.rqLock.Unlock()
+
+				_, ok := c.readQueue[id]
+				return ok
+			}
+			Eventually(mailboxOpen).Should(BeFalse())
+
+			data, err := ioutil.ReadAll(session)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal("echo: hello"))
+
+			_, err = session.Write([]byte("hello"))
+			Expect(err).To(Equal(errs.ErrSessionClosed))
+
+			Expect(session.Close()).To(Succeed())
+			_, err = session.Read(make([]byte, 1))
+			Expect(err).To(Equal(errs.ErrSessionClosed))
+		})
+	})
+}
@@ -0,0 +1,141 @@
+package sinks
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"sync"
+)
+
+// RotatingFile is an io.WriteCloser which writes to a file and rotates it once it reaches a maximum size. Rotated files
+// are renamed to path.1, path.2 and so on, with path.1 being the most recent. It is safe for concurrent use.
+//
+// RotatingFile can be combined with NewWriterSink or NewJSONLinesSink to persist broadcasts to a rotating log file.
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+	closed     bool
+}
+
+// NewRotatingFile opens the file at path for appending. The file is rotated before a write would make it larger than
+// maxSize bytes. At most maxBackups rotated files are kept. If maxSize is zero or less, the file is never rotated.
+func NewRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	rf := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+
+	return rf, nil
+}
+
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.closed {
+		return 0, os.ErrClosed
+	}
+
+	// The file may not have been reopened after a failed rotation.
+	if rf.file == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+
+	return n, err
+}
+
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.closed {
+		return nil
+	}
+	rf.closed = true
+
+	if rf.file == nil {
+		return nil
+	}
+
+	err := rf.file.Close()
+	rf.file = nil
+
+	return err
+}
+
+// open must be called with the lock held.
+func (rf *RotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Wrap(err, "could not open log file")
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return errors.Wrap(err, "could not stat log file")
+	}
+
+	rf.file = f
+	rf.size = info.Size()
+
+	return nil
+}
+
+// rotate must be called with the lock held. If the file can't be rotated, it is reopened so that later writes are
+// appended to it and rotation is attempted again.
+func (rf *RotatingFile) rotate() error {
+	err := rf.file.Close()
+	rf.file = nil
+
+	if err != nil {
+		err = errors.Wrap(err, "could not close log file")
+	} else {
+		err = rf.shift()
+	}
+
+	if openErr := rf.open(); err == nil {
+		err = openErr
+	}
+
+	return err
+}
+
+// shift renames the closed file to the first backup, shifting existing backups up by one and discarding the oldest. If
+// no backups are kept, the file is removed.
+func (rf *RotatingFile) shift() error {
+	if rf.maxBackups <= 0 {
+		return errors.Wrap(os.Remove(rf.path), "could not remove log file")
+	}
+
+	_ = os.Remove(rf.backupPath(rf.maxBackups))
+	for i := rf.maxBackups - 1; i >= 1; i-- {
+		_ = os.Rename(rf.backupPath(i), rf.backupPath(i+1))
+	}
+
+	return errors.Wrap(os.Rename(rf.path, rf.backupPath(1)), "could not rotate log file")
+}
+
+func (rf *RotatingFile) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", rf.path, n)
+}
@@ -0,0 +1,110 @@
+package sinks
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("RotatingFile", func() {
+		var dir, path string
+
+		g.BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "rcon-sinks")
+			Expect(err).ToNot(HaveOccurred())
+
+			path = filepath.Join(dir, "broadcasts.log")
+		})
+
+		g.AfterEach(func() {
+			_ = os.RemoveAll(dir)
+		})
+
+		read := func(path string) string {
+			data, err := ioutil.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+
+			return string(data)
+		}
+
+		write := func(rf *RotatingFile, lines ...string) {
+			for _, line := range lines {
+				Expect(rf.Write([]byte(line))).To(Equal(len(line)))
+			}
+		}
+
+		g.It("Should append to an existing file", func() {
+			Expect(ioutil.WriteFile(path, []byte("one\n"), 0644)).To(Succeed())
+
+			rf, err := NewRotatingFile(path, 8, 1)
+			Expect(err).ToNot(HaveOccurred())
+			write(rf, "two\n")
+			Expect(rf.Close()).To(Succeed())
+
+			Expect(read(path)).To(Equal("one\ntwo\n"))
+		})
+
+		g.It("Should rotate before the file grows past its maximum size", func() {
+			rf, err := NewRotatingFile(path, 8, 2)
+			Expect(err).ToNot(HaveOccurred())
+			defer rf.Close()
+
+			write(rf, "one\n", "two\n", "three\n", "four\n", "five\n")
+
+			Expect(read(path)).To(Equal("five\n"))
+			Expect(read(path + ".1")).To(Equal("four\n"))
+			Expect(read(path + ".2")).To(Equal("three\n"))
+			Expect(path + ".3").ToNot(BeAnExistingFile())
+		})
+
+		g.It("Should discard the file if no backups are kept", func() {
+			rf, err := NewRotatingFile(path, 4, 0)
+			Expect(err).ToNot(HaveOccurred())
+			defer rf.Close()
+
+			write(rf, "one\n", "two\n")
+
+			Expect(read(path)).To(Equal("two\n"))
+			Expect(path + ".1").ToNot(BeAnExistingFile())
+		})
+
+		g.It("Should keep writing to the file if it can't be rotated", func() {
+			// A non-empty directory can't be replaced by the file.
+			Expect(os.MkdirAll(filepath.Join(path+".1", "blocked"), 0755)).To(Succeed())
+
+			rf, err := NewRotatingFile(path, 4, 1)
+			Expect(err).ToNot(HaveOccurred())
+			defer rf.Close()
+
+			write(rf, "one\n")
+			_, err = rf.Write([]byte("two\n"))
+			Expect(err).To(HaveOccurred())
+
+			Expect(os.RemoveAll(path + ".1")).To(Succeed())
+			write(rf, "three\n")
+
+			Expect(read(path)).To(Equal("three\n"))
+			Expect(read(path + ".1")).To(Equal("one\n"))
+		})
+
+		g.It("Should fail writes once closed", func() {
+			rf, err := NewRotatingFile(path, 0, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rf.Close()).To(Succeed())
+			Expect(rf.Close()).To(Succeed())
+
+			_, err = rf.Write([]byte("one\n"))
+			Expect(err).To(Equal(os.ErrClosed))
+		})
+	})
+}
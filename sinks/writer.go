@@ -0,0 +1,87 @@
+// Package sinks provides ready-made broadcast handlers which persist broadcast messages. Sinks can be attached to a
+// client using the BroadcastSinks config field, alongside the regular BroadcastHandler.
+package sinks
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Formatter formats a broadcast message received at the provided time into the bytes which will be written by a sink.
+type Formatter func(message string, received time.Time) ([]byte, error)
+
+// WriterSink writes broadcast messages to an io.Writer. It is safe for concurrent use.
+type WriterSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format Formatter
+
+	// ErrorHandler is called if a broadcast could not be formatted or written. If nil, errors are ignored.
+	ErrorHandler func(error)
+}
+
+// NewWriterSink creates a sink which writes each broadcast message to w on its own line.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return NewFormattedSink(w, RawLine)
+}
+
+// NewJSONLinesSink creates a sink which writes each broadcast message to w as a JSON object on its own line.
+func NewJSONLinesSink(w io.Writer) *WriterSink {
+	return NewFormattedSink(w, JSONLine)
+}
+
+// NewFormattedSink creates a sink which writes each broadcast message to w using the provided formatter.
+func NewFormattedSink(w io.Writer, format Formatter) *WriterSink {
+	return &WriterSink{
+		w:      w,
+		format: format,
+	}
+}
+
+// Handle writes a broadcast message to the sink. It matches the signature of rcon.BroadcastHandler.
+func (s *WriterSink) Handle(message string) {
+	out, err := s.format(message, time.Now())
+	if err != nil {
+		s.handleError(err)
+		return
+	}
+
+	s.mu.Lock()
+	_, err = s.w.Write(out)
+	s.mu.Unlock()
+
+	if err != nil {
+		s.handleError(err)
+	}
+}
+
+func (s *WriterSink) handleError(err error) {
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(err)
+	}
+}
+
+// RawLine is a Formatter which writes the message followed by a newline.
+func RawLine(message string, _ time.Time) ([]byte, error) {
+	return []byte(message + "\n"), nil
+}
+
+type jsonLine struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// JSONLine is a Formatter which writes the message and the time it was received as a JSON object followed by a newline.
+func JSONLine(message string, received time.Time) ([]byte, error) {
+	out, err := json.Marshal(jsonLine{
+		Time:    received,
+		Message: message,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(out, '\n'), nil
+}
@@ -0,0 +1,232 @@
+package rcon
+
+import (
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/endian"
+	"github.com/refractorgscm/rcon/errs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ManagerSnapshot is the serializable state of a Manager, created by Manager.Snapshot. It can be encoded as JSON, YAML
+// or TOML. Passwords are stored as references to a SecretStore, never in plaintext.
+type ManagerSnapshot struct {
+	Servers       []ServerSnapshot       `json:"servers" yaml:"servers" toml:"servers"`
+	Subscriptions []SubscriptionSnapshot `json:"subscriptions" yaml:"subscriptions" toml:"subscriptions"`
+}
+
+// ServerSnapshot is the state of a single managed server. Config.Password is always empty, PasswordRef is used instead.
+type ServerSnapshot struct {
+	Config      FileConfig `json:"config" yaml:"config" toml:"config"`
+	PasswordRef string     `json:"password_ref" yaml:"password_ref" toml:"password_ref"`
+}
+
+// SubscriptionSnapshot is a named broadcast subscription made using Manager.SubscribeTagged. Listeners are functions
+// and can't be serialized, so they are provided by name when restoring.
+type SubscriptionSnapshot struct {
+	Name     string `json:"name" yaml:"name" toml:"name"`
+	Selector string `json:"selector" yaml:"selector" toml:"selector"`
+}
+
+// SecretStore maps passwords to references which can be stored in their place, and resolves them again.
+type SecretStore interface {
+	// Reference returns the reference under which the password of a server can be found.
+	Reference(server, password string) (string, error)
+
+	// Resolve returns the password a reference points to.
+	Resolve(ref string) (string, error)
+}
+
+// EnvSecretStore is a SecretStore which keeps passwords in environment variables named <Prefix>_<SERVER>_PASSWORD,
+// where SERVER is the upper-cased server name with characters other than letters and digits replaced by underscores.
+// The variables must be set by the supervising process, Reference only checks that they hold the password.
+type EnvSecretStore struct {
+	Prefix string
+}
+
+func (s EnvSecretStore) Reference(server, password string) (string, error) {
+	name := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+
+		return '_'
+	}, server)
+
+	ref := s.Prefix + "_" + name + "_PASSWORD"
+	if v, ok := os.LookupEnv(ref); !ok || v != password {
+		return "", errors.Wrapf(errs.ErrUnresolvedSecret, "%s does not hold the password of server %s", ref, server)
+	}
+
+	return ref, nil
+}
+
+func (s EnvSecretStore) Resolve(ref string) (string, error) {
+	v, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", errors.Wrapf(errs.ErrUnresolvedSecret, "%s is not set", ref)
+	}
+
+	return v, nil
+}
+
+// SubscribeTagged subscribes to broadcasts like SubscribeBroadcasts, selecting servers by tag expression. Unlike other
+// subscriptions, it is included in snapshots under its name, so it can be restored.
+func (m *Manager) SubscribeTagged(name, expr string, listener ManagedBroadcastListener) (func(), error) {
+	selector, err := ParseTagSelector(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.subscribe(name, expr, selector, listener), nil
+}
+
+// Snapshot returns the state of the manager: the servers with their configs and tags, and the subscriptions made using
+// SubscribeTagged. Passwords are replaced by references obtained from secrets.
+//
+// Only fields of the client configs which can be loaded from a config file are included. Handlers and other function
+// fields must be set again after restoring.
+func (m *Manager) Snapshot(secrets SecretStore) (*ManagerSnapshot, error) {
+	m.lock.RLock()
+	servers := make([]*ManagedServer, 0, len(m.servers))
+	for _, server := range m.servers {
+		servers = append(servers, server)
+	}
+	m.lock.RUnlock()
+
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Name < servers[j].Name
+	})
+
+	snapshot := &ManagerSnapshot{}
+
+	for _, server := range servers {
+		ref, err := secrets.Reference(server.Name, server.Client.Password)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not get password reference of server %s", server.Name)
+		}
+
+		snapshot.Servers = append(snapshot.Servers, ServerSnapshot{
+			Config:      fileConfigFromClient(server),
+			PasswordRef: ref,
+		})
+	}
+
+	m.subsLock.RLock()
+	for _, sub := range m.broadcasts {
+		if sub.name != "" {
+			snapshot.Subscriptions = append(snapshot.Subscriptions, SubscriptionSnapshot{
+				Name:     sub.name,
+				Selector: sub.expr,
+			})
+		}
+	}
+	m.subsLock.RUnlock()
+
+	return snapshot, nil
+}
+
+// RestoreOptions provides what Manager.Restore needs to restore a snapshot in addition to the snapshot itself.
+type RestoreOptions struct {
+	// Secrets resolves the password references of the snapshot.
+	Secrets SecretStore
+
+	// Listeners holds the listener of each subscription in the snapshot, keyed by subscription name.
+	Listeners map[string]ManagedBroadcastListener
+
+	// Logger is used for the restored clients.
+	Logger Logger
+}
+
+// Restore adds the servers and subscriptions of a snapshot to the manager. The restored clients are not connected.
+//
+// The snapshot is validated before anything is added, so if an error is returned, the manager is left unchanged.
+func (m *Manager) Restore(snapshot *ManagerSnapshot, opts RestoreOptions) error {
+	var configs []*ServerConfig
+
+	for i, server := range snapshot.Servers {
+		password, err := opts.Secrets.Resolve(server.PasswordRef)
+		if err != nil {
+			return errors.Wrapf(err, "could not resolve password of server %s", server.Config.Name)
+		}
+
+		fc := server.Config
+		fc.Password = password
+
+		sc, err := fc.ServerConfig("servers[" + strconv.Itoa(i) + "].config.")
+		if err != nil {
+			return err
+		}
+
+		configs = append(configs, sc)
+	}
+
+	selectors := make([]Selector, len(snapshot.Subscriptions))
+	for i, sub := range snapshot.Subscriptions {
+		if opts.Listeners[sub.Name] == nil {
+			return errors.Wrapf(errs.ErrMissingListener, "subscription %s", sub.Name)
+		}
+
+		selector, err := ParseTagSelector(sub.Selector)
+		if err != nil {
+			return errors.Wrapf(err, "subscription %s", sub.Name)
+		}
+
+		selectors[i] = selector
+	}
+
+	if err := m.AddConfigs(configs, opts.Logger); err != nil {
+		return err
+	}
+
+	for i, sub := range snapshot.Subscriptions {
+		m.subscribe(sub.Name, sub.Selector, selectors[i], opts.Listeners[sub.Name])
+	}
+
+	return nil
+}
+
+// fileConfigFromClient converts the config of a managed server to a FileConfig, leaving out the password.
+func fileConfigFromClient(server *ManagedServer) FileConfig {
+	c := server.Client
+	host, port := c.Address()
+
+	fc := FileConfig{
+		Name:                server.Name,
+		Host:                host,
+		Port:                int(port),
+		AuthMode:            string(c.authMode()),
+		QueryPort:           int(c.QueryPort),
+		ConnTimeout:         Duration(c.ConnTimeout),
+		DialTimeout:         Duration(c.DialTimeout),
+		QueueWriteTimeout:   Duration(c.QueueWriteTimeout),
+		QueueReadTimeout:    Duration(c.QueueReadTimeout),
+		Endian:              "little",
+		RestrictedPacketIDs: c.RestrictedPacketIDs,
+		DetectDialect:       c.DetectDialect,
+		ProbeTimeout:        Duration(c.ProbeTimeout),
+		RetryInitialConnect: c.RetryInitialConnect,
+		Tags:                copyTags(server.Tags),
+		Macros:              c.RegisteredMacros(),
+	}
+
+	if c.EndianMode == endian.Big {
+		fc.Endian = "big"
+	}
+
+	if p := c.ReconnectPolicy; p != nil {
+		fc.Reconnect = &FileReconnect{
+			InitialDelay:     Duration(p.InitialDelay),
+			MaxDelay:         Duration(p.MaxDelay),
+			Multiplier:       p.Multiplier,
+			MaxAttempts:      p.MaxAttempts,
+			BreakerThreshold: p.BreakerThreshold,
+			BreakerCooldown:  Duration(p.BreakerCooldown),
+		}
+	}
+
+	return fc
+}
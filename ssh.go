@@ -0,0 +1,209 @@
+package rcon
+
+import (
+	"github.com/pkg/errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSHClient is the part of *ssh.Client from golang.org/x/crypto/ssh used by SSHTransport. It is an interface so that
+// this package doesn't depend on the ssh package.
+type SSHClient interface {
+	Dial(network, addr string) (net.Conn, error)
+	SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error)
+	Close() error
+}
+
+// sshKeepaliveRequest is the global request sent to check if the connection to a jump host is still alive. Servers
+// reply to unknown requests with a failure, which is enough to know they are reachable.
+const sshKeepaliveRequest = "keepalive@openssh.com"
+
+// SSHTransport connects to the server through an SSH jump host, for servers which only expose RCON on localhost or a
+// private network. The connection to the jump host is kept open and shared by every connection made through the
+// transport, and is established again if it was lost.
+//
+// The host and port of the client config are resolved by the jump host, so they are usually 127.0.0.1 and the RCON
+// port. Hosts with the UnixScheme prefix are dialed as unix sockets on the jump host.
+type SSHTransport struct {
+	// Connect establishes the connection to the jump host, for example:
+	//
+	//	func(timeout time.Duration) (rcon.SSHClient, error) {
+	//		config.Timeout = timeout
+	//		return ssh.Dial("tcp", "jump.example.com:22", config)
+	//	}
+	Connect func(timeout time.Duration) (SSHClient, error)
+
+	lock   sync.Mutex
+	client SSHClient
+}
+
+// Dial dials the server through the jump host. If the connection to the jump host is broken, it is established again
+// once. Dials refused by the jump host or the server are returned as is, since the connection to the jump host is
+// shared by other connections.
+func (t *SSHTransport) Dial(host string, port uint16, timeout time.Duration) (net.Conn, error) {
+	network, addr := "tcp", net.JoinHostPort(host, strconv.Itoa(int(port)))
+	if strings.HasPrefix(host, UnixScheme) {
+		network, addr = "unix", strings.TrimPrefix(host, UnixScheme)
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for attempt := 0; ; attempt++ {
+		client, err := t.jumpClient(time.Until(deadline))
+		if err != nil {
+			return nil, errors.Wrap(err, "could not connect to jump host")
+		}
+
+		conn, err := dialSSH(client, network, addr, time.Until(deadline))
+		if err == nil {
+			return deadlinePipe(conn), nil
+		}
+
+		// The connection to the jump host may have been lost since it was established.
+		if !jumpHostFailed(client, err, timeout) {
+			return nil, errors.Wrap(err, "could not dial through jump host")
+		}
+
+		t.reset(client)
+
+		if attempt > 0 || time.Now().After(deadline) {
+			return nil, errors.Wrap(err, "could not dial through jump host")
+		}
+	}
+}
+
+// Close closes the connection to the jump host. Connections made through it are closed too.
+func (t *SSHTransport) Close() error {
+	t.lock.Lock()
+	client := t.client
+	t.client = nil
+	t.lock.Unlock()
+
+	if client == nil {
+		return nil
+	}
+
+	return client.Close()
+}
+
+func (t *SSHTransport) jumpClient(timeout time.Duration) (SSHClient, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	client, err := t.Connect(timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	t.client = client
+
+	return client, nil
+}
+
+// reset closes client and forgets it, unless it was already replaced.
+func (t *SSHTransport) reset(client SSHClient) {
+	t.lock.Lock()
+	if t.client == client {
+		t.client = nil
+	}
+	t.lock.Unlock()
+
+	_ = client.Close()
+}
+
+// jumpHostFailed reports whether err, returned by a dial through client, was caused by the connection to the jump host
+// failing rather than by the dial being refused. Unless the connection was closed, a keepalive request is sent to find
+// out, which fails if it isn't answered within timeout.
+func jumpHostFailed(client SSHClient, err error, timeout time.Duration) bool {
+	if errors.Cause(err) == io.EOF {
+		return true
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest(sshKeepaliveRequest, true, nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err != nil
+	case <-time.After(timeout):
+		return true
+	}
+}
+
+// dialSSH dials addr through client. SSH clients can't cancel a dial, so if timeout expires first, the dial is
+// abandoned and its connection closed once it completes.
+func dialSSH(client SSHClient, network, addr string, timeout time.Duration) (net.Conn, error) {
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		conn, err := client.Dial(network, addr)
+		done <- result{conn, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.conn, r.err
+	case <-time.After(timeout):
+		go func() {
+			if r := <-done; r.conn != nil {
+				_ = r.conn.Close()
+			}
+		}()
+
+		return nil, errors.New("dial timed out")
+	}
+}
+
+// deadlinePipe adds deadline support to conn, since connections tunneled over SSH don't support deadlines. Data is
+// copied through a pipe, so that a read which timed out doesn't lose any data.
+func deadlinePipe(conn net.Conn) net.Conn {
+	local, remote := net.Pipe()
+
+	go func() {
+		_, _ = io.Copy(remote, conn)
+		_ = remote.Close()
+	}()
+
+	go func() {
+		_, _ = io.Copy(conn, remote)
+		_ = conn.Close()
+	}()
+
+	return &pipeConn{Conn: local, tunnel: conn}
+}
+
+// pipeConn is the local end of a deadlinePipe. It reports the addresses of the tunneled connection.
+type pipeConn struct {
+	net.Conn
+	tunnel net.Conn
+}
+
+func (c *pipeConn) Close() error {
+	err := c.Conn.Close()
+	_ = c.tunnel.Close()
+
+	return err
+}
+
+func (c *pipeConn) LocalAddr() net.Addr {
+	return c.tunnel.LocalAddr()
+}
+
+func (c *pipeConn) RemoteAddr() net.Addr {
+	return c.tunnel.RemoteAddr()
+}
@@ -0,0 +1,176 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSSHTransport(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("SSHTransport", func() {
+		var jump *fakeJumpHost
+		var transport *SSHTransport
+
+		g.BeforeEach(func() {
+			jump = &fakeJumpHost{}
+			transport = &SSHTransport{Connect: func(time.Duration) (SSHClient, error) {
+				jump.lock.Lock()
+				defer jump.lock.Unlock()
+
+				jump.connects++
+				jump.closed = false
+
+				return jump, nil
+			}}
+		})
+
+		g.AfterEach(func() {
+			_ = transport.Close()
+		})
+
+		g.It("Should tunnel connections through the jump host", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{Transport: transport})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+			Expect(jump.Dials()).To(Equal(1))
+		})
+
+		g.It("Should keep the jump host connection if the server refuses a dial", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{Transport: transport})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			// Nothing listens on the port of a closed listener, so dials to it are refused.
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			refused := uint16(l.Addr().(*net.TCPAddr).Port)
+			Expect(l.Close()).To(Succeed())
+
+			_, err = transport.Dial("127.0.0.1", refused, time.Second)
+			Expect(err).To(HaveOccurred())
+
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+			Expect(jump.Connects()).To(Equal(1))
+		})
+
+		g.It("Should connect to the jump host again if the connection was lost", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			host, port := s.Addr()
+			conn, err := transport.Dial(host, port, time.Second)
+			Expect(err).ToNot(HaveOccurred())
+			_ = conn.Close()
+
+			Expect(jump.Close()).To(Succeed())
+
+			conn, err = transport.Dial(host, port, time.Second)
+			Expect(err).ToNot(HaveOccurred())
+			_ = conn.Close()
+
+			Expect(jump.Connects()).To(Equal(2))
+		})
+	})
+}
+
+// fakeJumpHost dials directly and returns connections without deadline support, like channels of an SSH client.
+// Closing it closes the connections dialed through it.
+type fakeJumpHost struct {
+	lock     sync.Mutex
+	connects int
+	dials    int
+	closed   bool
+	conns    []net.Conn
+}
+
+func (h *fakeJumpHost) Connects() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.connects
+}
+
+func (h *fakeJumpHost) Dials() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.dials
+}
+
+func (h *fakeJumpHost) Dial(network, addr string) (net.Conn, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.closed {
+		return nil, io.EOF
+	}
+
+	h.dials++
+
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	h.conns = append(h.conns, conn)
+
+	return noDeadlineConn{conn}, nil
+}
+
+func (h *fakeJumpHost) SendRequest(string, bool, []byte) (bool, []byte, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.closed {
+		return false, nil, io.EOF
+	}
+
+	return false, nil, nil
+}
+
+func (h *fakeJumpHost) Close() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.closed = true
+	for _, conn := range h.conns {
+		_ = conn.Close()
+	}
+	h.conns = nil
+
+	return nil
+}
+
+type noDeadlineConn struct {
+	net.Conn
+}
+
+func (noDeadlineConn) SetDeadline(time.Time) error {
+	return errors.New("deadline not supported")
+}
+
+func (noDeadlineConn) SetReadDeadline(time.Time) error {
+	return errors.New("deadline not supported")
+}
+
+func (noDeadlineConn) SetWriteDeadline(time.Time) error {
+	return errors.New("deadline not supported")
+}
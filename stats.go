@@ -0,0 +1,369 @@
+package rcon
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencySampleSize is the number of most recent command latencies kept for calculating latency statistics.
+const latencySampleSize = 1000
+
+// rttSampleSize is the number of most recent round trip times kept for calculating RTT statistics. It is smaller than
+// latencySampleSize so that the RTT statistics follow changes in network conditions quickly.
+const rttSampleSize = 100
+
+// Stats is a point-in-time snapshot of a client's statistics. It is returned by Client.Stats.
+type Stats struct {
+	// Uptime is the amount of time the current connection has been established for. It is zero if the client is not
+	// connected.
+	Uptime time.Duration
+
+	// Connects is the number of times the client has successfully connected.
+	Connects uint64
+
+	// Reconnects is the number of successful connections made after the first one.
+	Reconnects uint64
+
+	// CommandsExecuted is the number of commands which were executed successfully.
+	CommandsExecuted uint64
+
+	// CommandsFailed is the number of commands which returned an error.
+	CommandsFailed uint64
+
+	// AverageLatency, P50Latency, P95Latency and P99Latency are calculated from the most recent command latencies.
+	AverageLatency time.Duration
+	P50Latency     time.Duration
+	P95Latency     time.Duration
+	P99Latency     time.Duration
+
+	// CommandsWaiting is the number of commands waiting for a slot when MaxInFlightCommands is set. AverageCommandWait
+	// and P95CommandWait are calculated from the time the most recent commands waited for a slot.
+	CommandsWaiting    int
+	AverageCommandWait time.Duration
+	P95CommandWait     time.Duration
+
+	// LastRTT, MinRTT, AverageRTT and P95RTT are calculated from the round trip times of the most recent commands and
+	// heartbeats. Unlike command latencies, round trip times only cover the time between a packet being written to the
+	// connection and its response being read, which makes them a good measure of RCON latency.
+	LastRTT    time.Duration
+	MinRTT     time.Duration
+	AverageRTT time.Duration
+	P95RTT     time.Duration
+
+	// LastResponseTime is the time at which the last packet was received from the server.
+	LastResponseTime time.Time
+
+	// BroadcastsReceived is the number of broadcast messages received.
+	BroadcastsReceived uint64
+
+	// LastBroadcastTime is the time at which the last broadcast message was received.
+	LastBroadcastTime time.Time
+
+	// ClockSkew is the median skew of the most recent broadcasts with a server timestamp, see Broadcast.Skew. A
+	// positive value means the server's clock is behind the local clock. It is zero if BroadcastTimestamps is not set.
+	ClockSkew time.Duration
+
+	// BytesSent and BytesReceived are the number of bytes written to and read from the connection.
+	BytesSent     uint64
+	BytesReceived uint64
+
+	// PacketsSent and PacketsReceived are the number of packets written to and read from the connection, including
+	// auth packets, heartbeats and broadcasts.
+	PacketsSent     uint64
+	PacketsReceived uint64
+
+	// Socket holds the traffic of the current connection in each direction. It is reset when the client connects, and
+	// helps to diagnose asymmetric problems, such as a server which still accepts commands but stopped sending packets.
+	Socket SocketStats
+
+	// BreakerState is the state of the reconnect circuit breaker.
+	BreakerState BreakerState
+
+	// ConsecutiveReconnectFailures is the number of reconnect attempts which failed since the last successful one.
+	ConsecutiveReconnectFailures int
+
+	// LastError is the most recent error encountered by the client, or nil if no error has occurred.
+	LastError error
+
+	// LastErrorTime is the time at which LastError occurred.
+	LastErrorTime time.Time
+}
+
+// SocketStats counts the traffic of a single connection in each direction.
+type SocketStats struct {
+	BytesSent       uint64
+	BytesReceived   uint64
+	PacketsSent     uint64
+	PacketsReceived uint64
+
+	// LastSent and LastReceived are the times at which the last packet was written to and read from the connection.
+	LastSent     time.Time
+	LastReceived time.Time
+}
+
+type clientStats struct {
+	sync.Mutex
+
+	connectedAt        time.Time
+	connects           uint64
+	commandsExecuted   uint64
+	commandsFailed     uint64
+	broadcastsReceived uint64
+	lastBroadcastTime  time.Time
+	latencies          []time.Duration
+	latencyIdx         int
+	rtts               []time.Duration
+	rttIdx             int
+	lastRTT            time.Duration
+	lastResponseTime   time.Time
+	skews              []time.Duration
+	skewIdx            int
+	bytesSent          uint64
+	bytesReceived      uint64
+	packetsSent        uint64
+	packetsReceived    uint64
+	socket             SocketStats
+	lastError          error
+	lastErrorTime      time.Time
+	errors             errorHistory
+}
+
+func (s *clientStats) recordConnect() {
+	s.Lock()
+	s.connectedAt = time.Now()
+	s.connects++
+	s.Unlock()
+}
+
+func (s *clientStats) recordDisconnect() {
+	s.Lock()
+	s.connectedAt = time.Time{}
+	s.Unlock()
+}
+
+func (s *clientStats) recordCommand(latency time.Duration, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if err != nil {
+		s.commandsFailed++
+		s.setError(err)
+		return
+	}
+
+	s.commandsExecuted++
+
+	if len(s.latencies) < latencySampleSize {
+		s.latencies = append(s.latencies, latency)
+	} else {
+		s.latencies[s.latencyIdx] = latency
+		s.latencyIdx = (s.latencyIdx + 1) % latencySampleSize
+	}
+}
+
+func (s *clientStats) recordRTT(rtt time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.lastRTT = rtt
+
+	if len(s.rtts) < rttSampleSize {
+		s.rtts = append(s.rtts, rtt)
+	} else {
+		s.rtts[s.rttIdx] = rtt
+		s.rttIdx = (s.rttIdx + 1) % rttSampleSize
+	}
+}
+
+func (s *clientStats) recordResponse() {
+	s.Lock()
+	s.lastResponseTime = time.Now()
+	s.Unlock()
+}
+
+func (s *clientStats) recordBroadcast() {
+	s.Lock()
+	s.broadcastsReceived++
+	s.lastBroadcastTime = time.Now()
+	s.Unlock()
+}
+
+func (s *clientStats) lastBroadcast() time.Time {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.lastBroadcastTime
+}
+
+func (s *clientStats) recordSkew(skew time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.skews) < skewSampleSize {
+		s.skews = append(s.skews, skew)
+	} else {
+		s.skews[s.skewIdx] = skew
+		s.skewIdx = (s.skewIdx + 1) % skewSampleSize
+	}
+}
+
+// resetSocket starts counting the traffic of a new connection.
+func (s *clientStats) resetSocket() {
+	s.Lock()
+	s.socket = SocketStats{}
+	s.Unlock()
+}
+
+func (s *clientStats) recordBytesSent(n int) {
+	s.Lock()
+	s.bytesSent += uint64(n)
+	s.socket.BytesSent += uint64(n)
+	s.Unlock()
+}
+
+func (s *clientStats) recordBytesReceived(n int) {
+	s.Lock()
+	s.bytesReceived += uint64(n)
+	s.socket.BytesReceived += uint64(n)
+	s.Unlock()
+}
+
+func (s *clientStats) recordPacketSent() {
+	s.Lock()
+	s.packetsSent++
+	s.socket.PacketsSent++
+	s.socket.LastSent = time.Now()
+	s.Unlock()
+}
+
+func (s *clientStats) recordPacketReceived() {
+	s.Lock()
+	s.packetsReceived++
+	s.socket.PacketsReceived++
+	s.socket.LastReceived = time.Now()
+	s.Unlock()
+}
+
+func (s *clientStats) recordError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.Lock()
+	s.setError(err)
+	s.Unlock()
+}
+
+// setError must be called with the lock held.
+func (s *clientStats) setError(err error) {
+	record := newErrorRecord(err, s.connects)
+	if s.connectedAt.IsZero() {
+		// The error occurred while establishing the next connection.
+		record.Connection++
+	}
+
+	s.lastError = err
+	s.lastErrorTime = record.Time
+	s.errors.add(record)
+}
+
+func (s *clientStats) snapshot() Stats {
+	s.Lock()
+	defer s.Unlock()
+
+	stats := Stats{
+		Connects:           s.connects,
+		CommandsExecuted:   s.commandsExecuted,
+		CommandsFailed:     s.commandsFailed,
+		BroadcastsReceived: s.broadcastsReceived,
+		LastBroadcastTime:  s.lastBroadcastTime,
+		BytesSent:          s.bytesSent,
+		BytesReceived:      s.bytesReceived,
+		PacketsSent:        s.packetsSent,
+		PacketsReceived:    s.packetsReceived,
+		Socket:             s.socket,
+		LastError:          s.lastError,
+		LastErrorTime:      s.lastErrorTime,
+		LastRTT:            s.lastRTT,
+		LastResponseTime:   s.lastResponseTime,
+	}
+
+	if s.connects > 0 {
+		stats.Reconnects = s.connects - 1
+	}
+
+	if !s.connectedAt.IsZero() {
+		stats.Uptime = time.Since(s.connectedAt)
+	}
+
+	if len(s.latencies) > 0 {
+		sorted, average := sortSamples(s.latencies)
+
+		stats.AverageLatency = average
+		stats.P50Latency = percentile(sorted, 50)
+		stats.P95Latency = percentile(sorted, 95)
+		stats.P99Latency = percentile(sorted, 99)
+	}
+
+	if len(s.rtts) > 0 {
+		sorted, average := sortSamples(s.rtts)
+
+		stats.MinRTT = sorted[0]
+		stats.AverageRTT = average
+		stats.P95RTT = percentile(sorted, 95)
+	}
+
+	if len(s.skews) > 0 {
+		sorted, _ := sortSamples(s.skews)
+		stats.ClockSkew = percentile(sorted, 50)
+	}
+
+	return stats
+}
+
+// sortSamples returns a sorted copy of samples along with their average.
+func sortSamples(samples []time.Duration) ([]time.Duration, time.Duration) {
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, l := range sorted {
+		total += l
+	}
+
+	return sorted, total / time.Duration(len(sorted))
+}
+
+// percentile returns the p-th percentile of a sorted slice of durations using the nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	idx := (len(sorted)*p + 99) / 100
+	if idx < 1 {
+		idx = 1
+	}
+
+	return sorted[idx-1]
+}
+
+// countingReader wraps an io.Reader and reports the number of bytes read to the client's statistics.
+type countingReader struct {
+	r     io.Reader
+	stats *clientStats
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.stats.recordBytesReceived(n)
+	return n, err
+}
+
+// Stats returns a snapshot of the client's statistics.
+func (c *Client) Stats() Stats {
+	stats := c.stats.snapshot()
+	stats.BreakerState, stats.ConsecutiveReconnectFailures = c.breaker.current()
+	c.limiter.addStats(&stats)
+
+	return stats
+}
@@ -0,0 +1,171 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/packet"
+	"github.com/refractorgscm/rcon/rcontest"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Stats", func() {
+		g.It("Should count connects, commands and broadcasts", func() {
+			s := newTestServer(func(command string) string {
+				if command == "slow" {
+					time.Sleep(time.Millisecond * 200)
+				}
+
+				return "echo: " + command
+			})
+			defer s.Close()
+
+			c := newTestClient(s, &Config{
+				QueueReadTimeout: time.Millisecond * 50,
+				ReconnectPolicy:  &ReconnectPolicy{InitialDelay: time.Millisecond},
+				BroadcastChecker: func(p packet.Packet) bool {
+					return p.ID() == rcontest.BroadcastID
+				},
+				BroadcastHandler: func(string) {},
+			})
+			Expect(c.Stats().Uptime).To(BeZero())
+
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+			Expect(c.ExecCommand("players")).To(Equal("echo: players"))
+			_, err := c.ExecCommand("slow")
+			Expect(err).To(HaveOccurred())
+
+			s.Broadcast("Player joined")
+			Eventually(func() uint64 { return c.Stats().BroadcastsReceived }).Should(Equal(uint64(1)))
+
+			stats := c.Stats()
+			Expect(stats.Connects).To(Equal(uint64(1)))
+			Expect(stats.Reconnects).To(BeZero())
+			Expect(stats.Uptime).To(BeNumerically(">", 0))
+			Expect(stats.CommandsExecuted).To(Equal(uint64(2)))
+			Expect(stats.CommandsFailed).To(Equal(uint64(1)))
+			Expect(stats.AverageLatency).To(BeNumerically(">", 0))
+			Expect(stats.LastBroadcastTime).ToNot(BeZero())
+			Expect(errors.Cause(stats.LastError)).To(Equal(errors.Cause(err)))
+
+			s.CloseConnections()
+			Eventually(func() uint64 { return c.Stats().Reconnects }, time.Second).Should(Equal(uint64(1)))
+			Expect(c.Stats().Connects).To(Equal(uint64(2)))
+
+			Expect(c.Close()).To(Succeed())
+			Expect(c.Stats().Uptime).To(BeZero())
+		})
+
+		g.It("Should calculate latency percentiles from the most recent commands", func() {
+			var s clientStats
+			for i := 1; i <= 100; i++ {
+				s.recordCommand(time.Duration(i)*time.Millisecond, nil)
+			}
+
+			stats := s.snapshot()
+			Expect(stats.AverageLatency).To(Equal(time.Microsecond * 50500))
+			Expect(stats.P50Latency).To(Equal(time.Millisecond * 50))
+			Expect(stats.P95Latency).To(Equal(time.Millisecond * 95))
+			Expect(stats.P99Latency).To(Equal(time.Millisecond * 99))
+
+			// Older latencies are replaced once the sample is full.
+			for i := 0; i < latencySampleSize; i++ {
+				s.recordCommand(time.Second, nil)
+			}
+
+			stats = s.snapshot()
+			Expect(stats.AverageLatency).To(Equal(time.Second))
+			Expect(stats.P50Latency).To(Equal(time.Second))
+			Expect(stats.CommandsExecuted).To(Equal(uint64(100 + latencySampleSize)))
+		})
+
+		g.It("Should measure the round trip time of commands", func() {
+			s, err := rcontest.NewServer(rcontest.Config{
+				Password: testPassword,
+				Handler:  echoHandler,
+				Chaos:    &rcontest.Chaos{Latency: time.Millisecond * 20},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			defer s.Close()
+
+			c := newTestClient(s, &Config{})
+			Expect(c.Stats().LastRTT).To(BeZero())
+
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+
+			stats := c.Stats()
+			Expect(stats.LastRTT).To(BeNumerically(">=", time.Millisecond*20))
+			Expect(stats.MinRTT).To(BeNumerically(">=", time.Millisecond*20))
+			Expect(stats.LastResponseTime).ToNot(BeZero())
+		})
+
+		g.It("Should calculate RTT statistics from the most recent round trips", func() {
+			var s clientStats
+			for i := 100; i >= 1; i-- {
+				s.recordRTT(time.Duration(i) * time.Millisecond)
+			}
+
+			stats := s.snapshot()
+			Expect(stats.LastRTT).To(Equal(time.Millisecond))
+			Expect(stats.MinRTT).To(Equal(time.Millisecond))
+			Expect(stats.AverageRTT).To(Equal(time.Microsecond * 50500))
+			Expect(stats.P95RTT).To(Equal(time.Millisecond * 95))
+
+			// The sample is smaller than the latency sample, so it follows changes quickly.
+			for i := 0; i < rttSampleSize; i++ {
+				s.recordRTT(time.Second)
+			}
+
+			stats = s.snapshot()
+			Expect(stats.MinRTT).To(Equal(time.Second))
+			Expect(stats.AverageRTT).To(Equal(time.Second))
+		})
+
+		g.It("Should count the traffic of the current connection separately", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			c := newTestClient(s, &Config{ReconnectPolicy: &ReconnectPolicy{InitialDelay: time.Millisecond}})
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+
+			// The auth request and the command, each with a 4 byte size, 4 byte ID, 4 byte type and two null bytes.
+			stats := c.Stats()
+			Expect(stats.PacketsSent).To(Equal(uint64(2)))
+			Expect(stats.BytesSent).To(Equal(uint64(14+len(testPassword)) + uint64(14+len("status"))))
+			Expect(stats.PacketsReceived).To(BeNumerically(">=", 2))
+			Expect(stats.BytesReceived).To(BeNumerically(">=", 14*2+len("echo: status")))
+			Expect(stats.Socket.BytesSent).To(Equal(stats.BytesSent))
+			Expect(stats.Socket.BytesReceived).To(Equal(stats.BytesReceived))
+			Expect(stats.Socket.PacketsSent).To(Equal(stats.PacketsSent))
+			Expect(stats.Socket.PacketsReceived).To(Equal(stats.PacketsReceived))
+			Expect(stats.Socket.LastSent).ToNot(BeZero())
+			Expect(stats.Socket.LastReceived).ToNot(BeZero())
+
+			s.CloseConnections()
+			Eventually(func() uint64 { return c.Stats().Reconnects }, time.Second).Should(Equal(uint64(1)))
+
+			// Only the totals include the traffic of the first connection.
+			stats = c.Stats()
+			Expect(stats.Socket.PacketsSent).To(Equal(uint64(1)))
+			Expect(stats.PacketsSent).To(Equal(uint64(3)))
+			Expect(stats.Socket.BytesSent).To(Equal(uint64(14 + len(testPassword))))
+			Expect(stats.Socket.BytesReceived).To(BeNumerically("<", stats.BytesReceived))
+		})
+	})
+}
@@ -0,0 +1,185 @@
+package rcon
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+// SupervisorConfig configures a Supervisor.
+type SupervisorConfig struct {
+	// InitialDelay is the delay before the first restart after a failure.
+	//
+	// Default: 1s
+	InitialDelay time.Duration
+
+	// MaxDelay is the maximum delay between restarts.
+	//
+	// Default: 1m
+	MaxDelay time.Duration
+
+	// Multiplier is the factor the delay is multiplied by after each consecutive failure.
+	//
+	// Default: 2
+	Multiplier float64
+
+	// MaxRestarts is the number of consecutive restarts after which the supervisor gives up. If zero, it never gives
+	// up.
+	MaxRestarts int
+
+	// StableAfter is how long the client must have run for a failure not to count as consecutive, which resets the
+	// restart delay.
+	//
+	// Default: 1m
+	StableAfter time.Duration
+
+	// OnRestart is called with the error the client stopped with, before waiting delay to restart it.
+	OnRestart func(err error, restart int, delay time.Duration)
+}
+
+func (c *SupervisorConfig) setDefaults() {
+	if c.InitialDelay <= 0 {
+		c.InitialDelay = time.Second
+	}
+
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = time.Minute
+	}
+
+	if c.Multiplier < 1 {
+		c.Multiplier = 2
+	}
+
+	if c.StableAfter <= 0 {
+		c.StableAfter = time.Minute
+	}
+}
+
+// SupervisorHealth describes the health of a supervised client, for health checks of the host process.
+type SupervisorHealth struct {
+	// Running is true while Run is active.
+	Running bool
+
+	// State is the connection state of the client. The client is healthy while it is connected.
+	State State
+
+	// Restarts is the total number of restarts, and ConsecutiveFailures the number of failures since the client last
+	// ran for StableAfter.
+	Restarts            int
+	ConsecutiveFailures int
+
+	// LastError is the error the client last stopped with, and LastErrorAt the time it stopped.
+	LastError   error
+	LastErrorAt time.Time
+}
+
+// Healthy returns true if the supervisor is running and the client is connected.
+func (h SupervisorHealth) Healthy() bool {
+	return h.Running && h.State == StateConnected
+}
+
+// Supervisor keeps a client running in long-running daemons and services. The client's ReconnectPolicy handles
+// short outages, while the supervisor restarts the client with backoff once it stops running, for example because the
+// initial connect failed, reconnecting gave up or authentication was rejected.
+type Supervisor struct {
+	client *Client
+	config SupervisorConfig
+
+	lock   sync.Mutex
+	health SupervisorHealth
+}
+
+// NewSupervisor creates a supervisor for client. The client is connected by Run.
+func NewSupervisor(client *Client, config SupervisorConfig) *Supervisor {
+	config.setDefaults()
+
+	return &Supervisor{
+		client: client,
+		config: config,
+	}
+}
+
+// Client returns the supervised client.
+func (s *Supervisor) Client() *Client {
+	return s.client
+}
+
+// Run connects the client and restarts it whenever it stops running, until ctx is cancelled or the client is closed.
+// If ctx is cancelled, the client is closed and ctx.Err() is returned. If the client was closed, nil is returned. If
+// MaxRestarts consecutive restarts failed, the last error is returned.
+func (s *Supervisor) Run(ctx context.Context) error {
+	s.setRunning(true)
+	defer s.setRunning(false)
+
+	for {
+		started := time.Now()
+
+		err := s.client.ListenAndServeBroadcasts(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		failures := s.recordFailure(err, time.Since(started) >= s.config.StableAfter)
+		if s.config.MaxRestarts > 0 && failures > s.config.MaxRestarts {
+			return errors.Wrapf(err, "giving up after %d restarts", s.config.MaxRestarts)
+		}
+
+		delay := backoffDelay(s.config.InitialDelay, s.config.MaxDelay, s.config.Multiplier, failures)
+		s.client.log.Error("Client stopped, restarting in ", delay, ". Error: ", err)
+
+		if s.config.OnRestart != nil {
+			s.config.OnRestart(err, failures, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+
+		s.lock.Lock()
+		s.health.Restarts++
+		s.lock.Unlock()
+	}
+}
+
+// Health returns the current health of the supervised client.
+func (s *Supervisor) Health() SupervisorHealth {
+	s.lock.Lock()
+	health := s.health
+	s.lock.Unlock()
+
+	health.State = s.client.State()
+
+	return health
+}
+
+func (s *Supervisor) setRunning(running bool) {
+	s.lock.Lock()
+	s.health.Running = running
+	s.lock.Unlock()
+}
+
+// recordFailure records that the client stopped with err, and returns the number of consecutive failures. If stable is
+// true, earlier failures are no longer counted.
+func (s *Supervisor) recordFailure(err error, stable bool) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if stable {
+		s.health.ConsecutiveFailures = 0
+	}
+
+	s.health.ConsecutiveFailures++
+	s.health.LastError = err
+	s.health.LastErrorAt = time.Now()
+
+	return s.health.ConsecutiveFailures
+}
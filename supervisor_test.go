@@ -0,0 +1,47 @@
+package rcon
+
+import (
+	"context"
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestSupervisor(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Supervisor", func() {
+		g.It("Should restart supervised clients until the context is cancelled", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			failing := newTestClient(s, &Config{})
+			failing.Password = "wrong"
+			failingSup := NewSupervisor(failing, SupervisorConfig{
+				InitialDelay: time.Millisecond,
+				MaxRestarts:  2,
+			})
+			Expect(failingSup.Run(context.Background())).ToNot(Succeed())
+			Expect(failingSup.Health().Restarts).To(Equal(2))
+			Expect(failingSup.Health().Healthy()).To(BeFalse())
+
+			sup := NewSupervisor(newTestClient(s, &Config{}), SupervisorConfig{})
+			ctx, cancel := context.WithCancel(context.Background())
+			result := make(chan error, 1)
+			go func() {
+				result <- sup.Run(ctx)
+			}()
+
+			Eventually(func() bool { return sup.Health().Healthy() }).Should(BeTrue())
+			Expect(sup.Client().ExecCommand("status")).To(Equal("echo: status"))
+
+			cancel()
+			Eventually(result).Should(Receive(Equal(context.Canceled)))
+			Expect(sup.Health().Running).To(BeFalse())
+		})
+	})
+}
@@ -0,0 +1,119 @@
+package rcon
+
+import (
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"strings"
+)
+
+// ParseTagSelector parses a tag expression into a Selector matching servers by their tags.
+//
+// An expression is a comma separated list of terms, all of which must match:
+//
+//   - key=value matches servers whose tag has the value. Alternatives can be separated by "|", as in region=eu|us
+//   - key!=value matches servers whose tag doesn't have any of the values, including servers without the tag
+//   - key matches servers which have the tag
+//   - !key matches servers which don't have the tag
+//
+// For example, "region=eu,game=mordhau,!maintenance" selects all EU Mordhau servers which aren't tagged for
+// maintenance. An empty expression matches every server.
+func ParseTagSelector(expr string) (Selector, error) {
+	var terms []tagTerm
+
+	for _, raw := range strings.Split(expr, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			if strings.TrimSpace(expr) != "" {
+				return nil, errors.Wrapf(errs.ErrInvalidSelector, "empty term in %q", expr)
+			}
+			continue
+		}
+
+		term, err := parseTagTerm(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		terms = append(terms, term)
+	}
+
+	return func(server *ManagedServer) bool {
+		for _, term := range terms {
+			if !term.match(server.Tags) {
+				return false
+			}
+		}
+
+		return true
+	}, nil
+}
+
+// MustParseTagSelector is like ParseTagSelector but panics if the expression is invalid.
+func MustParseTagSelector(expr string) Selector {
+	selector, err := ParseTagSelector(expr)
+	if err != nil {
+		panic(err)
+	}
+
+	return selector
+}
+
+type tagTerm struct {
+	key    string
+	values map[string]bool
+	negate bool
+}
+
+func parseTagTerm(raw string) (tagTerm, error) {
+	term := tagTerm{}
+
+	key, value, hasValue := raw, "", false
+	if i := strings.Index(raw, "!="); i >= 0 {
+		key, value, hasValue, term.negate = raw[:i], raw[i+2:], true, true
+	} else if i := strings.Index(raw, "="); i >= 0 {
+		key, value, hasValue = raw[:i], raw[i+1:], true
+	} else if strings.HasPrefix(raw, "!") {
+		key, term.negate = raw[1:], true
+	}
+
+	term.key = strings.TrimSpace(key)
+	if term.key == "" {
+		return term, errors.Wrapf(errs.ErrInvalidSelector, "missing tag name in %q", raw)
+	}
+
+	if !hasValue {
+		return term, nil
+	}
+
+	term.values = map[string]bool{}
+	for _, v := range strings.Split(value, "|") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return term, errors.Wrapf(errs.ErrInvalidSelector, "missing tag value in %q", raw)
+		}
+
+		term.values[v] = true
+	}
+
+	return term, nil
+}
+
+func (t tagTerm) match(tags map[string]string) bool {
+	value, ok := tags[t.key]
+
+	if t.values == nil {
+		return ok != t.negate
+	}
+
+	return (ok && t.values[value]) != t.negate
+}
+
+// copyTags returns a copy of tags, so that callers can't modify the tags of a managed server.
+func copyTags(tags map[string]string) map[string]string {
+	copied := make(map[string]string, len(tags))
+	for k, v := range tags {
+		copied[k] = v
+	}
+
+	return copied
+}
@@ -0,0 +1,25 @@
+package rcon
+
+import "net"
+
+// NewClientFromConn creates a client which adopts conn, an established connection which was already authenticated,
+// instead of dialing the server itself. This allows a supervising process to hand its connections over to a new
+// process on restart, for example by passing the socket's file descriptor, or connections established with a custom
+// handshake to be used.
+//
+// The client is connected once NewClientFromConn returns, without authenticating or detecting the dialect. The config
+// should still hold the server's address and password, since the client dials the server itself if it reconnects
+// after the adopted connection was lost.
+func NewClientFromConn(conn net.Conn, config *Config, logger Logger) *Client {
+	c := NewClient(config, logger)
+
+	c.stateLock.Lock()
+	c.state = StateConnecting
+	c.useConn(conn)
+	c.stateLock.Unlock()
+
+	c.stats.recordConnect()
+	c.startConnection(nil)
+
+	return c
+}
@@ -0,0 +1,47 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/endian"
+	"github.com/refractorgscm/rcon/packet"
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestTakeover(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("NewClientFromConn", func() {
+		g.It("Should adopt an authenticated connection", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			host, port := s.Addr()
+			conn, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+			Expect(err).ToNot(HaveOccurred())
+
+			auth, err := packet.NewClientPacketWithID(endian.Little, packet.TypeAuth, testPassword,
+				packet.AuthPacketID).Build()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = conn.Write(auth)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, pType := range []packet.PacketType{packet.TypeCommandRes, packet.TypeAuthRes} {
+				res, err := packet.DecodeClientPacket(endian.Little, conn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res.Type()).To(Equal(pType))
+			}
+
+			c := NewClientFromConn(conn, &Config{Host: host, Port: port, Password: testPassword}, nil)
+			defer c.Close()
+
+			Expect(c.State()).To(Equal(StateConnected))
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+		})
+	})
+}
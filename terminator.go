@@ -0,0 +1,112 @@
+package rcon
+
+import (
+	"bytes"
+	"context"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"github.com/refractorgscm/rcon/packet"
+	"regexp"
+	"time"
+)
+
+// terminatedMailboxSize is the size of the mailbox of a command whose response is collected with a ResponseTerminator,
+// so that packets arriving in quick succession aren't dropped.
+const terminatedMailboxSize = 16
+
+// ResponseTerminator detects the end of a response which the server splits across several packets, for dialects
+// without a fragmentation sentinel. Packets with the command's ID are concatenated until the response is complete.
+//
+// A response is complete once it matches Pattern or ends with Suffix, or once no packet arrived for QuietPeriod. The
+// conditions can be combined, in which case the first one met ends the response. The response is returned unchanged,
+// including any terminating marker.
+type ResponseTerminator struct {
+	// Pattern completes the response once it matches. It is matched against the whole response received so far, so it
+	// should usually be anchored to the end, such as `(?m)^END$\s*$`.
+	Pattern *regexp.Regexp
+
+	// Suffix completes the response once it ends with Suffix.
+	Suffix string
+
+	// QuietPeriod completes the response once no further packet arrived for this long. If zero, the client waits for
+	// Pattern or Suffix until QueueReadTimeout.
+	QuietPeriod time.Duration
+}
+
+// complete returns true if response matches Pattern or ends with Suffix. If neither is set and there's no QuietPeriod,
+// the first packet is the whole response.
+func (t *ResponseTerminator) complete(response []byte) bool {
+	if t.Pattern == nil && t.Suffix == "" {
+		return t.QuietPeriod <= 0
+	}
+
+	if t.Pattern != nil && t.Pattern.Match(response) {
+		return true
+	}
+
+	return t.Suffix != "" && bytes.HasSuffix(response, []byte(t.Suffix))
+}
+
+// collectResponse queues a command packet and concatenates the packets received for it until t reports the response as
+// complete. It returns the last packet along with the body.
+func (c *Client) collectResponse(ctx context.Context, p packet.Packet, priority Priority, t *ResponseTerminator,
+	raw bool) (packet.Packet, []byte, error) {
+	mailbox := make(chan packet.Packet, terminatedMailboxSize)
+
+	c.rqLock.Lock()
+	c.readQueue[p.ID()] = mailbox
+	c.rqLock.Unlock()
+
+	defer c.closeMailbox(p.ID())
+
+	if err := c.enqueuePacket(p, priority, false); err != nil {
+		return nil, nil, errors.Wrap(err, "could not enqueue command packet")
+	}
+
+	res, err := c.waitMailbox(ctx, p.ID(), mailbox)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not get command response")
+	}
+
+	var body []byte
+
+	for {
+		// Raw bodies are concatenated, since the null bytes and newlines trimmed from bodies may belong to the middle
+		// of the response.
+		body = append(body, packetBody(res, true)...)
+
+		if c.MaxResponseSize > 0 && len(body) > c.MaxResponseSize {
+			return nil, nil, errors.Wrapf(errs.ErrResponseTooLarge, "response exceeded %d bytes", c.MaxResponseSize)
+		}
+
+		if t.complete(body) {
+			return res, trimResponse(body, raw), nil
+		}
+
+		if t.QuietPeriod <= 0 {
+			if res, err = c.waitMailbox(ctx, p.ID(), mailbox); err != nil {
+				return nil, nil, errors.Wrap(err, "could not get end of command response")
+			}
+
+			continue
+		}
+
+		select {
+		case res = <-mailbox:
+		case <-time.After(t.QuietPeriod):
+			return res, trimResponse(body, raw), nil
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// trimResponse trims leading and trailing null bytes and newlines from a collected response, like they are trimmed from
+// the body of a single packet, unless raw is set.
+func trimResponse(body []byte, raw bool) []byte {
+	if raw {
+		return body
+	}
+
+	return bytes.Trim(bytes.Trim(body, "\x00"), "\n")
+}
@@ -0,0 +1,43 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/refractorgscm/rcon/rcontest"
+	"testing"
+	"time"
+)
+
+func TestResponseTerminator(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("ResponseTerminator", func() {
+		g.It("Should collect responses split across packets", func() {
+			s, err := rcontest.NewServer(rcontest.Config{
+				Password:          testPassword,
+				Handler:           echoHandler,
+				ResponseChunkSize: 4,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			defer s.Close()
+
+			connect := func(terminator *ResponseTerminator) *Client {
+				c := newTestClient(s, &Config{Dialect: &Dialect{ResponseTerminator: terminator}})
+				Expect(c.Connect()).To(Succeed())
+
+				return c
+			}
+
+			c := connect(&ResponseTerminator{Suffix: "\nEND"})
+			defer c.Close()
+			Expect(c.ExecCommand("status\nEND")).To(Equal("echo: status\nEND"))
+
+			quiet := connect(&ResponseTerminator{QuietPeriod: time.Millisecond * 50})
+			defer quiet.Close()
+			Expect(quiet.ExecCommand("players")).To(Equal("echo: players"))
+		})
+	})
+}
@@ -0,0 +1,186 @@
+package rcon
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// skewSampleSize is the number of most recent broadcast clock skews kept for estimating the skew between the server's
+// clock and the local clock.
+const skewSampleSize = 100
+
+// Broadcast is a broadcast message along with the times it was sent and received at.
+type Broadcast struct {
+	Message string
+
+	// Seq is the sequence number of the broadcast. Delivered broadcasts are numbered from 1 in the order they were
+	// delivered, see Client.AckBroadcast.
+	Seq uint64
+
+	// ArrivalSeq is the arrival sequence number of the packet carrying the broadcast if ArrivalSequence is set, see
+	// HistoryEntry.ArrivalSeq. It is zero for broadcasts which weren't read from the connection, such as those of
+	// BroadcastSources or backfills.
+	ArrivalSeq uint64
+
+	// ReceivedAt is the local time at which the broadcast was received.
+	ReceivedAt time.Time
+
+	// ServerTime is the timestamp extracted from the message by the BroadcastTimestamps extractor. It is zero if no
+	// extractor is configured or the message didn't contain a timestamp.
+	ServerTime time.Time
+
+	// Skew is ReceivedAt minus ServerTime. It includes the delivery delay as well as the difference between the clocks.
+	// It is zero if ServerTime is zero.
+	Skew time.Duration
+
+	// Replayed is true if the broadcast was missed while disconnected, and recovered by BackfillBroadcasts after the
+	// client reconnected. ReceivedAt is the time it was recovered.
+	Replayed bool
+
+	// JSON holds the fields of the message if BroadcastJSON is set and the message is a JSON object. It is nil for
+	// messages which aren't JSON objects, which are delivered as they are. Messages which are JSON arrays can be
+	// unmarshalled with Decode.
+	JSON map[string]interface{}
+}
+
+// Decode unmarshals the message as JSON into v, which is typically a pointer to a struct describing the game's events,
+// or a pointer to a slice if the message is a JSON array. If the message isn't JSON, an error wrapping errs.ErrNotJSON
+// is returned.
+func (b Broadcast) Decode(v interface{}) error {
+	if !looksLikeJSON(b.Message) {
+		return errs.ErrNotJSON
+	}
+
+	if err := json.Unmarshal([]byte(b.Message), v); err != nil {
+		return errors.Wrap(errs.ErrNotJSON, err.Error())
+	}
+
+	return nil
+}
+
+// looksLikeJSON returns true if message could be a JSON object or array, which is cheaper to check than parsing it.
+func looksLikeJSON(message string) bool {
+	message = strings.TrimSpace(message)
+
+	return len(message) > 1 && (message[0] == '{' || message[0] == '[')
+}
+
+// looksLikeJSONObject returns true if message could be a JSON object. Unlike arrays, objects can be parsed into
+// Broadcast.JSON.
+func looksLikeJSONObject(message string) bool {
+	message = strings.TrimSpace(message)
+
+	return len(message) > 1 && message[0] == '{'
+}
+
+// BroadcastListener is a function which will be called with every broadcast after the BroadcastHandler.
+type BroadcastListener func(Broadcast)
+
+// TimestampExtractor extracts the server-side timestamp from a broadcast message. ok is false if the message doesn't
+// contain a timestamp.
+type TimestampExtractor interface {
+	Extract(message string, receivedAt time.Time) (ts time.Time, ok bool)
+}
+
+// TimestampExtractorFunc is an adapter allowing an ordinary function to be used as a TimestampExtractor.
+type TimestampExtractorFunc func(message string, receivedAt time.Time) (time.Time, bool)
+
+func (f TimestampExtractorFunc) Extract(message string, receivedAt time.Time) (time.Time, bool) {
+	return f(message, receivedAt)
+}
+
+// RegexpTimestampExtractor extracts timestamps using a regular expression. The timestamp is taken from the capture
+// group named "timestamp", or the first capture group if there is no such group, and parsed using Layout.
+//
+// Many games only log the time of day. If the parsed timestamp has no date, the date of the receive time is used, and
+// timestamps which would be more than 12 hours in the future are moved back a day to handle messages sent just before
+// midnight.
+type RegexpTimestampExtractor struct {
+	Pattern *regexp.Regexp
+	Layout  string
+
+	// Location is used for timestamps without a time zone.
+	//
+	// Default: time.Local
+	Location *time.Location
+
+	group int
+}
+
+// NewRegexpTimestampExtractor creates a RegexpTimestampExtractor. For example, to extract timestamps from
+// "[12:34:56] message" style broadcasts:
+//
+//	rcon.NewRegexpTimestampExtractor(regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2})\]`), "15:04:05", nil)
+func NewRegexpTimestampExtractor(pattern *regexp.Regexp, layout string, loc *time.Location) *RegexpTimestampExtractor {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	group := pattern.SubexpIndex("timestamp")
+	if group < 0 {
+		group = 1
+	}
+
+	return &RegexpTimestampExtractor{
+		Pattern:  pattern,
+		Layout:   layout,
+		Location: loc,
+		group:    group,
+	}
+}
+
+func (e *RegexpTimestampExtractor) Extract(message string, receivedAt time.Time) (time.Time, bool) {
+	match := e.Pattern.FindStringSubmatch(message)
+	if match == nil || e.group >= len(match) {
+		return time.Time{}, false
+	}
+
+	ts, err := time.ParseInLocation(e.Layout, match[e.group], e.Location)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	if ts.Year() == 0 {
+		local := receivedAt.In(ts.Location())
+		ts = time.Date(local.Year(), local.Month(), local.Day(), ts.Hour(), ts.Minute(), ts.Second(), ts.Nanosecond(),
+			ts.Location())
+
+		if ts.Sub(receivedAt) > 12*time.Hour {
+			ts = ts.AddDate(0, 0, -1)
+		}
+	}
+
+	return ts, true
+}
+
+// newBroadcast creates a Broadcast for a message received now, parsing it as JSON and extracting its timestamp if
+// configured to.
+func (c *Client) newBroadcast(message string) Broadcast {
+	b := Broadcast{
+		Message:    message,
+		ReceivedAt: time.Now(),
+	}
+
+	if c.BroadcastJSON && looksLikeJSONObject(message) {
+		if err := json.Unmarshal([]byte(message), &b.JSON); err != nil {
+			c.log.Debug("Broadcast is not valid JSON, delivering it as text. Error: ", err)
+			b.JSON = nil
+		}
+	}
+
+	if c.BroadcastTimestamps == nil {
+		return b
+	}
+
+	if ts, ok := c.BroadcastTimestamps.Extract(message, b.ReceivedAt); ok {
+		b.ServerTime = ts
+		b.Skew = b.ReceivedAt.Sub(ts)
+		c.stats.recordSkew(b.Skew)
+	}
+
+	return b
+}
@@ -0,0 +1,92 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestTimestamp(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("RegexpTimestampExtractor", func() {
+		received := time.Date(2021, 3, 4, 12, 0, 5, 0, time.UTC)
+
+		g.It("Should extract a timestamp from the named group", func() {
+			e := NewRegexpTimestampExtractor(
+				regexp.MustCompile(`^(\w+) (?P<timestamp>\S+)`), time.RFC3339, time.UTC)
+
+			ts, ok := e.Extract("Chat 2021-03-04T12:00:00Z hello", received)
+
+			Expect(ok).To(BeTrue())
+			Expect(received.Sub(ts)).To(Equal(5 * time.Second))
+		})
+
+		g.It("Should use the receive date for timestamps without a date", func() {
+			e := NewRegexpTimestampExtractor(regexp.MustCompile(`^\[(.+?)\]`), "15:04:05", time.UTC)
+
+			ts, ok := e.Extract("[11:59:00] hello", received)
+
+			Expect(ok).To(BeTrue())
+			Expect(ts).To(Equal(time.Date(2021, 3, 4, 11, 59, 0, 0, time.UTC)))
+		})
+
+		g.It("Should move timestamps from before midnight back a day", func() {
+			e := NewRegexpTimestampExtractor(regexp.MustCompile(`^\[(.+?)\]`), "15:04:05", time.UTC)
+
+			ts, ok := e.Extract("[23:59:59] hello", time.Date(2021, 3, 4, 0, 0, 1, 0, time.UTC))
+
+			Expect(ok).To(BeTrue())
+			Expect(ts).To(Equal(time.Date(2021, 3, 3, 23, 59, 59, 0, time.UTC)))
+		})
+
+		g.It("Should not extract anything from messages without a timestamp", func() {
+			e := NewRegexpTimestampExtractor(regexp.MustCompile(`^\[(.+?)\]`), "15:04:05", time.UTC)
+
+			_, ok := e.Extract("hello", received)
+
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	g.Describe("Broadcast JSON", func() {
+		g.It("Should parse JSON objects and fall back to text", func() {
+			c := NewClient(&Config{BroadcastJSON: true}, nil)
+
+			b := c.newBroadcast(`{"event":"kill","killer":"Bob"}`)
+			Expect(b.JSON).To(Equal(map[string]interface{}{"event": "kill", "killer": "Bob"}))
+
+			var event struct {
+				Event  string `json:"event"`
+				Killer string `json:"killer"`
+			}
+			Expect(b.Decode(&event)).To(Succeed())
+			Expect(event.Killer).To(Equal("Bob"))
+
+			b = c.newBroadcast("{Bob joined")
+			Expect(b.JSON).To(BeNil())
+			Expect(errors.Cause(b.Decode(&event))).To(Equal(errs.ErrNotJSON))
+		})
+
+		g.It("Should leave JSON arrays to Decode", func() {
+			c := NewClient(&Config{BroadcastJSON: true}, nil)
+
+			b := c.newBroadcast(`[{"event":"kill"},{"event":"join"}]`)
+			Expect(b.JSON).To(BeNil())
+
+			var events []struct {
+				Event string `json:"event"`
+			}
+			Expect(b.Decode(&events)).To(Succeed())
+			Expect(events).To(HaveLen(2))
+			Expect(events[1].Event).To(Equal("join"))
+		})
+	})
+}
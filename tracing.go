@@ -0,0 +1,87 @@
+package rcon
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Span names used by the client when tracing operations.
+const (
+	SpanConnect = "rcon.connect"
+	SpanExec    = "rcon.exec"
+)
+
+// Span attribute keys set by the client when tracing operations.
+const (
+	AttrServerAddress = "server.address"
+	AttrServerPort    = "server.port"
+	AttrCommandName   = "rcon.command.name"
+	AttrCommand       = "rcon.command"
+)
+
+// Tracer creates spans around client operations such as Connect and ExecCommand. It can be used to make RCON latency
+// show up in distributed traces. An OpenTelemetry implementation is available in the otelrcon module.
+type Tracer interface {
+	// StartSpan starts a new span with the provided name and attributes as a child of any span in ctx. The returned
+	// context must carry the new span.
+	StartSpan(ctx context.Context, name string, attributes map[string]string) (context.Context, Span)
+}
+
+// Span represents a single traced operation.
+type Span interface {
+	// End ends the span. If err is not nil, the span is marked as failed.
+	End(err error)
+}
+
+// CommandRedactor is a function which takes a command and returns a version of it which is safe to record in traces.
+type CommandRedactor func(command string) string
+
+// RedactArguments is the default CommandRedactor. It strips all arguments from the command, leaving only its name.
+func RedactArguments(command string) string {
+	return commandName(command)
+}
+
+// commandName returns the first word of a command.
+func commandName(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return fields[0]
+}
+
+type noopTracer struct{}
+
+func (noopTracer) StartSpan(ctx context.Context, _ string, _ map[string]string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End(error) {}
+
+// startSpan starts a span with the client's server attributes populated along with any extra attributes provided.
+func (c *Client) startSpan(ctx context.Context, name string, extra map[string]string) (context.Context, Span) {
+	host, port := c.Address()
+
+	attrs := map[string]string{
+		AttrServerAddress: host,
+		AttrServerPort:    strconv.Itoa(int(port)),
+	}
+
+	for k, v := range extra {
+		attrs[k] = v
+	}
+
+	return c.Tracer.StartSpan(ctx, name, attrs)
+}
+
+// commandAttributes returns the span attributes for a command, redacted using the configured CommandRedactor.
+func (c *Client) commandAttributes(command string) map[string]string {
+	return map[string]string{
+		AttrCommandName: commandName(command),
+		AttrCommand:     c.CommandRedactor(command),
+	}
+}
@@ -0,0 +1,52 @@
+package rcon
+
+import (
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Address scheme prefixes recognised in Config.Host.
+const (
+	// UnixScheme marks the host as a unix domain socket path, e.g. unix:///var/run/game.rcon. The port is ignored.
+	UnixScheme = "unix://"
+
+	// NamedPipeScheme marks the host as a Windows named pipe, e.g. npipe:////./pipe/game-rcon. The port is ignored.
+	//
+	// The standard library can't dial named pipes with deadline support, so DefaultTransport rejects them. Set
+	// Config.Transport to a transport which can, for example one built on github.com/Microsoft/go-winio's DialPipe.
+	NamedPipeScheme = "npipe://"
+)
+
+// Transport establishes the underlying connection to a server. The client performs authentication and packet framing on
+// top of the returned connection, so any stream transport can be used.
+type Transport interface {
+	Dial(host string, port uint16, timeout time.Duration) (net.Conn, error)
+}
+
+// TransportFunc is a function which implements Transport.
+type TransportFunc func(host string, port uint16, timeout time.Duration) (net.Conn, error)
+
+func (f TransportFunc) Dial(host string, port uint16, timeout time.Duration) (net.Conn, error) {
+	return f(host, port, timeout)
+}
+
+// DefaultTransport dials TCP, or a unix domain socket if the host has the UnixScheme prefix.
+var DefaultTransport Transport = TransportFunc(func(host string, port uint16, timeout time.Duration) (net.Conn, error) {
+	switch {
+	case strings.HasPrefix(host, UnixScheme):
+		return net.DialTimeout("unix", strings.TrimPrefix(host, UnixScheme), timeout)
+	case strings.HasPrefix(host, NamedPipeScheme):
+		return nil, errors.Wrap(errs.ErrUnsupportedTransport, "named pipes require a custom Transport")
+	default:
+		return net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))), timeout)
+	}
+})
+
+// isLocalAddress reports whether host is a unix socket or named pipe address, for which the port is unused.
+func isLocalAddress(host string) bool {
+	return strings.HasPrefix(host, UnixScheme) || strings.HasPrefix(host, NamedPipeScheme)
+}
@@ -0,0 +1,102 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("DefaultTransport", func() {
+		g.It("Should connect to unix domain sockets", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			dir, err := ioutil.TempDir("", "rcon")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			// The socket forwards connections to the test server.
+			path := filepath.Join(dir, "game.rcon")
+			l, err := net.Listen("unix", path)
+			Expect(err).ToNot(HaveOccurred())
+			defer l.Close()
+
+			host, port := s.Addr()
+			go func() {
+				for {
+					conn, err := l.Accept()
+					if err != nil {
+						return
+					}
+
+					upstream, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+					if err != nil {
+						_ = conn.Close()
+						continue
+					}
+
+					go func() {
+						_, _ = io.Copy(upstream, conn)
+						_ = upstream.Close()
+					}()
+					go func() {
+						_, _ = io.Copy(conn, upstream)
+						_ = conn.Close()
+					}()
+				}
+			}()
+
+			c := NewClient(&Config{Host: UnixScheme + path, Password: testPassword}, nil)
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+		})
+
+		g.It("Should reject named pipes", func() {
+			c := NewClient(&Config{Host: NamedPipeScheme + "//./pipe/game-rcon", Password: testPassword}, nil)
+
+			err := c.Connect()
+			Expect(errors.Cause(err)).To(Equal(errs.ErrUnsupportedTransport))
+			Expect(err.Error()).To(ContainSubstring("named pipes require a custom Transport"))
+			Expect(c.State()).To(Equal(StateDisconnected))
+		})
+	})
+
+	g.Describe("Transport", func() {
+		g.It("Should be used to establish the connection", func() {
+			s := newTestServer(echoHandler)
+			defer s.Close()
+
+			var dialed string
+			c := newTestClient(s, &Config{})
+			host, port := c.Host, c.Port
+			c.Host = NamedPipeScheme + "//./pipe/game-rcon"
+			c.Transport = TransportFunc(func(pipe string, _ uint16, timeout time.Duration) (net.Conn, error) {
+				dialed = pipe
+				return net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))), timeout)
+			})
+
+			Expect(c.Connect()).To(Succeed())
+			defer c.Close()
+
+			Expect(dialed).To(Equal(NamedPipeScheme + "//./pipe/game-rcon"))
+			Expect(c.ExecCommand("status")).To(Equal("echo: status"))
+		})
+	})
+}
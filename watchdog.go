@@ -0,0 +1,82 @@
+package rcon
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"time"
+)
+
+// runBroadcastWatchdog executes the SubscribeCommands and then watches broadcast activity until the connection is torn
+// down. If no broadcasts are received, it escalates from resubscribing to returning ErrBroadcastInactive, which tears
+// down the connection.
+func (c *Client) runBroadcastWatchdog(ctx context.Context) error {
+	defer c.log.Debug("Broadcast watchdog routine terminated")
+
+	c.runSubscribeCommands(ctx)
+
+	timeout := c.BroadcastInactivityTimeout
+	if timeout <= 0 {
+		return nil
+	}
+
+	since := time.Now()
+	resubscribed := false
+
+	for {
+		// Any broadcast resets the escalation.
+		if last := c.stats.lastBroadcast(); last.After(since) {
+			since = last
+			resubscribed = false
+		}
+
+		if wait := timeout - time.Since(since); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return nil
+			}
+
+			continue
+		}
+
+		// Broadcasts are discarded while paused, so their absence means nothing.
+		if c.Paused() {
+			since = time.Now()
+			continue
+		}
+
+		idle := time.Since(since)
+
+		if !resubscribed {
+			c.log.Info("No broadcasts received for ", idle.Round(time.Second), ", resubscribing")
+			c.events.emit(BroadcastInactiveEvent{Idle: idle, Action: InactivityResubscribe})
+
+			c.runSubscribeCommands(ctx)
+
+			since = time.Now()
+			resubscribed = true
+
+			continue
+		}
+
+		c.log.Error("No broadcasts received for ", idle.Round(time.Second), " after resubscribing, disconnecting")
+		c.events.emit(BroadcastInactiveEvent{Idle: idle, Action: InactivityReconnect})
+
+		return errors.Wrapf(errs.ErrBroadcastInactive, "no broadcasts received for %s", idle.Round(time.Second))
+	}
+}
+
+// runSubscribeCommands executes the SubscribeCommands. Failures are logged, since the subscriptions are retried by the
+// watchdog if broadcasts don't arrive.
+func (c *Client) runSubscribeCommands(ctx context.Context) {
+	for _, command := range c.SubscribeCommands {
+		if _, err := c.execCommandTraced(ctx, command, PriorityHigh, nil); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			c.log.Error("Subscribe command failed: ", command, " Error: ", err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package rcon
+
+import (
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/refractorgscm/rcon/errs"
+	"github.com/refractorgscm/rcon/packet"
+	"github.com/refractorgscm/rcon/rcontest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBroadcastWatchdog(t *testing.T) {
+	g := goblin.Goblin(t)
+
+	// Special hook for gomega
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("BroadcastInactivityTimeout", func() {
+		var lock sync.Mutex
+		var subscribes int
+		var s *rcontest.Server
+		var c *Client
+
+		g.BeforeEach(func() {
+			subscribes = 0
+			s = newTestServer(func(command string) string {
+				if command == "subscribe" {
+					lock.Lock()
+					subscribes++
+					lock.Unlock()
+				}
+
+				return ""
+			})
+
+			c = newTestClient(s, &Config{
+				SubscribeCommands:          []string{"subscribe"},
+				BroadcastInactivityTimeout: time.Millisecond * 100,
+				BroadcastChecker: func(p packet.Packet) bool {
+					return p.ID() == rcontest.BroadcastID
+				},
+				BroadcastHandler: func(string) {},
+			})
+		})
+
+		g.AfterEach(func() {
+			_ = c.Close()
+			_ = s.Close()
+		})
+
+		subscribeCount := func() int {
+			lock.Lock()
+			defer lock.Unlock()
+
+			return subscribes
+		}
+
+		g.It("Should resubscribe and then disconnect when no broadcasts arrive", func() {
+			events := recordEvents(c, EventBroadcastInactive)
+
+			Expect(c.Connect()).To(Succeed())
+			Eventually(subscribeCount).Should(Equal(1))
+
+			Eventually(c.State, time.Second).Should(Equal(StateDisconnected))
+			Expect(errors.Cause(c.Err())).To(Equal(errs.ErrBroadcastInactive))
+			Expect(subscribeCount()).To(Equal(2))
+
+			actions := []InactivityAction{}
+			for _, e := range events() {
+				actions = append(actions, e.(BroadcastInactiveEvent).Action)
+			}
+			Expect(actions).To(Equal([]InactivityAction{InactivityResubscribe, InactivityReconnect}))
+		})
+
+		g.It("Should not fire while broadcasts keep arriving", func() {
+			events := recordEvents(c, EventBroadcastInactive)
+
+			Expect(c.Connect()).To(Succeed())
+
+			stop := make(chan struct{})
+			defer close(stop)
+
+			go func() {
+				ticker := time.NewTicker(time.Millisecond * 20)
+				defer ticker.Stop()
+
+				for {
+					select {
+					case <-ticker.C:
+						s.Broadcast("tick")
+					case <-stop:
+						return
+					}
+				}
+			}()
+
+			Consistently(c.State, time.Millisecond*400).Should(Equal(StateConnected))
+			Expect(events()).To(BeEmpty())
+			Expect(subscribeCount()).To(Equal(1))
+		})
+	})
+}